@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+)
+
+const defaultAuditLogPath = "/var/log/terway/openapi-audit.log"
+
+var (
+	auditLogPath string
+	auditSince   time.Duration
+	auditFailed  bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [keyword]",
+	Short: "search the openAPI audit log.",
+	Long:  "search the openAPI audit log by eni, ip, pod, node, api or request id, all records are shown if keyword not specified.",
+	RunE:  runAudit,
+}
+
+func init() {
+	fs := auditCmd.Flags()
+	fs.StringVar(&auditLogPath, "file", defaultAuditLogPath, "audit log path, same as openapi_audit_log in eni config")
+	fs.DurationVar(&auditSince, "since", 0, "only show records newer than the duration, e.g. 1h")
+	fs.BoolVar(&auditFailed, "failed", false, "only show failed calls")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments")
+	}
+	keyword := ""
+	if len(args) == 1 {
+		keyword = args[0]
+	}
+
+	records, err := searchAudit(auditLogPath, keyword, auditSince, auditFailed)
+	if err != nil {
+		return err
+	}
+	return printAudit(cmd.OutOrStdout(), records)
+}
+
+// searchAudit read the log and the rotated backups, records are sorted by time
+func searchAudit(path, keyword string, since time.Duration, failedOnly bool) ([]*aliyunClient.AuditRecord, error) {
+	var after time.Time
+	if since > 0 {
+		after = time.Now().Add(-since)
+	}
+
+	var records []*aliyunClient.AuditRecord
+	for _, name := range aliyunClient.AuditFiles(path) {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = aliyunClient.SearchAudit(f, keyword, func(r *aliyunClient.AuditRecord) {
+			if r.Time.Before(after) {
+				return
+			}
+			if failedOnly && r.Error == "" {
+				return
+			}
+			records = append(records, r)
+		})
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+func printAudit(w io.Writer, records []*aliyunClient.AuditRecord) error {
+	data := pterm.TableData{
+		{"Time", "API", "RequestID", "Latency", "Caller", "Params", "Result"},
+	}
+	for _, r := range records {
+		var caller []string
+		for _, v := range []string{r.Controller, r.Node, r.Pod, r.ReconcileID} {
+			if v != "" {
+				caller = append(caller, v)
+			}
+		}
+		var params []string
+		for k, v := range r.Params {
+			params = append(params, k+"="+v)
+		}
+		sort.Strings(params)
+		result := "ok"
+		if r.Error != "" {
+			result = r.Error
+		}
+		data = append(data, []string{
+			r.Time.Local().Format(time.RFC3339),
+			r.API,
+			r.RequestID,
+			fmt.Sprintf("%.0fms", r.LatencyMs),
+			strings.Join(caller, ","),
+			strings.Join(params, " "),
+			result,
+		})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(data).WithWriter(w).Render()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	now := time.Now().UTC()
+	line := func(ts time.Time, api, eni, errMsg string) string {
+		return `{"time":"` + ts.Format(time.RFC3339Nano) + `","api":"` + api + `","params":{"NetworkInterfaceId":"` + eni + `"},"error":"` + errMsg + `"}` + "\n"
+	}
+	assert.NoError(t, os.WriteFile(path+".1", []byte(line(now.Add(-2*time.Hour), "CreateNetworkInterface", "eni-1", "")), 0600))
+	assert.NoError(t, os.WriteFile(path, []byte(
+		line(now.Add(-time.Minute), "AttachNetworkInterface", "eni-1", "")+
+			line(now, "AssignPrivateIpAddresses", "eni-2", "throttling")), 0600))
+
+	records, err := searchAudit(path, "eni-1", 0, false)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "CreateNetworkInterface", records[0].API)
+		assert.Equal(t, "AttachNetworkInterface", records[1].API)
+	}
+
+	records, err = searchAudit(path, "", time.Hour, false)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	records, err = searchAudit(path, "", 0, true)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "throttling", records[0].Error)
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printAudit(out, records))
+	assert.Contains(t, out.String(), "NetworkInterfaceId=eni-2")
+}
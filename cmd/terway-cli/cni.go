@@ -1,23 +1,28 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+
 	"github.com/Jeffail/gabs/v2"
 	"github.com/spf13/cobra"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	cliflag "k8s.io/component-base/cli/flag"
 
+	"github.com/AliyunContainerService/terway/pkg/probe"
 	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
 )
 
-type checkKernelVersionFunc func(int, int, int) bool
+type probeFeaturesFunc func() probe.Results
 
-var _checkKernelVersion checkKernelVersionFunc
+var _probeFeatures probeFeaturesFunc
 
 type switchDataPathV2Func func() bool
 
@@ -36,12 +41,19 @@ const (
 )
 
 type feature struct {
-	EBPF bool
-	EDT  bool
+	EBPF   bool
+	EDT    bool
+	IPVlan bool
 
 	EnableNetworkPolicy bool
+
+	// NetworkPriority the ingress priority config copied from the eni config to the terway plugin
+	NetworkPriority map[string]any
 }
 
+// networkPriorityKeys the eni config keys used by the cni plugin for ingress priority
+var networkPriorityKeys = []string{"network_priority_classes", "network_priority_bandwidth"}
+
 var (
 	outPutPath string
 
@@ -70,7 +82,7 @@ var cniCmd = &cobra.Command{
 func processCNIConfig(cmd *cobra.Command, args []string) error {
 	flag.Parse()
 
-	_checkKernelVersion = checkKernelVersion
+	_probeFeatures = probeFeatures
 
 	_switchDataPathV2 = switchDataPathV2
 
@@ -79,7 +91,9 @@ func processCNIConfig(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to set feature gates: %v", err)
 	}
 
-	err = processInput()
+	features := _probeFeatures()
+
+	err = processInput(features)
 	if err != nil {
 		return fmt.Errorf("failed process input: %v", err)
 	}
@@ -93,10 +107,17 @@ func processCNIConfig(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return storeRuntimeConfig(nodeCapabilitiesFile, cniJSON)
+	return storeRuntimeConfig(nodeCapabilitiesFile, cniJSON, features)
+}
+
+func probeFeatures() probe.Results {
+	log := funcr.New(func(prefix, args string) {
+		fmt.Println(prefix, args)
+	}, funcr.Options{})
+	return probe.Probe(logr.NewContext(context.Background(), log))
 }
 
-func processInput() error {
+func processInput(features probe.Results) error {
 	cm, err := getAllConfig(eniConfBasePath)
 	if err != nil {
 		return err
@@ -121,17 +142,17 @@ func processInput() error {
 	}
 
 	f := feature{}
-	f.EBPF = _checkKernelVersion(4, 19, 0)
-
-	if f.EBPF {
-		f.EDT, err = checkBpfFeature("bpf_skb_ecn_set_ce")
-		if err != nil {
-			return err
-		}
-	}
+	f.EBPF = features.EBPF()
+	f.EDT = f.EBPF && features[probe.EDT]
+	f.IPVlan = features.IPVlan()
 
 	f.EnableNetworkPolicy = cm.enableNetworkPolicy
 
+	f.NetworkPriority, err = networkPriorityConfig(cm.eniConfig)
+	if err != nil {
+		return err
+	}
+
 	out, err := mergeConfigList(configs, &f)
 	if err != nil {
 		return err
@@ -140,13 +161,18 @@ func processInput() error {
 	return os.WriteFile(outPutPath, []byte(out), 0644)
 }
 
-func checkBpfFeature(key string) (bool, error) {
-	out, err := exec.Command("bpftool", "-j", "feature", "probe").CombinedOutput()
+func networkPriorityConfig(eniConfig []byte) (map[string]any, error) {
+	eniConf, err := gabs.ParseJSON(eniConfig)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("error parse eni_conf, %w", err)
 	}
-
-	return strings.Contains(string(out), key), nil
+	r := make(map[string]any)
+	for _, key := range networkPriorityKeys {
+		if eniConf.Exists(key) {
+			r[key] = eniConf.Path(key).Data()
+		}
+	}
+	return r, nil
 }
 
 func mergeConfigList(configs [][]byte, f *feature) (string, error) {
@@ -199,6 +225,13 @@ func mergeConfigList(configs [][]byte, f *feature) (string, error) {
 			}
 
 		case pluginTypeTerway:
+			for key, val := range f.NetworkPriority {
+				_, err = plugin.Set(val, key)
+				if err != nil {
+					return "", err
+				}
+			}
+
 			if plugin.Exists("network_policy_provider") {
 				networkPolicyProvider, ok = plugin.Path("network_policy_provider").Data().(string)
 				if !ok {
@@ -231,6 +264,9 @@ func mergeConfigList(configs [][]byte, f *feature) (string, error) {
 
 					if _switchDataPathV2() {
 						datapath = dataPathV2
+					} else if !f.IPVlan {
+						fmt.Println("ipvlan is not supported, fall back to veth")
+						datapath = dataPathVeth
 					}
 				case dataPathV2:
 					datapath = dataPathV2
@@ -305,12 +341,13 @@ func mountHostBpf() error {
 	return nil
 }
 
-func storeRuntimeConfig(filePath string, container *gabs.Container) error {
+func storeRuntimeConfig(filePath string, container *gabs.Container, features probe.Results) error {
 	store := nodecap.NewFileNodeCapabilities(filePath)
 	err := store.Load()
 	if err != nil {
 		return err
 	}
+	features.Store(store)
 
 	hasCilium := false
 	// write back current runtime config
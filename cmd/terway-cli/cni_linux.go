@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/docker/docker/pkg/parsers/kernel"
 	"github.com/vishvananda/netlink"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 
@@ -60,10 +59,6 @@ func allowEBPFNetworkPolicy(require bool) (bool, error) {
 	return require, nil
 }
 
-func checkKernelVersion(k, major, minor int) bool {
-	return kernel.CheckKernelVersion(k, major, minor)
-}
-
 func isOldNode() (bool, error) {
 	_, err := netlink.LinkByName("cilium_net")
 	if err == nil {
@@ -47,8 +47,9 @@ func Test_mergeConfigList_ipvl(t *testing.T) {
             },
             "externalSetMarkChain":"KUBE-MARK-MASQ"
         }`)}, &feature{
-		EBPF: true,
-		EDT:  true,
+		EBPF:   true,
+		EDT:    true,
+		IPVlan: true,
 	})
 	assert.NoError(t, err)
 
@@ -81,8 +82,9 @@ func Test_mergeConfigList_ipvl_exist(t *testing.T) {
             },
             "externalSetMarkChain":"KUBE-MARK-MASQ"
         }`)}, &feature{
-		EBPF: true,
-		EDT:  true,
+		EBPF:   true,
+		EDT:    true,
+		IPVlan: true,
 	})
 	assert.NoError(t, err)
 
@@ -129,6 +131,30 @@ func Test_mergeConfigList_ipvl_unsupport(t *testing.T) {
 	assert.Equal(t, "portmap", g.Path("plugins.1.type").Data())
 }
 
+func Test_mergeConfigList_ipvl_fallback_veth(t *testing.T) {
+	_switchDataPathV2 = func() bool {
+		return false
+	}
+	out, err := mergeConfigList([][]byte{
+		[]byte(`{
+            "type":"terway",
+            "foo":"bar",
+            "eniip_virtual_type": "ipvlan"
+        }`)}, &feature{
+		EBPF:   true,
+		EDT:    true,
+		IPVlan: false,
+	})
+	assert.NoError(t, err)
+
+	g, err := gabs.ParseJSON([]byte(out))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "veth", g.Path("plugins.0.eniip_virtual_type").Data())
+	assert.Equal(t, "tc", g.Path("plugins.0.bandwidth_mode").Data())
+	assert.Equal(t, false, g.ExistsP("plugins.1"))
+}
+
 func Test_mergeConfigList_migrate_datapathv2(t *testing.T) {
 	_switchDataPathV2 = func() bool {
 		return true
@@ -269,3 +295,29 @@ func TestVethToDatapathV2(t *testing.T) {
 	assert.Equal(t, "datapathv2", g.Path("plugins.0.eniip_virtual_type").Data())
 	assert.Equal(t, "cilium-cni", g.Path("plugins.1.type").Data())
 }
+
+func Test_mergeConfigList_networkPriority(t *testing.T) {
+	_switchDataPathV2 = func() bool {
+		return false
+	}
+	networkPriority, err := networkPriorityConfig([]byte(`{
+		"version": "1",
+		"network_priority_classes": {"guaranteed": {"weight": 4, "rate_share": 60}},
+		"network_priority_bandwidth": 125000000
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, networkPriority, 2)
+
+	out, err := mergeConfigList([][]byte{
+		[]byte(`{
+            "type":"terway",
+            "enable_network_priority": true
+        }`)}, &feature{NetworkPriority: networkPriority})
+	assert.NoError(t, err)
+
+	g, err := gabs.ParseJSON([]byte(out))
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(60), g.Path("plugins.0.network_priority_classes.guaranteed.rate_share").Data())
+	assert.Equal(t, float64(125000000), g.Path("plugins.0.network_priority_bandwidth").Data())
+}
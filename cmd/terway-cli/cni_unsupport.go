@@ -6,10 +6,6 @@ func switchDataPathV2() bool {
 	return true
 }
 
-func checkKernelVersion(k, major, minor int) bool {
-	return false
-}
-
 func allowEBPFNetworkPolicy(enable bool) (bool, error) {
 	return enable, nil
 }
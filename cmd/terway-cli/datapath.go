@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+var (
+	datapathCmd = &cobra.Command{
+		Use:   "datapath",
+		Short: "migrate the running pods to the datapath of current cni config.",
+		Long: "migrate the running pods on shared eni between veth, datapathv2 and ipvlan, " +
+			"pods are migrated one by one and the migration stops at the first failure.",
+	}
+
+	datapathMigrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "start migrating the running pods to the datapath of current cni config.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDataPathCommand(cmd.OutOrStdout(), "migrate")
+		},
+	}
+
+	datapathRollbackCmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "start moving the migrated pods back to the previous datapath.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDataPathCommand(cmd.OutOrStdout(), "rollback")
+		},
+	}
+
+	datapathStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "show the progress of the migration.",
+		RunE:  runDataPathStatus,
+	}
+)
+
+func init() {
+	datapathCmd.AddCommand(datapathMigrateCmd, datapathRollbackCmd, datapathStatusCmd)
+}
+
+// executeNetworkService run the command of the network service, the output is returned
+func executeNetworkService(command string) (string, error) {
+	stream, err := client.ResourceExecute(ctx, &rpc.ResourceExecuteRequest{
+		Type:    tracing.ResourceTypeNetworkService,
+		Name:    "default",
+		Command: command,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	out := &strings.Builder{}
+	for {
+		message, err := stream.Recv()
+		if err == io.EOF {
+			return out.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(message.Message)
+	}
+}
+
+func runDataPathCommand(w io.Writer, command string) error {
+	out, err := executeNetworkService(command)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, out)
+	return err
+}
+
+func runDataPathStatus(cmd *cobra.Command, args []string) error {
+	out, err := executeNetworkService("migration")
+	if err != nil {
+		return err
+	}
+	status := &daemon.DataPathMigrationStatus{}
+	err = json.Unmarshal([]byte(out), status)
+	if err != nil {
+		return fmt.Errorf("error parse migration status, %s", strings.TrimSpace(out))
+	}
+	return printDataPathStatus(cmd.OutOrStdout(), status)
+}
+
+func printDataPathStatus(w io.Writer, status *daemon.DataPathMigrationStatus) error {
+	state := "idle"
+	if status.Running {
+		state = "running"
+	}
+	_, err := fmt.Fprintf(w, "migration: %s\n", state)
+	if err != nil || len(status.Records) == 0 {
+		return err
+	}
+
+	data := pterm.TableData{
+		{"Time", "Pod", "From", "To", "State", "Message"},
+	}
+	for _, r := range status.Records {
+		data = append(data, []string{
+			r.Time.Local().Format(time.RFC3339),
+			r.Pod,
+			r.From,
+			r.To,
+			r.State,
+			r.Message,
+		})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(data).WithWriter(w).Render()
+}
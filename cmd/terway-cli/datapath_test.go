@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func Test_printDataPathStatus(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, printDataPathStatus(out, &daemon.DataPathMigrationStatus{}))
+	assert.Equal(t, "migration: idle\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printDataPathStatus(out, &daemon.DataPathMigrationStatus{
+		Running: true,
+		Records: []daemon.DataPathMigration{
+			{Pod: "default/foo", From: "veth", To: "ipvlan", State: daemon.DataPathMigrationFailed, Message: "health check failed", Time: time.Now()},
+		},
+	}))
+	assert.Contains(t, out.String(), "migration: running")
+	assert.Contains(t, out.String(), "default/foo")
+	assert.Contains(t, out.String(), "health check failed")
+}
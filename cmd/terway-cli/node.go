@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -13,6 +14,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/AliyunContainerService/terway/pkg/probe"
 	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
 	"github.com/AliyunContainerService/terway/pkg/version"
 	"github.com/AliyunContainerService/terway/types"
@@ -56,6 +58,10 @@ var tasks = []Task{
 		Name: "dual stack",
 		Func: dualStack,
 	},
+	{
+		Name: "feature labels",
+		Func: setFeatureLabels,
+	},
 }
 
 var nodeconfigCmd = &cobra.Command{
@@ -93,6 +99,16 @@ func getENIConfig(cmd *cobra.Command, args []string) error {
 }
 
 func overrideCNI(cmd *cobra.Command, args []string) error {
+	_, node, err := getNode()
+	if err != nil {
+		return err
+	}
+
+	store := nodecap.NewFileNodeCapabilities(nodeCapabilitiesFile)
+	return setExclusiveMode(store, node.Labels, cniFilePath)
+}
+
+func getNode() (k8sClient.Client, *corev1.Node, error) {
 	restConfig := ctrl.GetConfigOrDie()
 	restConfig.UserAgent = version.UA
 	c, err := k8sClient.New(restConfig, k8sClient.Options{
@@ -100,7 +116,7 @@ func overrideCNI(cmd *cobra.Command, args []string) error {
 		Mapper: types.NewRESTMapper(),
 	})
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	node := &corev1.Node{}
@@ -117,11 +133,9 @@ func overrideCNI(cmd *cobra.Command, args []string) error {
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("get node %s error: %w", nodeName, err)
+		return nil, nil, fmt.Errorf("get node %s error: %w", nodeName, err)
 	}
-
-	store := nodecap.NewFileNodeCapabilities(nodeCapabilitiesFile)
-	return setExclusiveMode(store, node.Labels, cniFilePath)
+	return c, node, nil
 }
 
 func setExclusiveMode(store nodecap.NodeCapabilitiesStore, labels map[string]string, cniPath string) error {
@@ -179,3 +193,40 @@ func dualStack(cmd *cobra.Command, args []string) error {
 	store.Set(nodecap.NodeCapabilityIPv6, val)
 	return store.Save()
 }
+
+// setFeatureLabels expose the kernel features probed by the cni command as node labels
+func setFeatureLabels(cmd *cobra.Command, args []string) error {
+	store := nodecap.NewFileNodeCapabilities(nodeCapabilitiesFile)
+	err := store.Load()
+	if err != nil {
+		return err
+	}
+	labels := featureLabels(probe.Load(store))
+	if len(labels) == 0 {
+		return nil
+	}
+
+	c, node, err := getNode()
+	if err != nil {
+		return err
+	}
+
+	update := node.DeepCopy()
+	if update.Labels == nil {
+		update.Labels = make(map[string]string)
+	}
+	for k, v := range labels {
+		update.Labels[k] = v
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	return c.Patch(ctx, update, k8sClient.MergeFrom(node))
+}
+
+func featureLabels(features probe.Results) map[string]string {
+	labels := make(map[string]string, len(features))
+	for f, ok := range features {
+		labels[types.NodeFeatureLabelPrefix+string(f)] = strconv.FormatBool(ok)
+	}
+	return labels
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/AliyunContainerService/terway/pkg/probe"
 	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
 )
 
@@ -92,3 +93,11 @@ func TestExclusiveModeDoesNotWriteCNIConfigWhenNotSet(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, os.IsNotExist(err))
 }
+
+func TestFeatureLabels(t *testing.T) {
+	labels := featureLabels(probe.Results{probe.IPVlanL2: true, probe.EDT: false})
+	assert.Equal(t, map[string]string{
+		"feature.k8s.aliyun.com/ipvlan_l2": "true",
+		"feature.k8s.aliyun.com/edt":       "false",
+	}, labels)
+}
@@ -45,23 +45,28 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	wh "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	aliyun "github.com/AliyunContainerService/terway/pkg/aliyun/client"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/credential"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/quota"
 	"github.com/AliyunContainerService/terway/pkg/apis/crds"
 	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	"github.com/AliyunContainerService/terway/pkg/cert"
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/all"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
 	multiipnode "github.com/AliyunContainerService/terway/pkg/controller/multi-ip/node"
 	multiippod "github.com/AliyunContainerService/terway/pkg/controller/multi-ip/pod"
 	"github.com/AliyunContainerService/terway/pkg/controller/node"
 	"github.com/AliyunContainerService/terway/pkg/controller/preheating"
+	"github.com/AliyunContainerService/terway/pkg/controller/shard"
 	"github.com/AliyunContainerService/terway/pkg/controller/webhook"
 	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/pkg/utils/k8sclient"
 	"github.com/AliyunContainerService/terway/pkg/version"
@@ -81,7 +86,8 @@ func init() {
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(networkv1beta1.AddToScheme(scheme))
 
-	metrics.Registry.MustRegister(metric.OpenAPILatency)
+	metrics.Registry.MustRegister(metric.OpenAPILatency, metric.OpenAPIThrottleTotal, metric.OpenAPIEffectiveQPS)
+	metrics.Registry.MustRegister(metric.CredentialProvider, metric.CredentialExpiration)
 }
 
 func main() {
@@ -151,6 +157,7 @@ func main() {
 	if string(cfg.Credential.AccessKey) != "" && string(cfg.Credential.AccessSecret) != "" {
 		providers = append(providers, credential.NewAKPairProvider(string(cfg.Credential.AccessKey), string(cfg.Credential.AccessSecret)))
 	}
+	providers = append(providers, credential.NewOIDCProvider(cfg.RegionID))
 	providers = append(providers, credential.NewEncryptedCredentialProvider(cfg.CredentialPath))
 	providers = append(providers, credential.NewMetadataProvider())
 
@@ -212,6 +219,92 @@ func main() {
 		TracerProvider: tp,
 	}
 
+	if *cfg.EnableTrunk && cfg.MemberENIPoolMaxSize > 0 {
+		metrics.Registry.MustRegister(enipool.PoolRequestTotal, enipool.PoolIdleCount)
+
+		ctrlCtx.ENIPool = enipool.NewPool(aliyunClient, mgr.GetClient(), enipool.Options{
+			VPCID:     cfg.VPCID,
+			ClusterID: cfg.ClusterID,
+			MinSize:   cfg.MemberENIPoolMinSize,
+			MaxSize:   cfg.MemberENIPoolMaxSize,
+		})
+	}
+
+	// identity of the replica, kept across container restarts
+	identity, err := os.Hostname()
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.EnableMultiIPShard {
+		leaseDuration, err := time.ParseDuration(cfg.MultiIPShardLeaseDuration)
+		if err != nil {
+			panic(err)
+		}
+		renewPeriod, err := time.ParseDuration(cfg.MultiIPShardRenewPeriod)
+		if err != nil {
+			panic(err)
+		}
+		metrics.Registry.MustRegister(shard.ShardMembers)
+
+		ctrlCtx.Shard = shard.New(k8sclient.K8sClient, shard.Options{
+			Namespace:     cfg.ControllerNamespace,
+			Group:         cfg.ControllerName + "-shard",
+			Identity:      identity,
+			LeaseDuration: leaseDuration,
+			RenewPeriod:   renewPeriod,
+		})
+		err = mgr.Add(ctrlCtx.Shard)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// the replicas creating resources share the store, each one resolves the pending keys of its own.
+	// Without sharding, only the leader creates resources.
+	keyGen := aliyun.NewPersistentIdempotentKeyGenerator(storage.NewConfigMapStorage(k8sclient.K8sClient, cfg.ControllerNamespace, cfg.ControllerName+"-idempotent-keys", aliyun.SerializeIntent, aliyun.DeserializeIntent), identity)
+	aliyunClient.IdempotentKeyGen = keyGen
+	startAt := time.Now()
+	err = mgr.Add(&intentResolver{
+		needLeaderElection: !cfg.EnableMultiIPShard,
+		RunnableFunc: func(ctx context.Context) error {
+			innerErr := keyGen.Resolve(ctx, aliyunClient, startAt)
+			if innerErr != nil {
+				log.Error(innerErr, "failed to resolve pending idempotent keys")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	var auditSinks aliyun.MultiAuditSink
+	if cfg.OpenAPIAuditLog != "" {
+		fileSink, err := aliyun.NewFileAuditSink(cfg.OpenAPIAuditLog, 0, 0)
+		if err != nil {
+			panic(err)
+		}
+		auditSinks = append(auditSinks, fileSink)
+	}
+	if cfg.OpenAPIAuditEvent {
+		auditSinks = append(auditSinks, aliyun.NewEventAuditSink(mgr.GetEventRecorderFor(cfg.ControllerName)))
+	}
+	if len(auditSinks) > 0 {
+		aliyunClient.Audit = auditSinks
+	}
+
+	if len(cfg.OpenAPIQuota) > 0 {
+		period, err := time.ParseDuration(cfg.OpenAPIQuotaSyncPeriod)
+		if err != nil {
+			panic(err)
+		}
+		err = mgr.Add(quota.NewDistributor(k8sclient.K8sClient, cfg.ControllerNamespace, cfg.OpenAPIQuota, aliyunClient.RateLimiter, period))
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	for name := range register.Controllers {
 		if controlplane.IsControllerEnabled(name, register.Controllers[name].Enable, cfg.Controllers) {
 			err = register.Controllers[name].Creator(mgr, ctrlCtx)
@@ -383,3 +476,14 @@ func detectMultiIP(ctx context.Context, directClient client.Client, cfg *control
 	log.Info("daemon is not at crd mode, disable v2 ipam")
 	return nil
 }
+
+// intentResolver resolve the pending idempotent keys, on every replica if the replicas create resources
+type intentResolver struct {
+	manager.RunnableFunc
+
+	needLeaderElection bool
+}
+
+func (r *intentResolver) NeedLeaderElection() bool {
+	return r.needLeaderElection
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/aliyun/credential"
 	eni2 "github.com/AliyunContainerService/terway/pkg/aliyun/eni"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/instance"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/quota"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	"github.com/AliyunContainerService/terway/pkg/eni"
 	"github.com/AliyunContainerService/terway/pkg/factory"
@@ -23,6 +24,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/pkg/utils/k8sclient"
 	vswpool "github.com/AliyunContainerService/terway/pkg/vswitch"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
@@ -155,6 +157,7 @@ func (b *NetworkServiceBuilder) setupAliyunClient() error {
 	if string(b.config.AccessID) != "" && string(b.config.AccessSecret) != "" {
 		providers = append(providers, credential.NewAKPairProvider(string(b.config.AccessID), string(b.config.AccessSecret)))
 	}
+	providers = append(providers, credential.NewOIDCProvider(meta.RegionID))
 	providers = append(providers, credential.NewEncryptedCredentialProvider(utils.NormalizePath(b.config.CredentialPath)))
 	providers = append(providers, credential.NewMetadataProvider())
 
@@ -168,6 +171,42 @@ func (b *NetworkServiceBuilder) setupAliyunClient() error {
 		return err
 	}
 	b.aliyunClient = aliyunClient
+	b.ctx = client.WithAuditCaller(b.ctx, client.AuditCaller{Controller: auditController, Node: b.service.k8s.NodeName()})
+
+	var auditSinks client.MultiAuditSink
+	if b.config.OpenAPIAuditLog != "" {
+		fileSink, err := client.NewFileAuditSink(utils.NormalizePath(b.config.OpenAPIAuditLog), 0, 0)
+		if err != nil {
+			return fmt.Errorf("error open openapi audit log: %w", err)
+		}
+		auditSinks = append(auditSinks, fileSink)
+	}
+	if b.config.OpenAPIAuditEvent {
+		auditSinks = append(auditSinks, &auditEventSink{k8s: b.service.k8s})
+	}
+	if len(auditSinks) > 0 {
+		aliyunClient.Audit = auditSinks
+	}
+
+	keyStore, err := storage.NewDiskStorage(idempotentKeyDBName, utils.NormalizePath(idempotentKeyDBPath), client.SerializeIntent, client.DeserializeIntent)
+	if err != nil {
+		return fmt.Errorf("error init idempotent key store: %w", err)
+	}
+	// the store is on the node, not shared
+	keyGen := client.NewPersistentIdempotentKeyGenerator(keyStore, "")
+	aliyunClient.IdempotentKeyGen = keyGen
+	err = keyGen.Resolve(b.ctx, aliyunClient, time.Now())
+	if err != nil {
+		serviceLog.Error(err, "failed to resolve pending idempotent keys")
+	}
+
+	if b.config.OpenAPIQuotaCoordination {
+		namespace := os.Getenv("POD_NAMESPACE")
+		if namespace == "" {
+			namespace = "kube-system"
+		}
+		go quota.NewReceiver(k8sclient.K8sClient, namespace, aliyunClient.RateLimiter, time.Minute).Run(b.ctx)
+	}
 
 	return nil
 }
@@ -205,6 +244,14 @@ func (b *NetworkServiceBuilder) initInstanceLimit() error {
 		b.limit = limit
 	}
 
+	// limits from the catalogue may not carry the trunk or erdma quota, wait for the openAPI instead of disabling the feature
+	if b.config.EnableENITrunking && b.limit.TrunkUnknown {
+		return fmt.Errorf("trunk quota of instance type %s is unknown", b.limit.InstanceTypeID)
+	}
+	if b.config.EnableERDMA && b.limit.ERdmaUnknown {
+		return fmt.Errorf("erdma quota of instance type %s is unknown", b.limit.InstanceTypeID)
+	}
+
 	b.service.enableIPv4, b.service.enableIPv6 = checkInstance(b.limit, b.daemonMode, b.config)
 	return nil
 }
@@ -220,6 +267,7 @@ func (b *NetworkServiceBuilder) setupENIManager() error {
 	eniConfig := getENIConfig(b.config)
 	eniConfig.EnableIPv4 = enableIPv4
 	eniConfig.EnableIPv6 = enableIPv6
+	eniConfig.NodeName = b.service.k8s.NodeName()
 
 	// fall back to use primary eni's sg
 	if len(eniConfig.SecurityGroupIDs) == 0 {
@@ -318,6 +366,16 @@ func (b *NetworkServiceBuilder) setupENIManager() error {
 
 	podResources = filterENINotFound(podResources, attachedENIID)
 
+	enableEgressGateway := b.daemonMode == daemon.ModeENIMultiIP && b.config.IPAMType != types.IPAMTypeCRD
+	if enableEgressGateway {
+		records, err := b.service.initEgressGateway(attachedENIID)
+		if err != nil {
+			return err
+		}
+		// the reserved ips are restored to the eni pool as pods
+		podResources = append(podResources, records...)
+	}
+
 	err = preStartResourceManager(b.daemonMode, b.service.k8s)
 	if err != nil {
 		return err
@@ -365,6 +423,19 @@ func (b *NetworkServiceBuilder) setupENIManager() error {
 		//start gc loop
 		go b.service.startGarbageCollectionLoop(b.ctx)
 	}
+	if b.daemonMode == daemon.ModeENIMultiIP {
+		go b.service.startPodQoSController(b.ctx)
+
+		err = b.service.initDataPathMigration()
+		if err != nil {
+			return err
+		}
+	}
+	b.service.hostStackCIDRs = b.config.HostStackCIDRs
+	go b.service.startHostStackCIDRSync(b.ctx)
+	if enableEgressGateway {
+		go b.service.startEgressGatewaySync(b.ctx)
+	}
 	return nil
 }
 
@@ -402,6 +473,9 @@ func (b *NetworkServiceBuilder) PostInitForCRDV2() *NetworkServiceBuilder {
 
 	svc := b.RunENIMgr(b.ctx, mgr)
 	go b.service.startGarbageCollectionLoop(b.ctx)
+	go b.service.startPodQoSController(b.ctx)
+	b.service.hostStackCIDRs = b.config.HostStackCIDRs
+	go b.service.startHostStackCIDRSync(b.ctx)
 
 	return svc
 }
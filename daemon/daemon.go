@@ -7,8 +7,13 @@ import (
 	"fmt"
 	"net/netip"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -21,12 +26,14 @@ import (
 
 	"github.com/AliyunContainerService/terway/deviceplugin"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/metadata"
 
 	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/eni"
 	"github.com/AliyunContainerService/terway/pkg/factory"
 	"github.com/AliyunContainerService/terway/pkg/k8s"
 	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/probe"
 	"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/pkg/utils"
@@ -57,6 +64,13 @@ const (
 	IfEth0 = "eth0"
 
 	envEFLO = "eflo"
+
+	// idempotentKeyDBPath is the store of the idempotent keys in flight
+	idempotentKeyDBPath = "/var/lib/cni/terway/token.db"
+	idempotentKeyDBName = "tokens"
+
+	// auditController is the caller name of the openAPI calls made by the daemon
+	auditController = "terway-daemon"
 )
 
 type networkService struct {
@@ -74,6 +88,18 @@ type networkService struct {
 
 	ipamType types.IPAMType
 
+	// hostStackCIDRs is served to cni, reloaded from config
+	hostStackCIDRs []string
+
+	// egressGatewayDB store the ips reserved for egress gateways, nil if egress gateway is not supported
+	egressGatewayDB     storage.Storage
+	egressGatewayLock   sync.Mutex
+	egressGatewayPodIPs sets.Set[string]
+
+	// migrationDB store the datapath migration records, nil if the migration is not supported
+	migrationDB      storage.Storage
+	migrationRunning atomic.Bool
+
 	wg sync.WaitGroup
 
 	gcRulesOnce sync.Once
@@ -105,6 +131,7 @@ func (n *networkService) deletePodResource(info *daemon.PodInfo) error {
 
 func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*rpc.AllocIPReply, error) {
 	podID := utils.PodInfoKey(r.K8SPodNamespace, r.K8SPodName)
+	ctx = client.WithAuditCaller(ctx, client.AuditCaller{Controller: auditController, Node: n.k8s.NodeName(), Pod: podID})
 	l := logf.FromContext(ctx)
 	l.Info("alloc ip req")
 
@@ -246,11 +273,13 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 			c.BasicInfo = &rpc.BasicInfo{}
 		}
 		c.BasicInfo.ServiceCIDR = n.k8s.GetServiceCIDR().ToRPC()
+		c.BasicInfo.HostStackCIDRs = n.hostStackCIDRs
 
 		c.Pod = &rpc.Pod{
 			Ingress:         pod.TcIngress,
 			Egress:          pod.TcEgress,
 			NetworkPriority: pod.NetworkPriority,
+			NAT64:           pod.NAT64,
 		}
 	}
 
@@ -286,6 +315,25 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 		return nil, err
 	}
 
+	if n.egressGatewayChanged(pod, networkResource) {
+		// errors of other gateways are left to the periodic sync, only the gateway of this pod is required
+		failed, syncErr := n.syncEgressGateways(ctx)
+		if syncErr != nil {
+			l.Error(syncErr, "error sync egress gateways")
+		}
+		for _, gwPod := range egressGatewayPods([]daemon.PodResources{newRes}) {
+			if failed != nil && !failed.Has(gwPod.IP) {
+				continue
+			}
+			err = fmt.Errorf("error set egress gateway %s, %w", gwPod.Gateway.Name, syncErr)
+			_ = n.deletePodResource(pod)
+			_ = n.eniMgr.Release(ctx, cni, &eni.ReleaseRequest{
+				NetworkResources: resp,
+			})
+			return nil, err
+		}
+	}
+
 	reply.NetConfs = netConf
 	reply.Success = true
 
@@ -294,6 +342,7 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 
 func (n *networkService) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (*rpc.ReleaseIPReply, error) {
 	podID := utils.PodInfoKey(r.K8SPodNamespace, r.K8SPodName)
+	ctx = client.WithAuditCaller(ctx, client.AuditCaller{Controller: auditController, Node: n.k8s.NodeName(), Pod: podID})
 	l := logf.FromContext(ctx)
 	l.Info("release ip req")
 
@@ -501,6 +550,21 @@ func (n *networkService) RecordEvent(_ context.Context, r *rpc.EventRequest) (*r
 	return reply, nil
 }
 
+// auditEventSink emit the openAPI audit records on the pod or this node
+type auditEventSink struct {
+	k8s k8s.Kubernetes
+}
+
+func (s *auditEventSink) Write(r *client.AuditRecord) {
+	if r.Pod != "" {
+		namespace, name, _ := strings.Cut(r.Pod, "/")
+		if s.k8s.RecordPodEvent(name, namespace, r.EventType(), r.EventReason(), r.EventMessage()) == nil {
+			return
+		}
+	}
+	s.k8s.RecordNodeEvent(r.EventType(), r.EventReason(), r.EventMessage())
+}
+
 func (n *networkService) verifyPodNetworkType(podNetworkMode string) bool {
 	return (n.daemonMode == daemon.ModeENIMultiIP && podNetworkMode == daemon.PodNetworkTypeENIMultiIP) || // eni-multi-ip
 		// eni-only
@@ -643,6 +707,15 @@ func (n *networkService) gcPods(ctx context.Context) error {
 		})
 	}
 
+	// the ifb is left when the eni is detached before the pods on it are torn down
+	conf, err := loadCNIConf(filepath.Join(tmpCNIConfigPath, cinConfFile))
+	if err == nil && conf.EnableNetworkPriority {
+		err = gcIngressPriority(ctx)
+		if err != nil {
+			serviceLog.Error(err, "error gc ingress priority")
+		}
+	}
+
 	// clean runtime node records
 	err = n.cleanRuntimeNode(ctx, uidInLocal)
 	if err != nil {
@@ -694,6 +767,16 @@ func (n *networkService) cleanRuntimeNode(ctx context.Context, localUIDs sets.Se
 			}
 		}
 	}
+	// report the enis for drift detection, the time is updated only if changed
+	enis, err := runtimeNetworkInterfaces(n.enableIPv4, n.enableIPv6)
+	if err != nil {
+		l.Error(err, "error read enis from metadata")
+	} else if nodeRuntime.Status.NetworkInterfacesUpdateTime == nil || !reflect.DeepEqual(enis, nodeRuntime.Status.NetworkInterfaces) {
+		now := metav1.Now()
+		nodeRuntime.Status.NetworkInterfaces = enis
+		nodeRuntime.Status.NetworkInterfacesUpdateTime = &now
+	}
+
 	update := nodeRuntime.DeepCopy()
 	_, err = controllerutil.CreateOrPatch(ctx, n.k8s.GetClient(), update, func() error {
 		update.Status = nodeRuntime.Status
@@ -708,6 +791,51 @@ func (n *networkService) cleanRuntimeNode(ctx context.Context, localUIDs sets.Se
 	return nil
 }
 
+// runtimeNetworkInterfaces read the enis attached to the instance from the metadata, the primary eni is excluded
+func runtimeNetworkInterfaces(enableIPv4, enableIPv6 bool) (map[string]*networkv1beta1.RuntimeNetworkInterface, error) {
+	primaryMAC, err := metadata.GetPrimaryENIMAC()
+	if err != nil {
+		return nil, err
+	}
+	macs, err := metadata.GetENIsMAC()
+	if err != nil {
+		return nil, err
+	}
+
+	toString := func(item netip.Addr, _ int) string {
+		return item.String()
+	}
+	enis := make(map[string]*networkv1beta1.RuntimeNetworkInterface, len(macs))
+	for _, mac := range macs {
+		if mac == primaryMAC {
+			continue
+		}
+		id, err := metadata.GetENIID(mac)
+		if err != nil {
+			return nil, err
+		}
+		eni := &networkv1beta1.RuntimeNetworkInterface{}
+		if enableIPv4 {
+			ips, err := metadata.GetIPv4ByMac(mac)
+			if err != nil {
+				return nil, err
+			}
+			eni.IPv4 = lo.Map(ips, toString)
+			sort.Strings(eni.IPv4)
+		}
+		if enableIPv6 {
+			ips, err := metadata.GetIPv6ByMac(mac)
+			if err != nil {
+				return nil, err
+			}
+			eni.IPv6 = lo.Map(ips, toString)
+			sort.Strings(eni.IPv6)
+		}
+		enis[id] = eni
+	}
+	return enis, nil
+}
+
 // tracing
 func (n *networkService) Config() []tracing.MapKeyValueEntry {
 	// name, daemon_mode, configFilePath, kubeconfig, master
@@ -730,6 +858,15 @@ func (n *networkService) Trace() []tracing.MapKeyValueEntry {
 	trace := []tracing.MapKeyValueEntry{
 		{Key: tracingKeyPendingPodsCount, Value: fmt.Sprint(count)},
 	}
+
+	features := probe.Recorded()
+	for _, f := range probe.Features {
+		ok, found := features[f]
+		if !found {
+			continue
+		}
+		trace = append(trace, tracing.MapKeyValueEntry{Key: "features/" + string(f), Value: strconv.FormatBool(ok)})
+	}
 	resList, err := n.resourceDB.List()
 	if err != nil {
 		trace = append(trace, tracing.MapKeyValueEntry{Key: "error", Value: err.Error()})
@@ -767,6 +904,16 @@ func (n *networkService) Execute(cmd string, _ []string, message chan<- string)
 			out, _ := json.Marshal(objList)
 			message <- string(out)
 		}
+	case commandMigrate, commandRollback:
+		message <- n.startDataPathMigration(cmd == commandRollback)
+	case commandMigration:
+		status, err := n.dataPathMigrationStatus()
+		if err != nil {
+			message <- fmt.Sprintf("%s\n", err)
+		} else {
+			out, _ := json.Marshal(status)
+			message <- string(out)
+		}
 
 	default:
 		message <- "can't recognize command\n"
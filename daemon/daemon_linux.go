@@ -12,6 +12,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/link"
 	"github.com/AliyunContainerService/terway/plugin/datapath"
 	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/types"
 )
 
@@ -33,6 +34,11 @@ func gcPolicyRoutes(ctx context.Context, mac string, containerIPNet *types.IPNet
 	return err
 }
 
+// gcIngressPriority del the ingress priority ifb left by the detached enis
+func gcIngressPriority(ctx context.Context) error {
+	return utils.GCIngressPriorityIFB(ctx)
+}
+
 func gcLeakedRules(existIP sets.Set[string]) {
 	links, err := netlink.LinkList()
 	if err != nil {
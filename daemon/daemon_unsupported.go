@@ -12,6 +12,10 @@ import (
 
 func gcLeakedRules(existIP sets.Set[string]) {}
 
+func gcIngressPriority(ctx context.Context) error {
+	return nil
+}
+
 func gcPolicyRoutes(ctx context.Context, mac string, containerIPNet *types.IPNetSet, namespace, name string) error {
 	return nil
 }
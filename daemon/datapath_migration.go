@@ -0,0 +1,510 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/AliyunContainerService/terway/pkg/probe"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	commandMigrate   = "migrate"
+	commandMigration = "migration"
+	commandRollback  = "rollback"
+
+	migrationDBPath = "/var/lib/cni/terway/migration.db"
+	migrationDBName = "migration"
+
+	// migrationPodTimeout is the time a pod is allowed to switch the datapath, the pod is restored if exceeded
+	migrationPodTimeout = 1 * time.Minute
+
+	// cniLockPath is the lock held by the cni plugin, the datapath is not changed while the plugin is running
+	cniLockPath = "/var/run/eni/terway_cni.lock"
+	// defaultMTU same as the cni plugin
+	defaultMTU = 1500
+
+	eventReasonDataPathMigrated      = "DataPathMigrated"
+	eventReasonDataPathMigrateFailed = "DataPathMigrateFailed"
+)
+
+var migrationLog = serviceLog.WithName("datapath-migration")
+
+// migrationDataPaths the datapaths the shared eni pods can be migrated between, detected by the type of eth0
+var migrationDataPaths = map[cnitypes.DataPath]string{
+	cnitypes.PolicyRoute: "veth",
+	cnitypes.IPVlan:      "ipvlan",
+}
+
+// dataPathV2 is the veth datapath with the chained cilium-cni, the pods on it have the same eth0 as on veth
+const dataPathV2 = "datapathv2"
+
+// the steps switching the datapath of a pod, replaced in tests
+var (
+	getPodDataPath   = podDataPath
+	setupPodDataPath = setPodDataPath
+	checkPodHealth   = checkPodDataPath
+)
+
+func dataPathName(dp cnitypes.DataPath) string {
+	name, ok := migrationDataPaths[dp]
+	if !ok {
+		return fmt.Sprintf("unknown(%d)", dp)
+	}
+	return name
+}
+
+// targetDataPathName the name of the datapath the pods are migrated to, the veth datapath with chained plugins is datapathv2
+func targetDataPathName(dp cnitypes.DataPath, chained bool) string {
+	if dp == cnitypes.PolicyRoute && chained {
+		return dataPathV2
+	}
+	return dataPathName(dp)
+}
+
+func parseDataPath(name string) (cnitypes.DataPath, error) {
+	if name == dataPathV2 {
+		return cnitypes.PolicyRoute, nil
+	}
+	for dp, n := range migrationDataPaths {
+		if n == name {
+			return dp, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported datapath %s", name)
+}
+
+// migrationConf the cni config the pods are migrated with
+type migrationConf struct {
+	conf    *cnitypes.CNIConf
+	chained []chainedPlugin
+}
+
+func loadMigrationConf() (*migrationConf, error) {
+	path := filepath.Join(tmpCNIConfigPath, cinConfFile)
+	conf, err := loadCNIConf(path)
+	if err != nil {
+		return nil, err
+	}
+	chained, err := loadChainedPlugins(path)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationConf{conf: conf, chained: chained}, nil
+}
+
+// targetDataPath the datapath of the shared eni pods set up by the cni config
+func targetDataPath(conf *cnitypes.CNIConf, ipvlanSupported bool) cnitypes.DataPath {
+	if conf.IPVlan() && ipvlanSupported {
+		return cnitypes.IPVlan
+	}
+	return cnitypes.PolicyRoute
+}
+
+// dataPathConf the cni config the pods on the datapath are set up with
+func dataPathConf(conf *cnitypes.CNIConf, dp cnitypes.DataPath) *cnitypes.CNIConf {
+	c := *conf
+	switch {
+	case dp == cnitypes.IPVlan:
+		c.ENIIPVirtualType = "ipvlan"
+	case c.IPVlan():
+		c.ENIIPVirtualType = "veth"
+	}
+	if c.MTU == 0 {
+		c.MTU = defaultMTU
+	}
+	return &c
+}
+
+// migrationNetConf return the net conf of the pod, nil if the pod can not be migrated.
+// Only the pods on the shared eni with a single interface are migrated, the qos is the latest applied.
+func migrationNetConf(podRes daemon.PodResources) (*rpc.NetConf, string) {
+	if podRes.PodInfo == nil || podRes.NetNs == nil || podRes.ContainerID == nil {
+		return nil, "not set up"
+	}
+	if podRes.PodInfo.PodNetworkType != daemon.PodNetworkTypeENIMultiIP || podRes.PodInfo.PodENI {
+		return nil, "not on shared eni"
+	}
+	if podRes.PodInfo.NAT64 {
+		return nil, "nat64 is served on the veth datapath only"
+	}
+	if podRes.PodInfo.EgressGateway != nil {
+		return nil, "egress gateway is served on the veth datapath only"
+	}
+
+	var netConfs []*rpc.NetConf
+	err := json.Unmarshal([]byte(podRes.NetConf), &netConfs)
+	if err != nil {
+		return nil, fmt.Sprintf("error parse net conf, %s", err)
+	}
+	if len(netConfs) != 1 {
+		return nil, "multiple network interfaces"
+	}
+	alloc := netConfs[0]
+	if alloc.GetBasicInfo() == nil || alloc.GetENIInfo() == nil {
+		return nil, "incomplete net conf"
+	}
+	if alloc.GetENIInfo().GetTrunk() {
+		return nil, "on trunk eni"
+	}
+	alloc.Pod = &rpc.Pod{
+		Ingress:         podRes.PodInfo.TcIngress,
+		Egress:          podRes.PodInfo.TcEgress,
+		NetworkPriority: podRes.PodInfo.NetworkPriority,
+	}
+	return alloc, ""
+}
+
+// podPortMaps the host ports of the pod, same as the port mappings passed by the runtime
+func podPortMaps(pod *corev1.Pod) []cni.RuntimePortMapEntry {
+	var portMaps []cni.RuntimePortMapEntry
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.HostPort <= 0 {
+				continue
+			}
+			portMaps = append(portMaps, cni.RuntimePortMapEntry{
+				HostPort:      int(p.HostPort),
+				ContainerPort: int(p.ContainerPort),
+				Protocol:      strings.ToLower(string(p.Protocol)),
+				HostIP:        p.HostIP,
+			})
+		}
+	}
+	return portMaps
+}
+
+// initDataPathMigration open the store of the migration records
+func (n *networkService) initDataPathMigration() error {
+	db, err := storage.NewDiskStorage(migrationDBName, utils.NormalizePath(migrationDBPath), json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := &daemon.DataPathMigration{}
+		err := json.Unmarshal(bytes, record)
+		if err != nil {
+			return nil, err
+		}
+		return *record, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error init datapath migration store, %w", err)
+	}
+	n.migrationDB = db
+	return nil
+}
+
+// startDataPathMigration run the migration or the rollback in background, only one runs at a time
+func (n *networkService) startDataPathMigration(rollback bool) string {
+	if n.migrationDB == nil {
+		return "datapath migration is not supported in this mode\n"
+	}
+	if !n.migrationRunning.CompareAndSwap(false, true) {
+		return "datapath migration is running\n"
+	}
+
+	go func() {
+		defer n.migrationRunning.Store(false)
+
+		var err error
+		if rollback {
+			err = n.rollbackDataPath(context.Background())
+		} else {
+			err = n.migrateDataPath(context.Background())
+		}
+		if err != nil {
+			migrationLog.Error(err, "datapath migration stopped", "rollback", rollback)
+			return
+		}
+		migrationLog.Info("datapath migration finished", "rollback", rollback)
+	}()
+	return "datapath migration started\n"
+}
+
+// dataPathMigrationStatus return the records sorted by time
+func (n *networkService) dataPathMigrationStatus() (*daemon.DataPathMigrationStatus, error) {
+	status := &daemon.DataPathMigrationStatus{
+		Running: n.migrationRunning.Load(),
+	}
+	if n.migrationDB == nil {
+		return status, nil
+	}
+	records, err := n.migrationRecords()
+	if err != nil {
+		return nil, err
+	}
+	status.Records = records
+	return status, nil
+}
+
+func (n *networkService) migrationRecords() ([]daemon.DataPathMigration, error) {
+	objList, err := n.migrationDB.List()
+	if err != nil {
+		return nil, err
+	}
+	var records []daemon.DataPathMigration
+	for _, obj := range objList {
+		records = append(records, obj.(daemon.DataPathMigration))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+// migrateDataPath move the pods to the datapath of the current cni config one by one, stop at the first failure
+func (n *networkService) migrateDataPath(ctx context.Context) error {
+	mc, err := loadMigrationConf()
+	if err != nil {
+		return err
+	}
+	return n.migratePods(ctx, mc, targetDataPath(mc.conf, probe.Get(probe.IPVlanL2)))
+}
+
+func (n *networkService) migratePods(ctx context.Context, mc *migrationConf, to cnitypes.DataPath) error {
+	migrationLog.Info("start datapath migration", "to", targetDataPathName(to, len(mc.chained) > 0))
+
+	n.RLock()
+	objList, err := n.resourceDB.List()
+	n.RUnlock()
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, podRes := range getPodResources(objList) {
+		if podRes.PodInfo == nil {
+			continue
+		}
+		keys = append(keys, utils.PodInfoKey(podRes.PodInfo.Namespace, podRes.PodInfo.Name))
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		record, err := n.switchPodDataPath(ctx, mc, key, "", to)
+		if record == nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		putErr := n.migrationDB.Put(key, *record)
+		if err != nil {
+			return fmt.Errorf("error migrate pod %s, %w", key, err)
+		}
+		if putErr != nil {
+			return putErr
+		}
+	}
+	return nil
+}
+
+// rollbackDataPath move the migrated pods back one by one, stop at the first failure.
+// The pods recreated since migrated are set up by the cni plugin, so the records are dropped.
+func (n *networkService) rollbackDataPath(ctx context.Context) error {
+	mc, err := loadMigrationConf()
+	if err != nil {
+		return err
+	}
+	return n.rollbackPods(ctx, mc)
+}
+
+func (n *networkService) rollbackPods(ctx context.Context, mc *migrationConf) error {
+	records, err := n.migrationRecords()
+	if err != nil {
+		return err
+	}
+	migrationLog.Info("start datapath rollback")
+
+	for _, record := range records {
+		if record.State != daemon.DataPathMigrationMigrated {
+			continue
+		}
+		to, err := parseDataPath(record.From)
+		if err != nil {
+			return err
+		}
+		rollback, err := n.switchPodDataPath(ctx, mc, record.Pod, record.ContainerID, to)
+		if rollback == nil {
+			if err != nil {
+				return err
+			}
+			migrationLog.Info("pod changed since migrated, record dropped", "pod", record.Pod)
+			err = n.migrationDB.Delete(record.Pod)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			record.Message = fmt.Sprintf("rollback failed, %s", rollback.Message)
+		} else {
+			record.State = daemon.DataPathMigrationRolledBack
+			record.Message = ""
+		}
+		record.Time = rollback.Time
+		record.Chained = rollback.Chained
+		putErr := n.migrationDB.Put(record.Pod, record)
+		if err != nil {
+			return fmt.Errorf("error rollback pod %s, %w", record.Pod, err)
+		}
+		if putErr != nil {
+			return putErr
+		}
+	}
+	return nil
+}
+
+// switchPodDataPath move the pod to the datapath and check its health, the pod is restored to the previous datapath if failed.
+// The record is nil if the pod is skipped, the pod is skipped if containerID is set and not matched.
+// The cni requests of the pod are rejected while switching, other pods are not blocked.
+func (n *networkService) switchPodDataPath(ctx context.Context, mc *migrationConf, key, containerID string, to cnitypes.DataPath) (*daemon.DataPathMigration, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, nil
+	}
+	log := migrationLog.WithValues("pod", key)
+
+	_, exist := n.pendingPods.LoadOrStore(key, struct{}{})
+	if exist {
+		log.Info("pod skipped, cni request is processing")
+		return nil, nil
+	}
+	defer n.pendingPods.Delete(key)
+
+	n.RLock()
+	podRes, err := n.getPodResource(&daemon.PodInfo{Namespace: namespace, Name: name})
+	n.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	alloc, reason := migrationNetConf(podRes)
+	if alloc == nil {
+		log.V(4).Info("pod skipped", "reason", reason)
+		return nil, nil
+	}
+	if containerID != "" && containerID != *podRes.ContainerID {
+		return nil, nil
+	}
+	from, err := getPodDataPath(*podRes.NetNs)
+	if err != nil {
+		log.Info("pod skipped, error get datapath", "reason", err.Error())
+		return nil, nil
+	}
+	// veth and datapathv2 have the same eth0, they are told apart by the chained plugins recorded for the pod
+	fromChained, recorded := n.podChainedPlugins(key, *podRes.ContainerID)
+	if !recorded {
+		// the endpoints of the pod not switched before are created by the current config, the delete is skipped if not found
+		fromChained = mc.chained
+	}
+	fromName := dataPathName(from)
+	if from == cnitypes.PolicyRoute && recorded && len(fromChained) > 0 {
+		fromName = dataPathV2
+	}
+	toName := targetDataPathName(to, len(mc.chained) > 0)
+	if fromName == toName {
+		return nil, nil
+	}
+
+	pod := &corev1.Pod{}
+	err = n.k8s.GetClient().Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: name}, pod)
+	if err != nil {
+		return nil, fmt.Errorf("error get pod %s, %w", key, err)
+	}
+	if string(pod.UID) != podRes.PodInfo.PodUID {
+		return nil, nil
+	}
+	podConf := *mc.conf
+	// the host ports are served by terway only if the capability is declared
+	if mc.conf.Capabilities["portMappings"] {
+		podConf.RuntimeConfig.PortMaps = podPortMaps(pod)
+	}
+
+	record := &daemon.DataPathMigration{
+		Pod:         key,
+		ContainerID: *podRes.ContainerID,
+		From:        fromName,
+		To:          toName,
+		Chained:     chainedPluginConfs(mc.chained),
+	}
+	log.Info("switch pod datapath", "from", record.From, "to", record.To)
+
+	err = func() error {
+		ctx, cancel := context.WithTimeout(ctx, migrationPodTimeout)
+		defer cancel()
+
+		err := switchPodLink(ctx, fromChained, mc.chained, &podConf, &podRes, alloc, from, to)
+		if err != nil {
+			return fmt.Errorf("error set up %s datapath, %w", record.To, err)
+		}
+		err = checkPodHealth(ctx, &podConf, &podRes, alloc, to, func(msg string) {
+			_ = n.k8s.RecordPodEvent(name, namespace, corev1.EventTypeWarning, "ConfigCheck", msg)
+		})
+		if err != nil {
+			return fmt.Errorf("health check failed on %s datapath, %w", record.To, err)
+		}
+		return nil
+	}()
+	record.Time = time.Now()
+	if err == nil {
+		record.State = daemon.DataPathMigrationMigrated
+		log.Info("pod datapath switched", "from", record.From, "to", record.To)
+		_ = n.k8s.RecordPodEvent(name, namespace, corev1.EventTypeNormal, eventReasonDataPathMigrated,
+			fmt.Sprintf("Datapath switched from %s to %s.", record.From, record.To))
+		return record, nil
+	}
+
+	record.State = daemon.DataPathMigrationFailed
+	record.Message = err.Error()
+	record.Chained = chainedPluginConfs(fromChained)
+
+	// the context of the switch may be expired
+	restoreCtx, cancel := context.WithTimeout(context.Background(), migrationPodTimeout)
+	defer cancel()
+	restoreErr := switchPodLink(restoreCtx, mc.chained, fromChained, &podConf, &podRes, alloc, to, from)
+	if restoreErr != nil {
+		record.Message = fmt.Sprintf("%s, error restore %s datapath, %s", record.Message, record.From, restoreErr)
+	}
+	log.Error(err, "error switch pod datapath", "from", record.From, "to", record.To, "restoreErr", restoreErr)
+	_ = n.k8s.RecordPodEvent(name, namespace, corev1.EventTypeWarning, eventReasonDataPathMigrateFailed, record.Message)
+	return record, err
+}
+
+// podChainedPlugins return the chained plugins the pod is set up with by the last switch, false if the pod is not switched
+func (n *networkService) podChainedPlugins(key, containerID string) ([]chainedPlugin, bool) {
+	obj, err := n.migrationDB.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	record := obj.(daemon.DataPathMigration)
+	if record.ContainerID != containerID {
+		return nil, false
+	}
+	return chainedPluginsFromConfs(record.Chained), true
+}
+
+// switchPodLink recreate eth0 of the pod on the datapath, the endpoints of the chained plugins are recreated as well.
+// eth0 is kept if the datapath is not changed, e.g. between veth and datapathv2 only the endpoints are changed.
+func switchPodLink(ctx context.Context, fromChained, toChained []chainedPlugin, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, from, to cnitypes.DataPath) error {
+	err := runChainedPlugins(ctx, "DEL", fromChained, podRes, alloc)
+	if err != nil {
+		return err
+	}
+	if from != to {
+		err = setupPodDataPath(ctx, conf, podRes, alloc, from, to)
+		if err != nil {
+			return err
+		}
+	}
+	return runChainedPlugins(ctx, "ADD", toChained, podRes, alloc)
+}
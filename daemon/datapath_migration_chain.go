@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	current "github.com/containernetworking/cni/pkg/types/100"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+// cniBinPath the path of the cni plugins, mounted from the host
+const cniBinPath = "/opt/cni/bin"
+
+// chainedPlugin is a plugin called after terway in the cni config list, e.g. cilium-cni
+type chainedPlugin struct {
+	Type       string
+	CNIVersion string
+	conf       map[string]interface{}
+}
+
+// loadChainedPlugins return the plugins after terway in the cni config list
+func loadChainedPlugins(path string) ([]chainedPlugin, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	confList := struct {
+		Name       string                   `json:"name"`
+		CNIVersion string                   `json:"cniVersion"`
+		Plugins    []map[string]interface{} `json:"plugins"`
+	}{}
+	err = json.Unmarshal(b, &confList)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cni config %s, %w", path, err)
+	}
+
+	var plugins []chainedPlugin
+	found := false
+	for _, conf := range confList.Plugins {
+		pluginType, _ := conf["type"].(string)
+		if !found {
+			found = pluginType == "terway"
+			continue
+		}
+		// same as the runtime, the name and version of the list are used
+		conf["name"] = confList.Name
+		conf["cniVersion"] = confList.CNIVersion
+		plugins = append(plugins, chainedPlugin{Type: pluginType, CNIVersion: confList.CNIVersion, conf: conf})
+	}
+	if !found {
+		return nil, fmt.Errorf("terway plugin not found in %s", path)
+	}
+	return plugins, nil
+}
+
+// chainedPluginConfs the confs of the plugins, stored in the migration record
+func chainedPluginConfs(plugins []chainedPlugin) []map[string]interface{} {
+	var confs []map[string]interface{}
+	for _, plugin := range plugins {
+		confs = append(confs, plugin.conf)
+	}
+	return confs
+}
+
+// chainedPluginsFromConfs restore the plugins from the confs in the migration record
+func chainedPluginsFromConfs(confs []map[string]interface{}) []chainedPlugin {
+	var plugins []chainedPlugin
+	for _, conf := range confs {
+		pluginType, _ := conf["type"].(string)
+		cniVersion, _ := conf["cniVersion"].(string)
+		plugins = append(plugins, chainedPlugin{Type: pluginType, CNIVersion: cniVersion, conf: conf})
+	}
+	return plugins
+}
+
+// execChainedPlugin run the plugin binary, replaced in tests
+var execChainedPlugin = func(ctx context.Context, pluginType string, netConf []byte, args *invoke.Args) error {
+	pluginPath, err := invoke.FindInPath(pluginType, []string{cniBinPath})
+	if err != nil {
+		return err
+	}
+	if args.Command == "ADD" {
+		_, err = invoke.ExecPluginWithResult(ctx, pluginPath, netConf, args, nil)
+		return err
+	}
+	return invoke.ExecPluginWithoutResult(ctx, pluginPath, netConf, args, nil)
+}
+
+// runChainedPlugins call the chained plugins of the pod as the runtime does, ADD in order and DEL in reverse order.
+// The result of terway is passed as the prevResult.
+func runChainedPlugins(ctx context.Context, command string, plugins []chainedPlugin, podRes *daemon.PodResources, alloc *rpc.NetConf) error {
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	containerIPNet, err := types.BuildIPNet(alloc.GetBasicInfo().GetPodIP(), alloc.GetBasicInfo().GetPodCIDR())
+	if err != nil {
+		return err
+	}
+	gatewayIP, err := types.ToIPSet(alloc.GetBasicInfo().GetGatewayIP())
+	if err != nil {
+		return err
+	}
+	result := &current.Result{
+		CNIVersion: current.ImplementedSpecVersion,
+		Interfaces: []*current.Interface{{Name: IfEth0, Sandbox: *podRes.NetNs}},
+	}
+	if containerIPNet.IPv4 != nil && gatewayIP.IPv4 != nil {
+		result.IPs = append(result.IPs, &current.IPConfig{
+			Address:   *containerIPNet.IPv4,
+			Gateway:   gatewayIP.IPv4,
+			Interface: current.Int(0),
+		})
+	}
+	if containerIPNet.IPv6 != nil && gatewayIP.IPv6 != nil {
+		result.IPs = append(result.IPs, &current.IPConfig{
+			Address:   *containerIPNet.IPv6,
+			Gateway:   gatewayIP.IPv6,
+			Interface: current.Int(0),
+		})
+	}
+
+	args := &invoke.Args{
+		Command:     command,
+		ContainerID: *podRes.ContainerID,
+		NetNS:       *podRes.NetNs,
+		IfName:      IfEth0,
+		PluginArgsStr: fmt.Sprintf("IgnoreUnknown=1;K8S_POD_NAMESPACE=%s;K8S_POD_NAME=%s;K8S_POD_INFRA_CONTAINER_ID=%s",
+			podRes.PodInfo.Namespace, podRes.PodInfo.Name, *podRes.ContainerID),
+		Path: cniBinPath,
+	}
+
+	order := make([]chainedPlugin, 0, len(plugins))
+	if command == "DEL" {
+		for i := len(plugins) - 1; i >= 0; i-- {
+			order = append(order, plugins[i])
+		}
+	} else {
+		order = append(order, plugins...)
+	}
+	for _, plugin := range order {
+		prevResult, err := result.GetAsVersion(plugin.CNIVersion)
+		if err != nil {
+			return err
+		}
+		conf := make(map[string]interface{}, len(plugin.conf)+1)
+		for k, v := range plugin.conf {
+			conf[k] = v
+		}
+		conf["prevResult"] = prevResult
+		netConf, err := json.Marshal(conf)
+		if err != nil {
+			return err
+		}
+		err = execChainedPlugin(ctx, plugin.Type, netConf, args)
+		if err != nil {
+			return fmt.Errorf("error %s chained plugin %s, %w", command, plugin.Type, err)
+		}
+	}
+	return nil
+}
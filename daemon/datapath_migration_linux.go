@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+// podDataPath the datapath of the pod, detected by the type of eth0
+func podDataPath(netNSPath string) (cnitypes.DataPath, error) {
+	netNS, err := ns.GetNS(netNSPath)
+	if err != nil {
+		return 0, err
+	}
+	defer netNS.Close()
+
+	return datapath.PodDataPath(netNS, IfEth0)
+}
+
+// setPodDataPath tear down the host side of the pod on the previous datapath, then set up the pod on the new one
+func setPodDataPath(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, from, to cnitypes.DataPath) error {
+	netNS, err := ns.GetNS(*podRes.NetNs)
+	if err != nil {
+		return err
+	}
+	defer netNS.Close()
+
+	hostVETHName, err := link.VethNameForPod(podRes.PodInfo.Name, podRes.PodInfo.Namespace, alloc.IfName, "cali")
+	if err != nil {
+		return err
+	}
+
+	l, err := utils.GrabFileLock(cniLockPath)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	teardownCfg, err := datapath.ParseTearDownConf(alloc, dataPathConf(conf, from), rpc.IPType_TypeENIMultiIP)
+	if err != nil {
+		return err
+	}
+	teardownCfg.DP = from
+	teardownCfg.HostVETHName = hostVETHName
+
+	if len(conf.RuntimeConfig.PortMaps) > 0 {
+		err = datapath.TeardownHostPorts(ctx, teardownCfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+	}
+	switch from {
+	case cnitypes.IPVlan:
+		err = datapath.NewIPVlanDriver().Teardown(ctx, teardownCfg, netNS)
+	case cnitypes.PolicyRoute:
+		err = datapath.NewPolicyRoute().Teardown(ctx, teardownCfg, netNS)
+	}
+	if err != nil {
+		return fmt.Errorf("error tear down %s datapath, %w", dataPathName(from), err)
+	}
+
+	// the drivers rename a new link to eth0, so the link of the previous datapath is removed
+	err = netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(IfEth0)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return err
+		}
+		return utils.LinkDel(ctx, contLink)
+	})
+	if err != nil {
+		return fmt.Errorf("error remove %s in pod, %w", IfEth0, err)
+	}
+
+	setupCfg, err := datapath.ParseSetupConf(IfEth0, alloc, dataPathConf(conf, to), rpc.IPType_TypeENIMultiIP)
+	if err != nil {
+		return err
+	}
+	hostIPSet, err := utils.GetHostIP(setupCfg.ContainerIPNet.IPv4 != nil, setupCfg.ContainerIPNet.IPv6 != nil)
+	if err != nil {
+		return err
+	}
+	setupCfg.DP = to
+	setupCfg.HostVETHName = hostVETHName
+	setupCfg.HostIPSet = hostIPSet
+
+	switch to {
+	case cnitypes.IPVlan:
+		return datapath.NewIPVlanDriver().Setup(ctx, setupCfg, netNS)
+	case cnitypes.PolicyRoute:
+		return datapath.NewPolicyRoute().Setup(ctx, setupCfg, netNS)
+	}
+	return fmt.Errorf("datapath %s is not supported", dataPathName(to))
+}
+
+// checkPodDataPath run the check of the driver, then make sure eth0 carries the pod ips and the default route
+func checkPodDataPath(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, dp cnitypes.DataPath, recordEvent func(msg string)) error {
+	netNS, err := ns.GetNS(*podRes.NetNs)
+	if err != nil {
+		return err
+	}
+	defer netNS.Close()
+
+	checkCfg, err := datapath.ParseCheckConf(IfEth0, alloc, dataPathConf(conf, dp), rpc.IPType_TypeENIMultiIP)
+	if err != nil {
+		return err
+	}
+	checkCfg.DP = dp
+	checkCfg.NetNS = netNS
+	checkCfg.HostVETHName, _ = link.VethNameForPod(podRes.PodInfo.Name, podRes.PodInfo.Namespace, alloc.IfName, "cali")
+	checkCfg.HostIPSet, err = utils.GetHostIP(checkCfg.ContainerIPNet.IPv4 != nil, checkCfg.ContainerIPNet.IPv6 != nil)
+	if err != nil {
+		return err
+	}
+	checkCfg.RecordPodEvent = recordEvent
+
+	switch dp {
+	case cnitypes.IPVlan:
+		err = datapath.NewIPVlanDriver().Check(ctx, checkCfg)
+	case cnitypes.PolicyRoute:
+		err = datapath.NewPolicyRoute().Check(ctx, checkCfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := podDataPath(*podRes.NetNs)
+	if err != nil {
+		return err
+	}
+	if current != dp {
+		return fmt.Errorf("pod is on %s datapath", dataPathName(current))
+	}
+
+	return netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(IfEth0)
+		if err != nil {
+			return err
+		}
+		for _, ipNet := range []*net.IPNet{checkCfg.ContainerIPNet.IPv4, checkCfg.ContainerIPNet.IPv6} {
+			if ipNet == nil {
+				continue
+			}
+			family := netlink.FAMILY_V4
+			if ipNet.IP.To4() == nil {
+				family = netlink.FAMILY_V6
+			}
+			addrs, err := netlink.AddrList(contLink, family)
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, addr := range addrs {
+				if addr.IP.Equal(ipNet.IP) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("ip %s not found on %s", ipNet.IP, IfEth0)
+			}
+			if !checkCfg.DefaultRoute {
+				continue
+			}
+			routes, err := netlink.RouteListFiltered(family, &netlink.Route{LinkIndex: contLink.Attrs().Index}, netlink.RT_FILTER_OIF)
+			if err != nil {
+				return err
+			}
+			found = false
+			for _, r := range routes {
+				if r.Dst == nil || r.Dst.IP.IsUnspecified() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("default route not found on %s", IfEth0)
+			}
+		}
+		return nil
+	})
+}
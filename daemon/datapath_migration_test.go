@@ -0,0 +1,443 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func Test_targetDataPath(t *testing.T) {
+	assert.Equal(t, cnitypes.IPVlan, targetDataPath(&cnitypes.CNIConf{ENIIPVirtualType: "IPVlan"}, true))
+	assert.Equal(t, cnitypes.PolicyRoute, targetDataPath(&cnitypes.CNIConf{ENIIPVirtualType: "ipvlan"}, false))
+	assert.Equal(t, cnitypes.PolicyRoute, targetDataPath(&cnitypes.CNIConf{ENIIPVirtualType: "datapathv2"}, true))
+
+	conf := dataPathConf(&cnitypes.CNIConf{ENIIPVirtualType: "ipvlan"}, cnitypes.PolicyRoute)
+	assert.Equal(t, "veth", conf.ENIIPVirtualType)
+	assert.Equal(t, defaultMTU, conf.MTU)
+	conf = dataPathConf(&cnitypes.CNIConf{ENIIPVirtualType: "datapathv2", MTU: 9000}, cnitypes.PolicyRoute)
+	assert.Equal(t, "datapathv2", conf.ENIIPVirtualType)
+	assert.Equal(t, 9000, conf.MTU)
+	conf = dataPathConf(&cnitypes.CNIConf{ENIIPVirtualType: "veth"}, cnitypes.IPVlan)
+	assert.True(t, conf.IPVlan())
+
+	for _, dp := range []cnitypes.DataPath{cnitypes.PolicyRoute, cnitypes.IPVlan} {
+		parsed, err := parseDataPath(dataPathName(dp))
+		assert.NoError(t, err)
+		assert.Equal(t, dp, parsed)
+	}
+	assert.Equal(t, "datapathv2", targetDataPathName(cnitypes.PolicyRoute, true))
+	assert.Equal(t, "ipvlan", targetDataPathName(cnitypes.IPVlan, true))
+	parsed, err := parseDataPath(targetDataPathName(cnitypes.PolicyRoute, true))
+	assert.NoError(t, err)
+	assert.Equal(t, cnitypes.PolicyRoute, parsed)
+	_, err = parseDataPath(dataPathName(cnitypes.Vlan))
+	assert.Error(t, err)
+}
+
+func Test_migrationNetConf(t *testing.T) {
+	netNS, containerID := "/var/run/netns/foo", "c1"
+	netConf := func(confs ...*rpc.NetConf) string {
+		out, _ := json.Marshal(confs)
+		return string(out)
+	}
+	alloc := &rpc.NetConf{
+		BasicInfo: &rpc.BasicInfo{PodIP: &rpc.IPSet{IPv4: "192.168.0.10"}},
+		ENIInfo:   &rpc.ENIInfo{MAC: "00:00:00:00:00:01"},
+		Pod:       &rpc.Pod{Egress: 1},
+	}
+	podRes := func(info *daemon.PodInfo, conf string) daemon.PodResources {
+		return daemon.PodResources{PodInfo: info, NetNs: &netNS, ContainerID: &containerID, NetConf: conf}
+	}
+	shared := &daemon.PodInfo{Name: "foo", Namespace: "default", PodNetworkType: daemon.PodNetworkTypeENIMultiIP, TcEgress: 2, NetworkPriority: "burstable"}
+
+	got, _ := migrationNetConf(podRes(shared, netConf(alloc)))
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "192.168.0.10", got.GetBasicInfo().GetPodIP().GetIPv4())
+		assert.Equal(t, uint64(2), got.GetPod().GetEgress())
+		assert.Equal(t, "burstable", got.GetPod().GetNetworkPriority())
+	}
+
+	trunk := &rpc.NetConf{BasicInfo: alloc.BasicInfo, ENIInfo: &rpc.ENIInfo{MAC: "00:00:00:00:00:02", Trunk: true}}
+	for name, res := range map[string]daemon.PodResources{
+		"not set up":       {PodInfo: shared},
+		"exclusive eni":    podRes(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeVPCENI}, netConf(alloc)),
+		"pod eni":          podRes(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeENIMultiIP, PodENI: true}, netConf(alloc)),
+		"nat64":            podRes(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeENIMultiIP, NAT64: true}, netConf(alloc)),
+		"egress gateway":   podRes(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeENIMultiIP, EgressGateway: &types.EgressGateway{Name: "gw"}}, netConf(alloc)),
+		"multiple network": podRes(shared, netConf(alloc, alloc)),
+		"trunk":            podRes(shared, netConf(trunk)),
+		"invalid":          podRes(shared, "foo"),
+	} {
+		got, reason := migrationNetConf(res)
+		assert.Nil(t, got, name)
+		assert.NotEmpty(t, reason, name)
+	}
+}
+
+func Test_podPortMaps(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Ports: []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 80, HostPort: 8080, Protocol: corev1.ProtocolTCP}}},
+		{Ports: []corev1.ContainerPort{{ContainerPort: 53, HostPort: 5353, Protocol: corev1.ProtocolUDP, HostIP: "192.168.0.1"}}},
+	}}}
+	assert.Equal(t, []cni.RuntimePortMapEntry{
+		{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostPort: 5353, ContainerPort: 53, Protocol: "udp", HostIP: "192.168.0.1"},
+	}, podPortMaps(pod))
+}
+
+func TestNetworkService_dataPathMigrationStatus(t *testing.T) {
+	n := &networkService{}
+	status, err := n.dataPathMigrationStatus()
+	assert.NoError(t, err)
+	assert.Empty(t, status.Records)
+	assert.Contains(t, n.startDataPathMigration(false), "not supported")
+
+	n.migrationDB = storage.NewMemoryStorage()
+	now := time.Now()
+	assert.NoError(t, n.migrationDB.Put("default/bar", daemon.DataPathMigration{Pod: "default/bar", State: daemon.DataPathMigrationFailed, Time: now}))
+	assert.NoError(t, n.migrationDB.Put("default/foo", daemon.DataPathMigration{Pod: "default/foo", State: daemon.DataPathMigrationMigrated, Time: now.Add(-time.Minute)}))
+
+	n.migrationRunning.Store(true)
+	assert.Contains(t, n.startDataPathMigration(true), "running")
+	status, err = n.dataPathMigrationStatus()
+	assert.NoError(t, err)
+	assert.True(t, status.Running)
+	if assert.Len(t, status.Records, 2) {
+		assert.Equal(t, "default/foo", status.Records[0].Pod)
+		assert.Equal(t, "default/bar", status.Records[1].Pod)
+	}
+}
+
+func Test_loadChainedPlugins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "10-terway.conflist")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"terway-chainer","cniVersion":"0.4.0","plugins":[
+		{"type":"terway","eniip_virtual_type":"ipvlan"},
+		{"type":"cilium-cni","enable-debug":false}]}`), 0600))
+
+	plugins, err := loadChainedPlugins(path)
+	require.NoError(t, err)
+	if assert.Len(t, plugins, 1) {
+		assert.Equal(t, "cilium-cni", plugins[0].Type)
+		assert.Equal(t, "terway-chainer", plugins[0].conf["name"])
+		assert.Equal(t, "0.4.0", plugins[0].conf["cniVersion"])
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"terway","cniVersion":"0.4.0","plugins":[{"type":"terway"}]}`), 0600))
+	plugins, err = loadChainedPlugins(path)
+	assert.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"foo","cniVersion":"0.4.0","plugins":[{"type":"bridge"}]}`), 0600))
+	_, err = loadChainedPlugins(path)
+	assert.Error(t, err)
+}
+
+// chainedPluginCall is a call of the chained plugin in tests
+type chainedPluginCall struct {
+	Command string
+	Type    string
+	Conf    map[string]interface{}
+}
+
+func stubChainedPlugin(t *testing.T, fail string) *[]chainedPluginCall {
+	var calls []chainedPluginCall
+	prev := execChainedPlugin
+	execChainedPlugin = func(ctx context.Context, pluginType string, netConf []byte, args *invoke.Args) error {
+		call := chainedPluginCall{Command: args.Command, Type: pluginType}
+		if err := json.Unmarshal(netConf, &call.Conf); err != nil {
+			return err
+		}
+		calls = append(calls, call)
+		if args.Command+" "+pluginType == fail {
+			return errors.New("plugin failed")
+		}
+		return nil
+	}
+	t.Cleanup(func() { execChainedPlugin = prev })
+	return &calls
+}
+
+func Test_runChainedPlugins(t *testing.T) {
+	netNS, containerID := "/var/run/netns/foo", "c1"
+	podRes := &daemon.PodResources{PodInfo: &daemon.PodInfo{Name: "foo", Namespace: "default"}, NetNs: &netNS, ContainerID: &containerID}
+	plugins := []chainedPlugin{
+		{Type: "a", CNIVersion: "0.4.0", conf: map[string]interface{}{"type": "a"}},
+		{Type: "b", CNIVersion: "1.0.0", conf: map[string]interface{}{"type": "b"}},
+	}
+	calls := stubChainedPlugin(t, "")
+
+	assert.NoError(t, runChainedPlugins(context.Background(), "ADD", plugins, podRes, migrationTestAlloc()))
+	assert.NoError(t, runChainedPlugins(context.Background(), "DEL", plugins, podRes, migrationTestAlloc()))
+	if assert.Len(t, *calls, 4) {
+		var order []string
+		for _, call := range *calls {
+			order = append(order, call.Command+" "+call.Type)
+		}
+		assert.Equal(t, []string{"ADD a", "ADD b", "DEL b", "DEL a"}, order)
+
+		prevResult := (*calls)[0].Conf["prevResult"].(map[string]interface{})
+		assert.Equal(t, "0.4.0", prevResult["cniVersion"])
+		ips := prevResult["ips"].([]interface{})
+		assert.Equal(t, "192.168.0.10/24", ips[0].(map[string]interface{})["address"])
+		assert.Equal(t, "192.168.0.253", ips[0].(map[string]interface{})["gateway"])
+	}
+	// the config is not changed by the call
+	assert.NotContains(t, plugins[0].conf, "prevResult")
+}
+
+func migrationTestAlloc() *rpc.NetConf {
+	return &rpc.NetConf{
+		BasicInfo: &rpc.BasicInfo{
+			PodIP:     &rpc.IPSet{IPv4: "192.168.0.10"},
+			PodCIDR:   &rpc.IPSet{IPv4: "192.168.0.0/24"},
+			GatewayIP: &rpc.IPSet{IPv4: "192.168.0.253"},
+		},
+		ENIInfo: &rpc.ENIInfo{MAC: "00:00:00:00:00:01"},
+	}
+}
+
+// migrationTestSteps replace the steps switching the pod, the datapath of the pod follows the set up
+type migrationTestSteps struct {
+	dp       cnitypes.DataPath
+	sets     [][2]cnitypes.DataPath
+	setErr   map[cnitypes.DataPath]error
+	checkErr error
+}
+
+func stubMigrationSteps(t *testing.T, dp cnitypes.DataPath) *migrationTestSteps {
+	steps := &migrationTestSteps{dp: dp, setErr: map[cnitypes.DataPath]error{}}
+	prevGet, prevSet, prevCheck := getPodDataPath, setupPodDataPath, checkPodHealth
+	getPodDataPath = func(netNSPath string) (cnitypes.DataPath, error) {
+		return steps.dp, nil
+	}
+	setupPodDataPath = func(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, from, to cnitypes.DataPath) error {
+		steps.sets = append(steps.sets, [2]cnitypes.DataPath{from, to})
+		if err := steps.setErr[to]; err != nil {
+			return err
+		}
+		steps.dp = to
+		return nil
+	}
+	checkPodHealth = func(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, dp cnitypes.DataPath, recordEvent func(msg string)) error {
+		return steps.checkErr
+	}
+	t.Cleanup(func() {
+		getPodDataPath, setupPodDataPath, checkPodHealth = prevGet, prevSet, prevCheck
+	})
+	return steps
+}
+
+func newMigrationTestService(t *testing.T) *networkService {
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetClient").Return(fake.NewClientBuilder().WithObjects(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+	}).Build()).Maybe()
+	k8sClient.On("RecordPodEvent", "foo", "default", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	n := &networkService{
+		k8s:         k8sClient,
+		resourceDB:  storage.NewMemoryStorage(),
+		migrationDB: storage.NewMemoryStorage(),
+	}
+	netNS, containerID := "/var/run/netns/foo", "c1"
+	netConf, err := json.Marshal([]*rpc.NetConf{migrationTestAlloc()})
+	require.NoError(t, err)
+	require.NoError(t, n.resourceDB.Put("default/foo", daemon.PodResources{
+		PodInfo:     &daemon.PodInfo{Name: "foo", Namespace: "default", PodUID: "uid-1", PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		NetNs:       &netNS,
+		ContainerID: &containerID,
+		NetConf:     string(netConf),
+	}))
+	return n
+}
+
+func TestNetworkService_switchPodDataPath(t *testing.T) {
+	mc := &migrationConf{
+		conf:    &cnitypes.CNIConf{ENIIPVirtualType: "ipvlan"},
+		chained: []chainedPlugin{{Type: "cilium-cni", CNIVersion: "0.4.0", conf: map[string]interface{}{"type": "cilium-cni"}}},
+	}
+
+	t.Run("switched", func(t *testing.T) {
+		steps := stubMigrationSteps(t, cnitypes.PolicyRoute)
+		calls := stubChainedPlugin(t, "")
+		n := newMigrationTestService(t)
+
+		record, err := n.switchPodDataPath(context.Background(), mc, "default/foo", "", cnitypes.IPVlan)
+		assert.NoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, daemon.DataPathMigrationMigrated, record.State)
+			assert.Equal(t, "veth", record.From)
+			assert.Equal(t, "ipvlan", record.To)
+			assert.Equal(t, "c1", record.ContainerID)
+		}
+		assert.Equal(t, [][2]cnitypes.DataPath{{cnitypes.PolicyRoute, cnitypes.IPVlan}}, steps.sets)
+		// the endpoint of the chained plugin is recreated
+		if assert.Len(t, *calls, 2) {
+			assert.Equal(t, "DEL", (*calls)[0].Command)
+			assert.Equal(t, "ADD", (*calls)[1].Command)
+		}
+		// the pod is not locked after switched
+		_, locked := n.pendingPods.Load("default/foo")
+		assert.False(t, locked)
+
+		// already on the datapath
+		record, err = n.switchPodDataPath(context.Background(), mc, "default/foo", "", cnitypes.IPVlan)
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		steps := stubMigrationSteps(t, cnitypes.PolicyRoute)
+		steps.checkErr = errors.New("no default route")
+		calls := stubChainedPlugin(t, "")
+		n := newMigrationTestService(t)
+
+		record, err := n.switchPodDataPath(context.Background(), mc, "default/foo", "", cnitypes.IPVlan)
+		assert.Error(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, daemon.DataPathMigrationFailed, record.State)
+			assert.Contains(t, record.Message, "no default route")
+			assert.NotContains(t, record.Message, "error restore")
+		}
+		assert.Equal(t, [][2]cnitypes.DataPath{
+			{cnitypes.PolicyRoute, cnitypes.IPVlan},
+			{cnitypes.IPVlan, cnitypes.PolicyRoute},
+		}, steps.sets)
+		assert.Equal(t, cnitypes.PolicyRoute, steps.dp)
+		assert.Len(t, *calls, 4)
+	})
+
+	t.Run("restore failed", func(t *testing.T) {
+		steps := stubMigrationSteps(t, cnitypes.PolicyRoute)
+		stubChainedPlugin(t, "ADD cilium-cni")
+		n := newMigrationTestService(t)
+		steps.setErr[cnitypes.PolicyRoute] = errors.New("veth exists")
+
+		record, err := n.switchPodDataPath(context.Background(), mc, "default/foo", "", cnitypes.IPVlan)
+		assert.Error(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, daemon.DataPathMigrationFailed, record.State)
+			assert.Contains(t, record.Message, "plugin failed")
+			assert.Contains(t, record.Message, "error restore veth datapath, veth exists")
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		steps := stubMigrationSteps(t, cnitypes.PolicyRoute)
+		stubChainedPlugin(t, "")
+		n := newMigrationTestService(t)
+
+		// cni request of the pod is processing
+		n.pendingPods.Store("default/foo", struct{}{})
+		record, err := n.switchPodDataPath(context.Background(), mc, "default/foo", "", cnitypes.IPVlan)
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+		n.pendingPods.Delete("default/foo")
+
+		// the pod is recreated
+		record, err = n.switchPodDataPath(context.Background(), mc, "default/foo", "c0", cnitypes.IPVlan)
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+		assert.Empty(t, steps.sets)
+	})
+}
+
+func TestNetworkService_rollbackPods(t *testing.T) {
+	steps := stubMigrationSteps(t, cnitypes.IPVlan)
+	calls := stubChainedPlugin(t, "")
+	n := newMigrationTestService(t)
+	now := time.Now()
+	assert.NoError(t, n.migrationDB.Put("default/foo", daemon.DataPathMigration{
+		Pod: "default/foo", ContainerID: "c1", From: "datapathv2", To: "ipvlan", State: daemon.DataPathMigrationMigrated, Time: now,
+		Chained: []map[string]interface{}{{"type": "cilium-cni", "cniVersion": "0.4.0"}},
+	}))
+	assert.NoError(t, n.migrationDB.Put("default/bar", daemon.DataPathMigration{
+		Pod: "default/bar", ContainerID: "c2", From: "veth", To: "ipvlan", State: daemon.DataPathMigrationMigrated, Time: now,
+	}))
+	mc := &migrationConf{
+		conf:    &cnitypes.CNIConf{ENIIPVirtualType: "datapathv2"},
+		chained: []chainedPlugin{{Type: "cilium-cni", CNIVersion: "0.4.0", conf: map[string]interface{}{"type": "cilium-cni"}}},
+	}
+
+	assert.NoError(t, n.rollbackPods(context.Background(), mc))
+	assert.Equal(t, [][2]cnitypes.DataPath{{cnitypes.IPVlan, cnitypes.PolicyRoute}}, steps.sets)
+	assert.Len(t, *calls, 2)
+
+	records, err := n.migrationRecords()
+	assert.NoError(t, err)
+	// the record of the pod not found is dropped
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "default/foo", records[0].Pod)
+		assert.Equal(t, daemon.DataPathMigrationRolledBack, records[0].State)
+	}
+}
+
+func TestNetworkService_switchDataPathV2(t *testing.T) {
+	cilium := []chainedPlugin{{Type: "cilium-cni", CNIVersion: "0.4.0", conf: map[string]interface{}{"type": "cilium-cni", "cniVersion": "0.4.0"}}}
+	steps := stubMigrationSteps(t, cnitypes.PolicyRoute)
+	calls := stubChainedPlugin(t, "")
+	n := newMigrationTestService(t)
+
+	// the endpoint is added to the veth pod, eth0 is kept
+	v2 := &migrationConf{conf: &cnitypes.CNIConf{ENIIPVirtualType: "datapathv2"}, chained: cilium}
+	assert.NoError(t, n.migratePods(context.Background(), v2, cnitypes.PolicyRoute))
+	assert.Empty(t, steps.sets)
+	var order []string
+	for _, call := range *calls {
+		order = append(order, call.Command+" "+call.Type)
+	}
+	assert.Equal(t, []string{"DEL cilium-cni", "ADD cilium-cni"}, order)
+	records, err := n.migrationRecords()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "veth", records[0].From)
+		assert.Equal(t, "datapathv2", records[0].To)
+		assert.Equal(t, daemon.DataPathMigrationMigrated, records[0].State)
+	}
+
+	// already on datapathv2
+	record, err := n.switchPodDataPath(context.Background(), v2, "default/foo", "", cnitypes.PolicyRoute)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	// the endpoint is deleted by the recorded config, the current config has no chained plugin
+	*calls = nil
+	veth := &migrationConf{conf: &cnitypes.CNIConf{ENIIPVirtualType: "veth"}}
+	assert.NoError(t, n.rollbackPods(context.Background(), veth))
+	assert.Empty(t, steps.sets)
+	if assert.Len(t, *calls, 1) {
+		assert.Equal(t, "DEL", (*calls)[0].Command)
+		assert.Equal(t, "cilium-cni", (*calls)[0].Type)
+	}
+	records, err = n.migrationRecords()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, daemon.DataPathMigrationRolledBack, records[0].State)
+		assert.Empty(t, records[0].Chained)
+	}
+
+	// back on veth
+	record, err = n.switchPodDataPath(context.Background(), veth, "default/foo", "", cnitypes.PolicyRoute)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func podDataPath(netNSPath string) (cnitypes.DataPath, error) {
+	return 0, fmt.Errorf("datapath migration is not supported")
+}
+
+func setPodDataPath(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, from, to cnitypes.DataPath) error {
+	return fmt.Errorf("datapath migration is not supported")
+}
+
+func checkPodDataPath(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, alloc *rpc.NetConf, dp cnitypes.DataPath, recordEvent func(msg string)) error {
+	return fmt.Errorf("datapath migration is not supported")
+}
@@ -0,0 +1,442 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	egressGatewayResyncPeriod = 1 * time.Minute
+	egressGatewayAllocTimeout = 2 * time.Minute
+
+	// egressGatewayOwner is the namespace of the ip reserved in the eni pool, it is not a valid namespace so no pod conflicts with it
+	egressGatewayOwner = "_egress-gateway"
+
+	egressGatewayDBPath = "/var/lib/cni/terway/egress.db"
+	egressGatewayDBName = "egress"
+)
+
+var egressGatewayLog = serviceLog.WithName("egress-gateway")
+
+// egressGatewayPod is the pod using the egress gateway
+type egressGatewayPod struct {
+	IP      string
+	Gateway *types.EgressGateway
+}
+
+// egressGatewayIP is the ip reserved for the egress gateway on the eni
+type egressGatewayIP struct {
+	Name      string
+	IP        string
+	ENIMAC    string
+	GatewayIP string
+}
+
+// egressGatewayPods return the pods using egress gateway, only the ipv4 from the shared eni is supported
+func egressGatewayPods(podResources []daemon.PodResources) []egressGatewayPod {
+	var pods []egressGatewayPod
+	for _, podRes := range podResources {
+		if podRes.PodInfo == nil || podRes.PodInfo.EgressGateway == nil ||
+			podRes.PodInfo.PodNetworkType != daemon.PodNetworkTypeENIMultiIP || podRes.PodInfo.PodENI {
+			continue
+		}
+		for _, item := range podRes.GetResourceItemByType(daemon.ResourceTypeENIIP) {
+			if item.IPv4 == "" {
+				continue
+			}
+			pods = append(pods, egressGatewayPod{IP: item.IPv4, Gateway: podRes.PodInfo.EgressGateway})
+			break
+		}
+	}
+	return pods
+}
+
+// newEgressGatewayRecord store the ip reserved for the gateway in the format of pod resources, so the eni pool can restore it.
+// The gateway is kept in the pod info, so the designation of the reservation is known.
+func newEgressGatewayRecord(gw *types.EgressGateway, resources eni.NetworkResources) (daemon.PodResources, error) {
+	var netConf []*rpc.NetConf
+	var items []daemon.ResourceItem
+	for _, res := range resources {
+		netConf = append(netConf, res.ToRPC()...)
+		items = append(items, res.ToStore()...)
+	}
+	out, err := json.Marshal(netConf)
+	if err != nil {
+		return daemon.PodResources{}, err
+	}
+	return daemon.PodResources{
+		PodInfo: &daemon.PodInfo{
+			Namespace:      egressGatewayOwner,
+			Name:           gw.Name,
+			PodNetworkType: daemon.PodNetworkTypeENIMultiIP,
+			EgressGateway:  gw,
+		},
+		Resources: items,
+		NetConf:   string(out),
+	}, nil
+}
+
+func toEgressGatewayIP(record daemon.PodResources) (*egressGatewayIP, error) {
+	var netConf []*rpc.NetConf
+	err := json.Unmarshal([]byte(record.NetConf), &netConf)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range netConf {
+		if c.GetBasicInfo().GetPodIP().GetIPv4() == "" || c.GetENIInfo().GetMAC() == "" {
+			continue
+		}
+		gw := c.GetENIInfo().GetGatewayIP().GetIPv4()
+		if gw == "" {
+			gw = c.GetBasicInfo().GetGatewayIP().GetIPv4()
+		}
+		return &egressGatewayIP{
+			Name:      record.PodInfo.Name,
+			IP:        c.GetBasicInfo().GetPodIP().GetIPv4(),
+			ENIMAC:    c.GetENIInfo().GetMAC(),
+			GatewayIP: gw,
+		}, nil
+	}
+	return nil, fmt.Errorf("no ipv4 reserved for egress gateway %s", record.PodInfo.Name)
+}
+
+// initEgressGateway open the store of the reserved ips, the records on the detached enis are dropped.
+// The records are returned to restore the eni pool.
+func (n *networkService) initEgressGateway(attachedENIID map[string]*daemon.ENI) ([]daemon.PodResources, error) {
+	db, err := storage.NewDiskStorage(egressGatewayDBName, utils.NormalizePath(egressGatewayDBPath), json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := &daemon.PodResources{}
+		err := json.Unmarshal(bytes, record)
+		if err != nil {
+			return nil, err
+		}
+		return *record, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error init egress gateway store, %w", err)
+	}
+	n.egressGatewayDB = db
+	n.egressGatewayPodIPs = sets.New[string]()
+
+	objList, err := db.List()
+	if err != nil {
+		return nil, err
+	}
+	var records []daemon.PodResources
+	for _, record := range filterENINotFound(getPodResources(objList), attachedENIID) {
+		if len(record.Resources) == 0 {
+			egressGatewayLog.Info("eni of egress gateway not found", "gateway", record.PodInfo.Name)
+			err = db.Delete(record.PodInfo.Name)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// startEgressGatewaySync reconcile the egress gateways periodically, the gateways removed from the cluster are released
+func (n *networkService) startEgressGatewaySync(ctx context.Context) {
+	_ = wait.PollUntilContextCancel(ctx, egressGatewayResyncPeriod, true, func(ctx context.Context) (done bool, err error) {
+		n.RLock()
+		defer n.RUnlock()
+
+		_, err = n.syncEgressGateways(ctx)
+		if err != nil {
+			egressGatewayLog.Error(err, "error sync egress gateways")
+		}
+		return false, nil
+	})
+}
+
+// egressGatewayChanged whether the egress gateway should be synced after the pod is allocated,
+// the rules of the ip may be left by the previous pod
+func (n *networkService) egressGatewayChanged(pod *daemon.PodInfo, resources []daemon.ResourceItem) bool {
+	if n.egressGatewayDB == nil {
+		return false
+	}
+	if pod.EgressGateway != nil {
+		return true
+	}
+	n.egressGatewayLock.Lock()
+	defer n.egressGatewayLock.Unlock()
+
+	for _, item := range resources {
+		if item.IPv4 != "" && n.egressGatewayPodIPs.Has(item.IPv4) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncEgressGateways caller should hold the read lock of the service, so the pod resources are not removed.
+// Return the ips of the pods whose gateway is failed to set, nil if the sync is failed before any gateway is processed.
+func (n *networkService) syncEgressGateways(ctx context.Context) (sets.Set[string], error) {
+	if n.egressGatewayDB == nil {
+		return sets.New[string](), nil
+	}
+	conf, err := loadCNIConf(filepath.Join(tmpCNIConfigPath, cinConfFile))
+	if err != nil {
+		return nil, err
+	}
+
+	n.egressGatewayLock.Lock()
+	defer n.egressGatewayLock.Unlock()
+
+	return n.reconcileEgressGateways(ctx, conf)
+}
+
+// reconcileEgressGateways reserve the ip for the gateways used by the pods on the node.
+// The reservation is not released with the pods, so the ip is stable on the node,
+// it is released once the gateway is removed from all the namespaces and PodNetworkings, or the designation is changed.
+func (n *networkService) reconcileEgressGateways(ctx context.Context, conf *cnitypes.CNIConf) (sets.Set[string], error) {
+	objList, err := n.resourceDB.List()
+	if err != nil {
+		return nil, err
+	}
+	pods := egressGatewayPods(getPodResources(objList))
+	if conf.IPVlan() && len(pods) > 0 {
+		// the traffic of pod is not forwarded by the host stack
+		egressGatewayLog.Info("egress gateway is not supported by ipvlan datapath", "pods", len(pods))
+		pods = nil
+	}
+	gateways := make(map[string]*types.EgressGateway)
+	for _, pod := range pods {
+		gateways[pod.Gateway.Name] = pod.Gateway
+	}
+
+	objList, err = n.egressGatewayDB.List()
+	if err != nil {
+		return nil, err
+	}
+	reserved := make(map[string]daemon.PodResources)
+	for _, record := range getPodResources(objList) {
+		reserved[record.PodInfo.Name] = record
+	}
+	// rules are left only if any gateway was reserved, skip the datapath on nodes not using egress gateway
+	configured := len(pods) > 0 || len(reserved) > 0
+
+	var errs []error
+	release := func(name string) bool {
+		err := n.releaseEgressGatewayIP(ctx, reserved[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error release ip of egress gateway %s, %w", name, err))
+			return false
+		}
+		delete(reserved, name)
+		return true
+	}
+
+	var idle []string
+	for name := range reserved {
+		if _, ok := gateways[name]; !ok {
+			idle = append(idle, name)
+		}
+	}
+	if len(idle) > 0 {
+		declared, err := n.declaredEgressGateways(ctx)
+		if err != nil {
+			// keep the reservations, until the gateway is known to be removed
+			errs = append(errs, fmt.Errorf("error list egress gateways, %w", err))
+		} else {
+			for _, name := range idle {
+				if !declared.Has(name) {
+					release(name)
+				}
+			}
+		}
+	}
+
+	names := lo.Keys(gateways)
+	sort.Strings(names)
+	for _, name := range names {
+		gw := gateways[name]
+		if record, ok := reserved[name]; ok {
+			if egressGatewayVSwitch(record) == gw.VSwitchID {
+				continue
+			}
+			egressGatewayLog.Info("designation of egress gateway changed", "gateway", name, "vSwitch", gw.VSwitchID)
+			if !release(name) {
+				continue
+			}
+		}
+		record, err := n.reserveEgressGatewayIP(ctx, gw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reserve ip for egress gateway %s, %w", name, err))
+			continue
+		}
+		reserved[name] = record
+	}
+
+	ips := make(map[string]*egressGatewayIP)
+	nodeIPs := make(map[string]string)
+	for name, record := range reserved {
+		ip, err := toEgressGatewayIP(record)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ips[name] = ip
+		nodeIPs[name] = ip.IP
+	}
+	out, err := json.Marshal(nodeIPs)
+	if err == nil {
+		err = n.k8s.PatchNodeAnnotations(map[string]string{types.NodeEgressGatewayIPs: string(out)})
+	}
+	errs = append(errs, err)
+
+	failed := sets.New[string]()
+	if configured {
+		err = setEgressGateways(ctx, pods, ips)
+		if err != nil {
+			for _, pod := range pods {
+				failed.Insert(pod.IP)
+			}
+			return failed, utilerrors.NewAggregate(append(errs, err))
+		}
+	}
+	n.egressGatewayPodIPs = sets.New[string]()
+	for _, pod := range pods {
+		if _, ok := ips[pod.Gateway.Name]; ok {
+			n.egressGatewayPodIPs.Insert(pod.IP)
+		} else {
+			failed.Insert(pod.IP)
+		}
+	}
+	return failed, utilerrors.NewAggregate(errs)
+}
+
+// reserveEgressGatewayIP allocate the ip from the eni pool, the ip is hold by the gateway until released.
+// The ip is allocated from the eni in the designated vSwitch if set.
+func (n *networkService) reserveEgressGatewayIP(ctx context.Context, gw *types.EgressGateway) (daemon.PodResources, error) {
+	ctx, cancel := context.WithTimeout(ctx, egressGatewayAllocTimeout)
+	defer cancel()
+
+	request := &eni.LocalIPRequest{}
+	if gw.VSwitchID != "" {
+		for _, status := range n.eniMgr.Status() {
+			if status.VSwitchID == gw.VSwitchID {
+				request.NetworkInterfaceID = status.NetworkInterfaceID
+				break
+			}
+		}
+		if request.NetworkInterfaceID == "" {
+			return daemon.PodResources{}, fmt.Errorf("no eni in vSwitch %s", gw.VSwitchID)
+		}
+	}
+
+	cni := &daemon.CNI{
+		PodName:      gw.Name,
+		PodNamespace: egressGatewayOwner,
+		PodID:        utils.PodInfoKey(egressGatewayOwner, gw.Name),
+	}
+	resp, err := n.eniMgr.Allocate(ctx, cni, &eni.AllocRequest{
+		ResourceRequests: []eni.ResourceRequest{request},
+	})
+	if err == nil && len(resp) == 0 {
+		err = errors.New("no ip allocated")
+	}
+	var record daemon.PodResources
+	if err == nil {
+		record, err = newEgressGatewayRecord(gw, resp)
+	}
+	if err == nil {
+		err = n.egressGatewayDB.Put(gw.Name, record)
+	}
+	if err != nil {
+		_ = n.eniMgr.Release(ctx, cni, &eni.ReleaseRequest{
+			NetworkResources: resp,
+		})
+		return daemon.PodResources{}, err
+	}
+	egressGatewayLog.Info("ip reserved for egress gateway", "gateway", gw.Name, "resources", record.Resources)
+	return record, nil
+}
+
+func (n *networkService) releaseEgressGatewayIP(ctx context.Context, record daemon.PodResources) error {
+	cni := &daemon.CNI{
+		PodName:      record.PodInfo.Name,
+		PodNamespace: egressGatewayOwner,
+		PodID:        utils.PodInfoKey(egressGatewayOwner, record.PodInfo.Name),
+	}
+	for _, item := range record.Resources {
+		res := parseNetworkResource(item)
+		if res == nil {
+			continue
+		}
+		err := n.eniMgr.Release(ctx, cni, &eni.ReleaseRequest{
+			NetworkResources: []eni.NetworkResource{res},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	egressGatewayLog.Info("ip of egress gateway released", "gateway", record.PodInfo.Name, "resources", record.Resources)
+	return n.egressGatewayDB.Delete(record.PodInfo.Name)
+}
+
+// egressGatewayVSwitch return the vSwitch the reservation is designated to, empty for the record without designation
+func egressGatewayVSwitch(record daemon.PodResources) string {
+	if record.PodInfo.EgressGateway == nil {
+		return ""
+	}
+	return record.PodInfo.EgressGateway.VSwitchID
+}
+
+// declaredEgressGateways return the names of the gateways declared by the namespaces and PodNetworkings
+func (n *networkService) declaredEgressGateways(ctx context.Context) (sets.Set[string], error) {
+	c := n.k8s.GetClient()
+	names := sets.New[string]()
+
+	nsList := &corev1.NamespaceList{}
+	err := c.List(ctx, nsList)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range nsList.Items {
+		gw, err := types.ParseEgressGateway(ns.Annotations[types.NamespaceEgressGateway])
+		if err != nil || gw == nil {
+			continue
+		}
+		names.Insert(gw.Name)
+	}
+
+	pnList := &v1beta1.PodNetworkingList{}
+	err = c.List(ctx, pnList)
+	if err != nil {
+		// PodNetworking is not installed
+		if k8sErr.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+	for _, pn := range pnList.Items {
+		if pn.Spec.EgressGateway != nil {
+			names.Insert(pn.Spec.EgressGateway.Name)
+		}
+	}
+	return names, nil
+}
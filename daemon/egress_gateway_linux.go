@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+)
+
+// setEgressGateways reconcile the rules of the egress gateways on the host, the pods without reserved ip are skipped
+func setEgressGateways(ctx context.Context, pods []egressGatewayPod, ips map[string]*egressGatewayIP) error {
+	gateways := make(map[string]*datapath.EgressGateway)
+	for name, ip := range ips {
+		index, err := link.GetDeviceNumber(ip.ENIMAC)
+		if err != nil {
+			return fmt.Errorf("error get eni of egress gateway %s, %w", name, err)
+		}
+		eni, err := netlink.LinkByIndex(int(index))
+		if err != nil {
+			return fmt.Errorf("error get eni of egress gateway %s, %w", name, err)
+		}
+		gateways[name] = &datapath.EgressGateway{
+			Name:      name,
+			IP:        net.ParseIP(ip.IP),
+			ENI:       eni,
+			GatewayIP: net.ParseIP(ip.GatewayIP),
+		}
+	}
+
+	var dpPods []*datapath.EgressGatewayPod
+	for _, pod := range pods {
+		gw, ok := gateways[pod.Gateway.Name]
+		if !ok {
+			continue
+		}
+		dpPods = append(dpPods, &datapath.EgressGatewayPod{
+			IP:       net.ParseIP(pod.IP),
+			Gateway:  gw,
+			DstCIDRs: pod.Gateway.ParsedDestinationCIDRs(),
+		})
+	}
+	return datapath.SetEgressGateways(ctx, dpPods)
+}
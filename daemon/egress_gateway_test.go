@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func Test_egressGatewayPods(t *testing.T) {
+	gw := &types.EgressGateway{Name: "partner", DestinationCIDRs: []string{"203.0.113.0/24"}}
+	podRes := func(name, podNetworkType string, podENI bool, gw *types.EgressGateway) daemon.PodResources {
+		return daemon.PodResources{
+			PodInfo: &daemon.PodInfo{Name: name, Namespace: "default", PodNetworkType: podNetworkType, PodENI: podENI, EgressGateway: gw},
+			Resources: []daemon.ResourceItem{
+				{Type: daemon.ResourceTypeENIIP, IPv6: "fd00::" + name},
+				{Type: daemon.ResourceTypeENIIP, IPv4: "192.168.0." + name},
+			},
+		}
+	}
+
+	pods := egressGatewayPods([]daemon.PodResources{
+		podRes("10", daemon.PodNetworkTypeENIMultiIP, false, gw),
+		podRes("11", daemon.PodNetworkTypeENIMultiIP, false, nil),
+		podRes("12", daemon.PodNetworkTypeENIMultiIP, true, gw),
+		podRes("13", daemon.PodNetworkTypeVPCENI, false, gw),
+	})
+	assert.Equal(t, []egressGatewayPod{{IP: "192.168.0.10", Gateway: gw}}, pods)
+}
+
+func Test_toEgressGatewayIP(t *testing.T) {
+	gw := &types.EgressGateway{Name: "partner", DestinationCIDRs: []string{"203.0.113.0/24"}, VSwitchID: "vsw-1"}
+	record, err := newEgressGatewayRecord(gw, eni.NetworkResources{&eni.LocalIPResource{
+		PodID: "_egress-gateway/partner",
+		ENI: daemon.ENI{
+			ID:        "eni-1",
+			MAC:       "00:00:00:00:00:01",
+			GatewayIP: types.IPSet{IPv4: net.ParseIP("192.168.0.253")},
+		},
+		IP: types.IPSet2{IPv4: netip.MustParseAddr("192.168.0.100")},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, egressGatewayOwner, record.PodInfo.Namespace)
+	assert.Equal(t, "eni-1", record.Resources[0].ENIID)
+	assert.Equal(t, "vsw-1", egressGatewayVSwitch(record))
+
+	ip, err := toEgressGatewayIP(record)
+	require.NoError(t, err)
+	assert.Equal(t, &egressGatewayIP{Name: "partner", IP: "192.168.0.100", ENIMAC: "00:00:00:00:00:01", GatewayIP: "192.168.0.253"}, ip)
+
+	record.NetConf = "[]"
+	_, err = toEgressGatewayIP(record)
+	assert.Error(t, err)
+}
+
+func TestNetworkService_reconcileEgressGateways(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "partner",
+		Annotations: map[string]string{types.NamespaceEgressGateway: `{"name":"partner","destinationCIDRs":["203.0.113.0/24"]}`},
+	}}
+	c := fake.NewClientBuilder().WithScheme(types.Scheme).WithObjects(ns).Build()
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetClient").Return(c)
+	k8sClient.On("PatchNodeAnnotations", map[string]string{types.NodeEgressGatewayIPs: `{"partner":"192.168.0.100"}`}).Return(nil).Once()
+	k8sClient.On("PatchNodeAnnotations", map[string]string{types.NodeEgressGatewayIPs: "{}"}).Return(nil).Once()
+
+	n := &networkService{
+		k8s:                 k8sClient,
+		resourceDB:          storage.NewMemoryStorage(),
+		egressGatewayDB:     storage.NewMemoryStorage(),
+		egressGatewayPodIPs: sets.New[string]("192.168.0.10"),
+		eniMgr:              eni.NewManager(0, 0, 0, 0, nil, types.EniSelectionPolicyMostIPs, nil),
+	}
+	assert.NoError(t, n.resourceDB.Put("default/pod", daemon.PodResources{
+		PodInfo: &daemon.PodInfo{
+			Name: "pod", Namespace: "default", PodNetworkType: daemon.PodNetworkTypeENIMultiIP,
+			EgressGateway: &types.EgressGateway{Name: "partner", DestinationCIDRs: []string{"203.0.113.0/24"}},
+		},
+		Resources: []daemon.ResourceItem{{Type: daemon.ResourceTypeENIIP, IPv4: "192.168.0.10"}},
+	}))
+	record, err := newEgressGatewayRecord(&types.EgressGateway{Name: "partner"}, eni.NetworkResources{&eni.LocalIPResource{
+		ENI: daemon.ENI{ID: "eni-1", MAC: "00:00:00:00:00:01"},
+		IP:  types.IPSet2{IPv4: netip.MustParseAddr("192.168.0.100")},
+	}})
+	require.NoError(t, err)
+	assert.NoError(t, n.egressGatewayDB.Put("partner", record))
+
+	// ipvlan datapath is not supported, no pod uses the gateway.
+	// The datapath cleanup result depends on the host, so only the reservation is checked
+	failed, _ := n.reconcileEgressGateways(context.Background(), &cnitypes.CNIConf{ENIIPVirtualType: "IPVlan"})
+	// the pod is not failed, the gateway is skipped by the datapath
+	assert.Empty(t, failed)
+
+	// the reservation is kept while the gateway is declared
+	list, err := n.egressGatewayDB.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	// released once the gateway is removed
+	ns.Annotations = nil
+	assert.NoError(t, c.Update(context.Background(), ns))
+	_, _ = n.reconcileEgressGateways(context.Background(), &cnitypes.CNIConf{ENIIPVirtualType: "IPVlan"})
+
+	list, err = n.egressGatewayDB.List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestNetworkService_reserveEgressGatewayIP(t *testing.T) {
+	n := &networkService{
+		egressGatewayDB: storage.NewMemoryStorage(),
+		eniMgr:          eni.NewManager(0, 0, 0, 0, nil, types.EniSelectionPolicyMostIPs, nil),
+	}
+	_, err := n.reserveEgressGatewayIP(context.Background(), &types.EgressGateway{Name: "partner", VSwitchID: "vsw-1"})
+	assert.ErrorContains(t, err, "no eni in vSwitch vsw-1")
+}
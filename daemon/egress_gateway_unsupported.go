@@ -0,0 +1,15 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+)
+
+func setEgressGateways(ctx context.Context, pods []egressGatewayPod, ips map[string]*egressGatewayIP) error {
+	if len(pods) == 0 {
+		return nil
+	}
+	return fmt.Errorf("egress gateway is not supported")
+}
@@ -0,0 +1,364 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/AliyunContainerService/terway/pkg/k8s"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/pkg/utils/k8sclient"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	hostStackResyncPeriod = 30 * time.Minute
+	hostStackMaxRetries   = 5
+	// hostStackMountDelay the config file is updated by kubelet some time after the configmap is changed
+	hostStackMountDelay = 2 * time.Minute
+
+	hostStackQueueKey = "host-stack"
+	eniConfigName     = "eni-config"
+)
+
+var hostStackLog = serviceLog.WithName("host-stack")
+
+// hostStackWatcher watch the configmaps in the daemon namespace and the node, the dynamic config is picked by the node label
+type hostStackWatcher struct {
+	nodeName  string
+	namespace string
+
+	nodeLister      corev1listers.NodeLister
+	configMapLister corev1listers.ConfigMapLister
+}
+
+// dynamicConfig return the content of the dynamic config of the node, empty if not set
+func (w *hostStackWatcher) dynamicConfig() (string, error) {
+	node, err := w.nodeLister.Get(w.nodeName)
+	if err != nil {
+		return "", err
+	}
+	name := node.Labels[k8s.LabelDynamicConfig]
+	if name == "" {
+		return "", nil
+	}
+	cm, err := w.configMapLister.ConfigMaps(w.namespace).Get(name)
+	if err != nil {
+		return "", err
+	}
+	content, ok := cm.Data["eni_conf"]
+	if !ok {
+		return "", errors.New("configmap not included eni_conf")
+	}
+	return content, nil
+}
+
+// relevant whether the configmap is the eni-config or the dynamic config of the node
+func (w *hostStackWatcher) relevant(cm *corev1.ConfigMap) bool {
+	if cm.Name == eniConfigName {
+		return true
+	}
+	node, err := w.nodeLister.Get(w.nodeName)
+	if err != nil {
+		return true
+	}
+	return node.Labels[k8s.LabelDynamicConfig] == cm.Name
+}
+
+// startHostStackCIDRSync watch the config and the node, sync the changes of host stack cidrs to the running pods
+func (n *networkService) startHostStackCIDRSync(ctx context.Context) {
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+	nodeFactory := informers.NewSharedInformerFactoryWithOptions(k8sclient.K8sClient, hostStackResyncPeriod,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", n.k8s.NodeName()).String()
+		}))
+	cmFactory := informers.NewSharedInformerFactoryWithOptions(k8sclient.K8sClient, hostStackResyncPeriod,
+		informers.WithNamespace(namespace))
+	nodeInformer := nodeFactory.Core().V1().Nodes()
+	cmInformer := cmFactory.Core().V1().ConfigMaps()
+
+	w := &hostStackWatcher{
+		nodeName:        n.k8s.NodeName(),
+		namespace:       namespace,
+		nodeLister:      nodeInformer.Lister(),
+		configMapLister: cmInformer.Lister(),
+	}
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	_, err := nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			queue.Add(hostStackQueueKey)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*corev1.Node)
+			if !ok || oldNode.Labels[k8s.LabelDynamicConfig] == newNode.Labels[k8s.LabelDynamicConfig] {
+				return
+			}
+			queue.Add(hostStackQueueKey)
+		},
+	})
+	if err != nil {
+		hostStackLog.Error(err, "error add node event handler")
+		return
+	}
+	onConfigMap := func(obj interface{}) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok || !w.relevant(cm) {
+			return
+		}
+		queue.Add(hostStackQueueKey)
+		if cm.Name == eniConfigName {
+			queue.AddAfter(hostStackQueueKey, hostStackMountDelay)
+		}
+	}
+	_, err = cmInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: onConfigMap,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			onConfigMap(newObj)
+		},
+		DeleteFunc: onConfigMap,
+	})
+	if err != nil {
+		hostStackLog.Error(err, "error add configmap event handler")
+		return
+	}
+
+	nodeFactory.Start(ctx.Done())
+	cmFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced, cmInformer.Informer().HasSynced) {
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		err = n.syncHostStackCIDRs(ctx, w)
+		if err != nil && queue.NumRequeues(item) < hostStackMaxRetries {
+			hostStackLog.Error(err, "error sync host stack cidrs")
+			queue.AddRateLimited(item)
+		} else {
+			queue.Forget(item)
+		}
+		queue.Done(item)
+	}
+}
+
+// loadHostStackCIDRs read the host stack cidrs from the config file, merged with the node dynamic config
+func (n *networkService) loadHostStackCIDRs(w *hostStackWatcher) ([]string, error) {
+	dynamicCfg, err := w.dynamicConfig()
+	if err != nil {
+		return nil, err
+	}
+	config, err := daemon.GetConfigFromFileWithMerge(n.configFilePath, []byte(dynamicCfg))
+	if err != nil {
+		return nil, err
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config.HostStackCIDRs, nil
+}
+
+func (n *networkService) syncHostStackCIDRs(ctx context.Context, w *hostStackWatcher) error {
+	cidrs, err := n.loadHostStackCIDRs(w)
+	if err != nil {
+		return err
+	}
+
+	// serve the new cidrs first, the pods allocated after are setup with them.
+	// The lock is not held while the pods are updated, so the cni requests are not blocked.
+	n.Lock()
+	if !slices.Equal(n.hostStackCIDRs, cidrs) {
+		hostStackLog.Info("host stack cidrs changed", "from", n.hostStackCIDRs, "to", cidrs)
+	}
+	n.hostStackCIDRs = cidrs
+	n.Unlock()
+
+	conf, err := loadCNIConf(filepath.Join(tmpCNIConfigPath, cinConfFile))
+	if err != nil {
+		return err
+	}
+	return n.reconcileHostStackCIDRs(ctx, conf, cidrs)
+}
+
+// reconcileHostStackCIDRs update the datapath of pods which are setup with other host stack cidrs,
+// the cidrs served to the pod are recorded in the stored net conf.
+// Pods are updated from a snapshot of the resource db, the lock is only held to take the snapshot and store the result.
+func (n *networkService) reconcileHostStackCIDRs(ctx context.Context, conf *cnitypes.CNIConf, cidrs []string) error {
+	want, err := conf.GetHostStackCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+	want = n.ipStackCIDRs(want)
+
+	n.RLock()
+	objList, err := n.resourceDB.List()
+	n.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	// pods in ipvlan mode share the redirect filters on the eni
+	eniPods := make(map[string][]daemon.PodResources)
+	for _, podRes := range getPodResources(objList) {
+		if podRes.PodInfo == nil || podRes.NetNs == nil || podRes.NetConf == "" {
+			continue
+		}
+		var netConf []*rpc.NetConf
+		err = json.Unmarshal([]byte(podRes.NetConf), &netConf)
+		if err != nil {
+			continue
+		}
+		c := defaultNetConf(netConf)
+		if c == nil || slices.Equal(c.GetBasicInfo().GetHostStackCIDRs(), cidrs) {
+			continue
+		}
+
+		switch {
+		case c.GetENIInfo().GetTrunk():
+			// vlan datapath does not redirect to host stack
+		case podRes.PodInfo.PodNetworkType == daemon.PodNetworkTypeENIMultiIP && conf.IPVlan():
+			mac := c.GetENIInfo().GetMAC()
+			if mac == "" {
+				continue
+			}
+			eniPods[mac] = append(eniPods[mac], podRes)
+			continue
+		case podRes.PodInfo.PodNetworkType == daemon.PodNetworkTypeVPCENI:
+			prev, err := conf.GetHostStackCIDRs(c.GetBasicInfo().GetHostStackCIDRs())
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			err = setHostStackRoutes(ctx, *podRes.NetNs, want, removedCIDRs(prev, want))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error set host stack routes for pod %s/%s, %w", podRes.PodInfo.Namespace, podRes.PodInfo.Name, err))
+				continue
+			}
+		}
+		errs = append(errs, n.storeHostStackCIDRs(podRes, cidrs))
+	}
+
+	redirectCIDRs := slices.Clone(want)
+	if svc := n.k8s.GetServiceCIDR(); svc != nil {
+		redirectCIDRs = append(redirectCIDRs, n.ipStackCIDRs([]*net.IPNet{svc.IPv4, svc.IPv6})...)
+	}
+	for mac, pods := range eniPods {
+		err = setRedirectFilters(ctx, mac, redirectCIDRs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error set redirect filters for eni %s, %w", mac, err))
+			continue
+		}
+		for _, podRes := range pods {
+			errs = append(errs, n.storeHostStackCIDRs(podRes, cidrs))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// storeHostStackCIDRs record the cidrs applied to the pod.
+// The pod released or setup again since the snapshot is skipped, the new record is served with the latest cidrs.
+func (n *networkService) storeHostStackCIDRs(podRes daemon.PodResources, cidrs []string) error {
+	n.Lock()
+	defer n.Unlock()
+
+	current, err := n.getPodResource(podRes.PodInfo)
+	if err != nil {
+		return err
+	}
+	if current.PodInfo == nil || current.NetConf != podRes.NetConf {
+		return nil
+	}
+
+	var netConf []*rpc.NetConf
+	err = json.Unmarshal([]byte(podRes.NetConf), &netConf)
+	if err != nil {
+		return err
+	}
+	for _, c := range netConf {
+		if c.BasicInfo == nil {
+			c.BasicInfo = &rpc.BasicInfo{}
+		}
+		c.BasicInfo.HostStackCIDRs = cidrs
+	}
+	out, err := json.Marshal(netConf)
+	if err != nil {
+		return err
+	}
+	podRes.NetConf = string(out)
+	return n.resourceDB.Put(utils.PodInfoKey(podRes.PodInfo.Namespace, podRes.PodInfo.Name), podRes)
+}
+
+// ipStackCIDRs return the cidrs of the enabled ip family
+func (n *networkService) ipStackCIDRs(cidrs []*net.IPNet) []*net.IPNet {
+	var out []*net.IPNet
+	for _, cidr := range cidrs {
+		if cidr == nil {
+			continue
+		}
+		if cidr.IP.To4() != nil && n.enableIPv4 || cidr.IP.To4() == nil && n.enableIPv6 {
+			out = append(out, cidr)
+		}
+	}
+	return out
+}
+
+func defaultNetConf(netConf []*rpc.NetConf) *rpc.NetConf {
+	for _, c := range netConf {
+		if defaultIf(c.IfName) {
+			return c
+		}
+	}
+	return nil
+}
+
+// removedCIDRs return the cidrs in prev but not in want
+func removedCIDRs(prev, want []*net.IPNet) []*net.IPNet {
+	keep := sets.New[string]()
+	for _, cidr := range want {
+		keep.Insert(cidr.String())
+	}
+	var removed []*net.IPNet
+	for _, cidr := range prev {
+		if !keep.Has(cidr.String()) {
+			removed = append(removed, cidr)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"context"
+	"net"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+)
+
+// setRedirectFilters reconcile the filters on the eni which redirect the cidrs to host stack, same as the ipvlan setup
+func setRedirectFilters(ctx context.Context, mac string, cidrs []*net.IPNet) error {
+	index, err := link.GetDeviceNumber(mac)
+	if err != nil {
+		return err
+	}
+	eni, err := netlink.LinkByIndex(int(index))
+	if err != nil {
+		return err
+	}
+	return datapath.NewIPVlanDriver().SetupRedirectFilters(ctx, eni, cidrs)
+}
+
+// setHostStackRoutes update the routes to host stack in the pod net ns, same as the exclusive eni setup
+func setHostStackRoutes(ctx context.Context, netNSPath string, add, del []*net.IPNet) error {
+	netNS, err := ns.GetNS(netNSPath)
+	if err != nil {
+		return err
+	}
+	defer netNS.Close()
+
+	return datapath.SetHostStackRoutes(ctx, netNS, add, del)
+}
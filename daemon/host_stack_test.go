@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/AliyunContainerService/terway/pkg/k8s"
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func Test_removedCIDRs(t *testing.T) {
+	_, a, _ := net.ParseCIDR("169.254.20.10/32")
+	_, b, _ := net.ParseCIDR("192.168.0.0/24")
+	assert.Equal(t, []*net.IPNet{b}, removedCIDRs([]*net.IPNet{a, b}, []*net.IPNet{a}))
+	assert.Empty(t, removedCIDRs([]*net.IPNet{a}, []*net.IPNet{a, b}))
+}
+
+func TestNetworkService_ipStackCIDRs(t *testing.T) {
+	_, v4, _ := net.ParseCIDR("169.254.20.10/32")
+	_, v6, _ := net.ParseCIDR("fd00::10/128")
+
+	n := &networkService{enableIPv6: true}
+	assert.Equal(t, []*net.IPNet{v6}, n.ipStackCIDRs([]*net.IPNet{v4, nil, v6}))
+
+	n = &networkService{enableIPv4: true, enableIPv6: true}
+	assert.Equal(t, []*net.IPNet{v4, v6}, n.ipStackCIDRs([]*net.IPNet{v4, nil, v6}))
+}
+
+func TestNetworkService_reconcileHostStackCIDRs(t *testing.T) {
+	netNS := "/var/run/netns/not-exist"
+	podRes := func(name, podNetworkType string, trunk bool, cidrs []string) daemon.PodResources {
+		out, _ := json.Marshal([]*rpc.NetConf{{
+			BasicInfo: &rpc.BasicInfo{HostStackCIDRs: cidrs},
+			ENIInfo:   &rpc.ENIInfo{MAC: "00:00:00:00:00:01", Trunk: trunk},
+		}})
+		return daemon.PodResources{
+			PodInfo: &daemon.PodInfo{Name: name, Namespace: "default", PodNetworkType: podNetworkType},
+			NetNs:   &netNS,
+			NetConf: string(out),
+		}
+	}
+	storedCIDRs := func(n *networkService, name string) []string {
+		obj, err := n.resourceDB.Get("default/" + name)
+		assert.NoError(t, err)
+		var netConf []*rpc.NetConf
+		assert.NoError(t, json.Unmarshal([]byte(obj.(daemon.PodResources).NetConf), &netConf))
+		return netConf[0].GetBasicInfo().GetHostStackCIDRs()
+	}
+
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetServiceCIDR").Return(&types.IPNetSet{}).Maybe()
+	n := &networkService{k8s: k8sClient, resourceDB: storage.NewMemoryStorage(), enableIPv4: true}
+	for _, res := range []daemon.PodResources{
+		// veth datapath send all traffic to host stack
+		podRes("policy-route", daemon.PodNetworkTypeENIMultiIP, false, nil),
+		podRes("trunk", daemon.PodNetworkTypeVPCENI, true, []string{"192.168.0.0/24"}),
+		podRes("synced", daemon.PodNetworkTypeVPCENI, false, []string{"169.254.20.10/32"}),
+		// failed to enter the net ns
+		podRes("exclusive", daemon.PodNetworkTypeVPCENI, false, nil),
+	} {
+		assert.NoError(t, n.resourceDB.Put("default/"+res.PodInfo.Name, res))
+	}
+
+	cidrs := []string{"169.254.20.10/32"}
+	err := n.reconcileHostStackCIDRs(context.Background(), &cnitypes.CNIConf{}, cidrs)
+	assert.Error(t, err)
+
+	assert.Equal(t, cidrs, storedCIDRs(n, "policy-route"))
+	assert.Equal(t, cidrs, storedCIDRs(n, "trunk"))
+	assert.Equal(t, cidrs, storedCIDRs(n, "synced"))
+	assert.Empty(t, storedCIDRs(n, "exclusive"))
+
+	// pod setup again while the datapath is updating, the new record is kept
+	stale := podRes("synced", daemon.PodNetworkTypeVPCENI, false, nil)
+	assert.NoError(t, n.storeHostStackCIDRs(stale, []string{"192.168.0.0/24"}))
+	assert.Equal(t, cidrs, storedCIDRs(n, "synced"))
+
+	// pod released while the datapath is updating
+	released := podRes("released", daemon.PodNetworkTypeVPCENI, false, nil)
+	assert.NoError(t, n.storeHostStackCIDRs(released, cidrs))
+	_, err = n.resourceDB.Get("default/released")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestHostStackWatcher(t *testing.T) {
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	w := &hostStackWatcher{
+		nodeName:        "node-1",
+		namespace:       "kube-system",
+		nodeLister:      corev1listers.NewNodeLister(nodeIndexer),
+		configMapLister: corev1listers.NewConfigMapLister(cmIndexer),
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	assert.NoError(t, nodeIndexer.Add(node))
+	dynamic := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "dynamic", Namespace: "kube-system"},
+		Data:       map[string]string{"eni_conf": `{"host_stack_cidrs":["169.254.20.10/32"]}`},
+	}
+	assert.NoError(t, cmIndexer.Add(dynamic))
+
+	// no dynamic config
+	cfg, err := w.dynamicConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg)
+	assert.True(t, w.relevant(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: eniConfigName}}))
+	assert.False(t, w.relevant(dynamic))
+
+	labeled := node.DeepCopy()
+	labeled.Labels = map[string]string{k8s.LabelDynamicConfig: "dynamic"}
+	assert.NoError(t, nodeIndexer.Update(labeled))
+	cfg, err = w.dynamicConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, dynamic.Data["eni_conf"], cfg)
+	assert.True(t, w.relevant(dynamic))
+	assert.False(t, w.relevant(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other"}}))
+
+	// configmap is not found
+	assert.NoError(t, cmIndexer.Delete(dynamic))
+	_, err = w.dynamicConfig()
+	assert.Error(t, err)
+}
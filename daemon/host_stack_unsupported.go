@@ -0,0 +1,17 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+func setRedirectFilters(ctx context.Context, mac string, cidrs []*net.IPNet) error {
+	return fmt.Errorf("host stack cidrs can not be changed on running pods")
+}
+
+func setHostStackRoutes(ctx context.Context, netNSPath string, add, del []*net.IPNet) error {
+	return fmt.Errorf("host stack cidrs can not be changed on running pods")
+}
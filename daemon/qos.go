@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/AliyunContainerService/terway/pkg/k8s"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/pkg/utils/k8sclient"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	qosResyncPeriod = 30 * time.Minute
+	qosMaxRetries   = 5
+
+	eventReasonUpdateQoSSucceed = "UpdateQoSSucceed"
+	eventReasonUpdateQoSFailed  = "UpdateQoSFailed"
+)
+
+var qosLog = serviceLog.WithName("qos")
+
+// podQoS is the network qos can be changed on running pods
+type podQoS struct {
+	Ingress         uint64
+	Egress          uint64
+	NetworkPriority string
+}
+
+func qosOf(info *daemon.PodInfo) podQoS {
+	return podQoS{
+		Ingress:         info.TcIngress,
+		Egress:          info.TcEgress,
+		NetworkPriority: info.NetworkPriority,
+	}
+}
+
+// qosAnnotationChanged whether the annotations take effect on running pods changed
+func qosAnnotationChanged(oldPod, newPod *corev1.Pod) bool {
+	for _, key := range k8s.QoSAnnotations {
+		if oldPod.Annotations[key] != newPod.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasQoSAnnotation(pod *corev1.Pod) bool {
+	for _, key := range k8s.QoSAnnotations {
+		if _, ok := pod.Annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCNIConf read the terway plugin config from the cni config list
+func loadCNIConf(path string) (*cnitypes.CNIConf, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	confList := struct {
+		Plugins []json.RawMessage `json:"plugins"`
+	}{}
+	err = json.Unmarshal(b, &confList)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cni config %s, %w", path, err)
+	}
+	for _, raw := range confList.Plugins {
+		conf := &cnitypes.CNIConf{}
+		err = json.Unmarshal(raw, conf)
+		if err != nil {
+			return nil, fmt.Errorf("error parse cni config %s, %w", path, err)
+		}
+		if conf.Type == "terway" {
+			return conf, nil
+		}
+	}
+	return nil, fmt.Errorf("terway plugin not found in %s", path)
+}
+
+// startPodQoSController watch the local pods, apply the qos annotation changes without restarting the pod
+func (n *networkService) startPodQoSController(ctx context.Context) {
+	factory := informers.NewSharedInformerFactoryWithOptions(k8sclient.K8sClient, qosResyncPeriod,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", n.k8s.NodeName()).String()
+		}))
+	informer := factory.Core().V1().Pods().Informer()
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || !hasQoSAnnotation(pod) {
+				return
+			}
+			queue.Add(utils.PodInfoKey(pod.Namespace, pod.Name))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok || !qosAnnotationChanged(oldPod, newPod) {
+				return
+			}
+			queue.Add(utils.PodInfoKey(newPod.Namespace, newPod.Name))
+		},
+	})
+	if err != nil {
+		qosLog.Error(err, "error add pod event handler")
+		return
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		key := item.(string)
+		err = n.syncPodQoS(ctx, key)
+		if err != nil && queue.NumRequeues(item) < qosMaxRetries {
+			qosLog.Error(err, "error sync pod qos", "pod", key)
+			queue.AddRateLimited(item)
+		} else {
+			queue.Forget(item)
+		}
+		queue.Done(item)
+	}
+}
+
+// syncPodQoS compare the pod annotations with the qos applied at setup, update the datapath in place if changed
+func (n *networkService) syncPodQoS(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+
+	// the datapath is updated out of the service lock, so CNI requests of other pods are not blocked
+	n.RLock()
+	podRes, err := n.getPodResource(&daemon.PodInfo{Namespace: namespace, Name: name})
+	n.RUnlock()
+	if err != nil {
+		return err
+	}
+	// not setup yet or the qos is not applied by the shared eni datapath
+	if podRes.PodInfo == nil || podRes.NetNs == nil ||
+		podRes.PodInfo.PodNetworkType != daemon.PodNetworkTypeENIMultiIP || podRes.PodInfo.PodENI {
+		return nil
+	}
+
+	pod, err := n.k8s.GetPod(ctx, namespace, name, true)
+	if err != nil {
+		return err
+	}
+	if pod.PodUID != podRes.PodInfo.PodUID {
+		return nil
+	}
+
+	prev, want := qosOf(podRes.PodInfo), qosOf(pod)
+	if prev == want {
+		return nil
+	}
+
+	conf, err := loadCNIConf(filepath.Join(tmpCNIConfigPath, cinConfFile))
+	if err == nil {
+		err = applyPodQoS(ctx, conf, &podRes, prev, want)
+	}
+	if err != nil {
+		_ = n.k8s.RecordPodEvent(name, namespace, corev1.EventTypeWarning, eventReasonUpdateQoSFailed, err.Error())
+		return err
+	}
+
+	err = n.updatePodQoS(key, &podRes, want)
+	if err != nil {
+		return err
+	}
+
+	qosLog.Info("pod qos updated", "pod", key, "from", prev, "to", want)
+	_ = n.k8s.RecordPodEvent(name, namespace, corev1.EventTypeNormal, eventReasonUpdateQoSSucceed,
+		fmt.Sprintf("Network qos updated, ingress %d, egress %d, priority %q.", want.Ingress, want.Egress, want.NetworkPriority))
+	return nil
+}
+
+// updatePodQoS record the qos applied, skipped if the pod is released or recreated after the snapshot is taken
+func (n *networkService) updatePodQoS(key string, snapshot *daemon.PodResources, want podQoS) error {
+	n.Lock()
+	defer n.Unlock()
+
+	podRes, err := n.getPodResource(snapshot.PodInfo)
+	if err != nil {
+		return err
+	}
+	if podRes.PodInfo == nil || podRes.PodInfo.PodUID != snapshot.PodInfo.PodUID ||
+		podRes.ContainerID == nil || snapshot.ContainerID == nil || *podRes.ContainerID != *snapshot.ContainerID {
+		return nil
+	}
+
+	podRes.PodInfo.TcIngress = want.Ingress
+	podRes.PodInfo.TcEgress = want.Egress
+	podRes.PodInfo.NetworkPriority = want.NetworkPriority
+	return n.resourceDB.Put(key, podRes)
+}
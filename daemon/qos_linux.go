@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+// applyPodQoS update the priority filters on the eni and the tbf qdisc of the pod, same as the datapath setup
+func applyPodQoS(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, prev, want podQoS) error {
+	var item *daemon.ResourceItem
+	for i := range podRes.Resources {
+		if podRes.Resources[i].Type == daemon.ResourceTypeENIIP && podRes.Resources[i].ENIMAC != "" {
+			item = &podRes.Resources[i]
+			break
+		}
+	}
+	if item == nil {
+		return fmt.Errorf("eni not found for pod")
+	}
+
+	if prev.NetworkPriority != want.NetworkPriority && conf.EnableNetworkPriority {
+		index, err := link.GetDeviceNumber(item.ENIMAC)
+		if err != nil {
+			return err
+		}
+		eni, err := netlink.LinkByIndex(int(index))
+		if err != nil {
+			return err
+		}
+		ipNetSet := &types.IPNetSet{}
+		if item.IPv4 != "" {
+			ipNetSet.SetIPNet(item.IPv4 + "/32")
+		}
+		if item.IPv6 != "" {
+			ipNetSet.SetIPNet(item.IPv6 + "/128")
+		}
+		classID := datapath.PrioMap[want.NetworkPriority]
+		err = utils.SetEgressPriority(ctx, eni, classID, ipNetSet)
+		if err != nil {
+			return fmt.Errorf("error set egress priority, %w", err)
+		}
+		// the pods on the eni share the ingress classes on both datapath
+		if conf.IngressPriority(want.NetworkPriority, classID) != nil {
+			err = utils.SetIngressPriority(ctx, eni, datapath.IngressPriorityClasses(conf), classID, ipNetSet)
+		} else {
+			err = utils.DelIngressPriority(ctx, eni, ipNetSet)
+		}
+		if err != nil {
+			return fmt.Errorf("error set ingress priority, %w", err)
+		}
+	}
+
+	if prev.Ingress == want.Ingress && prev.Egress == want.Egress {
+		return nil
+	}
+	if conf.BandwidthMode == cnitypes.BandwidthModeEDT {
+		return fmt.Errorf("bandwidth can not be changed on running pods in %s mode", conf.BandwidthMode)
+	}
+
+	if prev.Egress != want.Egress {
+		netNS, err := ns.GetNS(*podRes.NetNs)
+		if err != nil {
+			return err
+		}
+		defer netNS.Close()
+
+		err = netNS.Do(func(_ ns.NetNS) error {
+			contLink, err := netlink.LinkByName(IfEth0)
+			if err != nil {
+				return err
+			}
+			return setBandwidth(ctx, contLink, want.Egress)
+		})
+		if err != nil {
+			return fmt.Errorf("error set egress bandwidth, %w", err)
+		}
+	}
+
+	if prev.Ingress != want.Ingress {
+		if conf.IPVlan() {
+			return fmt.Errorf("ingress bandwidth is not supported in ipvlan mode")
+		}
+		vethName, err := link.VethNameForPod(podRes.PodInfo.Name, podRes.PodInfo.Namespace, "", "cali")
+		if err != nil {
+			return err
+		}
+		hostVETH, err := netlink.LinkByName(vethName)
+		if err != nil {
+			return err
+		}
+		err = setBandwidth(ctx, hostVETH, want.Ingress)
+		if err != nil {
+			return fmt.Errorf("error set ingress bandwidth, %w", err)
+		}
+	}
+	return nil
+}
+
+// setBandwidth set the tbf qdisc, zero to remove the limit
+func setBandwidth(ctx context.Context, l netlink.Link, bandwidth uint64) error {
+	if bandwidth == 0 {
+		return utils.DelTC(ctx, l)
+	}
+	return utils.SetupTC(l, bandwidth)
+}
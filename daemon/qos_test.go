@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func Test_qosAnnotationChanged(t *testing.T) {
+	oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}}
+	newPod := oldPod.DeepCopy()
+	newPod.Annotations["foo"] = "baz"
+	assert.False(t, qosAnnotationChanged(oldPod, newPod))
+	assert.False(t, hasQoSAnnotation(newPod))
+
+	newPod.Annotations[types.NetworkPriority] = string(types.NetworkPrioGuaranteed)
+	assert.True(t, qosAnnotationChanged(oldPod, newPod))
+	assert.True(t, hasQoSAnnotation(newPod))
+}
+
+func Test_loadCNIConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), cinConfFile)
+	err := os.WriteFile(path, []byte(`{"cniVersion":"0.4.0","name":"terway-chainer","plugins":[
+{"type":"terway","bandwidth_mode":"tc","enable_network_priority":true},
+{"type":"cilium-cni"}]}`), 0600)
+	assert.NoError(t, err)
+
+	conf, err := loadCNIConf(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "tc", conf.BandwidthMode)
+	assert.True(t, conf.EnableNetworkPriority)
+
+	err = os.WriteFile(path, []byte(`{"plugins":[{"type":"cilium-cni"}]}`), 0600)
+	assert.NoError(t, err)
+	_, err = loadCNIConf(path)
+	assert.Error(t, err)
+}
+
+func TestNetworkService_syncPodQoS(t *testing.T) {
+	netNS := "/var/run/netns/foo"
+	podRes := func(info *daemon.PodInfo) daemon.PodResources {
+		return daemon.PodResources{
+			PodInfo: info,
+			NetNs:   &netNS,
+			Resources: []daemon.ResourceItem{
+				{Type: daemon.ResourceTypeENIIP, ENIMAC: "00:00:00:00:00:01", IPv4: "192.168.0.10"},
+			},
+		}
+	}
+	info := &daemon.PodInfo{
+		Name:            "pod-1",
+		Namespace:       "default",
+		PodUID:          "uid-1",
+		PodNetworkType:  daemon.PodNetworkTypeENIMultiIP,
+		NetworkPriority: string(types.NetworkPrioBurstable),
+	}
+
+	// not changed
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetPod", mock.Anything, "default", "pod-1", true).Return(info, nil).Once()
+	n := &networkService{k8s: k8sClient, resourceDB: storage.NewMemoryStorage()}
+	assert.NoError(t, n.resourceDB.Put("default/pod-1", podRes(info)))
+	assert.NoError(t, n.syncPodQoS(context.Background(), "default/pod-1"))
+
+	// not managed by the shared eni datapath, the pod is not read
+	trunk := *info
+	trunk.PodENI = true
+	assert.NoError(t, n.resourceDB.Put("default/pod-1", podRes(&trunk)))
+	assert.NoError(t, n.syncPodQoS(context.Background(), "default/pod-1"))
+
+	// not setup
+	assert.NoError(t, n.syncPodQoS(context.Background(), "default/pod-2"))
+
+	// failed to apply, the stored qos is kept
+	changed := *info
+	changed.NetworkPriority = string(types.NetworkPrioGuaranteed)
+	k8sClient.On("GetPod", mock.Anything, "default", "pod-1", true).Return(&changed, nil).Once()
+	k8sClient.On("RecordPodEvent", "pod-1", "default", corev1.EventTypeWarning, eventReasonUpdateQoSFailed, mock.Anything).Return(nil).Once()
+	assert.NoError(t, n.resourceDB.Put("default/pod-1", podRes(info)))
+	assert.Error(t, n.syncPodQoS(context.Background(), "default/pod-1"))
+
+	obj, err := n.resourceDB.Get("default/pod-1")
+	assert.NoError(t, err)
+	assert.Equal(t, string(types.NetworkPrioBurstable), obj.(daemon.PodResources).PodInfo.NetworkPriority)
+}
+
+func TestNetworkService_updatePodQoS(t *testing.T) {
+	containerID := "c-1"
+	info := &daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1"}
+	snapshot := daemon.PodResources{PodInfo: info, ContainerID: &containerID}
+	want := podQoS{Ingress: 100, NetworkPriority: string(types.NetworkPrioGuaranteed)}
+
+	n := &networkService{resourceDB: storage.NewMemoryStorage()}
+
+	// released while the datapath is updated
+	assert.NoError(t, n.updatePodQoS("default/pod-1", &snapshot, want))
+	_, err := n.resourceDB.Get("default/pod-1")
+	assert.Error(t, err)
+
+	// recreated while the datapath is updated
+	recreatedID := "c-2"
+	assert.NoError(t, n.resourceDB.Put("default/pod-1", daemon.PodResources{PodInfo: &daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1"}, ContainerID: &recreatedID}))
+	assert.NoError(t, n.updatePodQoS("default/pod-1", &snapshot, want))
+	obj, err := n.resourceDB.Get("default/pod-1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), obj.(daemon.PodResources).PodInfo.TcIngress)
+
+	assert.NoError(t, n.resourceDB.Put("default/pod-1", daemon.PodResources{PodInfo: &daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1"}, ContainerID: &containerID}))
+	assert.NoError(t, n.updatePodQoS("default/pod-1", &snapshot, want))
+	obj, err = n.resourceDB.Get("default/pod-1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), obj.(daemon.PodResources).PodInfo.TcIngress)
+	assert.Equal(t, string(types.NetworkPrioGuaranteed), obj.(daemon.PodResources).PodInfo.NetworkPriority)
+}
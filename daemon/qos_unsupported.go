@@ -0,0 +1,15 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func applyPodQoS(ctx context.Context, conf *cnitypes.CNIConf, podRes *daemon.PodResources, prev, want podQoS) error {
+	return fmt.Errorf("qos can not be changed on running pods")
+}
@@ -231,6 +231,10 @@ func runDebugServer(ctx context.Context, wg *sync.WaitGroup, debugSocketListen s
 func registerPrometheus() {
 	prometheus.MustRegister(metric.RPCLatency)
 	prometheus.MustRegister(metric.OpenAPILatency)
+	prometheus.MustRegister(metric.OpenAPIThrottleTotal)
+	prometheus.MustRegister(metric.OpenAPIEffectiveQPS)
+	prometheus.MustRegister(metric.CredentialProvider)
+	prometheus.MustRegister(metric.CredentialExpiration)
 	prometheus.MustRegister(metric.MetadataLatency)
 	// ResourcePool
 	prometheus.MustRegister(metric.ResourcePoolTotal)
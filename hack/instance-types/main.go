@@ -0,0 +1,110 @@
+// instance-types refresh the instance type catalogue embedded in terway, or check it against the openAPI.
+//
+//	go run -tags default_build ./hack/instance-types --region cn-hangzhou
+//	go run -tags default_build ./hack/instance-types --region cn-hangzhou --check
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/credential"
+)
+
+var (
+	accessKeyID     string
+	accessKeySecret string
+	credentialPath  string
+	region          string
+	output          string
+	check           bool
+)
+
+func init() {
+	flag.StringVar(&accessKeyID, "access-key-id", os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID"), "AlibabaCloud Access Key ID")
+	flag.StringVar(&accessKeySecret, "access-key-secret", os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET"), "AlibabaCloud Access Key Secret")
+	flag.StringVar(&credentialPath, "credential-path", "", "AlibabaCloud credential path")
+	flag.StringVar(&region, "region", "", "AlibabaCloud region")
+	flag.StringVar(&output, "output", "pkg/aliyun/client/instance_types.json", "catalogue file to write")
+	flag.BoolVar(&check, "check", false, "compare the embedded catalogue with the openAPI, exit 1 if differs")
+}
+
+func main() {
+	flag.Parse()
+	if region == "" {
+		fmt.Fprintln(os.Stderr, "--region is required")
+		os.Exit(2)
+	}
+
+	providers := []credential.Interface{
+		credential.NewAKPairProvider(accessKeyID, accessKeySecret),
+		credential.NewOIDCProvider(region),
+		credential.NewEncryptedCredentialProvider(credentialPath),
+		credential.NewMetadataProvider(),
+	}
+	c, err := credential.NewClientMgr(region, providers...)
+	if err != nil {
+		panic(err)
+	}
+	api, err := client.New(c, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	instanceTypes, err := api.DescribeInstanceTypes(context.Background(), nil)
+	if err != nil {
+		panic(err)
+	}
+	live := client.NewCatalogue(time.Now().UTC().Format("2006-01-02"), instanceTypes)
+
+	if check {
+		os.Exit(compare(client.EmbeddedCatalogue(), live))
+	}
+
+	out, err := live.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(output, out, 0644)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("wrote %d instance types to %s\n", len(live.InstanceTypes), output)
+}
+
+// compare print the instance types differ, return the exit code
+func compare(embedded, live *client.Catalogue) int {
+	var names []string
+	for name := range live.InstanceTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	code := 0
+	for _, name := range names {
+		liveLimit, _ := live.Get(name)
+		offline, ok := embedded.Get(name)
+		if !ok {
+			fmt.Printf("%s: missing in catalogue\n", name)
+			code = 1
+			continue
+		}
+		if offline.TrunkUnknown || offline.ERdmaUnknown {
+			fmt.Printf("%s: trunk or erdma quota missing in catalogue\n", name)
+			code = 1
+		}
+		for _, d := range client.DiffLimits(liveLimit, offline) {
+			fmt.Printf("%s: %s\n", name, d)
+			code = 1
+		}
+	}
+	if code == 0 {
+		fmt.Printf("catalogue %s is up to date\n", embedded.Version)
+	}
+	return code
+}
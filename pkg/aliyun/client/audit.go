@@ -0,0 +1,319 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var auditLog = logf.Log.WithName("audit")
+
+const (
+	defaultAuditMaxSize    = 100 << 20
+	defaultAuditMaxBackups = 3
+)
+
+// AuditCaller is who made the openAPI call
+type AuditCaller struct {
+	Controller string `json:"controller,omitempty"`
+	Node       string `json:"node,omitempty"`
+	// Pod is namespace/name
+	Pod string `json:"pod,omitempty"`
+}
+
+type auditCallerKey struct{}
+
+// WithAuditCaller attach the caller to the ctx, empty fields are inherited from the parent
+func WithAuditCaller(ctx context.Context, caller AuditCaller) context.Context {
+	parent := auditCallerFromContext(ctx)
+	if caller.Controller == "" {
+		caller.Controller = parent.Controller
+	}
+	if caller.Node == "" {
+		caller.Node = parent.Node
+	}
+	if caller.Pod == "" {
+		caller.Pod = parent.Pod
+	}
+	return context.WithValue(ctx, auditCallerKey{}, caller)
+}
+
+func auditCallerFromContext(ctx context.Context) AuditCaller {
+	caller, _ := ctx.Value(auditCallerKey{}).(AuditCaller)
+	return caller
+}
+
+// AuditRecord is a mutating openAPI call
+type AuditRecord struct {
+	Time        time.Time         `json:"time"`
+	API         string            `json:"api"`
+	Params      map[string]string `json:"params,omitempty"`
+	RequestID   string            `json:"requestID,omitempty"`
+	LatencyMs   float64           `json:"latencyMs"`
+	Error       string            `json:"error,omitempty"`
+	ReconcileID string            `json:"reconcileID,omitempty"`
+	AuditCaller
+}
+
+// Match return true if the api, request id or the caller equals to the keyword, or any of the params contains it
+func (r *AuditRecord) Match(keyword string) bool {
+	if keyword == "" {
+		return true
+	}
+	for _, v := range []string{r.API, r.RequestID, r.ReconcileID, r.Controller, r.Node, r.Pod} {
+		if v == keyword {
+			return true
+		}
+	}
+	for _, v := range r.Params {
+		if strings.Contains(v, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditSink persistent the audit records
+type AuditSink interface {
+	Write(r *AuditRecord)
+}
+
+// MultiAuditSink write the record to all the sinks
+type MultiAuditSink []AuditSink
+
+func (m MultiAuditSink) Write(r *AuditRecord) {
+	for _, s := range m {
+		s.Write(r)
+	}
+}
+
+// audit record the call if the sink is set
+func (a *OpenAPI) audit(ctx context.Context, api string, req any, requestID string, start time.Time, err error) {
+	if a.Audit == nil {
+		return
+	}
+	r := &AuditRecord{
+		Time:        start,
+		API:         api,
+		Params:      auditParams(req),
+		RequestID:   requestID,
+		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+		ReconcileID: string(controller.ReconcileIDFromContext(ctx)),
+		AuditCaller: auditCallerFromContext(ctx),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	a.Audit.Write(r)
+}
+
+// auditParams return the fields of the sdk request, same as LogFields
+func auditParams(obj any) map[string]string {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	params := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("name") == "" {
+			continue
+		}
+		fieldValue := v.Field(i)
+		if !fieldValue.IsValid() || fieldValue.IsZero() {
+			continue
+		}
+		if fieldValue.Kind() == reflect.Ptr {
+			fieldValue = fieldValue.Elem()
+		}
+		params[field.Name] = fmt.Sprint(fieldValue.Interface())
+	}
+	return params
+}
+
+// FileAuditSink write the records in json lines, the file is rotated by size
+type FileAuditSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileAuditSink open the file for append, maxSize and maxBackups use the default if not set
+func NewFileAuditSink(path string, maxSize int64, maxBackups int) (*FileAuditSink, error) {
+	if maxSize <= 0 {
+		maxSize = defaultAuditMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultAuditMaxBackups
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileAuditSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	return s, s.open()
+}
+
+func (s *FileAuditSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shift path.N to path.N+1, the oldest one is dropped
+func (s *FileAuditSink) rotate() error {
+	_ = s.file.Close()
+	for i := s.maxBackups - 1; i > 0; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	err := os.Rename(s.path, s.path+".1")
+	if err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *FileAuditSink) Write(r *AuditRecord) {
+	out, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	out = append(out, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return
+	}
+	if s.size > 0 && s.size+int64(len(out)) > s.maxSize {
+		err = s.rotate()
+		if err != nil {
+			auditLog.Error(err, "failed to rotate audit log", "path", s.path)
+			if s.file == nil {
+				return
+			}
+		}
+	}
+	n, err := s.file.Write(out)
+	s.size += int64(n)
+	if err != nil {
+		auditLog.Error(err, "failed to write audit log", "path", s.path)
+	}
+}
+
+func (s *FileAuditSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// AuditFiles return the audit log and the backups, the newest first
+func AuditFiles(path string) []string {
+	files := []string{path}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append(files, name)
+	}
+	return files
+}
+
+// SearchAudit read the records from r, fn is called for each record matches the keyword
+func SearchAudit(r io.Reader, keyword string, fn func(*AuditRecord)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		record := &AuditRecord{}
+		if json.Unmarshal(scanner.Bytes(), record) != nil {
+			continue
+		}
+		if record.Match(keyword) {
+			fn(record)
+		}
+	}
+	return scanner.Err()
+}
+
+// EventAuditSink emit the records as events, on the pod if known, otherwise on the node
+type EventAuditSink struct {
+	recorder record.EventRecorder
+}
+
+func NewEventAuditSink(recorder record.EventRecorder) *EventAuditSink {
+	return &EventAuditSink{recorder: recorder}
+}
+
+func (s *EventAuditSink) Write(r *AuditRecord) {
+	var ref *corev1.ObjectReference
+	if r.Pod != "" {
+		namespace, name, found := strings.Cut(r.Pod, "/")
+		if !found {
+			namespace, name = "", r.Pod
+		}
+		ref = &corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: namespace, Name: name}
+	} else if r.Node != "" {
+		ref = &corev1.ObjectReference{Kind: "Node", APIVersion: "v1", Name: r.Node}
+	} else {
+		return
+	}
+
+	s.recorder.Event(ref, r.EventType(), r.EventReason(), r.EventMessage())
+}
+
+// EventType is warning if the call failed
+func (r *AuditRecord) EventType() string {
+	if r.Error != "" {
+		return corev1.EventTypeWarning
+	}
+	return corev1.EventTypeNormal
+}
+
+func (r *AuditRecord) EventReason() string {
+	return "OpenAPI" + r.API
+}
+
+func (r *AuditRecord) EventMessage() string {
+	result := "succeed"
+	if r.Error != "" {
+		result = r.Error
+	}
+	return fmt.Sprintf("%s requestID %s latency %.0fms params %v: %s", r.API, r.RequestID, r.LatencyMs, r.Params, result)
+}
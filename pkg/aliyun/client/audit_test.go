@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeAuditSink struct {
+	records []*AuditRecord
+}
+
+func (f *fakeAuditSink) Write(r *AuditRecord) {
+	f.records = append(f.records, r)
+}
+
+func TestWithAuditCaller(t *testing.T) {
+	ctx := WithAuditCaller(context.Background(), AuditCaller{Controller: "pod", Pod: "default/foo"})
+	ctx = WithAuditCaller(ctx, AuditCaller{Node: "node-1"})
+
+	assert.Equal(t, AuditCaller{Controller: "pod", Node: "node-1", Pod: "default/foo"}, auditCallerFromContext(ctx))
+	assert.Equal(t, AuditCaller{}, auditCallerFromContext(context.Background()))
+}
+
+func TestOpenAPI_audit(t *testing.T) {
+	sink := &fakeAuditSink{}
+	a := &OpenAPI{Audit: sink}
+
+	req := ecs.CreateAttachNetworkInterfaceRequest()
+	req.NetworkInterfaceId = "eni-1"
+	req.InstanceId = "i-1"
+
+	ctx := WithAuditCaller(context.Background(), AuditCaller{Controller: "node", Node: "node-1"})
+	a.audit(ctx, APIAttachNetworkInterface, req, "request-1", time.Now(), errors.New("foo"))
+
+	if assert.Len(t, sink.records, 1) {
+		r := sink.records[0]
+		assert.Equal(t, APIAttachNetworkInterface, r.API)
+		assert.Equal(t, "request-1", r.RequestID)
+		assert.Equal(t, "foo", r.Error)
+		assert.Empty(t, r.ReconcileID)
+		assert.Equal(t, "node-1", r.Node)
+		assert.Equal(t, map[string]string{"NetworkInterfaceId": "eni-1", "InstanceId": "i-1"}, r.Params)
+	}
+
+	// disabled
+	a.Audit = nil
+	a.audit(ctx, APIAttachNetworkInterface, req, "request-1", time.Now(), nil)
+	assert.Len(t, sink.records, 1)
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := NewFileAuditSink(path, 300, 2)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		s.Write(&AuditRecord{
+			Time:   time.Now(),
+			API:    APIAssignPrivateIPAddress,
+			Params: map[string]string{"NetworkInterfaceId": "eni-" + strings.Repeat("x", i)},
+		})
+	}
+	assert.NoError(t, s.Close())
+
+	files := AuditFiles(path)
+	assert.Equal(t, []string{path, path + ".1", path + ".2"}, files)
+	for _, name := range files {
+		info, err := os.Stat(name)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300))
+	}
+
+	// the newest record is in the current file
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	var found []*AuditRecord
+	err = SearchAudit(f, "eni-xxxxxxxxx", func(r *AuditRecord) {
+		found = append(found, r)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+}
+
+func TestSearchAudit(t *testing.T) {
+	in := `{"time":"2024-01-01T00:00:00Z","api":"AttachNetworkInterface","params":{"NetworkInterfaceId":"eni-1"},"node":"node-1"}
+not json
+{"time":"2024-01-01T00:00:01Z","api":"AssignPrivateIpAddresses","params":{"NetworkInterfaceId":"eni-2"},"pod":"default/foo","error":"foo"}
+`
+	search := func(keyword string) []string {
+		var apis []string
+		err := SearchAudit(strings.NewReader(in), keyword, func(r *AuditRecord) {
+			apis = append(apis, r.API)
+		})
+		assert.NoError(t, err)
+		return apis
+	}
+
+	assert.Equal(t, []string{APIAttachNetworkInterface, APIAssignPrivateIPAddress}, search(""))
+	assert.Equal(t, []string{APIAttachNetworkInterface}, search("node-1"))
+	assert.Equal(t, []string{APIAssignPrivateIPAddress}, search("default/foo"))
+	assert.Equal(t, []string{APIAssignPrivateIPAddress}, search("eni-2"))
+	assert.Empty(t, search("default"))
+}
+
+func TestEventAuditSink(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	s := NewEventAuditSink(recorder)
+
+	s.Write(&AuditRecord{API: APIDeleteNetworkInterface, AuditCaller: AuditCaller{Node: "node-1"}})
+	s.Write(&AuditRecord{API: APIAssignPrivateIPAddress, AuditCaller: AuditCaller{Pod: "default/foo"}, Error: "foo"})
+	s.Write(&AuditRecord{API: APIDeleteNetworkInterface})
+
+	assert.Len(t, recorder.Events, 2)
+	assert.True(t, strings.HasPrefix(<-recorder.Events, "Normal OpenAPIDeleteNetworkInterface"))
+	assert.True(t, strings.HasPrefix(<-recorder.Events, "Warning OpenAPIAssignPrivateIpAddresses"))
+}
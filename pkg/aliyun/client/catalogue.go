@@ -0,0 +1,138 @@
+package client
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+)
+
+// catalogueData is the instance type limits known at build time, refreshed by hack/instance-types.
+// The shipped data covers the common families with the ENI and IP quotas of the instance family documentation,
+// trunk, ERDMA and bandwidth are not included until it is refreshed from the openAPI, the limits of those are unknown.
+//
+//go:embed instance_types.json
+var catalogueData []byte
+
+// CatalogueEntry is the limits of an instance type, fields are the same as DescribeInstanceTypes.
+// The trunk and erdma quotas are nil if unknown.
+type CatalogueEntry struct {
+	EniQuantity                 int   `json:"eniQuantity"`
+	EniTotalQuantity            int   `json:"eniTotalQuantity"`
+	EniPrivateIPAddressQuantity int   `json:"eniPrivateIpAddressQuantity"`
+	EniIPv6AddressQuantity      int   `json:"eniIpv6AddressQuantity"`
+	EniTrunkSupported           *bool `json:"eniTrunkSupported,omitempty"`
+	EriQuantity                 *int  `json:"eriQuantity,omitempty"`
+	InstanceBandwidthRx         int   `json:"instanceBandwidthRx"`
+	InstanceBandwidthTx         int   `json:"instanceBandwidthTx"`
+}
+
+// Catalogue is the offline instance type limits, used when the openAPI is unavailable
+type Catalogue struct {
+	Version       string                    `json:"version"`
+	InstanceTypes map[string]CatalogueEntry `json:"instanceTypes"`
+}
+
+// NewCatalogue build the catalogue from the DescribeInstanceTypes result
+func NewCatalogue(version string, instanceTypes []ecs.InstanceType) *Catalogue {
+	c := &Catalogue{
+		Version:       version,
+		InstanceTypes: make(map[string]CatalogueEntry, len(instanceTypes)),
+	}
+	for _, ins := range instanceTypes {
+		trunk, eri := ins.EniTrunkSupported, ins.EriQuantity
+		c.InstanceTypes[ins.InstanceTypeId] = CatalogueEntry{
+			EniQuantity:                 ins.EniQuantity,
+			EniTotalQuantity:            ins.EniTotalQuantity,
+			EniPrivateIPAddressQuantity: ins.EniPrivateIpAddressQuantity,
+			EniIPv6AddressQuantity:      ins.EniIpv6AddressQuantity,
+			EniTrunkSupported:           &trunk,
+			EriQuantity:                 &eri,
+			InstanceBandwidthRx:         ins.InstanceBandwidthRx,
+			InstanceBandwidthTx:         ins.InstanceBandwidthTx,
+		}
+	}
+	return c
+}
+
+// Get return the limits of the instance type, false if not in the catalogue.
+// The trunk and erdma quotas not carried by the entry are marked unknown in the limits, instead of zero.
+func (c *Catalogue) Get(instanceType string) (*Limits, bool) {
+	e, ok := c.InstanceTypes[instanceType]
+	if !ok {
+		return nil, false
+	}
+	ins := &ecs.InstanceType{
+		InstanceTypeId:              instanceType,
+		EniQuantity:                 e.EniQuantity,
+		EniTotalQuantity:            e.EniTotalQuantity,
+		EniPrivateIpAddressQuantity: e.EniPrivateIPAddressQuantity,
+		EniIpv6AddressQuantity:      e.EniIPv6AddressQuantity,
+		InstanceBandwidthRx:         e.InstanceBandwidthRx,
+		InstanceBandwidthTx:         e.InstanceBandwidthTx,
+	}
+	if e.EniTrunkSupported != nil {
+		ins.EniTrunkSupported = *e.EniTrunkSupported
+	}
+	if e.EriQuantity != nil {
+		ins.EriQuantity = *e.EriQuantity
+	}
+	limit := getInstanceType(ins)
+	limit.TrunkUnknown = e.EniTrunkSupported == nil
+	limit.ERdmaUnknown = e.EriQuantity == nil
+	return limit, true
+}
+
+// Marshal return the catalogue in json, instance types are sorted so the diff is readable
+func (c *Catalogue) Marshal() ([]byte, error) {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+var (
+	embeddedCatalogue     *Catalogue
+	embeddedCatalogueOnce sync.Once
+)
+
+// EmbeddedCatalogue return the catalogue built in the binary
+func EmbeddedCatalogue() *Catalogue {
+	embeddedCatalogueOnce.Do(func() {
+		embeddedCatalogue = &Catalogue{}
+		err := json.Unmarshal(catalogueData, embeddedCatalogue)
+		if err != nil {
+			panic(fmt.Sprintf("invalid instance type catalogue, %s", err))
+		}
+	})
+	return embeddedCatalogue
+}
+
+// DiffLimits return the fields differ between the two limits, the unknown fields are skipped
+func DiffLimits(a, b *Limits) []string {
+	fields := map[string][2]int{
+		"Adapters":       {a.Adapters, b.Adapters},
+		"TotalAdapters":  {a.TotalAdapters, b.TotalAdapters},
+		"IPv4PerAdapter": {a.IPv4PerAdapter, b.IPv4PerAdapter},
+		"IPv6PerAdapter": {a.IPv6PerAdapter, b.IPv6PerAdapter},
+	}
+	if !a.TrunkUnknown && !b.TrunkUnknown {
+		fields["MemberAdapterLimit"] = [2]int{a.MemberAdapterLimit, b.MemberAdapterLimit}
+		fields["MaxMemberAdapterLimit"] = [2]int{a.MaxMemberAdapterLimit, b.MaxMemberAdapterLimit}
+	}
+	if !a.ERdmaUnknown && !b.ERdmaUnknown {
+		fields["ERdmaAdapters"] = [2]int{a.ERdmaAdapters, b.ERdmaAdapters}
+	}
+	var diff []string
+	for name, v := range fields {
+		if v[0] != v[1] {
+			diff = append(diff, fmt.Sprintf("%s: %d != %d", name, v[0], v[1]))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
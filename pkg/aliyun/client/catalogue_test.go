@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+func TestEmbeddedCatalogue(t *testing.T) {
+	c := EmbeddedCatalogue()
+	assert.NotEmpty(t, c.Version)
+	assert.NotEmpty(t, c.InstanceTypes)
+
+	limit, ok := c.Get("ecs.g7.large")
+	assert.True(t, ok)
+	assert.Equal(t, 3, limit.Adapters)
+	assert.Equal(t, 6, limit.IPv4PerAdapter)
+	assert.True(t, limit.SupportMultiIPIPv6())
+
+	for name := range c.InstanceTypes {
+		limit, _ := c.Get(name)
+		assert.Greater(t, limit.Adapters, 1, name)
+		assert.Greater(t, limit.IPv4PerAdapter, 0, name)
+	}
+
+	// trunk and erdma quota is not shipped, they are unknown instead of unsupported
+	assert.True(t, limit.TrunkUnknown)
+	assert.True(t, limit.ERdmaUnknown)
+
+	// the file is in the format written by hack/instance-types
+	out, err := c.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, string(catalogueData), string(out))
+}
+
+func TestCatalogue(t *testing.T) {
+	ins := ecs.InstanceType{
+		InstanceTypeId:              "ecs.g7.large",
+		EniQuantity:                 3,
+		EniPrivateIpAddressQuantity: 6,
+		EniIpv6AddressQuantity:      6,
+		EniTotalQuantity:            11,
+		EniTrunkSupported:           true,
+		EriQuantity:                 0,
+	}
+	c := NewCatalogue("2024-06-01", []ecs.InstanceType{ins})
+
+	out, err := c.Marshal()
+	assert.NoError(t, err)
+	loaded := &Catalogue{}
+	assert.NoError(t, json.Unmarshal(out, loaded))
+	assert.Equal(t, c, loaded)
+
+	limit, ok := loaded.Get("ecs.g7.large")
+	assert.True(t, ok)
+	assert.Equal(t, getInstanceType(&ins), limit)
+	assert.False(t, limit.TrunkUnknown)
+	assert.False(t, limit.ERdmaUnknown)
+
+	_, ok = loaded.Get("ecs.foo")
+	assert.False(t, ok)
+}
+
+func TestDiffLimits(t *testing.T) {
+	a := &Limits{Adapters: 3, IPv4PerAdapter: 6}
+	b := &Limits{Adapters: 4, IPv4PerAdapter: 6, ERdmaAdapters: 1}
+	assert.Equal(t, []string{"Adapters: 3 != 4", "ERdmaAdapters: 0 != 1"}, DiffLimits(a, b))
+	assert.Empty(t, DiffLimits(a, a))
+
+	// unknown fields are not compared
+	b.ERdmaUnknown = true
+	assert.Equal(t, []string{"Adapters: 3 != 4"}, DiffLimits(a, b))
+}
+
+type fakeInstanceTypeAPI struct {
+	ECS
+	err error
+}
+
+func (f *fakeInstanceTypeAPI) DescribeInstanceTypes(ctx context.Context, types []string) ([]ecs.InstanceType, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []ecs.InstanceType{{InstanceTypeId: "ecs.g7.large", EniQuantity: 4, EniPrivateIpAddressQuantity: 6}}, nil
+}
+
+func TestECSLimitProvider_Catalogue(t *testing.T) {
+	p := &ECSLimitProvider{
+		cache: *cache.NewLRUExpireCache(10),
+		ttl:   time.Hour,
+		catalogue: NewCatalogue("v1", []ecs.InstanceType{
+			{InstanceTypeId: "ecs.g7.large", EniQuantity: 3, EniPrivateIpAddressQuantity: 6},
+		}),
+	}
+
+	// openAPI is unavailable
+	limit, err := p.GetLimit(&fakeInstanceTypeAPI{err: errors.New("unavailable")}, "ecs.g7.large")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, limit.Adapters)
+	_, err = p.GetLimit(&fakeInstanceTypeAPI{err: errors.New("unavailable")}, "ecs.g8.large")
+	assert.Error(t, err)
+
+	// live limit is preferred
+	limit, err = p.GetLimit(&fakeInstanceTypeAPI{}, "ecs.g7.large")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, limit.Adapters)
+}
@@ -28,6 +28,7 @@ const (
 	APIAttachNetworkInterface     = "AttachNetworkInterface"
 	APIDetachNetworkInterface     = "DetachNetworkInterface"
 	APIDeleteNetworkInterface     = "DeleteNetworkInterface"
+	APIModifyNetworkInterface     = "ModifyNetworkInterfaceAttribute"
 	APIAssignPrivateIPAddress     = "AssignPrivateIpAddresses"
 	APIUnAssignPrivateIPAddresses = "UnAssignPrivateIpAddresses"
 	APIAssignIPv6Addresses        = "AssignIpv6Addresses"
@@ -42,15 +43,30 @@ type OpenAPI struct {
 	RateLimiter *RateLimiter
 
 	Tracer trace.Tracer
+
+	// Audit record the mutating calls, disabled if nil
+	Audit AuditSink
+
+	eniCache *eniCache
 }
 
 func New(c credential.Client, cfg LimitConfig) (*OpenAPI, error) {
-	return &OpenAPI{
+	a := &OpenAPI{
 		ClientSet:        c,
 		IdempotentKeyGen: NewIdempotentKeyGenerator(),
 		RateLimiter:      NewRateLimiter(cfg),
 		Tracer:           otel.Tracer("openAPI"),
-	}, nil
+	}
+	a.eniCache = newENICache(a.describeNetworkInterface, defaultENICacheTTL, defaultENIBatchWindow)
+	return a, nil
+}
+
+// invalidateENI drop the cached describe result after the eni is changed
+func (a *OpenAPI) invalidateENI(eniID, instanceID string) {
+	if a.eniCache == nil {
+		return
+	}
+	a.eniCache.Invalidate(eniID, instanceID)
 }
 
 func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetworkInterfaceOption) (*NetworkInterface, error) {
@@ -67,6 +83,7 @@ func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetw
 		return nil, err
 	}
 	l := LogFields(logf.FromContext(ctx), req)
+	defer a.invalidateENI("", "")
 
 	var (
 		resp     *ecs.CreateNetworkInterfaceResponse
@@ -81,8 +98,10 @@ func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetw
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().CreateNetworkInterface(req)
 		metric.OpenAPILatency.WithLabelValues(APICreateNetworkInterface, fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		a.RateLimiter.Feedback(APICreateNetworkInterface, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
+			a.audit(ctx, APICreateNetworkInterface, req, apiErr.ErrRequestID(innerErr), start, innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
 
 			if apiErr.ErrorIs(innerErr, apiErr.IsURLError, apiErr.WarpFn(apiErr.ErrThrottling, apiErr.ErrInternalError)) {
@@ -92,6 +111,7 @@ func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetw
 			return true, innerErr
 		}
 		l.WithValues(LogFieldRequestID, resp.RequestId, LogFieldENIID, resp.NetworkInterfaceId).Info("eni created")
+		a.audit(ctx, APICreateNetworkInterface, req, resp.RequestId, start, nil)
 		return true, nil
 	})
 
@@ -99,20 +119,53 @@ func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetw
 		rollBackFunc()
 		return nil, err
 	}
+	// the token given by the caller is not recorded
+	if option.NetworkInterfaceOptions.ClientToken == "" {
+		a.keyDone(ctx, req.ClientToken)
+	}
+
+	return FromCreateResp(resp), nil
+}
 
-	return FromCreateResp(resp), err
+// keyDone remove the key of the succeed request, or leave it to the caller to remove after the result is recorded.
+// The request is succeed, the error is not returned.
+func (a *OpenAPI) keyDone(ctx context.Context, token string) {
+	pending := pendingKeysFrom(ctx)
+	if pending != nil {
+		pending.add(a.IdempotentKeyGen, token)
+		return
+	}
+	err := a.IdempotentKeyGen.Done(token)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to remove idempotent key")
+	}
 }
 
-// DescribeNetworkInterface list eni
+// DescribeNetworkInterface list eni, concurrent lookups are coalesced and the result is reused in a short window
 func (a *OpenAPI) DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*NetworkInterface, error) {
 	ctx, span := a.Tracer.Start(ctx, APIDescribeNetworkInterfaces)
 	defer span.End()
 
+	q := &describeQuery{
+		vpcID:        vpcID,
+		eniIDs:       eniID,
+		instanceID:   instanceID,
+		instanceType: instanceType,
+		status:       status,
+		tags:         tags,
+	}
+	if a.eniCache == nil {
+		return a.describeNetworkInterface(ctx, q)
+	}
+	return a.eniCache.Describe(ctx, q)
+}
+
+func (a *OpenAPI) describeNetworkInterface(ctx context.Context, q *describeQuery) ([]*NetworkInterface, error) {
 	var result []*NetworkInterface
 	nextToken := ""
 
 	var ecsTags []ecs.DescribeNetworkInterfacesTag
-	for k, v := range tags {
+	for k, v := range q.tags {
 		ecsTags = append(ecsTags, ecs.DescribeNetworkInterfacesTag{
 			Key:   k,
 			Value: v,
@@ -127,14 +180,14 @@ func (a *OpenAPI) DescribeNetworkInterface(ctx context.Context, vpcID string, en
 
 		req := ecs.CreateDescribeNetworkInterfacesRequest()
 		req.NextToken = nextToken
-		req.VpcId = vpcID
+		req.VpcId = q.vpcID
 		if len(ecsTags) > 0 {
 			req.Tag = &ecsTags
 		}
-		req.NetworkInterfaceId = &eniID
-		req.InstanceId = instanceID
-		req.Type = instanceType
-		req.Status = status
+		req.NetworkInterfaceId = &q.eniIDs
+		req.InstanceId = q.instanceID
+		req.Type = q.instanceType
+		req.Status = q.status
 
 		req.MaxResults = requests.NewInteger(maxSinglePageSize)
 
@@ -143,6 +196,7 @@ func (a *OpenAPI) DescribeNetworkInterface(ctx context.Context, vpcID string, en
 		start := time.Now()
 		resp, err := a.ClientSet.ECS().DescribeNetworkInterfaces(req)
 		metric.OpenAPILatency.WithLabelValues(APIDescribeNetworkInterfaces, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		a.RateLimiter.Feedback(APIDescribeNetworkInterfaces, err)
 		if err != nil {
 			err = apiErr.WarpError(err)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "error describe eni")
@@ -169,6 +223,7 @@ func (a *OpenAPI) DescribeNetworkInterface(ctx context.Context, vpcID string, en
 func (a *OpenAPI) AttachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
 	ctx, span := a.Tracer.Start(ctx, APIAttachNetworkInterface)
 	defer span.End()
+	defer a.invalidateENI(eniID, instanceID)
 
 	err := a.RateLimiter.Wait(ctx, APIAttachNetworkInterface)
 	if err != nil {
@@ -185,11 +240,14 @@ func (a *OpenAPI) AttachNetworkInterface(ctx context.Context, eniID, instanceID,
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().AttachNetworkInterface(req)
 	metric.OpenAPILatency.WithLabelValues(APIAttachNetworkInterface, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIAttachNetworkInterface, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, APIAttachNetworkInterface, req, apiErr.ErrRequestID(err), start, err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "attach eni failed")
 		return err
 	}
+	a.audit(ctx, APIAttachNetworkInterface, req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("attach eni")
 	return nil
 }
@@ -198,6 +256,7 @@ func (a *OpenAPI) AttachNetworkInterface(ctx context.Context, eniID, instanceID,
 func (a *OpenAPI) DetachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
 	ctx, span := a.Tracer.Start(ctx, APIDetachNetworkInterface)
 	defer span.End()
+	defer a.invalidateENI(eniID, instanceID)
 
 	req := ecs.CreateDetachNetworkInterfaceRequest()
 	req.NetworkInterfaceId = eniID
@@ -213,14 +272,17 @@ func (a *OpenAPI) DetachNetworkInterface(ctx context.Context, eniID, instanceID,
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().DetachNetworkInterface(req)
 	metric.OpenAPILatency.WithLabelValues(APIDetachNetworkInterface, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIDetachNetworkInterface, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, APIDetachNetworkInterface, req, apiErr.ErrRequestID(err), start, err)
 		if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidENINotFound, apiErr.ErrInvalidEcsIDNotFound) {
 			return nil
 		}
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "detach eni failed")
 		return err
 	}
+	a.audit(ctx, APIDetachNetworkInterface, req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("detach eni")
 	return nil
 }
@@ -229,6 +291,7 @@ func (a *OpenAPI) DetachNetworkInterface(ctx context.Context, eniID, instanceID,
 func (a *OpenAPI) DeleteNetworkInterface(ctx context.Context, eniID string) error {
 	ctx, span := a.Tracer.Start(ctx, APIDeleteNetworkInterface)
 	defer span.End()
+	defer a.invalidateENI(eniID, "")
 
 	req := ecs.CreateDeleteNetworkInterfaceRequest()
 	req.NetworkInterfaceId = eniID
@@ -242,15 +305,53 @@ func (a *OpenAPI) DeleteNetworkInterface(ctx context.Context, eniID string) erro
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().DeleteNetworkInterface(req)
 	metric.OpenAPILatency.WithLabelValues(APIDeleteNetworkInterface, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIDeleteNetworkInterface, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, APIDeleteNetworkInterface, req, apiErr.ErrRequestID(err), start, err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "delete eni failed")
 		return err
 	}
+	a.audit(ctx, APIDeleteNetworkInterface, req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("delete eni")
 	return nil
 }
 
+// ModifyNetworkInterfaceAttribute replace the security groups of the eni
+func (a *OpenAPI) ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error {
+	ctx, span := a.Tracer.Start(ctx, APIModifyNetworkInterface)
+	defer span.End()
+	defer a.invalidateENI(eniID, "")
+
+	if eniID == "" || len(securityGroupIDs) == 0 {
+		return ErrInvalidArgs
+	}
+
+	req := ecs.CreateModifyNetworkInterfaceAttributeRequest()
+	req.NetworkInterfaceId = eniID
+	req.SecurityGroupId = &securityGroupIDs
+
+	l := LogFields(logf.FromContext(ctx), req)
+
+	err := a.RateLimiter.Wait(ctx, APIModifyNetworkInterface)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	resp, err := a.ClientSet.ECS().ModifyNetworkInterfaceAttribute(req)
+	metric.OpenAPILatency.WithLabelValues(APIModifyNetworkInterface, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIModifyNetworkInterface, err)
+	if err != nil {
+		err = apiErr.WarpError(err)
+		a.audit(ctx, APIModifyNetworkInterface, req, apiErr.ErrRequestID(err), start, err)
+		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "modify eni attribute failed")
+		return err
+	}
+	a.audit(ctx, APIModifyNetworkInterface, req, resp.RequestId, start, nil)
+	l.WithValues(LogFieldRequestID, resp.RequestId, LogFieldSgID, securityGroupIDs).Info("modify eni attribute")
+	return nil
+}
+
 // WaitForNetworkInterface wait status of eni
 func (a *OpenAPI) WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*NetworkInterface, error) {
 	ctx, span := a.Tracer.Start(ctx, "WaitForNetworkInterface")
@@ -262,7 +363,8 @@ func (a *OpenAPI) WaitForNetworkInterface(ctx context.Context, eniID string, sta
 	}
 	err := wait.ExponentialBackoff(backoff,
 		func() (done bool, err error) {
-			eni, err := a.DescribeNetworkInterface(ctx, "", []string{eniID}, "", "", "", nil)
+			// the status is changing, always ask the openAPI
+			eni, err := a.describeNetworkInterface(ctx, &describeQuery{eniIDs: []string{eniID}})
 			if err != nil {
 				return false, nil
 			}
@@ -300,6 +402,7 @@ func (a *OpenAPI) AssignPrivateIPAddress(ctx context.Context, opts ...AssignPriv
 		return nil, err
 	}
 	l := LogFields(logf.FromContext(ctx), req)
+	defer a.invalidateENI(req.NetworkInterfaceId, "")
 
 	var (
 		resp     *ecs.AssignPrivateIpAddressesResponse
@@ -314,8 +417,10 @@ func (a *OpenAPI) AssignPrivateIPAddress(ctx context.Context, opts ...AssignPriv
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().AssignPrivateIpAddresses(req)
 		metric.OpenAPILatency.WithLabelValues(APIAssignPrivateIPAddress, fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		a.RateLimiter.Feedback(APIAssignPrivateIPAddress, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
+			a.audit(ctx, APIAssignPrivateIPAddress, req, apiErr.ErrRequestID(innerErr), start, innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
 
 			if apiErr.ErrorIs(innerErr, apiErr.IsURLError, apiErr.WarpFn(apiErr.ErrThrottling, apiErr.ErrInternalError, apiErr.ErrOperationConflict)) {
@@ -325,12 +430,14 @@ func (a *OpenAPI) AssignPrivateIPAddress(ctx context.Context, opts ...AssignPriv
 			return true, innerErr
 		}
 
+		a.audit(ctx, APIAssignPrivateIPAddress, req, resp.RequestId, start, nil)
 		return true, nil
 	})
 	if err != nil {
 		rollBackFunc()
 		return nil, err
 	}
+	a.keyDone(ctx, req.ClientToken)
 
 	ips, err := ip.ToIPAddrs(resp.AssignedPrivateIpAddressesSet.PrivateIpSet.PrivateIpAddress)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("assign private ip", "ips", ips)
@@ -348,6 +455,7 @@ func (a *OpenAPI) UnAssignPrivateIPAddresses(ctx context.Context, eniID string,
 
 	ctx, span := a.Tracer.Start(ctx, APIUnAssignPrivateIPAddresses)
 	defer span.End()
+	defer a.invalidateENI(eniID, "")
 
 	err := a.RateLimiter.Wait(ctx, APIUnAssignPrivateIPAddresses)
 	if err != nil {
@@ -364,9 +472,11 @@ func (a *OpenAPI) UnAssignPrivateIPAddresses(ctx context.Context, eniID string,
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().UnassignPrivateIpAddresses(req)
 	metric.OpenAPILatency.WithLabelValues(APIUnAssignPrivateIPAddresses, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIUnAssignPrivateIPAddresses, err)
 
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, APIUnAssignPrivateIPAddresses, req, apiErr.ErrRequestID(err), start, err)
 		if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Info("success")
 			return nil
@@ -375,6 +485,7 @@ func (a *OpenAPI) UnAssignPrivateIPAddresses(ctx context.Context, eniID string,
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "unassign private ip failed")
 		return err
 	}
+	a.audit(ctx, APIUnAssignPrivateIPAddresses, req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("success")
 	return nil
 }
@@ -394,6 +505,7 @@ func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6Add
 		return nil, err
 	}
 	l := LogFields(logf.FromContext(ctx), req)
+	defer a.invalidateENI(req.NetworkInterfaceId, "")
 
 	var (
 		resp     *ecs.AssignIpv6AddressesResponse
@@ -409,8 +521,10 @@ func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6Add
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().AssignIpv6Addresses(req)
 		metric.OpenAPILatency.WithLabelValues(APIAssignIPv6Addresses, fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		a.RateLimiter.Feedback(APIAssignIPv6Addresses, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
+			a.audit(ctx, APIAssignIPv6Addresses, req, apiErr.ErrRequestID(innerErr), start, innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
 
 			if apiErr.ErrorIs(innerErr, apiErr.IsURLError, apiErr.WarpFn(apiErr.ErrThrottling, apiErr.ErrInternalError, apiErr.ErrOperationConflict)) {
@@ -420,12 +534,14 @@ func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6Add
 			return true, innerErr
 		}
 
+		a.audit(ctx, APIAssignIPv6Addresses, req, resp.RequestId, start, nil)
 		return true, nil
 	})
 	if err != nil {
 		rollBackFunc()
 		return nil, err
 	}
+	a.keyDone(ctx, req.ClientToken)
 
 	ips, err := ip.ToIPAddrs(resp.Ipv6Sets.Ipv6Address)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("assign ipv6", "ips", ips)
@@ -438,6 +554,7 @@ func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6Add
 func (a *OpenAPI) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips []netip.Addr) error {
 	ctx, span := a.Tracer.Start(ctx, APIUnAssignIpv6Addresses)
 	defer span.End()
+	defer a.invalidateENI(eniID, "")
 
 	if len(ips) == 0 {
 		return nil
@@ -458,9 +575,11 @@ func (a *OpenAPI) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips [
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().UnassignIpv6Addresses(req)
 	metric.OpenAPILatency.WithLabelValues(APIUnAssignIpv6Addresses, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIUnAssignIpv6Addresses, err)
 
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, APIUnAssignIpv6Addresses, req, apiErr.ErrRequestID(err), start, err)
 		if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Info("success")
 			return nil
@@ -469,6 +588,7 @@ func (a *OpenAPI) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips [
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "unassign ipv6 ip failed")
 		return err
 	}
+	a.audit(ctx, APIUnAssignIpv6Addresses, req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("success")
 	return nil
 }
@@ -496,6 +616,7 @@ func (a *OpenAPI) DescribeInstanceTypes(ctx context.Context, types []string) ([]
 		start := time.Now()
 		resp, err := a.ClientSet.ECS().DescribeInstanceTypes(req)
 		metric.OpenAPILatency.WithLabelValues(APIDescribeInstanceTypes, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		a.RateLimiter.Feedback(APIDescribeInstanceTypes, err)
 
 		l := LogFields(logf.FromContext(ctx), req)
 
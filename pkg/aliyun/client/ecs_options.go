@@ -1,11 +1,43 @@
 package client
 
 import (
+	"sort"
+
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/types"
 )
 
+// ENIOwner is who owns the eni, it is written into the standard ownership tags on creation
+type ENIOwner struct {
+	ClusterID string
+	NodeName  string
+	// PodNamespace and PodName are set for the eni exclusive to a pod
+	PodNamespace string
+	PodName      string
+	// Component is the terway component creates the eni
+	Component string
+}
+
+// Tags return the ownership tags, empty fields are omitted
+func (o *ENIOwner) Tags() map[string]string {
+	tags := make(map[string]string)
+	for k, v := range map[string]string{
+		types.TagKeyClusterID:           o.ClusterID,
+		types.TagK8SNodeName:            o.NodeName,
+		types.TagKubernetesPodNamespace: o.PodNamespace,
+		types.TagKubernetesPodName:      o.PodName,
+		types.TagTerwayComponent:        o.Component,
+	} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
 // NetworkInterfaceOptions represents the common options for network interface operations.
 type NetworkInterfaceOptions struct {
 	Trunk                 bool
@@ -21,6 +53,13 @@ type NetworkInterfaceOptions struct {
 	Status                string
 	NetworkInterfaceID    string
 	DeleteENIOnECSRelease *bool
+
+	// ClientToken is the idempotency token, generated if empty
+	ClientToken string
+	// PrimaryIP is the primary ip for the eni, allocated by vSwitch if empty
+	PrimaryIP string
+	// Owner is written into the ownership tags, which take precedence over Tags
+	Owner *ENIOwner
 }
 
 type CreateNetworkInterfaceOption interface {
@@ -83,6 +122,15 @@ func (c *CreateNetworkInterfaceOptions) ApplyCreateNetworkInterface(options *Cre
 		if c.NetworkInterfaceOptions.DeleteENIOnECSRelease != nil {
 			options.NetworkInterfaceOptions.DeleteENIOnECSRelease = c.NetworkInterfaceOptions.DeleteENIOnECSRelease
 		}
+		if c.NetworkInterfaceOptions.ClientToken != "" {
+			options.NetworkInterfaceOptions.ClientToken = c.NetworkInterfaceOptions.ClientToken
+		}
+		if c.NetworkInterfaceOptions.PrimaryIP != "" {
+			options.NetworkInterfaceOptions.PrimaryIP = c.NetworkInterfaceOptions.PrimaryIP
+		}
+		if c.NetworkInterfaceOptions.Owner != nil {
+			options.NetworkInterfaceOptions.Owner = c.NetworkInterfaceOptions.Owner
+		}
 	}
 }
 
@@ -105,6 +153,7 @@ func (c *CreateNetworkInterfaceOptions) Finish(idempotentKeyGen IdempotentKeyGen
 	req.SecurityGroupIds = &c.NetworkInterfaceOptions.SecurityGroupIDs
 	req.ResourceGroupId = c.NetworkInterfaceOptions.ResourceGroupID
 	req.Description = eniDescription
+	req.PrimaryIpAddress = c.NetworkInterfaceOptions.PrimaryIP
 	if c.NetworkInterfaceOptions.IPCount > 1 {
 		req.SecondaryPrivateIpAddressCount = requests.NewInteger(c.NetworkInterfaceOptions.IPCount - 1)
 	}
@@ -116,24 +165,52 @@ func (c *CreateNetworkInterfaceOptions) Finish(idempotentKeyGen IdempotentKeyGen
 		req.DeleteOnRelease = requests.NewBoolean(*c.NetworkInterfaceOptions.DeleteENIOnECSRelease)
 	}
 
-	var tags []ecs.CreateNetworkInterfaceTag
+	merged := make(map[string]string, len(c.NetworkInterfaceOptions.Tags))
 	for k, v := range c.NetworkInterfaceOptions.Tags {
+		merged[k] = v
+	}
+	if c.NetworkInterfaceOptions.Owner != nil {
+		for k, v := range c.NetworkInterfaceOptions.Owner.Tags() {
+			merged[k] = v
+		}
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	// keep the order, so the hash is the same for the same args
+	sort.Strings(keys)
+	var tags []ecs.CreateNetworkInterfaceTag
+	for _, k := range keys {
 		tags = append(tags, ecs.CreateNetworkInterfaceTag{
 			Key:   k,
-			Value: v,
+			Value: merged[k],
 		})
 	}
 	req.Tag = &tags
 
-	argsHash := md5Hash(req)
-	req.ClientToken = idempotentKeyGen.GenerateKey(argsHash)
-
 	if c.Backoff == nil {
 		c.Backoff = &wait.Backoff{
 			Steps: 1,
 		}
 	}
 
+	// token is given by caller, it is kept across retries and restarts
+	if c.NetworkInterfaceOptions.ClientToken != "" {
+		req.ClientToken = c.NetworkInterfaceOptions.ClientToken
+		tags = append(tags, ecs.CreateNetworkInterfaceTag{Key: TagClientToken, Value: req.ClientToken})
+		return req, func() {}, nil
+	}
+
+	argsHash := md5Hash(req)
+	token, err := idempotentKeyGen.GenerateKey(argsHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ClientToken = token
+	// the eni can be found by the token, if the response is lost
+	tags = append(tags, ecs.CreateNetworkInterfaceTag{Key: TagClientToken, Value: req.ClientToken})
+
 	return req, func() {
 		idempotentKeyGen.PutBack(argsHash, req.ClientToken)
 	}, nil
@@ -167,7 +244,11 @@ func (c *AssignPrivateIPAddressOptions) Finish(idempotentKeyGen IdempotentKeyGen
 	req.SecondaryPrivateIpAddressCount = requests.NewInteger(c.NetworkInterfaceOptions.IPCount)
 
 	argsHash := md5Hash(req)
-	req.ClientToken = idempotentKeyGen.GenerateKey(argsHash)
+	token, err := idempotentKeyGen.GenerateKey(argsHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ClientToken = token
 
 	if c.Backoff == nil {
 		c.Backoff = &wait.Backoff{
@@ -208,7 +289,11 @@ func (c *AssignIPv6AddressesOptions) Finish(idempotentKeyGen IdempotentKeyGen) (
 	req.Ipv6AddressCount = requests.NewInteger(c.NetworkInterfaceOptions.IPv6Count)
 
 	argsHash := md5Hash(req)
-	req.ClientToken = idempotentKeyGen.GenerateKey(argsHash)
+	token, err := idempotentKeyGen.GenerateKey(argsHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ClientToken = token
 
 	if c.Backoff == nil {
 		c.Backoff = &wait.Backoff{
@@ -2,10 +2,14 @@ package client
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/types"
 )
 
 // MockIdempotentKeyGen is a mock implementation of IdempotentKeyGen interface
@@ -13,17 +17,21 @@ type MockIdempotentKeyGen struct {
 	generatedKeys map[string]string
 }
 
-func (m *MockIdempotentKeyGen) GenerateKey(argsHash string) string {
+func (m *MockIdempotentKeyGen) GenerateKey(argsHash string) (string, error) {
 	if _, ok := m.generatedKeys[argsHash]; !ok {
 		m.generatedKeys[argsHash] = "mockToken"
 	}
-	return m.generatedKeys[argsHash]
+	return m.generatedKeys[argsHash], nil
 }
 
 func (m *MockIdempotentKeyGen) PutBack(argsHash string, clientToken string) {
 	delete(m.generatedKeys, argsHash)
 }
 
+func (m *MockIdempotentKeyGen) Done(clientToken string) error {
+	return nil
+}
+
 // TestCreateNetworkInterfaceOptions_Finish tests the Finish function of CreateNetworkInterfaceOptions
 func TestCreateNetworkInterfaceOptions_Finish(t *testing.T) {
 	// Prepare the test data
@@ -36,6 +44,7 @@ func TestCreateNetworkInterfaceOptions_Finish(t *testing.T) {
 		ERDMA:            true,
 		IPCount:          2,
 		IPv6Count:        1,
+		PrimaryIP:        "192.168.0.10",
 	}
 
 	c := &CreateNetworkInterfaceOptions{
@@ -56,7 +65,9 @@ func TestCreateNetworkInterfaceOptions_Finish(t *testing.T) {
 	assert.Equal(t, 1, len(*req.SecurityGroupIds))
 	assert.Equal(t, niOptions.ResourceGroupID, req.ResourceGroupId)
 	assert.Equal(t, eniDescription, req.Description)
+	assert.Equal(t, "192.168.0.10", req.PrimaryIpAddress)
 	assert.Equal(t, "mockToken", req.ClientToken)
+	assert.Contains(t, *req.Tag, ecs.CreateNetworkInterfaceTag{Key: TagClientToken, Value: "mockToken"})
 	assert.Equal(t, requests.NewInteger(1), req.SecondaryPrivateIpAddressCount)
 	assert.Equal(t, requests.NewInteger(1), req.Ipv6AddressCount)
 	assert.NotNil(t, c.Backoff)
@@ -65,6 +76,24 @@ func TestCreateNetworkInterfaceOptions_Finish(t *testing.T) {
 	cleanup()
 }
 
+func TestCreateNetworkInterfaceOptions_FinishWithClientToken(t *testing.T) {
+	c := &CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			VSwitchID:        "vsw-xxxxxx",
+			SecurityGroupIDs: []string{"sg-xxxxxx"},
+			ClientToken:      "token",
+		},
+	}
+
+	keyGen := &MockIdempotentKeyGen{generatedKeys: map[string]string{}}
+	req, cleanup, err := c.Finish(keyGen)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "token", req.ClientToken)
+	assert.Empty(t, keyGen.generatedKeys)
+	cleanup()
+}
+
 func TestCreateNetworkInterfaceOptions_ApplyCreateNetworkInterface(t *testing.T) {
 	type fields struct {
 		NetworkInterfaceOptions *NetworkInterfaceOptions
@@ -128,3 +157,66 @@ func TestCreateNetworkInterfaceOptions_ApplyCreateNetworkInterface(t *testing.T)
 		})
 	}
 }
+
+func TestCreateNetworkInterfaceOptions_FinishWithOwner(t *testing.T) {
+	c := &CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			VSwitchID:        "vsw-xxxxxx",
+			SecurityGroupIDs: []string{"sg-xxxxxx"},
+			Tags:             map[string]string{"foo": "bar", types.TagK8SNodeName: "spoofed"},
+			Owner: &ENIOwner{
+				ClusterID:    "c-1",
+				NodeName:     "node-1",
+				PodNamespace: "default",
+				PodName:      "foo",
+				Component:    "pod",
+			},
+		},
+	}
+
+	keyGen := &MockIdempotentKeyGen{generatedKeys: map[string]string{}}
+	req, _, err := c.Finish(keyGen)
+	assert.NoError(t, err)
+
+	tags := map[string]string{}
+	for _, tag := range *req.Tag {
+		tags[tag.Key] = tag.Value
+	}
+	assert.Equal(t, "bar", tags["foo"])
+	assert.Equal(t, "c-1", tags[types.TagKeyClusterID])
+	assert.Equal(t, "node-1", tags[types.TagK8SNodeName])
+	assert.Equal(t, "default", tags[types.TagKubernetesPodNamespace])
+	assert.Equal(t, "foo", tags[types.TagKubernetesPodName])
+	assert.Equal(t, "pod", tags[types.TagTerwayComponent])
+	assert.Equal(t, "mockToken", tags[TagClientToken])
+}
+
+func TestCreateNetworkInterfaceOptions_FinishSameTokenSameArgs(t *testing.T) {
+	newOpts := func() *CreateNetworkInterfaceOptions {
+		return &CreateNetworkInterfaceOptions{
+			NetworkInterfaceOptions: &NetworkInterfaceOptions{
+				VSwitchID:        "vsw-xxxxxx",
+				SecurityGroupIDs: []string{"sg-xxxxxx"},
+				ClientToken:      "token-1",
+				Owner:            &ENIOwner{ClusterID: "c-1", PodNamespace: "default", PodName: "foo", Component: "pod"},
+			},
+		}
+	}
+
+	// the request retried with the same token must carry the same params, or ECS rejects it with IdempotentParameterMismatch
+	req1, _, err := newOpts().Finish(&MockIdempotentKeyGen{})
+	assert.NoError(t, err)
+	time.Sleep(1100 * time.Millisecond)
+	req2, _, err := newOpts().Finish(&MockIdempotentKeyGen{})
+	assert.NoError(t, err)
+	assert.Equal(t, *req1.Tag, *req2.Tag)
+	assert.Equal(t, "token-1", req2.ClientToken)
+}
+
+func TestENIOwner_Tags(t *testing.T) {
+	o := &ENIOwner{NodeName: "node-1", Component: "terway-daemon"}
+	assert.Equal(t, map[string]string{
+		types.TagK8SNodeName:     "node-1",
+		types.TagTerwayComponent: "terway-daemon",
+	}, o.Tags())
+}
@@ -14,7 +14,7 @@ import (
 	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 )
 
-func (a *OpenAPI) CreateElasticNetworkInterface(zoneID, nodeID, vSwitchID, securityGroupID string) (string, string, error) {
+func (a *OpenAPI) CreateElasticNetworkInterface(ctx context.Context, zoneID, nodeID, vSwitchID, securityGroupID string) (string, string, error) {
 	req := eflo.CreateCreateElasticNetworkInterfaceRequest()
 	req.ZoneId = zoneID
 	req.NodeId = nodeID
@@ -25,8 +25,10 @@ func (a *OpenAPI) CreateElasticNetworkInterface(zoneID, nodeID, vSwitchID, secur
 	resp, err := a.ClientSet.EFLO().CreateElasticNetworkInterface(req)
 	metric.OpenAPILatency.WithLabelValues("CreateElasticNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
 	if err != nil {
+		a.audit(ctx, "CreateElasticNetworkInterface", req, apiErr.ErrRequestID(err), start, err)
 		return "", "", err
 	}
+	a.audit(ctx, "CreateElasticNetworkInterface", req, resp.RequestId, start, nil)
 
 	return resp.Content.NodeId, resp.Content.ElasticNetworkInterfaceId, nil
 }
@@ -44,10 +46,12 @@ func (a *OpenAPI) DeleteElasticNetworkInterface(ctx context.Context, eniID strin
 	metric.OpenAPILatency.WithLabelValues("DeleteElasticNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, "DeleteElasticNetworkInterface", req, apiErr.ErrRequestID(err), start, err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
 		return err
 	}
 
+	a.audit(ctx, "DeleteElasticNetworkInterface", req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("succeed")
 	return nil
 }
@@ -67,11 +71,13 @@ func (a *OpenAPI) AssignLeniPrivateIPAddress(ctx context.Context, eniID, prefer
 	metric.OpenAPILatency.WithLabelValues("AssignLeniPrivateIPAddress", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, "AssignLeniPrivateIPAddress", req, apiErr.ErrRequestID(err), start, err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
 
 		return "", err
 	}
 
+	a.audit(ctx, "AssignLeniPrivateIPAddress", req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("assign", "ipName", resp.Content.IpName, "ip", resp.Content.Ip, "private", resp.Content.PrivateIpAddress)
 
 	return resp.Content.IpName, nil
@@ -93,10 +99,12 @@ func (a *OpenAPI) UnassignLeniPrivateIPAddress(ctx context.Context, eniID, ipNam
 	metric.OpenAPILatency.WithLabelValues("UnassignLeniPrivateIpAddress", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
 	if err != nil {
 		err = apiErr.WarpError(err)
+		a.audit(ctx, "UnassignLeniPrivateIpAddress", req, apiErr.ErrRequestID(err), start, err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
 		return err
 	}
 
+	a.audit(ctx, "UnassignLeniPrivateIpAddress", req, resp.RequestId, start, nil)
 	l.WithValues(LogFieldRequestID, resp.RequestId).Info("success")
 
 	return nil
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// defaultENICacheTTL is the consistency window, describe result is reused within it
+	defaultENICacheTTL = 2 * time.Second
+	// defaultENIBatchWindow is how long the eni id lookups are gathered into one request
+	defaultENIBatchWindow = 20 * time.Millisecond
+	// maxENIIDsPerRequest is the max eni ids in one DescribeNetworkInterfaces request
+	maxENIIDsPerRequest = 100
+	// defaultENIDescribeTimeout bounds the request shared by callers, as it is not bound to any of them
+	defaultENIDescribeTimeout = time.Minute
+)
+
+// describeQuery is the filter of DescribeNetworkInterfaces
+type describeQuery struct {
+	vpcID        string
+	eniIDs       []string
+	instanceID   string
+	instanceType string
+	status       string
+	tags         map[string]string
+}
+
+// byIDOnly the query can be served by eni id lookups
+func (q *describeQuery) byIDOnly() bool {
+	return len(q.eniIDs) > 0 && q.vpcID == "" && q.instanceID == "" && q.instanceType == "" && q.status == "" && len(q.tags) == 0
+}
+
+func (q *describeQuery) key() string {
+	ids := append([]string(nil), q.eniIDs...)
+	sort.Strings(ids)
+	tags := make([]string, 0, len(q.tags))
+	for k, v := range q.tags {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return strings.Join([]string{q.vpcID, q.instanceID, q.instanceType, q.status, strings.Join(ids, ","), strings.Join(tags, ",")}, "|")
+}
+
+type describeFunc func(ctx context.Context, q *describeQuery) ([]*NetworkInterface, error)
+
+type queryEntry struct {
+	enis     []*NetworkInterface
+	expireAt time.Time
+
+	instanceID string
+	// eniIDs is the eni queried and returned
+	eniIDs sets.Set[string]
+	// list is the query not limited to eni ids or instance, any eni change may affect it
+	list bool
+}
+
+type idEntry struct {
+	// eni is nil if not found
+	eni      *NetworkInterface
+	expireAt time.Time
+}
+
+// idBatch is the eni id lookups waiting to be sent
+type idBatch struct {
+	ids  sets.Set[string]
+	done chan struct{}
+
+	result map[string]*NetworkInterface
+	err    error
+}
+
+// eniCache coalesce DescribeNetworkInterfaces from all callers.
+// Lookups by eni id are batched into multi id requests, other queries are deduplicated by the filter.
+// Results are reused within the ttl, and dropped once the eni is changed by this client.
+type eniCache struct {
+	describe describeFunc
+	ttl      time.Duration
+	window   time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	group singleflight.Group
+
+	lock sync.Mutex
+	// gen is increased on every invalidation, results fetched across an invalidation are not cached
+	gen     uint64
+	queries map[string]*queryEntry
+	ids     map[string]*idEntry
+	batch   *idBatch
+}
+
+func newENICache(describe describeFunc, ttl, window time.Duration) *eniCache {
+	return &eniCache{
+		describe: describe,
+		ttl:      ttl,
+		window:   window,
+		timeout:  defaultENIDescribeTimeout,
+		now:      time.Now,
+		queries:  make(map[string]*queryEntry),
+		ids:      make(map[string]*idEntry),
+	}
+}
+
+// Describe return the enis matching the query, from cache if possible
+func (c *eniCache) Describe(ctx context.Context, q *describeQuery) ([]*NetworkInterface, error) {
+	if q.byIDOnly() {
+		return c.describeByID(ctx, q.eniIDs)
+	}
+
+	key := q.key()
+	c.lock.Lock()
+	e, ok := c.queries[key]
+	if ok && c.now().Before(e.expireAt) {
+		c.lock.Unlock()
+		return copyENIs(e.enis), nil
+	}
+	c.lock.Unlock()
+
+	// the request is shared by callers, so it is not canceled with the first one
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		c.lock.Lock()
+		gen := c.gen
+		c.lock.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+		defer cancel()
+		enis, err := c.describe(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if gen == c.gen {
+			expireAt := c.now().Add(c.ttl)
+			entry := &queryEntry{
+				enis:       enis,
+				expireAt:   expireAt,
+				instanceID: q.instanceID,
+				eniIDs:     sets.New[string](q.eniIDs...),
+				list:       len(q.eniIDs) == 0 && q.instanceID == "",
+			}
+			for _, eni := range enis {
+				entry.eniIDs.Insert(eni.NetworkInterfaceID)
+				c.ids[eni.NetworkInterfaceID] = &idEntry{eni: eni, expireAt: expireAt}
+			}
+			c.queries[key] = entry
+		}
+		return enis, nil
+	})
+
+	var r singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r = <-ch:
+	}
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return copyENIs(r.Val.([]*NetworkInterface)), nil
+}
+
+func (c *eniCache) describeByID(ctx context.Context, eniIDs []string) ([]*NetworkInterface, error) {
+	c.lock.Lock()
+	now := c.now()
+	var result []*NetworkInterface
+	missing := sets.New[string]()
+	for _, id := range eniIDs {
+		e, ok := c.ids[id]
+		if !ok || !now.Before(e.expireAt) {
+			missing.Insert(id)
+			continue
+		}
+		if e.eni != nil {
+			result = append(result, e.eni)
+		}
+	}
+	if missing.Len() == 0 {
+		c.lock.Unlock()
+		return copyENIs(result), nil
+	}
+
+	b := c.batch
+	if b == nil {
+		b = &idBatch{
+			ids:  sets.New[string](),
+			done: make(chan struct{}),
+		}
+		c.batch = b
+		time.AfterFunc(c.window, func() {
+			c.flush(b)
+		})
+	}
+	b.ids = b.ids.Union(missing)
+	c.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.done:
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	for _, id := range sets.List(missing) {
+		if eni, ok := b.result[id]; ok {
+			result = append(result, eni)
+		}
+	}
+	return copyENIs(result), nil
+}
+
+// flush send the batched lookups, the batch is not shared by any caller after it
+func (c *eniCache) flush(b *idBatch) {
+	c.lock.Lock()
+	if c.batch == b {
+		c.batch = nil
+	}
+	gen := c.gen
+	c.lock.Unlock()
+
+	defer close(b.done)
+
+	// the batch is shared by callers, so it is not bound to any of them
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	ids := sets.List(b.ids)
+	result := make(map[string]*NetworkInterface, len(ids))
+	for start := 0; start < len(ids); start += maxENIIDsPerRequest {
+		end := start + maxENIIDsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+		enis, err := c.describe(ctx, &describeQuery{eniIDs: ids[start:end]})
+		if err != nil {
+			b.err = err
+			return
+		}
+		for _, eni := range enis {
+			result[eni.NetworkInterfaceID] = eni
+		}
+	}
+	b.result = result
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen != c.gen {
+		return
+	}
+	expireAt := c.now().Add(c.ttl)
+	for _, id := range ids {
+		c.ids[id] = &idEntry{eni: result[id], expireAt: expireAt}
+	}
+}
+
+// Invalidate drop the cached result related to the eni or instance.
+// eniID is empty for a new eni, only the list queries are dropped.
+func (c *eniCache) Invalidate(eniID, instanceID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	if eniID != "" {
+		delete(c.ids, eniID)
+	}
+	for key, e := range c.queries {
+		if e.list ||
+			(eniID != "" && e.eniIDs.Has(eniID)) ||
+			(instanceID != "" && e.instanceID == instanceID) {
+			delete(c.queries, key)
+		}
+	}
+
+	// drop the expired entries as well, to keep the cache small
+	now := c.now()
+	for id, e := range c.ids {
+		if !now.Before(e.expireAt) {
+			delete(c.ids, id)
+		}
+	}
+	for key, e := range c.queries {
+		if !now.Before(e.expireAt) {
+			delete(c.queries, key)
+		}
+	}
+}
+
+// copyENIs return a shallow copy, so callers can not modify the cached one
+func copyENIs(in []*NetworkInterface) []*NetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := make([]*NetworkInterface, 0, len(in))
+	for _, eni := range in {
+		cp := *eni
+		out = append(out, &cp)
+	}
+	return out
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDescribe record the queries, and return an eni for each queried id
+type fakeDescribe struct {
+	lock    sync.Mutex
+	queries []*describeQuery
+	// block is closed to let the describe return
+	block chan struct{}
+}
+
+func (f *fakeDescribe) describe(ctx context.Context, q *describeQuery) ([]*NetworkInterface, error) {
+	f.lock.Lock()
+	f.queries = append(f.queries, q)
+	f.lock.Unlock()
+
+	if f.block != nil {
+		<-f.block
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []*NetworkInterface
+	for _, id := range q.eniIDs {
+		if id == "eni-not-found" {
+			continue
+		}
+		result = append(result, &NetworkInterface{NetworkInterfaceID: id, InstanceID: q.instanceID})
+	}
+	if q.instanceID != "" {
+		result = append(result, &NetworkInterface{NetworkInterfaceID: "eni-on-" + q.instanceID, InstanceID: q.instanceID})
+	}
+	return result, nil
+}
+
+func (f *fakeDescribe) count() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.queries)
+}
+
+func TestENICache_BatchByID(t *testing.T) {
+	f := &fakeDescribe{}
+	c := newENICache(f.describe, time.Minute, 20*time.Millisecond)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("eni-%d", i)
+			enis, err := c.Describe(context.Background(), &describeQuery{eniIDs: []string{id, "eni-not-found"}})
+			assert.NoError(t, err)
+			if assert.Len(t, enis, 1) {
+				assert.Equal(t, id, enis[0].NetworkInterfaceID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 151 ids are split into 2 requests
+	assert.Equal(t, 2, f.count())
+	for _, q := range f.queries {
+		assert.LessOrEqual(t, len(q.eniIDs), maxENIIDsPerRequest)
+	}
+
+	// served from cache, not found is cached as well
+	enis, err := c.Describe(context.Background(), &describeQuery{eniIDs: []string{"eni-1", "eni-not-found"}})
+	assert.NoError(t, err)
+	assert.Len(t, enis, 1)
+	assert.Equal(t, 2, f.count())
+}
+
+func TestENICache_Query(t *testing.T) {
+	f := &fakeDescribe{block: make(chan struct{})}
+	c := newENICache(f.describe, time.Minute, 20*time.Millisecond)
+
+	q := &describeQuery{instanceID: "i-1", status: ENIStatusInUse}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enis, err := c.Describe(context.Background(), q)
+			assert.NoError(t, err)
+			assert.Len(t, enis, 1)
+		}()
+	}
+	// wait all callers joined
+	time.Sleep(50 * time.Millisecond)
+	close(f.block)
+	wg.Wait()
+	assert.Equal(t, 1, f.count())
+
+	// reused within the ttl
+	enis, err := c.Describe(context.Background(), q)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f.count())
+
+	// the caller can not modify the cache
+	enis[0].Status = ENIStatusDeleting
+	enis, err = c.Describe(context.Background(), &describeQuery{eniIDs: []string{"eni-on-i-1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f.count(), "eni found by the instance query is cached by id")
+	if assert.Len(t, enis, 1) {
+		assert.Empty(t, enis[0].Status)
+	}
+
+	// expired
+	c.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = c.Describe(context.Background(), q)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, f.count())
+}
+
+func TestENICache_Invalidate(t *testing.T) {
+	f := &fakeDescribe{}
+	c := newENICache(f.describe, time.Minute, time.Millisecond)
+	ctx := context.Background()
+
+	byInstance := &describeQuery{instanceID: "i-1"}
+	list := &describeQuery{vpcID: "vpc-1", status: ENIStatusAvailable}
+	byID := &describeQuery{eniIDs: []string{"eni-1"}}
+	for _, q := range []*describeQuery{byInstance, list, byID} {
+		_, err := c.Describe(ctx, q)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, f.count())
+
+	// a new eni only affects the list query
+	c.Invalidate("", "")
+	for _, q := range []*describeQuery{byInstance, list, byID} {
+		_, err := c.Describe(ctx, q)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 4, f.count())
+
+	// attach eni-1 to i-1
+	c.Invalidate("eni-1", "i-1")
+	for _, q := range []*describeQuery{byInstance, list, byID} {
+		_, err := c.Describe(ctx, q)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 7, f.count())
+}
+
+func TestENICache_InvalidateInFlight(t *testing.T) {
+	f := &fakeDescribe{block: make(chan struct{})}
+	c := newENICache(f.describe, time.Minute, time.Millisecond)
+	q := &describeQuery{instanceID: "i-1"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := c.Describe(context.Background(), q)
+		assert.NoError(t, err)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	c.Invalidate("eni-1", "i-1")
+	close(f.block)
+	<-done
+
+	// the result may miss the change, so it is not cached
+	_, err := c.Describe(context.Background(), q)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, f.count())
+}
+
+func TestENICache_CallerCanceled(t *testing.T) {
+	f := &fakeDescribe{block: make(chan struct{})}
+	c := newENICache(f.describe, time.Minute, time.Millisecond)
+	q := &describeQuery{instanceID: "i-1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan struct{})
+	go func() {
+		defer close(first)
+		_, err := c.Describe(ctx, q)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	second := make(chan struct{})
+	go func() {
+		defer close(second)
+		enis, err := c.Describe(context.Background(), q)
+		assert.NoError(t, err)
+		assert.Len(t, enis, 1)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the first caller is gone, the shared request is not canceled with it
+	cancel()
+	<-first
+	close(f.block)
+	<-second
+	assert.Equal(t, 1, f.count())
+}
+
+func TestENICache_DescribeTimeout(t *testing.T) {
+	f := &fakeDescribe{}
+	c := newENICache(func(ctx context.Context, q *describeQuery) ([]*NetworkInterface, error) {
+		<-ctx.Done()
+		return f.describe(ctx, q)
+	}, time.Minute, time.Millisecond)
+	c.timeout = 10 * time.Millisecond
+
+	_, err := c.Describe(context.Background(), &describeQuery{instanceID: "i-1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = c.Describe(context.Background(), &describeQuery{eniIDs: []string{"eni-1"}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 
 	apiErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 )
@@ -51,6 +52,10 @@ const (
 	ErrThrottling = "Throttling"
 
 	ErrOperationConflict = "Operation.Conflict"
+
+	// ErrPrivateIPAddressDuplicated the primary ip is already used
+	// for API CreateNetworkInterface
+	ErrPrivateIPAddressDuplicated = "InvalidPrivateIpAddress.Duplicated"
 )
 
 // define well known err
@@ -133,6 +138,16 @@ func IsURLError(err error) bool {
 	return errors.As(err, &urlErr)
 }
 
+// IsThrottling return true if the request is rejected by flow control, e.g. Throttling.User
+func IsThrottling(err error) bool {
+	var respErr *apiErr.ServerError
+	ok := errors.As(err, &respErr)
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(respErr.ErrorCode(), ErrThrottling)
+}
+
 func WarpFn(codes ...string) CheckErr {
 	return func(err error) bool {
 		return ErrorCodeIs(err, codes...)
@@ -118,3 +118,10 @@ func TestErrorIs(t *testing.T) {
 	// Test case 3: Check if no check functions are provided
 	assert.False(t, ErrorIs(err))
 }
+
+func TestIsThrottling(t *testing.T) {
+	assert.True(t, IsThrottling(apiErr.NewServerError(400, "{\"Code\": \"Throttling\"}", "")))
+	assert.True(t, IsThrottling(WarpError(apiErr.NewServerError(400, "{\"Code\": \"Throttling.User\"}", ""))))
+	assert.False(t, IsThrottling(apiErr.NewServerError(400, "{\"Code\": \"InvalidParameter\"}", "")))
+	assert.False(t, IsThrottling(errors.New("Throttling")))
+}
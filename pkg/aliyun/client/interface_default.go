@@ -18,6 +18,7 @@ type ECS interface {
 	AttachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error
 	DetachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error
 	DeleteNetworkInterface(ctx context.Context, eniID string) error
+	ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error
 	WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*NetworkInterface, error)
 	AssignPrivateIPAddress(ctx context.Context, opts ...AssignPrivateIPAddressOption) ([]netip.Addr, error)
 	UnAssignPrivateIPAddresses(ctx context.Context, eniID string, ips []netip.Addr) error
@@ -44,6 +44,14 @@ type Limits struct {
 	InstanceBandwidthRx int
 
 	InstanceBandwidthTx int
+
+	// TrunkUnknown is set when the limits come from the catalogue without the trunk quota,
+	// MemberAdapterLimit and MaxMemberAdapterLimit are not known and must not be used
+	TrunkUnknown bool
+
+	// ERdmaUnknown is set when the limits come from the catalogue without the erdma quota,
+	// ERdmaAdapters is not known and must not be used
+	ERdmaUnknown bool
 }
 
 func (l *Limits) SupportMultiIPIPv6() bool {
@@ -119,13 +127,17 @@ type ECSLimitProvider struct {
 	cache cache.LRUExpireCache
 	ttl   time.Duration
 
+	// catalogue is the fallback when the openAPI is unavailable
+	catalogue *Catalogue
+
 	g singleflight.Group
 }
 
 func NewECSLimitProvider() *ECSLimitProvider {
 	return &ECSLimitProvider{
-		cache: *cache.NewLRUExpireCache(10 * 1000),
-		ttl:   15 * 24 * time.Hour,
+		cache:     *cache.NewLRUExpireCache(10 * 1000),
+		ttl:       15 * 24 * time.Hour,
+		catalogue: EmbeddedCatalogue(),
 	}
 }
 
@@ -152,6 +164,12 @@ func (d *ECSLimitProvider) GetLimit(client interface{}, instanceType string) (*L
 		return ins, nil
 	})
 	if err != nil {
+		// not cached, so the openAPI is tried again next time
+		limit, ok := d.catalogue.Get(instanceType)
+		if ok {
+			logf.Log.Error(err, "failed to describe instance type, use the limit from catalogue", "instanceType", instanceType, "version", d.catalogue.Version)
+			return limit, nil
+		}
 		return nil, err
 	}
 
@@ -164,6 +182,13 @@ func (d *ECSLimitProvider) GetLimit(client interface{}, instanceType string) (*L
 
 		d.cache.Add(instanceTypeID, limit, d.ttl)
 		logf.Log.Info("instance limit", instanceTypeID, limit)
+
+		if offline, ok := d.catalogue.Get(instanceTypeID); ok {
+			diff := DiffLimits(limit, offline)
+			if len(diff) > 0 {
+				logf.Log.Info("instance limit differs from catalogue, the catalogue should be refreshed", "instanceType", instanceTypeID, "version", d.catalogue.Version, "diff", diff)
+			}
+		}
 	}
 	if instanceType == "" {
 		return nil, nil
@@ -246,6 +246,24 @@ func (_m *ECS) DetachNetworkInterface(ctx context.Context, eniID string, instanc
 	return r0
 }
 
+// ModifyNetworkInterfaceAttribute provides a mock function with given fields: ctx, eniID, securityGroupIDs
+func (_m *ECS) ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error {
+	ret := _m.Called(ctx, eniID, securityGroupIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ModifyNetworkInterfaceAttribute")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, eniID, securityGroupIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnAssignIpv6Addresses provides a mock function with given fields: ctx, eniID, ips
 func (_m *ECS) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips []netip.Addr) error {
 	ret := _m.Called(ctx, eniID, ips)
@@ -2,11 +2,13 @@ package client
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 	"github.com/AliyunContainerService/terway/pkg/metric"
 )
 
@@ -18,22 +20,33 @@ type Limit struct {
 }
 
 var defaultLimit = map[string]int{
-	"":                           500,
-	"AttachNetworkInterface":     500,
-	"CreateNetworkInterface":     500,
-	"DeleteNetworkInterface":     500,
-	"DescribeNetworkInterfaces":  800,
-	"DetachNetworkInterface":     400,
-	"AssignPrivateIpAddresses":   400,
-	"UnassignPrivateIpAddresses": 400,
-	"AssignIpv6Addresses":        400,
-	"UnassignIpv6Addresses":      400,
-	"DescribeInstanceTypes":      400,
-	"DescribeVSwitches":          300,
+	"":                                500,
+	"AttachNetworkInterface":          500,
+	"CreateNetworkInterface":          500,
+	"DeleteNetworkInterface":          500,
+	"DescribeNetworkInterfaces":       800,
+	"DetachNetworkInterface":          400,
+	"ModifyNetworkInterfaceAttribute": 300,
+	"AssignPrivateIpAddresses":        400,
+	"UnassignPrivateIpAddresses":      400,
+	"AssignIpv6Addresses":             400,
+	"UnassignIpv6Addresses":           400,
+	"DescribeInstanceTypes":           400,
+	"DescribeVSwitches":               300,
 }
 
 const (
 	longThrottleLatency = 5 * time.Second
+
+	// throttleDecreaseFactor is the multiplier applied to the rate on throttling
+	throttleDecreaseFactor = 0.5
+	// throttleCooldown avoid shrinking the rate many times for one throttling burst
+	throttleCooldown = time.Second
+	// minRateRatio is the lowest rate kept after throttling, relative to the max
+	minRateRatio = 0.05
+	// recoverInterval and recoverRatio control how fast the rate is increased after throttling
+	recoverInterval = 10 * time.Second
+	recoverRatio    = 0.1
 )
 
 func FromMap(in map[string]int) LimitConfig {
@@ -47,24 +60,126 @@ func FromMap(in map[string]int) LimitConfig {
 	return l
 }
 
+// adaptiveLimiter is a token bucket, the rate is halved on throttling and increased linearly after (AIMD)
+type adaptiveLimiter struct {
+	*rate.Limiter
+
+	name string
+
+	lock sync.Mutex
+	// max is the configured rate, or the quota assigned by controlplane
+	max          rate.Limit
+	lastThrottle time.Time
+	lastRecover  time.Time
+}
+
+func newAdaptiveLimiter(name string, l Limit) *adaptiveLimiter {
+	a := &adaptiveLimiter{
+		Limiter: rate.NewLimiter(rate.Limit(l.QPS), l.Burst),
+		name:    name,
+		max:     rate.Limit(l.QPS),
+	}
+	metric.OpenAPIEffectiveQPS.WithLabelValues(name).Set(l.QPS)
+	return a
+}
+
+func (a *adaptiveLimiter) Wait(ctx context.Context) error {
+	a.recover(time.Now())
+	return a.Limiter.Wait(ctx)
+}
+
+// throttled shrink the rate by half, but not lower than minRateRatio of max
+func (a *adaptiveLimiter) throttled(now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if now.Sub(a.lastThrottle) < throttleCooldown {
+		return
+	}
+	a.lastThrottle = now
+	a.lastRecover = now
+
+	limit := a.Limit() * throttleDecreaseFactor
+	if limit < a.max*minRateRatio {
+		limit = a.max * minRateRatio
+	}
+	a.set(now, limit)
+}
+
+// recover increase the rate by recoverRatio of max for every recoverInterval passed
+func (a *adaptiveLimiter) recover(now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	current := a.Limit()
+	if current >= a.max {
+		return
+	}
+	steps := now.Sub(a.lastRecover) / recoverInterval
+	if steps <= 0 {
+		return
+	}
+	a.lastRecover = a.lastRecover.Add(steps * recoverInterval)
+
+	limit := current + a.max*recoverRatio*rate.Limit(steps)
+	if limit > a.max {
+		limit = a.max
+	}
+	a.set(now, limit)
+}
+
+// setMax change the max rate, a lower max take effect immediately and a higher one is reached by recover
+func (a *adaptiveLimiter) setMax(l Limit) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	if a.Limit() >= a.max || a.Limit() > rate.Limit(l.QPS) {
+		a.set(now, rate.Limit(l.QPS))
+	}
+	a.max = rate.Limit(l.QPS)
+	a.SetBurstAt(now, l.Burst)
+}
+
+func (a *adaptiveLimiter) set(now time.Time, limit rate.Limit) {
+	a.SetLimitAt(now, limit)
+	metric.OpenAPIEffectiveQPS.WithLabelValues(a.name).Set(float64(limit))
+}
+
+// RateLimiter is the per api rate limiter for openAPI.
+// The rate is adjusted by the throttling feedback, and can be overridden by the quota from controlplane.
 type RateLimiter struct {
-	store map[string]*rate.Limiter
+	store map[string]*adaptiveLimiter
+	// configured is the limit from config, restored when the quota is withdrawn
+	configured map[string]Limit
 }
 
 func NewRateLimiter(cfg LimitConfig) *RateLimiter {
 	r := &RateLimiter{
-		store: make(map[string]*rate.Limiter),
+		store:      make(map[string]*adaptiveLimiter),
+		configured: make(map[string]Limit),
 	}
 	for k, v := range defaultLimit {
-		r.store[k] = rate.NewLimiter(rate.Limit(float64(v)/60), v)
+		r.configured[k] = Limit{QPS: float64(v) / 60, Burst: v}
 	}
 	for k, v := range cfg {
-		r.store[k] = rate.NewLimiter(rate.Limit(v.QPS), v.Burst)
+		r.configured[k] = v
+	}
+	for k, v := range r.configured {
+		r.store[k] = newAdaptiveLimiter(k, v)
 	}
 
 	return r
 }
 
+func (r *RateLimiter) get(name string) *adaptiveLimiter {
+	v, ok := r.store[name]
+	if ok {
+		return v
+	}
+	return r.store[""]
+}
+
 func (r *RateLimiter) Wait(ctx context.Context, name string) error {
 	start := time.Now()
 	defer func() {
@@ -76,9 +191,39 @@ func (r *RateLimiter) Wait(ctx context.Context, name string) error {
 			l.Info("client rate limit", "api", name, "took", took.Seconds())
 		}
 	}()
-	v, ok := r.store[name]
+	return r.get(name).Wait(ctx)
+}
+
+// Feedback report the result of the api call, the rate is shrunk if the call is throttled
+func (r *RateLimiter) Feedback(name string, err error) {
+	if !apiErr.IsThrottling(err) {
+		return
+	}
+	metric.OpenAPIThrottleTotal.WithLabelValues(name).Inc()
+	r.get(name).throttled(time.Now())
+}
+
+// Limit return the configured limit for the api
+func (r *RateLimiter) Limit(name string) Limit {
+	v, ok := r.configured[name]
 	if ok {
-		return v.Wait(ctx)
+		return v
 	}
-	return r.store[""].Wait(ctx)
+	return r.configured[""]
+}
+
+// SetQuota override the max rate of apis, api not in the quota is restored to the configured limit
+func (r *RateLimiter) SetQuota(quota LimitConfig) {
+	for k, v := range r.store {
+		l, ok := quota[k]
+		if !ok {
+			l = r.configured[k]
+		}
+		v.setMax(l)
+	}
+}
+
+// ResetQuota restore all apis to the configured limit
+func (r *RateLimiter) ResetQuota() {
+	r.SetQuota(nil)
 }
@@ -2,11 +2,14 @@ package client
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
+	apiErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
 )
 
 func TestNewRateLimiter(t *testing.T) {
@@ -72,3 +75,63 @@ func TestRateLimiter_Wait(t *testing.T) {
 	wg.Wait()
 	assert.True(t, 1*time.Second < time.Since(start))
 }
+
+func TestAdaptiveLimiter(t *testing.T) {
+	a := newAdaptiveLimiter("foo", Limit{QPS: 10, Burst: 10})
+	now := time.Now()
+
+	a.throttled(now)
+	assert.Equal(t, rate.Limit(5), a.Limit())
+
+	// only shrink once in cooldown
+	a.throttled(now.Add(throttleCooldown / 2))
+	assert.Equal(t, rate.Limit(5), a.Limit())
+
+	for i := 1; i < 10; i++ {
+		a.throttled(now.Add(time.Duration(i) * throttleCooldown))
+	}
+	assert.Equal(t, rate.Limit(10*minRateRatio), a.Limit())
+
+	last := now.Add(9 * throttleCooldown)
+	a.recover(last.Add(recoverInterval / 2))
+	assert.Equal(t, rate.Limit(10*minRateRatio), a.Limit())
+
+	a.recover(last.Add(recoverInterval))
+	assert.InDelta(t, 10*minRateRatio+10*recoverRatio, float64(a.Limit()), 0.001)
+
+	a.recover(last.Add(100 * recoverInterval))
+	assert.Equal(t, rate.Limit(10), a.Limit())
+}
+
+func TestRateLimiter_Feedback(t *testing.T) {
+	r := NewRateLimiter(map[string]Limit{
+		"foo": {
+			QPS:   10,
+			Burst: 10,
+		},
+	})
+
+	r.Feedback("foo", errors.New("Throttling"))
+	assert.Equal(t, rate.Limit(10), r.store["foo"].Limit())
+
+	r.Feedback("foo", apiErr.NewServerError(400, "{\"Code\": \"Throttling.User\"}", ""))
+	assert.Equal(t, rate.Limit(5), r.store["foo"].Limit())
+}
+
+func TestRateLimiter_SetQuota(t *testing.T) {
+	r := NewRateLimiter(map[string]Limit{
+		"foo": {
+			QPS:   10,
+			Burst: 10,
+		},
+	})
+
+	r.SetQuota(LimitConfig{"foo": {QPS: 1, Burst: 60}})
+	assert.Equal(t, rate.Limit(1), r.store["foo"].Limit())
+	assert.Equal(t, 60, r.store["foo"].Burst())
+
+	r.ResetQuota()
+	assert.Equal(t, rate.Limit(10), r.store["foo"].Limit())
+	assert.Equal(t, 10, r.store["foo"].Burst())
+	assert.Equal(t, Limit{QPS: 10, Burst: 10}, r.Limit("foo"))
+}
@@ -1,20 +1,36 @@
 package client
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"k8s.io/utils/lru"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
 )
 
+// TagClientToken is the tag key of the idempotency key, set on the eni created
+const TagClientToken = "terway-client-token"
+
+// intentTTL is how long a pending idempotency key is kept, the openAPI forgets it after a while
+const intentTTL = time.Hour
+
 type IdempotentKeyGen interface {
-	GenerateKey(paramHash string) string
+	// GenerateKey return the key for the request, the request should not be sent if an error is returned
+	GenerateKey(paramHash string) (string, error)
 	PutBack(paramHash string, uuid string)
+	// Done is called when the request using the key is succeed
+	Done(uuid string) error
 }
 
 // SimpleIdempotentKeyGenerator implements the generation and management of idempotency keys.
@@ -36,7 +52,7 @@ func NewIdempotentKeyGenerator() *SimpleIdempotentKeyGenerator {
 
 // GenerateKey generates an idempotency key based on the given parameter hash.
 // multiple key is supported
-func (g *SimpleIdempotentKeyGenerator) GenerateKey(paramHash string) string {
+func (g *SimpleIdempotentKeyGenerator) GenerateKey(paramHash string) (string, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -52,11 +68,11 @@ func (g *SimpleIdempotentKeyGenerator) GenerateKey(paramHash string) string {
 			} else {
 				g.cache.Add(paramHash, uuids)
 			}
-			return id
+			return id, nil
 		}
 	}
 
-	return uuid.NewString()
+	return uuid.NewString(), nil
 }
 
 // PutBack adds the specified idempotency key back into the cache for reuse, associating it with the given parameter hash.
@@ -75,6 +91,198 @@ func (g *SimpleIdempotentKeyGenerator) PutBack(paramHash string, uuid string) {
 	}
 }
 
+// Done the key is not used any more
+func (g *SimpleIdempotentKeyGenerator) Done(uuid string) error {
+	return nil
+}
+
+// Intent is a request in flight, with the idempotency key it used
+type Intent struct {
+	Token     string    `json:"token"`
+	ParamHash string    `json:"paramHash"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Owner is the replica sent the request, only the owner resolves the intent
+	Owner string `json:"owner,omitempty"`
+}
+
+// PersistentIdempotentKeyGenerator keep the keys in flight in a storage, so they survive restarts.
+// A key is recorded before the request is sent, and removed once the request is succeed.
+// The storage may be shared by replicas, each one only resolves the intents it owns.
+type PersistentIdempotentKeyGenerator struct {
+	*SimpleIdempotentKeyGenerator
+
+	store storage.Storage
+	owner string
+}
+
+func NewPersistentIdempotentKeyGenerator(store storage.Storage, owner string) *PersistentIdempotentKeyGenerator {
+	return &PersistentIdempotentKeyGenerator{
+		SimpleIdempotentKeyGenerator: NewIdempotentKeyGenerator(),
+		store:                        store,
+		owner:                        owner,
+	}
+}
+
+// GenerateKey record the key before the request is sent, the request is not idempotent across restarts if the key is not recorded
+func (g *PersistentIdempotentKeyGenerator) GenerateKey(paramHash string) (string, error) {
+	token, err := g.SimpleIdempotentKeyGenerator.GenerateKey(paramHash)
+	if err != nil {
+		return "", err
+	}
+	err = g.store.Put(intentKey(g.owner, token), &Intent{
+		Token:     token,
+		ParamHash: paramHash,
+		CreatedAt: time.Now(),
+		Owner:     g.owner,
+	})
+	if err != nil {
+		// the key is not used, keep it for the retry
+		g.SimpleIdempotentKeyGenerator.PutBack(paramHash, token)
+		return "", fmt.Errorf("error persist idempotent key %s, %w", token, err)
+	}
+	return token, nil
+}
+
+func (g *PersistentIdempotentKeyGenerator) Done(uuid string) error {
+	err := g.store.Delete(intentKey(g.owner, uuid))
+	if err != nil {
+		return fmt.Errorf("error remove idempotent key %s, %w", uuid, err)
+	}
+	return nil
+}
+
+type pendingKeysKey struct{}
+
+// PendingKeys hold the keys of the succeed requests, until the caller has recorded the result.
+// If the caller crashes before, the intents are resolved on restart.
+type PendingKeys struct {
+	mu     sync.Mutex
+	gen    IdempotentKeyGen
+	tokens []string
+}
+
+// WithPendingKeys defer the keys of the requests sent with the ctx, the caller call Done after the result is recorded
+func WithPendingKeys(ctx context.Context) (context.Context, *PendingKeys) {
+	p := &PendingKeys{}
+	return context.WithValue(ctx, pendingKeysKey{}, p), p
+}
+
+func pendingKeysFrom(ctx context.Context) *PendingKeys {
+	p, _ := ctx.Value(pendingKeysKey{}).(*PendingKeys)
+	return p
+}
+
+func (p *PendingKeys) add(gen IdempotentKeyGen, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gen = gen
+	p.tokens = append(p.tokens, token)
+}
+
+// Done remove the keys held, the keys failed to remove are left to the resolve on restart
+func (p *PendingKeys) Done() error {
+	p.mu.Lock()
+	tokens := p.tokens
+	p.tokens = nil
+	p.mu.Unlock()
+
+	var errs []error
+	for _, token := range tokens {
+		err := p.gen.Done(token)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// intentKey the key of the intent in the store, scoped by the owner
+func intentKey(owner, token string) string {
+	if owner == "" {
+		return token
+	}
+	return owner + "." + token
+}
+
+// intentResolver is the openAPI used to resolve the pending intents
+type intentResolver interface {
+	DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*NetworkInterface, error)
+	DeleteNetworkInterface(ctx context.Context, eniID string) error
+}
+
+// Resolve settle the intents of this owner created before the given time, should be called on startup.
+// The eni created by a pending request is found by the token tag, it has no owner and is deleted.
+// Keys of other pending requests are given back, so the retry is still idempotent.
+// Intents of other owners are left to them, the expired ones are removed, the eni created is left to the orphan eni controller.
+func (g *PersistentIdempotentKeyGenerator) Resolve(ctx context.Context, api intentResolver, before time.Time) error {
+	l := logf.FromContext(ctx).WithName("idempotent-key")
+
+	items, err := g.store.List()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		intent, ok := item.(*Intent)
+		if !ok || !intent.CreatedAt.Before(before) {
+			continue
+		}
+		if intent.Owner != g.owner {
+			// the owner is gone, the openAPI has forgotten the key
+			if time.Since(intent.CreatedAt) >= intentTTL {
+				err = g.store.Delete(intentKey(intent.Owner, intent.Token))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		enis, err := api.DescribeNetworkInterface(ctx, "", nil, "", "", "", map[string]string{TagClientToken: intent.Token})
+		if err != nil {
+			return err
+		}
+		orphan := false
+		for _, eni := range enis {
+			orphan = true
+			if eni.Status != ENIStatusAvailable {
+				l.Info("eni of pending request is in use, skip", "token", intent.Token, LogFieldENIID, eni.NetworkInterfaceID, "status", eni.Status)
+				continue
+			}
+			err = api.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+			if err != nil {
+				return err
+			}
+			l.Info("deleted eni of pending request", "token", intent.Token, LogFieldENIID, eni.NetworkInterfaceID)
+		}
+
+		if !orphan && time.Since(intent.CreatedAt) < intentTTL {
+			g.SimpleIdempotentKeyGenerator.PutBack(intent.ParamHash, intent.Token)
+			l.Info("restored pending idempotent key", "token", intent.Token)
+			continue
+		}
+		err = g.store.Delete(intentKey(intent.Owner, intent.Token))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SerializeIntent is the storage.Serializer for Intent
+func SerializeIntent(item interface{}) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+// DeserializeIntent is the storage.Deserializer for Intent
+func DeserializeIntent(data []byte) (interface{}, error) {
+	intent := &Intent{}
+	err := json.Unmarshal(data, intent)
+	if err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
 func md5Hash(obj any) string {
 	out, _ := json.Marshal(obj)
 	hash := md5.Sum(out)
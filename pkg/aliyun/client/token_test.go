@@ -1,9 +1,14 @@
 package client
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
 )
 
 func TestGenerateKey(t *testing.T) {
@@ -11,7 +16,8 @@ func TestGenerateKey(t *testing.T) {
 
 	// Test case 1: paramHash does not exist in cache
 	paramHash1 := "paramHash1"
-	key1 := generator.GenerateKey(paramHash1)
+	key1, err := generator.GenerateKey(paramHash1)
+	assert.NoError(t, err)
 	assert.NotEmpty(t, key1)
 
 	// Test case 2: paramHash exists in cache with multiple UUIDs
@@ -20,12 +26,12 @@ func TestGenerateKey(t *testing.T) {
 	uuids := []string{"uuid1", "uuid2", "uuid3"}
 	generator.cache.Add(paramHash2, uuids)
 
-	assert.Equal(t, "uuid3", generator.GenerateKey(paramHash2))
+	assert.Equal(t, "uuid3", mustGenerateKey(t, generator, paramHash2))
 	uuids2, ok := generator.cache.Get(paramHash2)
 	assert.True(t, ok)
 	assert.Len(t, uuids2.([]string), 2)
 
-	assert.Equal(t, "uuid2", generator.GenerateKey(paramHash2))
+	assert.Equal(t, "uuid2", mustGenerateKey(t, generator, paramHash2))
 	uuids2, ok = generator.cache.Get(paramHash2)
 	assert.True(t, ok)
 	assert.Len(t, uuids2.([]string), 1)
@@ -55,3 +61,180 @@ func TestPutBack(t *testing.T) {
 
 	assert.Equal(t, uuids, []string{uuid1, uuid2})
 }
+
+func mustGenerateKey(t *testing.T, g IdempotentKeyGen, paramHash string) string {
+	key, err := g.GenerateKey(paramHash)
+	assert.NoError(t, err)
+	return key
+}
+
+type fakeIntentResolver struct {
+	enis    map[string]*NetworkInterface
+	deleted []string
+}
+
+func (f *fakeIntentResolver) DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*NetworkInterface, error) {
+	var result []*NetworkInterface
+	for token, eni := range f.enis {
+		if tags[TagClientToken] == token {
+			result = append(result, eni)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeIntentResolver) DeleteNetworkInterface(ctx context.Context, eniID string) error {
+	f.deleted = append(f.deleted, eniID)
+	return nil
+}
+
+func TestPersistentIdempotentKeyGenerator(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	generator := NewPersistentIdempotentKeyGenerator(store, "")
+
+	created := mustGenerateKey(t, generator, "create")
+	assign := mustGenerateKey(t, generator, "assign")
+	inUse := mustGenerateKey(t, generator, "inUse")
+	done := mustGenerateKey(t, generator, "done")
+	assert.NoError(t, generator.Done(done))
+
+	items, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+
+	// restart
+	generator = NewPersistentIdempotentKeyGenerator(store, "")
+	api := &fakeIntentResolver{enis: map[string]*NetworkInterface{
+		created: {NetworkInterfaceID: "eni-1", Status: ENIStatusAvailable},
+		inUse:   {NetworkInterfaceID: "eni-2", Status: ENIStatusInUse},
+	}}
+	assert.NoError(t, generator.Resolve(context.Background(), api, time.Now()))
+
+	// the eni of pending create is deleted
+	assert.Equal(t, []string{"eni-1"}, api.deleted)
+	// the key of pending assign is restored
+	assert.Equal(t, assign, mustGenerateKey(t, generator, "assign"))
+	assert.NotEqual(t, created, mustGenerateKey(t, generator, "create"))
+
+	_, err = store.Get(created)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.Get(inUse)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.Get(assign)
+	assert.NoError(t, err)
+}
+
+func TestPersistentIdempotentKeyGenerator_ResolveBefore(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	generator := NewPersistentIdempotentKeyGenerator(store, "")
+	start := time.Now()
+	created := mustGenerateKey(t, generator, "create")
+
+	api := &fakeIntentResolver{enis: map[string]*NetworkInterface{
+		created: {NetworkInterfaceID: "eni-1", Status: ENIStatusAvailable},
+	}}
+	// the key is created after start, it is in flight
+	assert.NoError(t, generator.Resolve(context.Background(), api, start))
+	assert.Empty(t, api.deleted)
+}
+
+func TestPersistentIdempotentKeyGenerator_Owner(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	a := NewPersistentIdempotentKeyGenerator(store, "a")
+	b := NewPersistentIdempotentKeyGenerator(store, "b")
+	createdByA := mustGenerateKey(t, a, "create")
+	createdByB := mustGenerateKey(t, b, "create")
+
+	// a restarts, the request of b is in flight
+	a = NewPersistentIdempotentKeyGenerator(store, "a")
+	api := &fakeIntentResolver{enis: map[string]*NetworkInterface{
+		createdByA: {NetworkInterfaceID: "eni-1", Status: ENIStatusAvailable},
+		createdByB: {NetworkInterfaceID: "eni-2", Status: ENIStatusAvailable},
+	}}
+	assert.NoError(t, a.Resolve(context.Background(), api, time.Now()))
+	assert.Equal(t, []string{"eni-1"}, api.deleted)
+	_, err := store.Get("b." + createdByB)
+	assert.NoError(t, err)
+	_, err = store.Get("a." + createdByA)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.NoError(t, b.Done(createdByB))
+	_, err = store.Get("b." + createdByB)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// the key of a gone owner is removed once expired
+	assert.NoError(t, store.Put("c.token", &Intent{Token: "token", ParamHash: "create", CreatedAt: time.Now().Add(-2 * intentTTL), Owner: "c"}))
+	api = &fakeIntentResolver{enis: map[string]*NetworkInterface{
+		"token": {NetworkInterfaceID: "eni-3", Status: ENIStatusAvailable},
+	}}
+	assert.NoError(t, a.Resolve(context.Background(), api, time.Now()))
+	assert.Empty(t, api.deleted)
+	_, err = store.Get("c.token")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+type failedStorage struct {
+	storage.Storage
+}
+
+func (f *failedStorage) Put(key string, value interface{}) error {
+	return fmt.Errorf("apiserver unavailable")
+}
+
+func (f *failedStorage) Delete(key string) error {
+	return fmt.Errorf("apiserver unavailable")
+}
+
+func TestPersistentIdempotentKeyGenerator_StoreFailed(t *testing.T) {
+	generator := NewPersistentIdempotentKeyGenerator(&failedStorage{Storage: storage.NewMemoryStorage()}, "a")
+	_, err := generator.GenerateKey("create")
+	assert.Error(t, err)
+	assert.Error(t, generator.Done("token"))
+
+	opt := &CreateNetworkInterfaceOptions{NetworkInterfaceOptions: &NetworkInterfaceOptions{
+		VSwitchID:        "vsw-xxxxxx",
+		SecurityGroupIDs: []string{"sg-xxxxxx"},
+	}}
+	_, _, err = opt.Finish(generator)
+	assert.Error(t, err)
+}
+
+func TestIntentSerialize(t *testing.T) {
+	in := &Intent{Token: "token", ParamHash: "hash", CreatedAt: time.Now().Truncate(time.Second)}
+	data, err := SerializeIntent(in)
+	assert.NoError(t, err)
+	out, err := DeserializeIntent(data)
+	assert.NoError(t, err)
+	assert.True(t, in.CreatedAt.Equal(out.(*Intent).CreatedAt))
+	assert.Equal(t, in.Token, out.(*Intent).Token)
+}
+
+func TestPendingKeys(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	generator := NewPersistentIdempotentKeyGenerator(store, "")
+	a := &OpenAPI{IdempotentKeyGen: generator}
+
+	// the key is kept until the caller recorded the result
+	ctx, pending := WithPendingKeys(context.Background())
+	token := mustGenerateKey(t, generator, "create")
+	a.keyDone(ctx, token)
+	_, err := store.Get(token)
+	assert.NoError(t, err)
+
+	assert.NoError(t, pending.Done())
+	_, err = store.Get(token)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// removed at once without the caller
+	token = mustGenerateKey(t, generator, "create")
+	a.keyDone(context.Background(), token)
+	_, err = store.Get(token)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// the failure is not returned to the request
+	a.IdempotentKeyGen = NewPersistentIdempotentKeyGenerator(&failedStorage{Storage: storage.NewMemoryStorage()}, "")
+	a.keyDone(context.Background(), "token")
+	ctx, pending = WithPendingKeys(context.Background())
+	a.keyDone(ctx, "token")
+	assert.Error(t, pending.Done())
+}
@@ -23,7 +23,7 @@ func (a *OpenAPI) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*v
 	ctx, span := a.Tracer.Start(ctx, APIDescribeVSwitches)
 	defer span.End()
 
-	err := a.RateLimiter.Wait(ctx, APIDescribeInstanceTypes)
+	err := a.RateLimiter.Wait(ctx, APIDescribeVSwitches)
 	if err != nil {
 		return nil, err
 	}
@@ -36,6 +36,7 @@ func (a *OpenAPI) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*v
 	start := time.Now()
 	resp, err := a.ClientSet.VPC().DescribeVSwitches(req)
 	metric.OpenAPILatency.WithLabelValues(APIDescribeVSwitches, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	a.RateLimiter.Feedback(APIDescribeVSwitches, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "DescribeVSwitches failed")
@@ -4,19 +4,26 @@ package credential
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/eflo"
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
 )
 
 type Client interface {
@@ -70,11 +77,15 @@ func clientCfg() *sdk.Config {
 type ClientMgr struct {
 	regionID string
 
-	auth Interface
+	// providers are tried in order, the first one succeed is used
+	providers []Interface
+	// changed is set when the credential files are changed
+	changed atomic.Bool
 
 	// protect things below
 	sync.RWMutex
 
+	active   string
 	expireAt time.Time
 	updateAt time.Time
 
@@ -106,22 +117,88 @@ func NewClientMgr(regionID string, providers ...Interface) (*ClientMgr, error) {
 	if err != nil {
 		return nil, err
 	}
-	for _, p := range providers {
-		c, err := p.Resolve()
+	mgr.providers = providers
+	_, _, err = mgr.resolve()
+	if err != nil {
+		return nil, err
+	}
+	mgr.watchFiles()
+
+	return mgr, nil
+}
+
+// resolve return the credential of the first provider succeed, later providers are the fallback
+func (c *ClientMgr) resolve() (*Credential, Interface, error) {
+	var errs []error
+	for _, p := range c.providers {
+		cc, err := p.Resolve()
 		if err != nil {
-			return nil, err
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
 		}
-		if c == nil {
+		if cc == nil {
 			continue
 		}
-		mgr.auth = p
-		break
+		if len(errs) > 0 {
+			mgrLog.Error(errors.Join(errs...), "fallback to credential provider", "provider", p.Name())
+		}
+		return cc, p, nil
 	}
-	if mgr.auth == nil {
-		return nil, errors.New("unable to found a valid credential provider")
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("unable to found a valid credential provider, %w", errors.Join(errs...))
 	}
+	return nil, nil, errors.New("unable to found a valid credential provider")
+}
 
-	return mgr, nil
+// watchFiles reload the credential once the credential files are changed.
+// Dirs are watched, as files in secret or projected volume are replaced by swapping the symlink.
+func (c *ClientMgr) watchFiles() {
+	dirs := sets.New[string]()
+	for _, p := range c.providers {
+		fp, ok := p.(FileProvider)
+		if !ok {
+			continue
+		}
+		for _, f := range fp.Files() {
+			dirs.Insert(filepath.Dir(f))
+		}
+	}
+	if dirs.Len() == 0 {
+		return
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		mgrLog.Error(err, "failed to watch credential files")
+		return
+	}
+	for _, dir := range sets.List(dirs) {
+		err = w.Add(dir)
+		if err != nil {
+			mgrLog.Error(err, "failed to watch credential dir", "dir", dir)
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if e.Op == fsnotify.Chmod {
+					continue
+				}
+				mgrLog.Info("credential file changed", "file", e.Name, "op", e.Op.String())
+				c.changed.Store(true)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				mgrLog.Error(err, "watch credential files error")
+			}
+		}
+	}()
 }
 
 func (c *ClientMgr) VPC() *vpc.Client {
@@ -164,15 +241,19 @@ func (c *ClientMgr) EFLO() *eflo.Client {
 }
 
 func (c *ClientMgr) refreshToken() (bool, error) {
-	if c.updateAt.IsZero() || c.expireAt.Before(time.Now()) || time.Since(c.updateAt) > tokenReSyncPeriod {
+	changed := c.changed.Swap(false)
+	if changed || c.updateAt.IsZero() || c.expireAt.Before(time.Now()) || time.Since(c.updateAt) > tokenReSyncPeriod {
 		var err error
 		defer func() {
 			if err == nil {
 				c.updateAt = time.Now()
+			} else if changed {
+				// retry on next call
+				c.changed.Store(true)
 			}
 		}()
 
-		cc, err := c.auth.Resolve()
+		cc, p, err := c.resolve()
 		if err != nil {
 			return false, err
 		}
@@ -208,6 +289,18 @@ func (c *ClientMgr) refreshToken() (bool, error) {
 		}
 
 		c.expireAt = cc.Expiration
+		if c.active != p.Name() {
+			mgrLog.Info("use credential provider", "provider", p.Name(), "previous", c.active)
+			c.active = p.Name()
+		}
+		for _, pp := range c.providers {
+			v := 0.0
+			if pp.Name() == c.active {
+				v = 1
+			}
+			metric.CredentialProvider.WithLabelValues(pp.Name()).Set(v)
+		}
+		metric.CredentialExpiration.Set(float64(c.expireAt.Unix()))
 		return true, nil
 	}
 
@@ -1,8 +1,13 @@
 package credential
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +23,79 @@ func TestClientMgr_refreshToken(t *testing.T) {
 
 	assert.Equalf(t, "vpc", mgr.ecs.Network, "default endpoint should be vpc")
 }
+
+type fakeProvider struct {
+	name  string
+	cred  *Credential
+	err   error
+	files []string
+	calls int
+}
+
+func (f *fakeProvider) Resolve() (*Credential, error) {
+	f.calls++
+	return f.cred, f.err
+}
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) Files() []string {
+	return f.files
+}
+
+func TestClientMgr_Fallback(t *testing.T) {
+	broken := &fakeProvider{name: "broken", err: errors.New("broken")}
+	skipped := &fakeProvider{name: "skipped"}
+	ak := NewAKPairProvider("foo", "bar")
+
+	mgr, err := NewClientMgr("foo", broken, skipped, ak)
+	assert.NoError(t, err)
+	ok, err := mgr.refreshToken()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, ak.Name(), mgr.active)
+
+	// the preferred provider is back
+	broken.err = nil
+	broken.cred = &Credential{Credential: credentials.NewAccessKeyCredential("a", "b"), Expiration: time.Now().Add(time.Hour)}
+	mgr.updateAt = time.Time{}
+	ok, err = mgr.refreshToken()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "broken", mgr.active)
+
+	_, err = NewClientMgr("foo", &fakeProvider{name: "broken", err: errors.New("broken")}, skipped)
+	assert.ErrorContains(t, err, "broken")
+}
+
+func TestClientMgr_WatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token-config")
+	assert.NoError(t, os.WriteFile(file, []byte("foo"), 0600))
+
+	p := &fakeProvider{
+		name:  "file",
+		cred:  &Credential{Credential: credentials.NewAccessKeyCredential("a", "b"), Expiration: time.Now().Add(time.Hour)},
+		files: []string{file},
+	}
+	mgr, err := NewClientMgr("foo", p)
+	assert.NoError(t, err)
+	ok, err := mgr.refreshToken()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, _ = mgr.refreshToken()
+	assert.False(t, ok)
+
+	assert.NoError(t, os.WriteFile(file, []byte("bar"), 0600))
+	assert.Eventually(t, func() bool {
+		return mgr.changed.Load()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	calls := p.calls
+	ok, err = mgr.refreshToken()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, calls+1, p.calls)
+}
@@ -0,0 +1,145 @@
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials"
+)
+
+// env injected by the RRSA (RAM Roles for Service Accounts) webhook
+const (
+	envRoleARN         = "ALIBABA_CLOUD_ROLE_ARN"
+	envOIDCProviderARN = "ALIBABA_CLOUD_OIDC_PROVIDER_ARN"
+	envOIDCTokenFile   = "ALIBABA_CLOUD_OIDC_TOKEN_FILE"
+	envSTSEndpoint     = "STS_ENDPOINT"
+)
+
+const (
+	oidcSessionName = "terway"
+	oidcDuration    = time.Hour
+	// oidcRefreshAhead the credential is renewed before it is expired
+	oidcRefreshAhead = 10 * time.Minute
+)
+
+// OIDCProvider exchange the projected service account token for sts credential
+type OIDCProvider struct {
+	roleARN         string
+	oidcProviderARN string
+	tokenFile       string
+	endpoint        string
+
+	httpClient *http.Client
+
+	lock   sync.Mutex
+	cached *Credential
+}
+
+// NewOIDCProvider read the role from env, the provider is skipped if env is not set
+func NewOIDCProvider(regionID string) *OIDCProvider {
+	scheme := "https"
+	if os.Getenv("ALICLOUD_CLIENT_SCHEME") == "HTTP" {
+		scheme = "http"
+	}
+	endpoint := os.Getenv(envSTSEndpoint)
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("sts-vpc.%s.aliyuncs.com", regionID)
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = scheme + "://" + endpoint
+	}
+
+	return &OIDCProvider{
+		roleARN:         os.Getenv(envRoleARN),
+		oidcProviderARN: os.Getenv(envOIDCProviderARN),
+		tokenFile:       os.Getenv(envOIDCTokenFile),
+		endpoint:        endpoint,
+		httpClient:      &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+type assumeRoleWithOIDCResponse struct {
+	RequestID   string `json:"RequestId"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+	Credentials struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		AccessKeySecret string `json:"AccessKeySecret"`
+		SecurityToken   string `json:"SecurityToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+func (o *OIDCProvider) Resolve() (*Credential, error) {
+	if o.roleARN == "" || o.oidcProviderARN == "" || o.tokenFile == "" {
+		return nil, nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.cached != nil && time.Until(o.cached.Expiration) > oidcRefreshAhead {
+		return o.cached, nil
+	}
+
+	token, err := os.ReadFile(o.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oidc token %s, err: %w", o.tokenFile, err)
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithOIDC")
+	form.Set("Format", "JSON")
+	form.Set("Version", "2015-04-01")
+	form.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	form.Set("RoleArn", o.roleARN)
+	form.Set("OIDCProviderArn", o.oidcProviderARN)
+	form.Set("OIDCToken", strings.TrimSpace(string(token)))
+	form.Set("RoleSessionName", oidcSessionName)
+	form.Set("DurationSeconds", strconv.Itoa(int(oidcDuration.Seconds())))
+
+	log.Info("assume role with oidc", "role", o.roleARN)
+	resp, err := o.httpClient.PostForm(o.endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role with oidc, err: %w", err)
+	}
+	defer resp.Body.Close()
+
+	out := &assumeRoleWithOIDCResponse{}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return nil, fmt.Errorf("error decode assume role response, status %d, err: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to assume role with oidc, status %d, code %s, message %s, requestID %s", resp.StatusCode, out.Code, out.Message, out.RequestID)
+	}
+
+	t, err := time.Parse("2006-01-02T15:04:05Z", out.Credentials.Expiration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expiration time, err: %w", err)
+	}
+	o.cached = &Credential{
+		Credential: credentials.NewStsTokenCredential(out.Credentials.AccessKeyID, out.Credentials.AccessKeySecret, out.Credentials.SecurityToken),
+		Expiration: t,
+	}
+	return o.cached, nil
+}
+
+// Files the token is rotated by kubelet, a failed exchange is retried with the new token at once
+func (o *OIDCProvider) Files() []string {
+	if o.tokenFile == "" {
+		return nil
+	}
+	return []string{o.tokenFile}
+}
+
+func (o *OIDCProvider) Name() string {
+	return "OIDCProvider"
+}
@@ -0,0 +1,73 @@
+package credential
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCProvider_Resolve(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "AssumeRoleWithOIDC", r.Form.Get("Action"))
+		assert.Equal(t, "acs:ram::1:role/terway", r.Form.Get("RoleArn"))
+		assert.Equal(t, "token", r.Form.Get("OIDCToken"))
+		assert.Equal(t, "acs:ram::1:oidc-provider/ack", r.Form.Get("OIDCProviderArn"))
+		_, _ = w.Write([]byte(`{"RequestId":"2","Credentials":{"AccessKeyId":"ak","AccessKeySecret":"sk","SecurityToken":"sts","Expiration":"` +
+			time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z") + `"}}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("token\n"), 0600))
+
+	t.Setenv(envSTSEndpoint, server.URL)
+	p := NewOIDCProvider("cn-hangzhou")
+	c, err := p.Resolve()
+	assert.NoError(t, err)
+	assert.Nil(t, c, "skipped if not configured")
+
+	t.Setenv(envRoleARN, "acs:ram::1:role/terway")
+	t.Setenv(envOIDCTokenFile, tokenFile)
+	t.Setenv(envOIDCProviderARN, "acs:ram::1:oidc-provider/ack")
+	p = NewOIDCProvider("cn-hangzhou")
+	assert.Equal(t, []string{tokenFile}, p.Files())
+
+	c, err = p.Resolve()
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+	assert.True(t, c.Expiration.After(time.Now()))
+
+	// cached
+	_, err = p.Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOIDCProvider_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"Code":"AuthenticationFail.OIDCToken.Expired","Message":"expired","RequestId":"1"}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0600))
+
+	p := &OIDCProvider{
+		roleARN:         "acs:ram::1:role/terway",
+		oidcProviderARN: "acs:ram::1:oidc-provider/ack",
+		tokenFile:       tokenFile,
+		endpoint:        server.URL,
+		httpClient:      server.Client(),
+	}
+	_, err := p.Resolve()
+	assert.ErrorContains(t, err, "AuthenticationFail.OIDCToken.Expired")
+}
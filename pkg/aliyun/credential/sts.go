@@ -84,6 +84,13 @@ func (e *EncryptedCredentialProvider) Resolve() (*Credential, error) {
 	}, nil
 }
 
+func (e *EncryptedCredentialProvider) Files() []string {
+	if e.credentialPath == "" {
+		return nil
+	}
+	return []string{e.credentialPath}
+}
+
 func (e *EncryptedCredentialProvider) Name() string {
 	return "EncryptedCredentialProvider"
 }
@@ -18,3 +18,8 @@ type Interface interface {
 	Resolve() (*Credential, error)
 	Name() string
 }
+
+// FileProvider is the provider reading credential from files, the credential is reloaded once the files are changed
+type FileProvider interface {
+	Files() []string
+}
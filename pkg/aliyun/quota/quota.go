@@ -0,0 +1,221 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota share the openAPI quota of the account between the controlplane and node daemons.
+// The controlplane publish the quota for each daemon in a Lease, and daemons apply it to the rate limiter.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+)
+
+const (
+	// LeaseName is the lease holding the quota
+	LeaseName = "terway-openapi-quota"
+	// AnnotationQuota is the quota for each daemon in json, api name to requests per minute
+	AnnotationQuota = "k8s.aliyun.com/openapi-quota"
+
+	holderIdentity = "terway-controlplane"
+)
+
+// Distributor split the account quota to node daemons.
+// The controlplane keeps its configured limit, the rest is shared by all nodes equally.
+type Distributor struct {
+	client    kubernetes.Interface
+	namespace string
+	// total is the account quota, api name to requests per minute
+	total   map[string]int
+	limiter *client.RateLimiter
+	period  time.Duration
+}
+
+func NewDistributor(c kubernetes.Interface, namespace string, total map[string]int, limiter *client.RateLimiter, period time.Duration) *Distributor {
+	return &Distributor{
+		client:    c,
+		namespace: namespace,
+		total:     total,
+		limiter:   limiter,
+		period:    period,
+	}
+}
+
+// Start publish the quota until ctx is done
+func (d *Distributor) Start(ctx context.Context) error {
+	l := logf.FromContext(ctx).WithName("openapi-quota")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := d.sync(ctx)
+		if err != nil {
+			l.Error(err, "failed to publish openAPI quota")
+		}
+	}, d.period)
+	return nil
+}
+
+// NeedLeaderElection only the leader publish the quota
+func (d *Distributor) NeedLeaderElection() bool {
+	return true
+}
+
+func (d *Distributor) sync(ctx context.Context) error {
+	nodes, err := d.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(share(d.total, d.limiter, len(nodes.Items)))
+	if err != nil {
+		return err
+	}
+
+	now := metav1.NewMicroTime(time.Now())
+	duration := int32(3 * d.period / time.Second)
+	holder := holderIdentity
+
+	leases := d.client.CoordinationV1().Leases(d.namespace)
+	lease, err := leases.Get(ctx, LeaseName, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErr.IsNotFound(err) {
+			return err
+		}
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        LeaseName,
+				Namespace:   d.namespace,
+				Annotations: map[string]string{AnnotationQuota: string(raw)},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[AnnotationQuota] = string(raw)
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// share compute the quota for each daemon, at least one request per minute is given
+func share(total map[string]int, limiter *client.RateLimiter, nodes int) map[string]int {
+	if nodes < 1 {
+		nodes = 1
+	}
+	result := make(map[string]int, len(total))
+	for api, perMinute := range total {
+		left := perMinute - int(limiter.Limit(api).QPS*60)
+		n := left / nodes
+		if n < 1 {
+			n = 1
+		}
+		result[api] = n
+	}
+	return result
+}
+
+// Receiver apply the quota published by the controlplane.
+// The configured limit is restored if the quota is not renewed in time.
+type Receiver struct {
+	client    kubernetes.Interface
+	namespace string
+	limiter   *client.RateLimiter
+	period    time.Duration
+
+	applied bool
+}
+
+func NewReceiver(c kubernetes.Interface, namespace string, limiter *client.RateLimiter, period time.Duration) *Receiver {
+	return &Receiver{
+		client:    c,
+		namespace: namespace,
+		limiter:   limiter,
+		period:    period,
+	}
+}
+
+// Run sync the quota until ctx is done
+func (r *Receiver) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, r.sync, r.period)
+}
+
+func (r *Receiver) sync(ctx context.Context) {
+	l := logf.FromContext(ctx).WithName("openapi-quota")
+
+	quota, err := r.quota(ctx, time.Now())
+	if err != nil {
+		l.Error(err, "failed to get openAPI quota")
+	}
+	if quota == nil {
+		if r.applied {
+			l.Info("openAPI quota is withdrawn, use the configured limit")
+			r.limiter.ResetQuota()
+			r.applied = false
+		}
+		return
+	}
+
+	r.limiter.SetQuota(client.FromMap(quota))
+	if !r.applied {
+		l.Info("openAPI quota applied", "quota", quota)
+	}
+	r.applied = true
+}
+
+// quota return the quota in the lease, nil if the lease is not found or expired
+func (r *Receiver) quota(ctx context.Context, now time.Time) (map[string]int, error) {
+	lease, err := r.client.CoordinationV1().Leases(r.namespace).Get(ctx, LeaseName, metav1.GetOptions{})
+	if err != nil {
+		if k8sErr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return nil, nil
+	}
+	expireAt := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	if now.After(expireAt) {
+		return nil, nil
+	}
+
+	raw, ok := lease.Annotations[AnnotationQuota]
+	if !ok {
+		return nil, nil
+	}
+	quota := make(map[string]int)
+	err = json.Unmarshal([]byte(raw), &quota)
+	if err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
@@ -0,0 +1,51 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+)
+
+func TestDistributorAndReceiver(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	)
+
+	controlplane := client.NewRateLimiter(client.FromMap(map[string]int{"AttachNetworkInterface": 60}))
+	d := NewDistributor(cs, "kube-system", map[string]int{"AttachNetworkInterface": 1060, "DetachNetworkInterface": 10}, controlplane, time.Minute)
+	assert.NoError(t, d.sync(ctx))
+	// update the existed lease
+	assert.NoError(t, d.sync(ctx))
+
+	daemon := client.NewRateLimiter(nil)
+	r := NewReceiver(cs, "kube-system", daemon, time.Minute)
+
+	quota, err := r.quota(ctx, time.Now())
+	assert.NoError(t, err)
+	// (1060 - 60) / 2 nodes, and at least 1 is given
+	assert.Equal(t, map[string]int{"AttachNetworkInterface": 500, "DetachNetworkInterface": 1}, quota)
+
+	r.sync(ctx)
+	assert.True(t, r.applied)
+
+	// lease expired
+	quota, err = r.quota(ctx, time.Now().Add(4*time.Minute))
+	assert.NoError(t, err)
+	assert.Nil(t, quota)
+}
+
+func TestReceiver_NoLease(t *testing.T) {
+	r := NewReceiver(fake.NewSimpleClientset(), "kube-system", client.NewRateLimiter(nil), time.Minute)
+	r.applied = true
+	r.sync(context.Background())
+	assert.False(t, r.applied)
+}
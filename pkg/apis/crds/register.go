@@ -56,7 +56,7 @@ func getCRD(name string) apiextensionsv1.CustomResourceDefinition {
 		version = "v0.2.0"
 	case CRDNodeRuntime:
 		crdBytes = crdsNodeRuntime
-		version = "v0.1.0"
+		version = "v0.2.0"
 	default:
 		panic(fmt.Sprintf("crd %s name not exist", name))
 	}
@@ -33,9 +33,20 @@ type RuntimePodStatus struct {
 type NodeRuntimeSpec struct {
 }
 
+// RuntimeNetworkInterface is the eni seen by the daemon on the node
+type RuntimeNetworkInterface struct {
+	IPv4 []string `json:"ipv4,omitempty"`
+	IPv6 []string `json:"ipv6,omitempty"`
+}
+
 type NodeRuntimeStatus struct {
 	// runtime status, indexed by pod uid
 	Pods map[string]*RuntimePodStatus `json:"pods,omitempty"`
+
+	// enis attached to the instance read from the instance metadata, indexed by eni id. The primary eni is excluded.
+	NetworkInterfaces map[string]*RuntimeNetworkInterface `json:"networkInterfaces,omitempty"`
+	// the time network interfaces are reported
+	NetworkInterfacesUpdateTime *metav1.Time `json:"networkInterfacesUpdateTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -154,6 +154,9 @@ type NetworkInterface struct {
 	IPv6CIDR string `json:"ipv6CIDR"`
 
 	Conditions map[string]Condition `json:"conditions,omitempty"`
+
+	// CustomSecurityGroupIDs is set when the eni is dedicated to pods requesting their own security groups
+	CustomSecurityGroupIDs []string `json:"customSecurityGroupIDs,omitempty"`
 }
 
 type Condition struct {
@@ -67,6 +67,8 @@ type PodENIStatus struct {
 	PodLastSeen metav1.Time `json:"podLastSeen,omitempty"`
 	// ENIInfos is the status after eni is attached, it is indexed by eni id
 	ENIInfos map[string]ENIInfo `json:"eniInfos,omitempty"`
+	// Conditions records the progress of in-place changes on the eni, it is indexed by condition type
+	Conditions map[string]Condition `json:"conditions,omitempty"`
 }
 
 // Allocation for eni record
@@ -80,11 +82,29 @@ type Allocation struct {
 	Interface      string            `json:"interface,omitempty"`
 	DefaultRoute   bool              `json:"defaultRoute,omitempty"`
 	ExtraRoutes    []Route           `json:"extraRoutes,omitempty"`
+	ExtraRules     []Rule            `json:"extraRules,omitempty"`
 	ExtraConfig    map[string]string `json:"extraConfig,omitempty"`
 }
 
 type Route struct {
 	Dst string `json:"dst,omitempty"`
+	// Gateway is the next hop, default to the gateway of the interface
+	Gateway string `json:"gateway,omitempty"`
+	Metric  int    `json:"metric,omitempty"`
+	// Table is the route table, default to main table
+	Table int `json:"table,omitempty"`
+	// Src is the source address hint
+	Src string `json:"src,omitempty"`
+	MTU int    `json:"mtu,omitempty"`
+}
+
+// Rule is the policy routing rule in the pod net ns
+type Rule struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	FwMark   uint32 `json:"fwmark,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Table    int    `json:"table,omitempty"`
 }
 
 // ENI eni info
@@ -96,6 +116,8 @@ type ENI struct {
 	ResourceGroupID   string            `json:"resourceGroupID,omitempty"`
 	SecurityGroupIDs  []string          `json:"securityGroupIDs,omitempty"`
 	AttachmentOptions AttachmentOptions `json:"attachmentOptions,omitempty"`
+
+	NetworkInterfaceTrafficMode NetworkInterfaceTrafficMode `json:"networkInterfaceTrafficMode,omitempty"`
 }
 
 type AttachmentOptions struct {
@@ -165,11 +187,32 @@ const (
 	ENIStatusDeleted ENIBindStatus = "Deleted"
 )
 
+// ENIStep is the last step finished (or in progress) for the eni, used to resume the operation after restart
+type ENIStep string
+
+const (
+	// ENIStepCreated eni is created
+	ENIStepCreated ENIStep = "Created"
+	// ENIStepAttaching attach is requested, waiting eni to be InUse
+	ENIStepAttaching ENIStep = "Attaching"
+	// ENIStepAttached eni is InUse
+	ENIStepAttached ENIStep = "Attached"
+	// ENIStepDetaching detach is requested, waiting eni to be Available
+	ENIStepDetaching ENIStep = "Detaching"
+	// ENIStepDetached eni is Available
+	ENIStepDetached ENIStep = "Detached"
+)
+
 type ENIInfo struct {
 	ID     string        `json:"id,omitempty"`
 	Type   ENIType       `json:"type,omitempty"`
 	Vid    int           `json:"vid,omitempty"`    // vlan id for trunk
 	Status ENIBindStatus `json:"status,omitempty"` // the status for operate the eni
+
+	// Step is the progress of the attach/detach
+	Step ENIStep `json:"step,omitempty"`
+	// ClientToken is the idempotency token used to create the eni
+	ClientToken string `json:"clientToken,omitempty"`
 }
 
 // ENIType for this eni, only Secondary and Member is supported
@@ -245,6 +288,36 @@ type PodNetworkingSpec struct {
 	VSwitchOptions   []string `json:"vSwitchOptions,omitempty"`
 	// +kubebuilder:default={ "vSwitchSelectionPolicy": "ordered" }
 	VSwitchSelectOptions VSwitchSelectOptions `json:"vSwitchSelectOptions,omitempty"`
+
+	// ReservedIPs is the ipv4 pool for pods using this podNetworking, the eni primary ip is taken from it.
+	// Each item can be an ip, a cidr or an ip range like "192.168.0.10-192.168.0.20".
+	ReservedIPs []string `json:"reservedIPs,omitempty"`
+
+	// ExtraRoutes are added in the pod net ns, for the interface of this podNetworking
+	ExtraRoutes []Route `json:"extraRoutes,omitempty"`
+	// ExtraRules are the policy routing rules added in the pod net ns
+	ExtraRules []Rule `json:"extraRules,omitempty"`
+
+	// EnableNAT64 translate the traffic of ipv6 only pods to the nat64 prefix, so ipv4 only services are reachable.
+	// The node should have nat64 configured.
+	EnableNAT64 bool `json:"enableNAT64,omitempty"`
+
+	// EgressGateway SNAT the egress traffic of pods to a stable ip reserved on the node, only for the shared eni.
+	EgressGateway *EgressGateway `json:"egressGateway,omitempty"`
+}
+
+// EgressGateway the ip is reserved from the eni pool of each node, pods with the same name share the ip
+type EgressGateway struct {
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// DestinationCIDRs only the traffic to the ipv4 cidrs is SNATed
+	// +kubebuilder:validation:MinItems=1
+	DestinationCIDRs []string `json:"destinationCIDRs"`
+	// VSwitchID the ip is reserved on the eni in the vSwitch, so the source ip is in the cidr of the vSwitch. Any eni is used if empty
+	// +kubebuilder:validation:Pattern=`^vsw-`
+	// +optional
+	VSwitchID string `json:"vSwitchID,omitempty"`
 }
 
 // PodNetworkingStatus defines the observed state of PodNetworking
@@ -257,6 +330,16 @@ type PodNetworkingStatus struct {
 	UpdateAt metav1.Time `json:"updateAt,omitempty"`
 	// Message for the status
 	Message string `json:"message,omitempty"`
+	// ReservedIPs is the usage of the reserved ip pool
+	ReservedIPs *ReservedIPsStatus `json:"reservedIPs,omitempty"`
+}
+
+// ReservedIPsStatus is the usage of the reserved ip pool
+type ReservedIPsStatus struct {
+	// Total is the count of ip in the pool
+	Total int `json:"total"`
+	// InUse is the ip used by pods, the key is the ip and value is the podENI in namespace/name form
+	InUse map[string]string `json:"inUse,omitempty"`
 }
 
 // VSwitch VSwitch info
@@ -286,7 +369,7 @@ type ENIOptions struct {
 }
 
 // ENIAttachType
-// +kubebuilder:validation:Enum=Trunk;ENI;Default
+// +kubebuilder:validation:Enum=Trunk;ENI;Default;Shared
 type ENIAttachType string
 
 const (
@@ -296,6 +379,8 @@ const (
 	ENIOptionTypeENI ENIAttachType = "ENI"
 	// ENIOptionTypeTrunk use trunk eni
 	ENIOptionTypeTrunk ENIAttachType = "Trunk"
+	// ENIOptionTypeShared use ip from the shared eni, only security groups is honored
+	ENIOptionTypeShared ENIAttachType = "Shared"
 )
 
 type VSwitchSelectOptions struct {
@@ -35,6 +35,11 @@ func (in *Allocation) DeepCopyInto(out *Allocation) {
 		*out = make([]Route, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExtraRules != nil {
+		in, out := &in.ExtraRules, &out.ExtraRules
+		*out = make([]Rule, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraConfig != nil {
 		in, out := &in.ExtraConfig, &out.ExtraConfig
 		*out = make(map[string]string, len(*in))
@@ -211,6 +216,26 @@ func (in *ENISpec) DeepCopy() *ENISpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressGateway) DeepCopyInto(out *EgressGateway) {
+	*out = *in
+	if in.DestinationCIDRs != nil {
+		in, out := &in.DestinationCIDRs, &out.DestinationCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressGateway.
+func (in *EgressGateway) DeepCopy() *EgressGateway {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressGateway)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Flavor) DeepCopyInto(out *Flavor) {
 	*out = *in
@@ -318,6 +343,11 @@ func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.CustomSecurityGroupIDs != nil {
+		in, out := &in.CustomSecurityGroupIDs, &out.CustomSecurityGroupIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
@@ -512,6 +542,26 @@ func (in *NodeRuntimeStatus) DeepCopyInto(out *NodeRuntimeStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make(map[string]*RuntimeNetworkInterface, len(*in))
+		for key, val := range *in {
+			var outVal *RuntimeNetworkInterface
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = new(RuntimeNetworkInterface)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.NetworkInterfacesUpdateTime != nil {
+		in, out := &in.NetworkInterfacesUpdateTime, &out.NetworkInterfacesUpdateTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeRuntimeStatus.
@@ -681,6 +731,13 @@ func (in *PodENIStatus) DeepCopyInto(out *PodENIStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(map[string]Condition, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodENIStatus.
@@ -769,6 +826,26 @@ func (in *PodNetworkingSpec) DeepCopyInto(out *PodNetworkingSpec) {
 		copy(*out, *in)
 	}
 	out.VSwitchSelectOptions = in.VSwitchSelectOptions
+	if in.ReservedIPs != nil {
+		in, out := &in.ReservedIPs, &out.ReservedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraRoutes != nil {
+		in, out := &in.ExtraRoutes, &out.ExtraRoutes
+		*out = make([]Route, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraRules != nil {
+		in, out := &in.ExtraRules, &out.ExtraRules
+		*out = make([]Rule, len(*in))
+		copy(*out, *in)
+	}
+	if in.EgressGateway != nil {
+		in, out := &in.EgressGateway, &out.EgressGateway
+		*out = new(EgressGateway)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkingSpec.
@@ -790,6 +867,11 @@ func (in *PodNetworkingStatus) DeepCopyInto(out *PodNetworkingStatus) {
 		copy(*out, *in)
 	}
 	in.UpdateAt.DeepCopyInto(&out.UpdateAt)
+	if in.ReservedIPs != nil {
+		in, out := &in.ReservedIPs, &out.ReservedIPs
+		*out = new(ReservedIPsStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkingStatus.
@@ -817,6 +899,28 @@ func (in *PoolSpec) DeepCopy() *PoolSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPsStatus) DeepCopyInto(out *ReservedIPsStatus) {
+	*out = *in
+	if in.InUse != nil {
+		in, out := &in.InUse, &out.InUse
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPsStatus.
+func (in *ReservedIPsStatus) DeepCopy() *ReservedIPsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -832,6 +936,21 @@ func (in *Route) DeepCopy() *Route {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rule) DeepCopyInto(out *Rule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rule.
+func (in *Rule) DeepCopy() *Rule {
+	if in == nil {
+		return nil
+	}
+	out := new(Rule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimePodSpec) DeepCopyInto(out *RuntimePodSpec) {
 	*out = *in
@@ -847,6 +966,31 @@ func (in *RuntimePodSpec) DeepCopy() *RuntimePodSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeNetworkInterface) DeepCopyInto(out *RuntimeNetworkInterface) {
+	*out = *in
+	if in.IPv4 != nil {
+		in, out := &in.IPv4, &out.IPv4
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPv6 != nil {
+		in, out := &in.IPv6, &out.IPv6
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeNetworkInterface.
+func (in *RuntimeNetworkInterface) DeepCopy() *RuntimeNetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeNetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimePodStatus) DeepCopyInto(out *RuntimePodStatus) {
 	*out = *in
@@ -23,6 +23,7 @@ import (
 	_ "github.com/AliyunContainerService/terway/pkg/controller/multi-ip/node"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/multi-ip/pod"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/node"
+	_ "github.com/AliyunContainerService/terway/pkg/controller/orphan-eni"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod-eni"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod-networking"
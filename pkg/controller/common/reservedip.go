@@ -0,0 +1,86 @@
+package common
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// MaxReservedIPs is the max ip count of a reserved ip pool
+const MaxReservedIPs = 65536
+
+// ParseReservedIPs parse the ip, cidr or ip range ("start-end") list into sorted ipv4 addresses
+func ParseReservedIPs(items []string) ([]netip.Addr, error) {
+	set := make(map[netip.Addr]struct{})
+	add := func(start, end netip.Addr) error {
+		for ip := start; ip.IsValid() && ip.Compare(end) <= 0; ip = ip.Next() {
+			set[ip] = struct{}{}
+			if len(set) > MaxReservedIPs {
+				return fmt.Errorf("reserved ip count exceeded %d", MaxReservedIPs)
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+
+		var start, end netip.Addr
+		switch {
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %s, %w", item, err)
+			}
+			prefix = prefix.Masked()
+			start = prefix.Addr()
+			end = lastIP(prefix)
+		case strings.Contains(item, "-"):
+			parts := strings.SplitN(item, "-", 2)
+			var err error
+			start, err = netip.ParseAddr(strings.TrimSpace(parts[0]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip range %s, %w", item, err)
+			}
+			end, err = netip.ParseAddr(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip range %s, %w", item, err)
+			}
+			if end.Less(start) {
+				return nil, fmt.Errorf("invalid ip range %s, end is less than start", item)
+			}
+		default:
+			ip, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip %s, %w", item, err)
+			}
+			start, end = ip, ip
+		}
+		if !start.Is4() || !end.Is4() {
+			return nil, fmt.Errorf("only ipv4 is supported, %s", item)
+		}
+
+		err := add(start, end)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]netip.Addr, 0, len(set))
+	for ip := range set {
+		result = append(result, ip)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Less(result[j])
+	})
+	return result, nil
+}
+
+func lastIP(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().As4()
+	for i := prefix.Bits(); i < 32; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	return netip.AddrFrom4(b)
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReservedIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "ip, cidr and range",
+			items: []string{"192.168.0.10", "192.168.1.0/30", "192.168.0.8-192.168.0.10"},
+			want:  []string{"192.168.0.8", "192.168.0.9", "192.168.0.10", "192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"},
+		},
+		{
+			name:    "invalid range",
+			items:   []string{"192.168.0.10-192.168.0.8"},
+			wantErr: true,
+		},
+		{
+			name:    "ipv6",
+			items:   []string{"fd00::1"},
+			wantErr: true,
+		},
+		{
+			name:    "too many",
+			items:   []string{"10.0.0.0/8"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReservedIPs(tt.items)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var want []netip.Addr
+			for _, ip := range tt.want {
+				want = append(want, netip.MustParseAddr(ip))
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
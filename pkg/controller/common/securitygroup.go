@@ -0,0 +1,11 @@
+package common
+
+import (
+	"github.com/samber/lo"
+)
+
+// SecurityGroupsEqual compare two security group lists, the order is ignored
+func SecurityGroupsEqual(a, b []string) bool {
+	left, right := lo.Difference(a, b)
+	return len(left) == 0 && len(right) == 0
+}
@@ -0,0 +1,25 @@
+package enipool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// PoolRequestTotal the request to the member eni pool, result is hit or miss
+	PoolRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "member_eni_pool_request_total",
+			Help: "controlplane member eni pool request total",
+		},
+		[]string{"vsw", "security_groups", "resource_group", "ipv6_count", "erdma", "result"},
+	)
+
+	// PoolIdleCount the idle eni count in the member eni pool
+	PoolIdleCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "member_eni_pool_idle_count",
+			Help: "controlplane member eni pool idle count",
+		},
+		[]string{"vsw", "security_groups", "resource_group", "ipv6_count", "erdma"},
+	)
+)
@@ -0,0 +1,396 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package enipool keeps pre-created member enis for trunk pods, so the eni creation is out of the pod startup path.
+package enipool
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/backoff"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// TagENIPool is set on enis created by the pool
+const TagENIPool = "terway-eni-pool"
+
+// componentENIPool is the TagTerwayComponent of enis created by the pool
+const componentENIPool = "eni-pool"
+
+// demandGracePeriod keep the demand after the last request, before the podENI is created
+const demandGracePeriod = 10 * time.Minute
+
+// API is the openAPI used by the pool
+type API interface {
+	CreateNetworkInterface(ctx context.Context, opts ...aliyunClient.CreateNetworkInterfaceOption) (*aliyunClient.NetworkInterface, error)
+	DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*aliyunClient.NetworkInterface, error)
+	DeleteNetworkInterface(ctx context.Context, eniID string) error
+}
+
+// Request describe the eni wanted, enis are only shared by the requests with the same spec
+type Request struct {
+	VSwitchID        string
+	SecurityGroupIDs []string
+	ResourceGroupID  string
+	IPv6Count        int
+	ERDMA            bool
+}
+
+func (r *Request) key() string {
+	return strings.Join(r.labels(), "/")
+}
+
+// labels is the label values of the metrics
+func (r *Request) labels() []string {
+	sgs := append([]string{}, r.SecurityGroupIDs...)
+	sort.Strings(sgs)
+	return []string{r.VSwitchID, strings.Join(sgs, ","), r.ResourceGroupID, strconv.Itoa(r.IPv6Count), strconv.FormatBool(r.ERDMA)}
+}
+
+// requestOf return the request the eni satisfied
+func requestOf(eni *aliyunClient.NetworkInterface) Request {
+	return Request{
+		VSwitchID:        eni.VSwitchID,
+		SecurityGroupIDs: eni.SecurityGroupIDs,
+		ResourceGroupID:  eni.ResourceGroupID,
+		IPv6Count:        len(eni.IPv6Set),
+		ERDMA:            eni.NetworkInterfaceTrafficMode == aliyunClient.ENITrafficModeRDMA,
+	}
+}
+
+// demand is the pool wanted, it is dropped if no pod is using the spec
+type demand struct {
+	req Request
+	// lastRequested is the time of the last Acquire
+	lastRequested time.Time
+}
+
+type Options struct {
+	VPCID     string
+	ClusterID string
+
+	// MinSize is the idle eni count kept for each eni spec
+	MinSize int
+	// MaxSize is the max idle eni count for each eni spec
+	MaxSize int
+}
+
+// Pool is a per eni spec (vSwitch, security groups, resource group and options) pool of unattached member enis.
+// Pools are created on demand, after the first request for the spec, and dropped after the pods using the spec are gone.
+type Pool struct {
+	api    API
+	client client.Client
+	opts   Options
+
+	lock sync.Mutex
+	// synced is set after the pool is recovered from the openAPI
+	synced  bool
+	idles   map[string][]*aliyunClient.NetworkInterface
+	demands map[string]*demand
+	// owned is the eni held by the pool
+	owned sets.Set[string]
+
+	notify chan struct{}
+}
+
+func NewPool(api API, c client.Client, opts Options) *Pool {
+	return &Pool{
+		api:     api,
+		client:  c,
+		opts:    opts,
+		idles:   make(map[string][]*aliyunClient.NetworkInterface),
+		demands: make(map[string]*demand),
+		owned:   sets.New[string](),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Acquire take an eni from the pool, nil is returned if the pool is empty.
+// The request is recorded, so the pool will be filled for latter use.
+func (p *Pool) Acquire(req Request) *aliyunClient.NetworkInterface {
+	key := req.key()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	d, ok := p.demands[key]
+	if !ok {
+		d = &demand{req: req}
+		p.demands[key] = d
+	}
+	d.lastRequested = time.Now()
+	defer p.trigger()
+
+	idles := p.idles[key]
+	if len(idles) == 0 {
+		PoolRequestTotal.WithLabelValues(append(req.labels(), "miss")...).Inc()
+		return nil
+	}
+	eni := idles[0]
+	p.idles[key] = idles[1:]
+	p.owned.Delete(eni.NetworkInterfaceID)
+
+	PoolRequestTotal.WithLabelValues(append(req.labels(), "hit")...).Inc()
+	PoolIdleCount.WithLabelValues(req.labels()...).Set(float64(len(p.idles[key])))
+	return eni
+}
+
+// Owns return true if the eni should be left to the pool.
+// Before the pool is synced, all enis created by the pool is considered as owned.
+func (p *Pool) Owns(eni *aliyunClient.NetworkInterface) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.owned.Has(eni.NetworkInterfaceID) {
+		return true
+	}
+	if p.synced {
+		return false
+	}
+	for _, tag := range eni.Tags {
+		if tag.TagKey == TagENIPool {
+			return true
+		}
+	}
+	return false
+}
+
+// Run recover the pool and keep it between min and max size, until ctx is done
+func (p *Pool) Run(ctx context.Context, period time.Duration) {
+	l := logf.FromContext(ctx).WithName("eni-pool")
+
+	err := wait.PollUntilContextCancel(ctx, period, true, func(ctx context.Context) (bool, error) {
+		err := p.recover(ctx)
+		if err != nil {
+			l.Error(err, "failed to recover eni pool")
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		p.sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.notify:
+		}
+	}
+}
+
+// recover adopt the enis created by the pool, which is not used by any pod
+func (p *Pool) recover(ctx context.Context) error {
+	enis, err := p.api.DescribeNetworkInterface(ctx, p.opts.VPCID, nil, "", aliyunClient.ENITypeSecondary, aliyunClient.ENIStatusAvailable, p.tags())
+	if err != nil {
+		return err
+	}
+
+	podENIs := &v1beta1.PodENIList{}
+	err = p.client.List(ctx, podENIs)
+	if err != nil {
+		return err
+	}
+	inUse := sets.New[string]()
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			inUse.Insert(alloc.ENI.ID)
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, eni := range enis {
+		if inUse.Has(eni.NetworkInterfaceID) || p.owned.Has(eni.NetworkInterfaceID) {
+			continue
+		}
+		req := requestOf(eni)
+		key := req.key()
+		if _, ok := p.demands[key]; !ok {
+			// dropped after the grace period, if no pod is using it
+			p.demands[key] = &demand{req: req, lastRequested: time.Now()}
+		}
+		p.idles[key] = append(p.idles[key], eni)
+		p.owned.Insert(eni.NetworkInterfaceID)
+	}
+	p.synced = true
+
+	logf.FromContext(ctx).Info("eni pool recovered", "count", p.owned.Len())
+	return nil
+}
+
+// sync create or delete eni for each pool
+func (p *Pool) sync(ctx context.Context) {
+	l := logf.FromContext(ctx).WithName("eni-pool")
+
+	err := p.shrink(ctx)
+	if err != nil {
+		l.Error(err, "failed to shrink eni pool")
+	}
+
+	p.lock.Lock()
+	demands := make(map[string]Request, len(p.demands))
+	for k, v := range p.demands {
+		demands[k] = v.req
+	}
+	p.lock.Unlock()
+
+	for key, req := range demands {
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.lock.Lock()
+		idle := len(p.idles[key])
+		var toDel []*aliyunClient.NetworkInterface
+		if idle > p.opts.MaxSize {
+			toDel = append(toDel, p.idles[key][p.opts.MaxSize:]...)
+			p.idles[key] = p.idles[key][:p.opts.MaxSize]
+			for _, eni := range toDel {
+				p.owned.Delete(eni.NetworkInterfaceID)
+			}
+		}
+		p.lock.Unlock()
+
+		for _, eni := range toDel {
+			err := p.api.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+			if err != nil {
+				// leave it to the gc
+				l.Error(err, "failed to delete eni", "eni", eni.NetworkInterfaceID)
+			}
+		}
+
+		for i := idle; i < p.opts.MinSize; i++ {
+			eni, err := p.create(ctx, req)
+			if err != nil {
+				l.Error(err, "failed to create eni", "vsw", req.VSwitchID)
+				break
+			}
+
+			p.lock.Lock()
+			p.idles[key] = append(p.idles[key], eni)
+			p.owned.Insert(eni.NetworkInterfaceID)
+			p.lock.Unlock()
+		}
+
+		p.lock.Lock()
+		PoolIdleCount.WithLabelValues(req.labels()...).Set(float64(len(p.idles[key])))
+		p.lock.Unlock()
+	}
+}
+
+// shrink drop the demand no pod is using, and release the idle enis of it
+func (p *Pool) shrink(ctx context.Context) error {
+	podENIs := &v1beta1.PodENIList{}
+	err := p.client.List(ctx, podENIs)
+	if err != nil {
+		return err
+	}
+	inUse := sets.New[string]()
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			req := Request{
+				VSwitchID:        alloc.ENI.VSwitchID,
+				SecurityGroupIDs: alloc.ENI.SecurityGroupIDs,
+				ResourceGroupID:  alloc.ENI.ResourceGroupID,
+				ERDMA:            alloc.ENI.NetworkInterfaceTrafficMode == v1beta1.NetworkInterfaceTrafficModeHighPerformance,
+			}
+			if alloc.IPv6 != "" {
+				req.IPv6Count = 1
+			}
+			inUse.Insert(req.key())
+		}
+	}
+
+	p.lock.Lock()
+	var toDel []*aliyunClient.NetworkInterface
+	for key, d := range p.demands {
+		if inUse.Has(key) || time.Since(d.lastRequested) < demandGracePeriod {
+			continue
+		}
+		for _, eni := range p.idles[key] {
+			p.owned.Delete(eni.NetworkInterfaceID)
+		}
+		toDel = append(toDel, p.idles[key]...)
+		delete(p.idles, key)
+		delete(p.demands, key)
+		PoolIdleCount.DeleteLabelValues(d.req.labels()...)
+	}
+	p.lock.Unlock()
+
+	l := logf.FromContext(ctx).WithName("eni-pool")
+	for _, eni := range toDel {
+		err = p.api.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+		if err != nil {
+			// leave it to the gc
+			l.Error(err, "failed to delete eni", "eni", eni.NetworkInterfaceID)
+		}
+	}
+	return nil
+}
+
+func (p *Pool) create(ctx context.Context, req Request) (*aliyunClient.NetworkInterface, error) {
+	deleteENIOnECSRelease := true
+	bo := backoff.Backoff(backoff.ENICreate)
+	return p.api.CreateNetworkInterface(ctx, &aliyunClient.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &aliyunClient.NetworkInterfaceOptions{
+			VSwitchID:             req.VSwitchID,
+			SecurityGroupIDs:      req.SecurityGroupIDs,
+			ResourceGroupID:       req.ResourceGroupID,
+			ERDMA:                 req.ERDMA,
+			IPCount:               1,
+			IPv6Count:             req.IPv6Count,
+			Tags:                  p.tags(),
+			DeleteENIOnECSRelease: &deleteENIOnECSRelease,
+			Owner:                 &aliyunClient.ENIOwner{ClusterID: p.opts.ClusterID, Component: componentENIPool},
+		},
+		Backoff: &bo,
+	})
+}
+
+func (p *Pool) tags() map[string]string {
+	return map[string]string{
+		types.TagKeyClusterID:               p.opts.ClusterID,
+		types.NetworkInterfaceTagCreatorKey: types.TagTerwayController,
+		TagENIPool:                          "true",
+	}
+}
+
+func (p *Pool) trigger() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,225 @@
+package enipool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+	return scheme
+}
+
+func TestPool_AcquireAndSync(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	// the eni is created with the spec requested and owned by the pool
+	openAPI.On("CreateNetworkInterface", mock.Anything, mock.MatchedBy(func(opts *aliyunClient.CreateNetworkInterfaceOptions) bool {
+		o := opts.NetworkInterfaceOptions
+		return o.VSwitchID == "vsw-1" && o.ERDMA && o.IPv6Count == 1 &&
+			o.Owner != nil && *o.Owner == aliyunClient.ENIOwner{ClusterID: "c1", Component: componentENIPool}
+	})).Return(&aliyunClient.NetworkInterface{
+		NetworkInterfaceID: "eni-1",
+		VSwitchID:          "vsw-1",
+		SecurityGroupIDs:   []string{"sg-1"},
+	}, nil).Once()
+
+	p := NewPool(openAPI, fake.NewClientBuilder().WithScheme(newScheme()).Build(), Options{ClusterID: "c1", MinSize: 1, MaxSize: 2})
+	p.synced = true
+
+	req := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, IPv6Count: 1, ERDMA: true}
+
+	// first request is a miss, and the demand is recorded
+	assert.Nil(t, p.Acquire(req))
+
+	p.sync(context.Background())
+	assert.True(t, p.Owns(&aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-1"}))
+
+	eni := p.Acquire(req)
+	assert.NotNil(t, eni)
+	assert.Equal(t, "eni-1", eni.NetworkInterfaceID)
+	assert.False(t, p.Owns(eni))
+}
+
+func TestPool_SyncDeleteExceeded(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DeleteNetworkInterface", mock.Anything, "eni-3").Return(nil).Once()
+
+	p := NewPool(openAPI, fake.NewClientBuilder().WithScheme(newScheme()).Build(), Options{MinSize: 1, MaxSize: 2})
+	p.synced = true
+
+	req := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}
+	key := req.key()
+	p.demands[key] = &demand{req: req, lastRequested: time.Now()}
+	for _, id := range []string{"eni-1", "eni-2", "eni-3"} {
+		p.idles[key] = append(p.idles[key], &aliyunClient.NetworkInterface{NetworkInterfaceID: id})
+		p.owned.Insert(id)
+	}
+
+	p.sync(context.Background())
+
+	assert.Len(t, p.idles[key], 2)
+	assert.False(t, p.owned.Has("eni-3"))
+}
+
+func TestPool_Recover(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DescribeNetworkInterface", mock.Anything, "vpc-1", mock.Anything, "", aliyunClient.ENITypeSecondary, aliyunClient.ENIStatusAvailable, mock.Anything).Return([]*aliyunClient.NetworkInterface{
+		{
+			NetworkInterfaceID: "eni-1",
+			VSwitchID:          "vsw-1",
+			SecurityGroupIDs:   []string{"sg-2", "sg-1"},
+		},
+		{
+			NetworkInterfaceID: "eni-2",
+			VSwitchID:          "vsw-1",
+			SecurityGroupIDs:   []string{"sg-1"},
+		},
+	}, nil).Once()
+
+	podENI := &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1beta1.PodENISpec{
+			Allocations: []v1beta1.Allocation{
+				{ENI: v1beta1.ENI{ID: "eni-2"}},
+			},
+		},
+	}
+
+	p := NewPool(openAPI, fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(podENI).Build(), Options{VPCID: "vpc-1", MinSize: 0, MaxSize: 2})
+
+	tagged := &aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-2", Tags: []ecs.Tag{{TagKey: TagENIPool, TagValue: "true"}}}
+	// keep all tagged eni before recovered
+	assert.True(t, p.Owns(tagged))
+
+	err := p.recover(context.Background())
+	assert.NoError(t, err)
+
+	assert.False(t, p.Owns(tagged))
+	eni := p.Acquire(Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1", "sg-2"}})
+	assert.NotNil(t, eni)
+	assert.Equal(t, "eni-1", eni.NetworkInterfaceID)
+}
+
+func TestPool_KeyBySpec(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(func(ctx context.Context, opts ...aliyunClient.CreateNetworkInterfaceOption) (*aliyunClient.NetworkInterface, error) {
+		option := &aliyunClient.CreateNetworkInterfaceOptions{}
+		for _, opt := range opts {
+			opt.ApplyCreateNetworkInterface(option)
+		}
+		eni := &aliyunClient.NetworkInterface{
+			NetworkInterfaceID: "eni-v4",
+			VSwitchID:          option.NetworkInterfaceOptions.VSwitchID,
+		}
+		if option.NetworkInterfaceOptions.IPv6Count > 0 {
+			eni.NetworkInterfaceID = "eni-v6"
+		}
+		if option.NetworkInterfaceOptions.ERDMA {
+			eni.NetworkInterfaceID = "eni-erdma"
+		}
+		return eni, nil
+	}).Times(3)
+
+	p := NewPool(openAPI, fake.NewClientBuilder().WithScheme(newScheme()).Build(), Options{MinSize: 1, MaxSize: 1})
+	p.synced = true
+
+	v4 := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}
+	v6 := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, IPv6Count: 1}
+	erdma := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, ERDMA: true}
+	assert.Nil(t, p.Acquire(v4))
+	assert.Nil(t, p.Acquire(v6))
+	assert.Nil(t, p.Acquire(erdma))
+
+	p.sync(context.Background())
+
+	// the eni is only given to the request with the same options
+	eni := p.Acquire(v6)
+	if assert.NotNil(t, eni) {
+		assert.Equal(t, "eni-v6", eni.NetworkInterfaceID)
+	}
+	eni = p.Acquire(erdma)
+	if assert.NotNil(t, eni) {
+		assert.Equal(t, "eni-erdma", eni.NetworkInterfaceID)
+	}
+	assert.Nil(t, p.Acquire(v6))
+
+	// the security groups or resource group differ
+	assert.Nil(t, p.Acquire(Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-2"}}))
+	assert.Nil(t, p.Acquire(Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, ResourceGroupID: "rg-1"}))
+
+	eni = p.Acquire(v4)
+	if assert.NotNil(t, eni) {
+		assert.Equal(t, "eni-v4", eni.NetworkInterfaceID)
+	}
+}
+
+func TestPool_Shrink(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DeleteNetworkInterface", mock.Anything, "eni-2").Return(nil).Once()
+	openAPI.On("DeleteNetworkInterface", mock.Anything, "eni-5").Return(nil).Once()
+
+	podENI := &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1beta1.PodENISpec{
+			Allocations: []v1beta1.Allocation{
+				{ENI: v1beta1.ENI{ID: "eni-0", VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}},
+				{ENI: v1beta1.ENI{ID: "eni-00", VSwitchID: "vsw-4", SecurityGroupIDs: []string{"sg-1"},
+					NetworkInterfaceTrafficMode: v1beta1.NetworkInterfaceTrafficModeHighPerformance}},
+			},
+		},
+	}
+	p := NewPool(openAPI, fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(podENI).Build(), Options{MinSize: 1, MaxSize: 1})
+	p.synced = true
+
+	// used by the pod
+	used := Request{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}
+	// the pod is gone
+	gone := Request{VSwitchID: "vsw-2", SecurityGroupIDs: []string{"sg-1"}}
+	// requested recently, the podENI is not created yet
+	pending := Request{VSwitchID: "vsw-3", SecurityGroupIDs: []string{"sg-1"}}
+	// used by the pod with the erdma eni only
+	usedERDMA := Request{VSwitchID: "vsw-4", SecurityGroupIDs: []string{"sg-1"}, ERDMA: true}
+	goneStandard := Request{VSwitchID: "vsw-4", SecurityGroupIDs: []string{"sg-1"}}
+
+	expired := time.Now().Add(-2 * demandGracePeriod)
+	for i, d := range []*demand{
+		{req: used, lastRequested: expired},
+		{req: gone, lastRequested: expired},
+		{req: pending, lastRequested: time.Now()},
+		{req: usedERDMA, lastRequested: expired},
+		{req: goneStandard, lastRequested: expired},
+	} {
+		key := d.req.key()
+		id := fmt.Sprintf("eni-%d", i+1)
+		p.demands[key] = d
+		p.idles[key] = []*aliyunClient.NetworkInterface{{NetworkInterfaceID: id}}
+		p.owned.Insert(id)
+	}
+
+	err := p.shrink(context.Background())
+	assert.NoError(t, err)
+
+	assert.Len(t, p.demands, 3)
+	assert.NotContains(t, p.demands, gone.key())
+	assert.NotContains(t, p.demands, goneStandard.key())
+	assert.NotContains(t, p.idles, gone.key())
+	assert.False(t, p.owned.Has("eni-2"))
+	assert.True(t, p.owned.Has("eni-1"))
+	assert.True(t, p.owned.Has("eni-3"))
+	assert.True(t, p.owned.Has("eni-4"))
+	assert.False(t, p.owned.Has("eni-5"))
+}
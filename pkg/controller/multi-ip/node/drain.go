@@ -0,0 +1,104 @@
+package node
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+const (
+	drainRetryPeriod = 5 * time.Second
+
+	// drainTimeout is the max time the finalizer is kept, the enis left are reclaimed by the orphan eni hunter
+	drainTimeout = 10 * time.Minute
+)
+
+// isDraining whether the k8s node is marked to be removed
+func (n *ReconcileNode) isDraining(ctx context.Context, name string) (bool, error) {
+	k8sNode := &corev1.Node{}
+	err := n.client.Get(ctx, client.ObjectKey{Name: name}, k8sNode)
+	if err != nil {
+		if k8sErr.IsNotFound(err) {
+			// the node cr is removed with the node, leave it to the finalizer
+			return false, nil
+		}
+		return false, err
+	}
+	return !k8sNode.DeletionTimestamp.IsZero() || types.NodeDraining(k8sNode), nil
+}
+
+// drainPool keep no idle ip for the node, the change is not persisted
+func drainPool(node *networkv1beta1.Node) {
+	node.Spec.Pool = &networkv1beta1.PoolSpec{}
+}
+
+// releaseENIs detach and delete all the enis recorded in the node cr, the ips are released with the eni.
+// The primary eni is not recorded, member enis are handled by the pod eni controller.
+// Return true if no eni is left.
+func (n *ReconcileNode) releaseENIs(ctx context.Context, node *networkv1beta1.Node) bool {
+	ctx, span := n.tracer.Start(ctx, "releaseENIs")
+	defer span.End()
+
+	for _, eni := range node.Status.NetworkInterfaces {
+		if eni.Status == aliyunClient.ENIStatusDeleting || eni.Status == aliyunClient.ENIStatusDetaching {
+			continue
+		}
+		eni.Status = aliyunClient.ENIStatusDeleting
+		MetaCtx(ctx).StatusChanged.Store(true)
+	}
+
+	_ = n.handleStatus(ctx, node)
+
+	return len(node.Status.NetworkInterfaces) == 0
+}
+
+// finalize release the enis before the node cr is gone, the finalizer is removed after all enis are released
+func (n *ReconcileNode) finalize(ctx context.Context, node *networkv1beta1.Node) (reconcile.Result, error) {
+	n.cache.Delete(node.Name)
+
+	if !controllerutil.ContainsFinalizer(node, finalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	l := logf.FromContext(ctx)
+
+	if len(node.Status.NetworkInterfaces) > 0 && node.Spec.NodeMetadata.InstanceID != "" {
+		ctx = context.WithValue(ctx, ctxMetaKey{}, &NodeStatus{
+			NeedSyncOpenAPI: &atomic.Bool{},
+			StatusChanged:   &atomic.Bool{},
+		})
+
+		released := n.releaseENIs(ctx, node)
+		if MetaCtx(ctx).StatusChanged.Load() {
+			err := n.client.Status().Update(ctx, node)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+
+		if !released {
+			if time.Since(node.DeletionTimestamp.Time) < drainTimeout {
+				l.Info("wait enis released", "left", len(node.Status.NetworkInterfaces))
+				return reconcile.Result{RequeueAfter: drainRetryPeriod}, nil
+			}
+			n.record.Eventf(node, corev1.EventTypeWarning, "ReleaseENIFailed", "timeout release enis, %d left", len(node.Status.NetworkInterfaces))
+		} else {
+			n.record.Event(node, corev1.EventTypeNormal, "ReleaseENISucceed", "all enis are released")
+		}
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	controllerutil.RemoveFinalizer(node, finalizer)
+	return reconcile.Result{}, n.client.Patch(ctx, node, patch)
+}
@@ -0,0 +1,129 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func drainingNode(deletedAt time.Time) *networkv1beta1.Node {
+	return &networkv1beta1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "node-1",
+			Finalizers:        []string{finalizer},
+			DeletionTimestamp: &metav1.Time{Time: deletedAt},
+		},
+		Spec: networkv1beta1.NodeSpec{
+			NodeMetadata: networkv1beta1.NodeMetadata{InstanceID: "i-1"},
+		},
+		Status: networkv1beta1.NodeStatus{
+			NetworkInterfaces: map[string]*networkv1beta1.NetworkInterface{
+				"eni-1": {
+					ID:                   "eni-1",
+					Status:               aliyunClient.ENIStatusInUse,
+					NetworkInterfaceType: networkv1beta1.ENITypeSecondary,
+					IPv4: map[string]*networkv1beta1.IP{
+						"127.0.0.1": {IP: "127.0.0.1", Primary: true, Status: networkv1beta1.IPStatusValid},
+						"127.0.0.2": {IP: "127.0.0.2", Status: networkv1beta1.IPStatusValid},
+					},
+				},
+				"eni-2": {
+					ID:                   "eni-2",
+					Status:               aliyunClient.ENIStatusInUse,
+					NetworkInterfaceType: networkv1beta1.ENITypeTrunk,
+				},
+			},
+		},
+	}
+}
+
+func newDrainReconciler(t *testing.T, openAPI *mocks.Interface, objs ...client.Object) *ReconcileNode {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkv1beta1.AddToScheme(scheme)
+
+	return &ReconcileNode{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		record: record.NewFakeRecorder(10),
+		aliyun: openAPI,
+		tracer: trace.NewNoopTracerProvider().Tracer(""),
+	}
+}
+
+func TestReconcileNode_finalize(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DetachNetworkInterface", mock.Anything, mock.Anything, "i-1", "").Return(nil)
+	openAPI.On("WaitForNetworkInterface", mock.Anything, "eni-1", aliyunClient.ENIStatusAvailable, mock.Anything, true).Return(&aliyunClient.NetworkInterface{}, nil)
+	// already gone
+	openAPI.On("WaitForNetworkInterface", mock.Anything, "eni-2", aliyunClient.ENIStatusAvailable, mock.Anything, true).Return(nil, apiErr.ErrNotFound)
+	openAPI.On("DeleteNetworkInterface", mock.Anything, "eni-1").Return(nil).Once()
+
+	node := drainingNode(time.Now())
+	n := newDrainReconciler(t, openAPI, node)
+
+	result, err := n.finalize(context.Background(), node)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+
+	// the finalizer is removed, the cr is gone
+	err = n.client.Get(context.Background(), client.ObjectKey{Name: "node-1"}, &networkv1beta1.Node{})
+	assert.True(t, k8sErr.IsNotFound(err))
+}
+
+func TestReconcileNode_finalizeRetry(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DetachNetworkInterface", mock.Anything, mock.Anything, "i-1", "").Return(errors.New("foo"))
+
+	node := drainingNode(time.Now())
+	n := newDrainReconciler(t, openAPI, node)
+
+	result, err := n.finalize(context.Background(), node)
+	assert.NoError(t, err)
+	assert.Equal(t, drainRetryPeriod, result.RequeueAfter)
+
+	got := &networkv1beta1.Node{}
+	assert.NoError(t, n.client.Get(context.Background(), client.ObjectKey{Name: "node-1"}, got))
+	assert.Contains(t, got.Finalizers, finalizer)
+	for _, eni := range got.Status.NetworkInterfaces {
+		assert.Equal(t, aliyunClient.ENIStatusDeleting, eni.Status)
+	}
+
+	// give up after timeout
+	node = drainingNode(time.Now().Add(-2 * drainTimeout))
+	n = newDrainReconciler(t, openAPI, node)
+	result, err = n.finalize(context.Background(), node)
+	assert.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.Contains(t, <-n.record.(*record.FakeRecorder).Events, "ReleaseENIFailed")
+}
+
+func TestReconcileNode_isDraining(t *testing.T) {
+	n := newDrainReconciler(t, mocks.NewInterface(t),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Annotations: map[string]string{types.NodeENIRelease: "true"}}},
+	)
+
+	for name, want := range map[string]bool{"node-1": false, "node-2": true, "node-3": false} {
+		draining, err := n.isDraining(context.Background(), name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, draining, name)
+	}
+}
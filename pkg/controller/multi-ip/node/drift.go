@@ -0,0 +1,174 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// predicateForDriftReport only the nodeRuntime with new eni report is handled
+type predicateForDriftReport struct {
+	predicate.Funcs
+}
+
+func (p *predicateForDriftReport) Create(e event.CreateEvent) bool {
+	nodeRuntime, ok := e.Object.(*networkv1beta1.NodeRuntime)
+	return ok && nodeRuntime.Status.NetworkInterfacesUpdateTime != nil
+}
+
+func (p *predicateForDriftReport) Update(e event.UpdateEvent) bool {
+	oldObj, ok := e.ObjectOld.(*networkv1beta1.NodeRuntime)
+	if !ok {
+		return false
+	}
+	newObj, ok := e.ObjectNew.(*networkv1beta1.NodeRuntime)
+	if !ok || newObj.Status.NetworkInterfacesUpdateTime == nil {
+		return false
+	}
+	return !newObj.Status.NetworkInterfacesUpdateTime.Equal(oldObj.Status.NetworkInterfacesUpdateTime)
+}
+
+func (p *predicateForDriftReport) Delete(e event.DeleteEvent) bool {
+	return false
+}
+
+func (p *predicateForDriftReport) Generic(e event.GenericEvent) bool {
+	return false
+}
+
+// detectDrift compare the enis reported by the daemon with the node cr, return true if a sync with openAPI is needed.
+// Each report is checked once, so a stale report cause at most one sync.
+func (n *ReconcileNode) detectDrift(ctx context.Context, node *networkv1beta1.Node) bool {
+	nodeRuntime := &networkv1beta1.NodeRuntime{}
+	err := n.client.Get(ctx, client.ObjectKey{Name: node.Name}, nodeRuntime)
+	if err != nil {
+		return false
+	}
+	reportAt := nodeRuntime.Status.NetworkInterfacesUpdateTime
+	if reportAt == nil {
+		return false
+	}
+
+	meta := MetaCtx(ctx)
+	if !reportAt.Time.After(meta.LastDriftReport) {
+		return false
+	}
+	meta.LastDriftReport = reportAt.Time
+
+	ignored, err := n.podENIs(ctx, node.Name)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "error list podENIs")
+		return false
+	}
+	ignored = ignored.Union(meta.UnmanagedENIs)
+
+	diff := networkInterfacesDiff(node.Status.NetworkInterfaces, nodeRuntime.Status.NetworkInterfaces, ignored, node.Spec.ENISpec.EnableIPv4, node.Spec.ENISpec.EnableIPv6)
+	if len(diff) == 0 {
+		return false
+	}
+
+	DriftSyncOpenAPITotal.WithLabelValues(node.Name).Inc()
+	logf.FromContext(ctx).Info("eni drift detected, sync with openAPI", "diff", diff, "reportAt", reportAt.Time)
+	return true
+}
+
+// recordUnmanagedENIs remember the reported enis still not in the cr after a sync with openAPI.
+// They are not managed by the cr, e.g. created by users or filtered by the tag filter.
+func (n *ReconcileNode) recordUnmanagedENIs(ctx context.Context, node *networkv1beta1.Node) {
+	nodeRuntime := &networkv1beta1.NodeRuntime{}
+	err := n.client.Get(ctx, client.ObjectKey{Name: node.Name}, nodeRuntime)
+	if err != nil {
+		return
+	}
+	unmanaged := sets.New[string]()
+	for id := range nodeRuntime.Status.NetworkInterfaces {
+		if _, ok := node.Status.NetworkInterfaces[id]; !ok {
+			unmanaged.Insert(id)
+		}
+	}
+	MetaCtx(ctx).UnmanagedENIs = unmanaged
+}
+
+// podENIs the enis used by the podENIs on the node, they are managed by the podENI controller
+func (n *ReconcileNode) podENIs(ctx context.Context, nodeName string) (sets.Set[string], error) {
+	podENIs := &networkv1beta1.PodENIList{}
+	err := n.client.List(ctx, podENIs, client.MatchingLabels{types.ENIRelatedNodeName: nodeName})
+	if err != nil {
+		return nil, err
+	}
+	ids := sets.New[string]()
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			ids.Insert(alloc.ENI.ID)
+		}
+	}
+	return ids, nil
+}
+
+// networkInterfacesDiff return the differences between the node cr and the daemon report.
+// Enis not in use and ips being deleted are not compared, as the report may not catch up.
+// Enis ignored are not managed by the cr.
+func networkInterfacesDiff(expect map[string]*networkv1beta1.NetworkInterface, actual map[string]*networkv1beta1.RuntimeNetworkInterface, ignored sets.Set[string], ipv4, ipv6 bool) []string {
+	var diff []string
+	for id, eni := range expect {
+		if eni.Status != aliyunClient.ENIStatusInUse {
+			continue
+		}
+		// member enis are attached to the trunk, not shown in the metadata
+		if eni.NetworkInterfaceType == networkv1beta1.ENITypeMember {
+			continue
+		}
+		report, ok := actual[id]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("eni %s not attached", id))
+			continue
+		}
+		if ipv4 {
+			diff = append(diff, ipDiff(id, eni.IPv4, report.IPv4)...)
+		}
+		if ipv6 {
+			diff = append(diff, ipDiff(id, eni.IPv6, report.IPv6)...)
+		}
+	}
+	for id := range actual {
+		if ignored.Has(id) {
+			continue
+		}
+		if _, ok := expect[id]; !ok {
+			diff = append(diff, fmt.Sprintf("eni %s not recorded", id))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+func ipDiff(eniID string, expect map[string]*networkv1beta1.IP, actual []string) []string {
+	valid := sets.New[string]()
+	all := sets.New[string]()
+	for _, ip := range expect {
+		all.Insert(ip.IP)
+		if ip.Status != networkv1beta1.IPStatusDeleting {
+			valid.Insert(ip.IP)
+		}
+	}
+	reported := sets.New[string](actual...)
+
+	var diff []string
+	for _, ip := range sets.List(valid.Difference(reported)) {
+		diff = append(diff, fmt.Sprintf("eni %s ip %s not assigned", eniID, ip))
+	}
+	for _, ip := range sets.List(reported.Difference(all)) {
+		diff = append(diff, fmt.Sprintf("eni %s ip %s not recorded", eniID, ip))
+	}
+	return diff
+}
@@ -0,0 +1,149 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func driftNode() *networkv1beta1.Node {
+	return &networkv1beta1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec: networkv1beta1.NodeSpec{
+			ENISpec: &networkv1beta1.ENISpec{EnableIPv4: true},
+		},
+		Status: networkv1beta1.NodeStatus{
+			NetworkInterfaces: map[string]*networkv1beta1.NetworkInterface{
+				"eni-1": {
+					ID:     "eni-1",
+					Status: aliyunClient.ENIStatusInUse,
+					IPv4: map[string]*networkv1beta1.IP{
+						"192.168.0.1": {IP: "192.168.0.1", Primary: true, Status: networkv1beta1.IPStatusValid},
+						"192.168.0.2": {IP: "192.168.0.2", Status: networkv1beta1.IPStatusValid},
+						"192.168.0.3": {IP: "192.168.0.3", Status: networkv1beta1.IPStatusDeleting},
+					},
+				},
+				"eni-2": {
+					ID:     "eni-2",
+					Status: aliyunClient.ENIStatusDeleting,
+				},
+			},
+		},
+	}
+}
+
+func Test_networkInterfacesDiff(t *testing.T) {
+	expect := driftNode().Status.NetworkInterfaces
+
+	// the deleting ip may be already released, the deleting eni may be detached
+	assert.Empty(t, networkInterfacesDiff(expect, map[string]*networkv1beta1.RuntimeNetworkInterface{
+		"eni-1": {IPv4: []string{"192.168.0.1", "192.168.0.2"}},
+	}, nil, true, false))
+	assert.Empty(t, networkInterfacesDiff(expect, map[string]*networkv1beta1.RuntimeNetworkInterface{
+		"eni-1": {IPv4: []string{"192.168.0.1", "192.168.0.2", "192.168.0.3"}},
+		"eni-2": {},
+	}, nil, true, false))
+
+	assert.Equal(t, []string{
+		"eni eni-1 ip 192.168.0.2 not assigned",
+		"eni eni-1 ip 192.168.0.4 not recorded",
+		"eni eni-3 not recorded",
+	}, networkInterfacesDiff(expect, map[string]*networkv1beta1.RuntimeNetworkInterface{
+		"eni-1": {IPv4: []string{"192.168.0.1", "192.168.0.4"}},
+		"eni-2": {},
+		"eni-3": {},
+	}, nil, true, false))
+
+	// the enis not managed by the cr
+	assert.Empty(t, networkInterfacesDiff(expect, map[string]*networkv1beta1.RuntimeNetworkInterface{
+		"eni-1": {IPv4: []string{"192.168.0.1", "192.168.0.2"}},
+		"eni-3": {},
+	}, sets.New[string]("eni-3"), true, false))
+
+	assert.Equal(t, []string{"eni eni-1 not attached"}, networkInterfacesDiff(expect, nil, nil, true, false))
+}
+
+func TestReconcileNode_detectDrift(t *testing.T) {
+	reportAt := metav1.NewTime(time.Now())
+	nodeRuntime := &networkv1beta1.NodeRuntime{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: networkv1beta1.NodeRuntimeStatus{
+			NetworkInterfaces:           map[string]*networkv1beta1.RuntimeNetworkInterface{},
+			NetworkInterfacesUpdateTime: &reportAt,
+		},
+	}
+	n := newDrainReconciler(t, mocks.NewInterface(t), nodeRuntime)
+	ctx := MetaIntoCtx(context.Background())
+
+	assert.True(t, n.detectDrift(ctx, driftNode()))
+	// same report is checked once
+	assert.False(t, n.detectDrift(ctx, driftNode()))
+
+	// no report
+	n = newDrainReconciler(t, mocks.NewInterface(t))
+	assert.False(t, n.detectDrift(MetaIntoCtx(context.Background()), driftNode()))
+}
+
+func TestReconcileNode_detectDriftUnmanaged(t *testing.T) {
+	reportAt := metav1.NewTime(time.Now())
+	nodeRuntime := &networkv1beta1.NodeRuntime{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: networkv1beta1.NodeRuntimeStatus{
+			NetworkInterfaces: map[string]*networkv1beta1.RuntimeNetworkInterface{
+				"eni-1":    {IPv4: []string{"192.168.0.1", "192.168.0.2"}},
+				"eni-pod":  {IPv4: []string{"192.168.0.10"}},
+				"eni-user": {IPv4: []string{"192.168.0.20"}},
+			},
+			NetworkInterfacesUpdateTime: &reportAt,
+		},
+	}
+	podENI := &networkv1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Labels:    map[string]string{types.ENIRelatedNodeName: "node-1"},
+		},
+		Spec: networkv1beta1.PodENISpec{
+			Allocations: []networkv1beta1.Allocation{{ENI: networkv1beta1.ENI{ID: "eni-pod"}}},
+		},
+	}
+	n := newDrainReconciler(t, mocks.NewInterface(t), nodeRuntime, podENI)
+	ctx := MetaIntoCtx(context.Background())
+
+	// the eni created by user is not in the cr after a sync
+	assert.True(t, n.detectDrift(ctx, driftNode()))
+	n.recordUnmanagedENIs(ctx, driftNode())
+	assert.Equal(t, sets.New[string]("eni-pod", "eni-user"), MetaCtx(ctx).UnmanagedENIs)
+
+	// the next report is not a drift
+	nodeRuntime.Status.NetworkInterfacesUpdateTime = &metav1.Time{Time: reportAt.Add(time.Minute)}
+	assert.NoError(t, n.client.Status().Update(ctx, nodeRuntime))
+	assert.False(t, n.detectDrift(ctx, driftNode()))
+}
+
+func Test_predicateForDriftReport(t *testing.T) {
+	p := &predicateForDriftReport{}
+	reportAt := metav1.NewTime(time.Now())
+	oldObj := &networkv1beta1.NodeRuntime{
+		Status: networkv1beta1.NodeRuntimeStatus{NetworkInterfacesUpdateTime: &reportAt},
+	}
+	newObj := oldObj.DeepCopy()
+	newObj.Status.Pods = map[string]*networkv1beta1.RuntimePodStatus{"pod-1": {}}
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}))
+
+	newObj.Status.NetworkInterfacesUpdateTime = &metav1.Time{Time: reportAt.Add(time.Minute)}
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}))
+
+	assert.True(t, p.Create(event.CreateEvent{Object: oldObj}))
+	assert.False(t, p.Create(event.CreateEvent{Object: &networkv1beta1.NodeRuntime{}}))
+}
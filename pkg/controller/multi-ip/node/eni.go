@@ -2,6 +2,7 @@ package node
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/go-logr/logr"
@@ -9,13 +10,15 @@ import (
 
 	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
 	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/types"
 )
 
 type Condition string
 
 const (
-	ConditionInsufficientIP = "InsufficientIP"
-	ConditionOperationErr   = "OperationErr"
+	ConditionInsufficientIP        = "InsufficientIP"
+	ConditionOperationErr          = "OperationErr"
+	ConditionSecurityGroupMismatch = "SecurityGroupMismatch"
 )
 
 type eniTypeKey struct {
@@ -44,6 +47,9 @@ type eniOptions struct {
 	// if eniRef is nil , we use options to create the eni
 	eniRef *networkv1beta1.NetworkInterface
 
+	// securityGroupIDs for new eni dedicated to pods with their own security groups
+	securityGroupIDs []string
+
 	addIPv4N int
 	addIPv6N int
 
@@ -51,6 +57,14 @@ type eniOptions struct {
 	errors []error
 }
 
+// customSecurityGroupIDs return the security groups the eni is dedicated to, nil for the node default
+func (o *eniOptions) customSecurityGroupIDs() []string {
+	if o.eniRef != nil {
+		return o.eniRef.CustomSecurityGroupIDs
+	}
+	return o.securityGroupIDs
+}
+
 var EniOptions = map[eniTypeKey]*aliyunClient.CreateNetworkInterfaceOptions{
 	secondaryKey: {
 		NetworkInterfaceOptions: &aliyunClient.NetworkInterfaceOptions{
@@ -119,6 +133,14 @@ func releaseUnUsedIP(log logr.Logger, eni *networkv1beta1.NetworkInterface, toDe
 	return max(releasedV4, releasedV6)
 }
 
+// poolIPs return the ips of the eni used by pods, in ipv6 only stack the primary ipv4 is not used
+func poolIPs(eniSpec *networkv1beta1.ENISpec, eni *networkv1beta1.NetworkInterface) map[string]*networkv1beta1.IP {
+	if eniSpec != nil && !eniSpec.EnableIPv4 && eniSpec.EnableIPv6 {
+		return eni.IPv6
+	}
+	return eni.IPv4
+}
+
 func newENIFromAPI(eni *aliyunClient.NetworkInterface) *networkv1beta1.NetworkInterface {
 
 	return &networkv1beta1.NetworkInterface{
@@ -130,6 +152,7 @@ func newENIFromAPI(eni *aliyunClient.NetworkInterface) *networkv1beta1.NetworkIn
 		PrimaryIPAddress:            eni.PrivateIPAddress,
 		NetworkInterfaceTrafficMode: networkv1beta1.NetworkInterfaceTrafficMode(eni.NetworkInterfaceTrafficMode),
 		NetworkInterfaceType:        networkv1beta1.ENIType(eni.Type),
+		CustomSecurityGroupIDs:      customSecurityGroupsFromTags(eni.Tags),
 		IPv4: lo.SliceToMap(eni.PrivateIPSets, func(item ecs.PrivateIpSet) (string, *networkv1beta1.IP) {
 			return item.PrivateIpAddress, &networkv1beta1.IP{
 				IP:      item.PrivateIpAddress,
@@ -146,6 +169,16 @@ func newENIFromAPI(eni *aliyunClient.NetworkInterface) *networkv1beta1.NetworkIn
 	}
 }
 
+// customSecurityGroupsFromTags return the security groups the eni is dedicated to, nil if the eni is shared
+func customSecurityGroupsFromTags(tags []ecs.Tag) []string {
+	for _, tag := range tags {
+		if tag.TagKey == types.TagENISecurityGroups && tag.TagValue != "" {
+			return strings.Split(tag.TagValue, ",")
+		}
+	}
+	return nil
+}
+
 func mergeIPMap(log logr.Logger, remote, current map[string]*networkv1beta1.IP) {
 	// delete remote not in current
 	for k := range current {
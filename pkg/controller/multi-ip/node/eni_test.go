@@ -251,3 +251,18 @@ func TestIPUsage(t *testing.T) {
 		})
 	}
 }
+
+func Test_poolIPs(t *testing.T) {
+	eni := &networkv1beta1.NetworkInterface{
+		IPv4: map[string]*networkv1beta1.IP{
+			"192.168.0.1": {IP: "192.168.0.1", Primary: true},
+		},
+		IPv6: map[string]*networkv1beta1.IP{
+			"fd00::1": {IP: "fd00::1"},
+		},
+	}
+
+	assert.Equal(t, eni.IPv4, poolIPs(nil, eni))
+	assert.Equal(t, eni.IPv4, poolIPs(&networkv1beta1.ENISpec{EnableIPv4: true, EnableIPv6: true}, eni))
+	assert.Equal(t, eni.IPv6, poolIPs(&networkv1beta1.ENISpec{EnableIPv6: true}, eni))
+}
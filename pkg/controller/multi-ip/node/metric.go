@@ -21,4 +21,13 @@ var (
 		},
 		[]string{"node"},
 	)
+
+	// DriftSyncOpenAPITotal sync with openapi as the enis reported by the daemon differ from the cr
+	DriftSyncOpenAPITotal = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "drift_sync_openapi_count_total",
+			Help: "controlplane sync data with openapi as drift detected total",
+		},
+		[]string{"node"},
+	)
 )
@@ -15,6 +15,9 @@ type PodRequest struct {
 
 	RequireERDMA bool
 
+	// SecurityGroupIDs is the security groups requested by the pod, nil for the node default
+	SecurityGroupIDs []string
+
 	// status form pod status, only used in takeover
 	IPv4 string
 	IPv6 string
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/netip"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,12 +25,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -43,6 +45,8 @@ import (
 	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	register "github.com/AliyunContainerService/terway/pkg/controller"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/pkg/controller/shard"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/pkg/vswitch"
 	"github.com/AliyunContainerService/terway/types"
@@ -89,21 +93,45 @@ func init() {
 			return err
 		}
 
+		var sgLimiter *rate.Limiter
+		if !ctrlCtx.Config.DisableSecurityGroupSync {
+			sgLimiter = rate.NewLimiter(rate.Limit(ctrlCtx.Config.SecurityGroupSyncQPS), 1)
+		}
+
 		// metric and tracer
 
-		metrics.Registry.MustRegister(ResourcePoolTotal)
+		metrics.Registry.MustRegister(ResourcePoolTotal, DriftSyncOpenAPITotal)
 		tracer := ctrlCtx.TracerProvider.Tracer(ControllerName)
 
+		// nodes taken from other replicas are not notified by any event
+		ctrlCtx.Shard.AddHandler(func(ctx context.Context) {
+			nodes := &networkv1beta1.NodeList{}
+			err := mgr.GetClient().List(ctx, nodes)
+			if err != nil {
+				logf.FromContext(ctx).Error(err, "failed to list nodes")
+				return
+			}
+			for _, item := range nodes.Items {
+				if ctrlCtx.Shard.Owns(item.Name) {
+					Notify(ctx, item.Name)
+				}
+			}
+		})
+
 		ctrl, err := controller.New(ControllerName, mgr, controller.Options{
 			MaxConcurrentReconciles: ctrlCtx.Config.MultiIPNodeMaxConcurrent,
+			NeedLeaderElection:      ctrlCtx.Shard.ControllerNeedLeaderElection(),
 			Reconciler: &ReconcileNode{
 				client:             mgr.GetClient(),
 				scheme:             mgr.GetScheme(),
 				record:             mgr.GetEventRecorderFor(ControllerName),
 				aliyun:             ctrlCtx.AliyunClient,
 				vswpool:            ctrlCtx.VSwitchPool,
+				shard:              ctrlCtx.Shard,
+				clusterID:          ctrlCtx.Config.ClusterID,
 				fullSyncNodePeriod: fullSyncPeriod,
 				gcPeriod:           gcPeriod,
+				sgLimiter:          sgLimiter,
 				tracer:             tracer,
 			},
 			RateLimiter: workqueue.NewMaxOfRateLimiter(
@@ -121,6 +149,16 @@ func init() {
 			return err
 		}
 
+		// the enis reported by the daemon are compared with the cr, the nodeRuntime has the same name as the node
+		err = ctrl.Watch(
+			source.Kind(mgr.GetCache(), &networkv1beta1.NodeRuntime{}),
+			&handler.EnqueueRequestForObject{},
+			&predicateForDriftReport{},
+		)
+		if err != nil {
+			return err
+		}
+
 		return ctrl.Watch(
 			&source.Channel{Source: EventCh},
 			&handler.EnqueueRequestForObject{},
@@ -135,6 +173,10 @@ type NodeStatus struct {
 	StatusChanged     *atomic.Bool
 	LastGCTime        time.Time
 	LastReconcileTime time.Time
+	// LastDriftReport is the last daemon report compared with the cr
+	LastDriftReport time.Time
+	// UnmanagedENIs the enis attached but not managed by the cr, ignored in the drift detection
+	UnmanagedENIs sets.Set[string]
 }
 
 type ReconcileNode struct {
@@ -145,11 +187,20 @@ type ReconcileNode struct {
 	aliyun  register.Interface
 	vswpool *vswitch.SwitchPool
 
+	// shard decide the nodes handled by this replica, nil if sharding is disabled
+	shard *shard.Shard
+
+	// clusterID is tagged on the enis created, for the orphan eni hunter
+	clusterID string
+
 	cache sync.Map
 
 	fullSyncNodePeriod time.Duration
 	gcPeriod           time.Duration
 
+	// sgLimiter limit the rate of updating security groups on exist enis, nil to disable
+	sgLimiter *rate.Limiter
+
 	tracer trace.Tracer
 }
 
@@ -166,6 +217,12 @@ func MetaCtx(ctx context.Context) *NodeStatus {
 }
 
 func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if !n.shard.Owns(request.Name) {
+		n.cache.Delete(request.Name)
+		return reconcile.Result{}, nil
+	}
+
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Controller: ControllerName, Node: request.Name})
 	ctx, span := n.tracer.Start(ctx, "reconcile", trace.WithAttributes(attribute.String("node", request.Name)))
 	defer span.End()
 
@@ -204,13 +261,9 @@ func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request
 	}
 
 	if !node.DeletionTimestamp.IsZero() {
-		patch := client.MergeFrom(node.DeepCopy())
-		changed := controllerutil.RemoveFinalizer(node, finalizer)
-		n.cache.Delete(request.Name)
-		if changed {
-			err = n.client.Patch(ctx, node, patch)
-		}
-		return reconcile.Result{}, err
+		var result reconcile.Result
+		result, err = n.finalize(ctx, node)
+		return result, err
 	}
 
 	// check if daemon has ready
@@ -243,6 +296,9 @@ func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request
 	// write into ctx for latter use
 	// this should be thread safe to access NodeStatus
 	ctx = context.WithValue(ctx, ctxMetaKey{}, nodeStatus)
+	// the idempotent keys of the openAPI calls are removed after the result is recorded in the node cr,
+	// the keys are left to the resolve on restart if the status is not updated
+	ctx, pendingKeys := aliyunClient.WithPendingKeys(ctx)
 
 	defer func() {
 		nodeStatus.LastReconcileTime = time.Now()
@@ -259,6 +315,16 @@ func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request
 		return reconcile.Result{}, err
 	}
 
+	// the node is going to be removed, release the enis once no pod is using them
+	draining, err := n.isDraining(ctx, node.Name)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if draining {
+		span.AddEvent("draining")
+		drainPool(node)
+	}
+
 	// on first startup, only node with pods on it, need to do a full sync
 	// for legacy , we need to handle trunk eni
 	if len(podRequests) != 0 && len(node.Status.NetworkInterfaces) == 0 {
@@ -270,20 +336,34 @@ func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request
 		if node.Status.NextSyncOpenAPITime.Before(&now) {
 			span.AddEvent("ttlReachedSyncOpenAPI")
 
+			nodeStatus.NeedSyncOpenAPI.Store(true)
+		} else if n.detectDrift(ctx, node) {
+			span.AddEvent("driftSyncOpenAPI")
+
 			nodeStatus.NeedSyncOpenAPI.Store(true)
 		}
 	}
 
+	syncOpenAPI := nodeStatus.NeedSyncOpenAPI.Load()
 	err = n.syncWithAPI(ctx, node)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if syncOpenAPI {
+		n.recordUnmanagedENIs(ctx, node)
+	}
 
 	syncErr := n.syncPods(ctx, podRequests, node)
 	if syncErr != nil {
 		l.Error(syncErr, "syncPods error")
 	}
 
+	if draining && syncErr == nil && len(podRequests) == 0 {
+		n.releaseENIs(ctx, node)
+	}
+
+	sgPending := n.syncSecurityGroups(ctx, node)
+
 	afterStatus, err := runtime.DefaultUnstructuredConverter.ToUnstructured(node.Status.DeepCopy())
 	if err != nil {
 		return reconcile.Result{}, err
@@ -297,13 +377,33 @@ func (n *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request
 		if err != nil && nodeStatus.StatusChanged.CompareAndSwap(true, false) {
 			nodeStatus.NeedSyncOpenAPI.Store(true)
 		}
+		if err == nil {
+			keysDone(ctx, pendingKeys)
+		}
 
 		return reconcile.Result{RequeueAfter: 1 * time.Second}, err
 	}
+	keysDone(ctx, pendingKeys)
+
+	if draining && syncErr == nil && len(podRequests) == 0 && len(node.Status.NetworkInterfaces) > 0 {
+		return reconcile.Result{RequeueAfter: drainRetryPeriod}, nil
+	}
+
+	if sgPending && syncErr == nil {
+		return reconcile.Result{RequeueAfter: securityGroupRetryPeriod}, nil
+	}
 
 	return reconcile.Result{}, syncErr
 }
 
+// keysDone remove the idempotent keys of the openAPI calls recorded in the node cr
+func keysDone(ctx context.Context, pendingKeys *aliyunClient.PendingKeys) {
+	err := pendingKeys.Done()
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to remove idempotent keys")
+	}
+}
+
 // syncWithAPI will sync all eni from openAPI. Need to re-sync with local pods.
 func (n *ReconcileNode) syncWithAPI(ctx context.Context, node *networkv1beta1.Node) error {
 	if !MetaCtx(ctx).NeedSyncOpenAPI.Load() {
@@ -354,10 +454,13 @@ func (n *ReconcileNode) syncWithAPI(ctx context.Context, node *networkv1beta1.No
 			node.Status.NetworkInterfaces[item.NetworkInterfaceID] = remote
 		} else {
 			// exist record
-			// only ip is updated
+			// only ip and security groups is updated
 			mergeIPMap(log, remote.IPv4, crENI.IPv4)
 			mergeIPMap(log, remote.IPv6, crENI.IPv6)
 
+			// keep track of the actual security groups, so the drift can be corrected
+			crENI.SecurityGroupIDs = remote.SecurityGroupIDs
+
 			// nb(l1b0k): use Deleting status in cr for eni we don't wanted
 			if crENI.Status != aliyunClient.ENIStatusDeleting {
 				crENI.Status = remote.Status
@@ -444,13 +547,23 @@ func (n *ReconcileNode) getPods(ctx context.Context, node *networkv1beta1.Node)
 			return nil, err
 		}
 
+		// custom security groups is not supported for erdma
+		var sgIDs []string
+		if !requireERDMA {
+			sgIDs = types.PodSecurityGroupIDs(&pod)
+			if common.SecurityGroupsEqual(sgIDs, node.Spec.ENISpec.SecurityGroupIDs) {
+				sgIDs = nil
+			}
+		}
+
 		podsMapper[pod.Namespace+"/"+pod.Name] = &PodRequest{
-			PodUID:       string(pod.UID),
-			RequireIPv4:  node.Spec.ENISpec.EnableIPv4,
-			RequireIPv6:  node.Spec.ENISpec.EnableIPv6,
-			RequireERDMA: requireERDMA,
-			IPv4:         ipv4,
-			IPv6:         ipv6,
+			PodUID:           string(pod.UID),
+			RequireIPv4:      node.Spec.ENISpec.EnableIPv4,
+			RequireIPv6:      node.Spec.ENISpec.EnableIPv6,
+			RequireERDMA:     requireERDMA,
+			SecurityGroupIDs: sgIDs,
+			IPv4:             ipv4,
+			IPv6:             ipv6,
 		}
 	}
 	return podsMapper, nil
@@ -592,6 +705,11 @@ func assignIPFromLocalPool(log logr.Logger, podsMapper map[string]*PodRequest, i
 						continue
 					}
 
+					// eni is dedicated to the security groups
+					if !common.SecurityGroupsEqual(info.SecurityGroupIDs, v.NetworkInterface.CustomSecurityGroupIDs) {
+						continue
+					}
+
 					if v.IP.Status == networkv1beta1.IPStatusValid && v.IP.PodID == "" {
 						info.ipv4Ref = &EniIP{
 							NetworkInterface: v.NetworkInterface,
@@ -631,6 +749,10 @@ func assignIPFromLocalPool(log logr.Logger, podsMapper map[string]*PodRequest, i
 						continue
 					}
 
+					if !common.SecurityGroupsEqual(info.SecurityGroupIDs, v.NetworkInterface.CustomSecurityGroupIDs) {
+						continue
+					}
+
 					if info.ipv4Ref != nil && v.NetworkInterface.ID != info.ipv4Ref.NetworkInterface.ID {
 						// we have chosen the eni
 						continue
@@ -678,27 +800,59 @@ func (n *ReconcileNode) addIP(ctx context.Context, unSucceedPods map[string]*Pod
 	defer span.End()
 
 	normalPods := lo.PickBy(unSucceedPods, func(key string, value *PodRequest) bool {
-		return !value.RequireERDMA
+		return !value.RequireERDMA && len(value.SecurityGroupIDs) == 0
 	})
 	rdmaPods := lo.PickBy(unSucceedPods, func(key string, value *PodRequest) bool {
 		return value.RequireERDMA
 	})
+	sgPods := lo.GroupBy(lo.Filter(lo.Values(unSucceedPods), func(item *PodRequest, index int) bool {
+		return !item.RequireERDMA && len(item.SecurityGroupIDs) > 0
+	}), func(item *PodRequest) string {
+		return securityGroupsKey(item.SecurityGroupIDs)
+	})
 
 	// before create eni , we need to check the quota
 	options := getEniOptions(node)
 
 	// handle trunk/secondary eni
 	assignEniWithOptions(node, len(normalPods)+node.Spec.Pool.MinPoolSize, options, func(option *eniOptions) bool {
-		return n.validateENI(ctx, option, []eniTypeKey{secondaryKey, trunkKey})
+		return len(option.customSecurityGroupIDs()) == 0 &&
+			n.validateENI(ctx, option, []eniTypeKey{secondaryKey, trunkKey})
 	})
 	assignEniWithOptions(node, len(rdmaPods), options, func(option *eniOptions) bool {
 		return n.validateENI(ctx, option, []eniTypeKey{rdmaKey})
 	})
 
+	// handle secondary eni dedicated to the security groups, the empty slot is shared with others
+	keys := lo.Keys(sgPods)
+	sort.Strings(keys)
+	for _, key := range keys {
+		sgIDs := sgPods[key][0].SecurityGroupIDs
+
+		free := lo.Filter(options, func(item *eniOptions, index int) bool {
+			return item.eniRef == nil && item.addIPv4N <= 0 && item.addIPv6N <= 0
+		})
+		assignEniWithOptions(node, len(sgPods[key]), options, func(option *eniOptions) bool {
+			if option.eniRef == nil {
+				if !lo.Contains(free, option) {
+					return false
+				}
+			} else if !common.SecurityGroupsEqual(option.eniRef.CustomSecurityGroupIDs, sgIDs) {
+				return false
+			}
+			return n.validateENI(ctx, option, []eniTypeKey{secondaryKey})
+		})
+		for _, option := range free {
+			if option.addIPv4N > 0 || option.addIPv6N > 0 {
+				option.securityGroupIDs = sgIDs
+			}
+		}
+	}
+
 	err := n.allocateFromOptions(ctx, node, options)
 
 	// update node condition based on eni status
-	updateNodeCondition(ctx, n.client, node.Name, options)
+	updateNodeCondition(ctx, n.client, node, options)
 
 	updateCrCondition(options)
 
@@ -717,7 +871,12 @@ func updateCrCondition(options []*eniOptions) {
 			return
 		}
 		if len(item.errors) == 0 {
-			item.eniRef.Conditions = nil
+			// only clean up conditions managed here
+			delete(item.eniRef.Conditions, ConditionInsufficientIP)
+			delete(item.eniRef.Conditions, ConditionOperationErr)
+			if len(item.eniRef.Conditions) == 0 {
+				item.eniRef.Conditions = nil
+			}
 			return
 		}
 
@@ -760,7 +919,8 @@ func updateCrCondition(options []*eniOptions) {
 	})
 }
 
-func updateNodeCondition(ctx context.Context, c client.Client, nodeName string, options []*eniOptions) {
+func updateNodeCondition(ctx context.Context, c client.Client, node *networkv1beta1.Node, options []*eniOptions) {
+	nodeName := node.Name
 	l := logf.FromContext(ctx)
 	k8sNode := &corev1.Node{}
 	err := c.Get(ctx, client.ObjectKey{Name: nodeName}, k8sNode)
@@ -782,7 +942,7 @@ func updateNodeCondition(ctx context.Context, c client.Client, nodeName string,
 		}
 
 		if item.eniRef != nil {
-			for _, v := range item.eniRef.IPv4 {
+			for _, v := range poolIPs(node.Spec.ENISpec, item.eniRef) {
 				if v != nil {
 					if v.Status == networkv1beta1.IPStatusValid && v.PodID == "" {
 						hasIPLeft = true
@@ -926,7 +1086,7 @@ func assignEniWithOptions(node *networkv1beta1.Node, toAdd int, options []*eniOp
 
 			// for trunk , just create it
 			if option.eniTypeKey == trunkKey {
-				if toAddIPv4 <= 0 {
+				if eniSpec.EnableIPv4 && toAddIPv4 <= 0 {
 					toAddIPv4 = 1
 				}
 				if eniSpec.EnableIPv6 && toAddIPv6 <= 0 {
@@ -1030,13 +1190,13 @@ func (n *ReconcileNode) handleStatus(ctx context.Context, node *networkv1beta1.N
 					log.Error(err, "run gc failed")
 					continue
 				}
-			}
-
-			// wait eni detached
-			err = n.aliyun.DeleteNetworkInterface(ctx, eni.ID)
-			if err != nil {
-				log.Error(err, "run gc failed")
-				continue
+			} else {
+				// wait eni detached
+				err = n.aliyun.DeleteNetworkInterface(ctx, eni.ID)
+				if err != nil {
+					log.Error(err, "run gc failed")
+					continue
+				}
 			}
 			MetaCtx(ctx).StatusChanged.Store(true)
 
@@ -1124,11 +1284,7 @@ func (n *ReconcileNode) adjustPool(ctx context.Context, node *networkv1beta1.Nod
 		if eni.Status != aliyunClient.ENIStatusInUse {
 			continue
 		}
-		if node.Spec.ENISpec.EnableIPv4 {
-			idles += IdlesWithAvailable(eni.IPv4)
-		} else {
-			idles += IdlesWithAvailable(eni.IPv6)
-		}
+		idles += IdlesWithAvailable(poolIPs(node.Spec.ENISpec, eni))
 	}
 
 	toDel := idles - keepN
@@ -1170,20 +1326,30 @@ func (n *ReconcileNode) createENI(ctx context.Context, node *networkv1beta1.Node
 	}
 	bo := backoff.Backoff(backoff.ENICreate)
 
-	tags := node.Spec.ENISpec.Tag
-	if tags == nil {
-		tags = map[string]string{}
+	tags := make(map[string]string, len(node.Spec.ENISpec.Tag)+2)
+	for k, v := range node.Spec.ENISpec.Tag {
+		tags[k] = v
 	}
 	// keep the ds behave
 	tags[types.NetworkInterfaceTagCreatorKey] = types.NetworkInterfaceTagCreatorValue
+	// persist the dedication, so it is restored when the eni is synced from openAPI
+	if len(opt.securityGroupIDs) > 0 {
+		tags[types.TagENISecurityGroups] = securityGroupsKey(opt.securityGroupIDs)
+	}
+
+	sgIDs := node.Spec.ENISpec.SecurityGroupIDs
+	if len(opt.securityGroupIDs) > 0 {
+		sgIDs = opt.securityGroupIDs
+	}
 	createOpts := &aliyunClient.CreateNetworkInterfaceOptions{
 		NetworkInterfaceOptions: &aliyunClient.NetworkInterfaceOptions{
 			VSwitchID:        vsw.ID,
-			SecurityGroupIDs: node.Spec.ENISpec.SecurityGroupIDs,
+			SecurityGroupIDs: sgIDs,
 			ResourceGroupID:  node.Spec.ENISpec.ResourceGroupID,
-			Tags:             node.Spec.ENISpec.Tag,
+			Tags:             tags,
 			IPCount:          opt.addIPv4N,
 			IPv6Count:        opt.addIPv6N,
+			Owner:            &aliyunClient.ENIOwner{ClusterID: n.clusterID, NodeName: node.Name, Component: ControllerName},
 		},
 		Backoff: &bo,
 	}
@@ -1234,6 +1400,7 @@ func (n *ReconcileNode) createENI(ctx context.Context, node *networkv1beta1.Node
 	// update vsw
 	networkInterface.IPv4CIDR = vsw.IPv4CIDR
 	networkInterface.IPv6CIDR = vsw.IPv6CIDR
+	networkInterface.CustomSecurityGroupIDs = opt.securityGroupIDs
 
 	node.Status.NetworkInterfaces[eni.NetworkInterfaceID] = networkInterface
 	// if changed , but we update failed , that case ,need to sync openAPI...
@@ -1425,6 +1592,13 @@ func getEniOptions(node *networkv1beta1.Node) []*eniOptions {
 	return result
 }
 
+// securityGroupsKey is used to group the pods by security groups, the order is ignored
+func securityGroupsKey(ids []string) string {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
 func addIPToMap(in map[string]*networkv1beta1.IP, ip *networkv1beta1.IP) {
 	v, ok := in[ip.IP]
 	if ok {
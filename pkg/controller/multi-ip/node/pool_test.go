@@ -29,6 +29,7 @@ import (
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
 	vswpool "github.com/AliyunContainerService/terway/pkg/vswitch"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
 )
 
 func MetaIntoCtx(ctx context.Context) context.Context {
@@ -416,17 +417,19 @@ func TestReconcileNodeSyncWithAPI(t *testing.T) {
 			CreationTime:                "",
 		},
 		{
-			Status:                      "InUse",
-			MacAddress:                  "",
-			NetworkInterfaceID:          "eni-2",
-			VSwitchID:                   "vsw-1",
-			PrivateIPAddress:            "",
-			PrivateIPSets:               nil,
-			ZoneID:                      "zone-1",
-			SecurityGroupIDs:            nil,
-			ResourceGroupID:             "",
-			IPv6Set:                     nil,
-			Tags:                        nil,
+			Status:             "InUse",
+			MacAddress:         "",
+			NetworkInterfaceID: "eni-2",
+			VSwitchID:          "vsw-1",
+			PrivateIPAddress:   "",
+			PrivateIPSets:      nil,
+			ZoneID:             "zone-1",
+			SecurityGroupIDs:   nil,
+			ResourceGroupID:    "",
+			IPv6Set:            nil,
+			Tags: []ecs.Tag{
+				{TagKey: terwayTypes.TagENISecurityGroups, TagValue: "sg-1,sg-2"},
+			},
 			Type:                        "Secondary",
 			InstanceID:                  "",
 			TrunkNetworkInterfaceID:     "",
@@ -523,6 +526,8 @@ func TestReconcileNodeSyncWithAPI(t *testing.T) {
 
 	assert.Equal(t, 2, len(node.Status.NetworkInterfaces))
 	assert.Equal(t, "192.168.0.0/16", node.Status.NetworkInterfaces["eni-2"].IPv4CIDR)
+	// the dedication is restored from the tag
+	assert.Equal(t, []string{"sg-1", "sg-2"}, node.Status.NetworkInterfaces["eni-2"].CustomSecurityGroupIDs)
 	assert.Len(t, node.Status.NetworkInterfaces["eni-3"].IPv4, 2)
 	assert.Nil(t, node.Status.NetworkInterfaces["eni-4"])
 }
@@ -846,6 +851,58 @@ func Test_assignIPFromLocalPool(t *testing.T) {
 
 			},
 		},
+		{
+			name: "pod with custom security groups",
+			args: args{
+				log: logr.Discard(),
+				podsMapper: map[string]*PodRequest{
+					"pod-1": {
+						RequireIPv4: true,
+					},
+					"pod-2": {
+						RequireIPv4:      true,
+						SecurityGroupIDs: []string{"sg-2", "sg-1"},
+					},
+					"pod-3": {
+						RequireIPv4:      true,
+						SecurityGroupIDs: []string{"sg-3"},
+					},
+				},
+				ipv4Map: map[string]*EniIP{
+					"192.168.0.1": {
+						IP: &networkv1beta1.IP{
+							IP:     "192.168.0.1",
+							Status: networkv1beta1.IPStatusValid,
+						},
+						NetworkInterface: &networkv1beta1.NetworkInterface{
+							ID:                     "eni-1",
+							Status:                 "InUse",
+							CustomSecurityGroupIDs: []string{"sg-1", "sg-2"},
+						},
+					},
+					"192.168.0.2": {
+						IP: &networkv1beta1.IP{
+							IP:     "192.168.0.2",
+							Status: networkv1beta1.IPStatusValid,
+						},
+						NetworkInterface: &networkv1beta1.NetworkInterface{
+							ID:     "eni-2",
+							Status: "InUse",
+						},
+					},
+				},
+				ipv6Map: map[string]*EniIP{},
+			},
+			checkResultFunc: func(t *testing.T, got map[string]*PodRequest) {
+				assert.Len(t, got, 1)
+				assert.Contains(t, got, "pod-3")
+			},
+			checkPodsMapFunc: func(t *testing.T, got map[string]*PodRequest) {
+				assert.Equal(t, "eni-2", got["pod-1"].ipv4Ref.NetworkInterface.ID)
+				assert.Equal(t, "eni-1", got["pod-2"].ipv4Ref.NetworkInterface.ID)
+				assert.Nil(t, got["pod-3"].ipv4Ref)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1575,6 +1632,81 @@ func Test_assignEniWithOptions(t *testing.T) {
 				assert.Equal(t, 2, options[1].addIPv6N)
 			},
 		},
+		{
+			name: "test ipv6 only trunk",
+			args: args{
+				node: &networkv1beta1.Node{
+					Spec: networkv1beta1.NodeSpec{
+						NodeCap: networkv1beta1.NodeCap{
+							IPv4PerAdapter: 10,
+							IPv6PerAdapter: 10,
+						},
+						ENISpec: &networkv1beta1.ENISpec{
+							VSwitchOptions: []string{"vsw-1"},
+							EnableIPv6:     true,
+							EnableTrunk:    true,
+						},
+					},
+				},
+				toAdd: 0,
+				options: []*eniOptions{
+					{
+						eniTypeKey: trunkKey,
+					},
+				},
+				filterFunc: func(option *eniOptions) bool {
+					return true
+				},
+			},
+			checkResult: func(t *testing.T, options []*eniOptions) {
+				assert.Equal(t, 0, options[0].addIPv4N)
+				assert.Equal(t, 1, options[0].addIPv6N)
+			},
+		},
+		{
+			name: "test ipv6 only exist eni",
+			args: args{
+				node: &networkv1beta1.Node{
+					Spec: networkv1beta1.NodeSpec{
+						NodeCap: networkv1beta1.NodeCap{
+							IPv4PerAdapter: 10,
+							IPv6PerAdapter: 10,
+						},
+						ENISpec: &networkv1beta1.ENISpec{
+							VSwitchOptions: []string{"vsw-1"},
+							EnableIPv6:     true,
+						},
+					},
+				},
+				toAdd: 3,
+				options: []*eniOptions{
+					{
+						eniTypeKey: secondaryKey,
+						eniRef: &networkv1beta1.NetworkInterface{
+							ID: "eni-1",
+							IPv4: map[string]*networkv1beta1.IP{
+								"192.168.0.1": {IP: "192.168.0.1", Primary: true, Status: networkv1beta1.IPStatusValid},
+							},
+							IPv6: map[string]*networkv1beta1.IP{
+								"fd00::1": {IP: "fd00::1", Status: networkv1beta1.IPStatusValid},
+							},
+						},
+					},
+					{
+						eniTypeKey: secondaryKey,
+					},
+				},
+				filterFunc: func(option *eniOptions) bool {
+					return true
+				},
+			},
+			checkResult: func(t *testing.T, options []*eniOptions) {
+				assert.Equal(t, 0, options[0].addIPv4N)
+				assert.Equal(t, 2, options[0].addIPv6N)
+				assert.Equal(t, 0, options[1].addIPv4N)
+				assert.Equal(t, 0, options[1].addIPv6N)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2196,7 +2328,7 @@ var _ = Describe("Node Controller", func() {
 
 	Context("Check update node status", func() {
 		It("Empty eni, should report InsufficientIP", func() {
-			updateNodeCondition(ctx, k8sClient, "foo", nil)
+			updateNodeCondition(ctx, k8sClient, &networkv1beta1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
 
 			node := &corev1.Node{}
 			err := k8sClient.Get(ctx, types.NamespacedName{Name: "foo"}, node)
@@ -2227,7 +2359,7 @@ var _ = Describe("Node Controller", func() {
 			err = k8sClient.Status().Update(ctx, node)
 			Expect(err).NotTo(HaveOccurred())
 
-			updateNodeCondition(ctx, k8sClient, "foo", nil)
+			updateNodeCondition(ctx, k8sClient, &networkv1beta1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
 
 			node = &corev1.Node{}
 			err = k8sClient.Get(ctx, types.NamespacedName{Name: "foo"}, node)
@@ -2243,7 +2375,7 @@ var _ = Describe("Node Controller", func() {
 		})
 
 		It("Empty eni should be SufficientIP", func() {
-			updateNodeCondition(ctx, k8sClient, "foo", []*eniOptions{
+			updateNodeCondition(ctx, k8sClient, &networkv1beta1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, []*eniOptions{
 				{
 					eniTypeKey: eniTypeKey{},
 					eniRef:     nil,
@@ -2267,3 +2399,9 @@ var _ = Describe("Node Controller", func() {
 		})
 	})
 })
+
+func Test_securityGroupsKey(t *testing.T) {
+	assert.Equal(t, "sg-1,sg-2", securityGroupsKey([]string{"sg-2", "sg-1"}))
+	assert.Equal(t, securityGroupsKey([]string{"sg-1", "sg-2"}), securityGroupsKey([]string{"sg-2", "sg-1"}))
+	assert.Equal(t, "", securityGroupsKey(nil))
+}
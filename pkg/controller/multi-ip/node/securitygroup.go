@@ -0,0 +1,95 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// securityGroupRetryPeriod is the delay before the next try, when the rollout is rate limited
+const securityGroupRetryPeriod = 10 * time.Second
+
+// syncSecurityGroups apply the security groups in spec (or the custom one) to the enis already attached.
+// ENIs are updated one by one under the rate limiter, so the change is rolled out gradually across the cluster.
+// Return true if some eni is still pending.
+func (n *ReconcileNode) syncSecurityGroups(ctx context.Context, node *networkv1beta1.Node) bool {
+	if n.sgLimiter == nil {
+		return false
+	}
+
+	ctx, span := n.tracer.Start(ctx, "syncSecurityGroups")
+	defer span.End()
+
+	l := logf.FromContext(ctx).WithName("syncSecurityGroups")
+
+	pending := false
+
+	for _, eni := range sortNetworkInterface(node) {
+		if eni.Status != aliyunClient.ENIStatusInUse {
+			continue
+		}
+		// eni dedicated to pods keeps its own security groups
+		expected := node.Spec.ENISpec.SecurityGroupIDs
+		if len(eni.CustomSecurityGroupIDs) > 0 {
+			expected = eni.CustomSecurityGroupIDs
+		}
+		if len(expected) == 0 {
+			continue
+		}
+		if common.SecurityGroupsEqual(eni.SecurityGroupIDs, expected) {
+			if _, ok := eni.Conditions[ConditionSecurityGroupMismatch]; ok {
+				delete(eni.Conditions, ConditionSecurityGroupMismatch)
+				MetaCtx(ctx).StatusChanged.Store(true)
+			}
+			continue
+		}
+
+		if !n.sgLimiter.Allow() {
+			pending = true
+			setCondition(eni, ConditionSecurityGroupMismatch, fmt.Sprintf("waiting for update, current %v, expected %v", eni.SecurityGroupIDs, expected))
+			continue
+		}
+
+		err := n.aliyun.ModifyNetworkInterfaceAttribute(ctx, eni.ID, expected)
+		if err != nil {
+			l.Error(err, "failed to update security groups", "eni", eni.ID)
+			pending = true
+			str := err.Error()
+			setCondition(eni, ConditionSecurityGroupMismatch, str[:min(len(str), 256)])
+			continue
+		}
+
+		l.Info("security groups updated", "eni", eni.ID, "from", eni.SecurityGroupIDs, "to", expected)
+		n.record.Eventf(node, corev1.EventTypeNormal, types.EventUpdateENISecurityGroupSucceed, "eni %s security groups updated to %v", eni.ID, expected)
+
+		eni.SecurityGroupIDs = append([]string{}, expected...)
+		delete(eni.Conditions, ConditionSecurityGroupMismatch)
+		MetaCtx(ctx).StatusChanged.Store(true)
+	}
+
+	return pending
+}
+
+// setCondition set the condition, ObservedTime is kept if the message is unchanged
+func setCondition(eni *networkv1beta1.NetworkInterface, conditionType, message string) {
+	if eni.Conditions == nil {
+		eni.Conditions = make(map[string]networkv1beta1.Condition)
+	}
+	prev, ok := eni.Conditions[conditionType]
+	if ok && prev.Message == message {
+		return
+	}
+	eni.Conditions[conditionType] = networkv1beta1.Condition{
+		ObservedTime: metav1.Now(),
+		Message:      message,
+	}
+}
@@ -0,0 +1,112 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/tools/record"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+)
+
+func TestReconcileNode_syncSecurityGroups(t *testing.T) {
+	newNode := func() *networkv1beta1.Node {
+		return &networkv1beta1.Node{
+			Spec: networkv1beta1.NodeSpec{
+				ENISpec: &networkv1beta1.ENISpec{
+					SecurityGroupIDs: []string{"sg-1", "sg-2"},
+				},
+			},
+			Status: networkv1beta1.NodeStatus{
+				NetworkInterfaces: map[string]*networkv1beta1.NetworkInterface{
+					"eni-1": {
+						ID:               "eni-1",
+						Status:           aliyunClient.ENIStatusInUse,
+						SecurityGroupIDs: []string{"sg-2", "sg-1"},
+						Conditions: map[string]networkv1beta1.Condition{
+							ConditionSecurityGroupMismatch: {Message: "foo"},
+						},
+					},
+					"eni-2": {
+						ID:               "eni-2",
+						Status:           aliyunClient.ENIStatusInUse,
+						SecurityGroupIDs: []string{"sg-old"},
+					},
+					"eni-3": {
+						ID:               "eni-3",
+						Status:           aliyunClient.ENIStatusDeleting,
+						SecurityGroupIDs: []string{"sg-old"},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		n := &ReconcileNode{
+			aliyun: mocks.NewInterface(t),
+			tracer: trace.NewNoopTracerProvider().Tracer(""),
+		}
+		node := newNode()
+		assert.False(t, n.syncSecurityGroups(MetaIntoCtx(context.TODO()), node))
+		assert.Equal(t, []string{"sg-old"}, node.Status.NetworkInterfaces["eni-2"].SecurityGroupIDs)
+	})
+
+	t.Run("update success", func(t *testing.T) {
+		openAPI := mocks.NewInterface(t)
+		openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-2", []string{"sg-1", "sg-2"}).Return(nil).Once()
+
+		n := &ReconcileNode{
+			aliyun:    openAPI,
+			record:    record.NewFakeRecorder(10),
+			tracer:    trace.NewNoopTracerProvider().Tracer(""),
+			sgLimiter: rate.NewLimiter(rate.Inf, 1),
+		}
+		ctx := MetaIntoCtx(context.TODO())
+		node := newNode()
+		assert.False(t, n.syncSecurityGroups(ctx, node))
+		assert.True(t, MetaCtx(ctx).StatusChanged.Load())
+
+		assert.Equal(t, []string{"sg-1", "sg-2"}, node.Status.NetworkInterfaces["eni-2"].SecurityGroupIDs)
+		assert.Equal(t, []string{"sg-old"}, node.Status.NetworkInterfaces["eni-3"].SecurityGroupIDs)
+		assert.NotContains(t, node.Status.NetworkInterfaces["eni-1"].Conditions, ConditionSecurityGroupMismatch)
+	})
+
+	t.Run("update failed", func(t *testing.T) {
+		openAPI := mocks.NewInterface(t)
+		openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-2", []string{"sg-1", "sg-2"}).Return(errors.New("forbidden")).Once()
+
+		n := &ReconcileNode{
+			aliyun:    openAPI,
+			record:    record.NewFakeRecorder(10),
+			tracer:    trace.NewNoopTracerProvider().Tracer(""),
+			sgLimiter: rate.NewLimiter(rate.Inf, 1),
+		}
+		node := newNode()
+		assert.True(t, n.syncSecurityGroups(MetaIntoCtx(context.TODO()), node))
+
+		assert.Equal(t, []string{"sg-old"}, node.Status.NetworkInterfaces["eni-2"].SecurityGroupIDs)
+		assert.Equal(t, "forbidden", node.Status.NetworkInterfaces["eni-2"].Conditions[ConditionSecurityGroupMismatch].Message)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		n := &ReconcileNode{
+			aliyun:    mocks.NewInterface(t),
+			record:    record.NewFakeRecorder(10),
+			tracer:    trace.NewNoopTracerProvider().Tracer(""),
+			sgLimiter: rate.NewLimiter(0, 0),
+		}
+		node := newNode()
+		assert.True(t, n.syncSecurityGroups(MetaIntoCtx(context.TODO()), node))
+
+		assert.Equal(t, []string{"sg-old"}, node.Status.NetworkInterfaces["eni-2"].SecurityGroupIDs)
+		assert.Contains(t, node.Status.NetworkInterfaces["eni-2"].Conditions, ConditionSecurityGroupMismatch)
+	})
+}
@@ -16,6 +16,7 @@ import (
 
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	"github.com/AliyunContainerService/terway/pkg/controller/multi-ip/node"
+	"github.com/AliyunContainerService/terway/pkg/controller/shard"
 )
 
 const ControllerName = "multi-ip-pod"
@@ -26,12 +27,14 @@ func init() {
 		return ctrl.NewControllerManagedBy(mgr).
 			WithOptions(controller.Options{
 				MaxConcurrentReconciles: ctrlCtx.Config.MultiIPPodMaxConcurrent,
+				NeedLeaderElection:      ctrlCtx.Shard.ControllerNeedLeaderElection(),
 			}).
 			For(&corev1.Pod{}, builder.WithPredicates(&predicateForPodEvent{})).
 			Complete(&ReconcilePod{
 				client: mgr.GetClient(),
 				scheme: mgr.GetScheme(),
 				record: mgr.GetEventRecorderFor(ControllerName),
+				shard:  ctrlCtx.Shard,
 			})
 	}, false)
 }
@@ -45,6 +48,9 @@ type ReconcilePod struct {
 	scheme *runtime.Scheme
 
 	record record.EventRecorder
+
+	// shard decide the nodes handled by this replica, nil if sharding is disabled
+	shard *shard.Shard
 }
 
 func (r *ReconcilePod) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -62,6 +68,9 @@ func (r *ReconcilePod) Reconcile(ctx context.Context, request reconcile.Request)
 	if !needProcess(pod) {
 		return reconcile.Result{}, nil
 	}
+	if !r.shard.Owns(pod.Spec.NodeName) {
+		return reconcile.Result{}, nil
+	}
 
 	node.Notify(ctx, pod.Spec.NodeName)
 
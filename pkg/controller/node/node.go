@@ -29,6 +29,7 @@ import (
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	"github.com/AliyunContainerService/terway/pkg/controller/common"
 	"github.com/AliyunContainerService/terway/pkg/controller/multi-ip/node"
+	"github.com/AliyunContainerService/terway/pkg/controller/shard"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/controlplane"
 )
@@ -54,6 +55,7 @@ func init() {
 		return ctrl.NewControllerManagedBy(mgr).
 			WithOptions(controller.Options{
 				MaxConcurrentReconciles: controlplane.GetConfig().NodeMaxConcurrent,
+				NeedLeaderElection:      ctrlCtx.Shard.ControllerNeedLeaderElection(),
 				LogConstructor: func(request *reconcile.Request) logr.Logger {
 					log := mgr.GetLogger()
 					if request != nil {
@@ -70,6 +72,7 @@ func init() {
 				scheme: mgr.GetScheme(),
 				record: mgr.GetEventRecorderFor(ControllerName),
 				aliyun: ctrlCtx.AliyunClient,
+				shard:  ctrlCtx.Shard,
 			})
 	}, false)
 }
@@ -82,9 +85,16 @@ type ReconcileNode struct {
 
 	aliyun register.Interface
 	record record.EventRecorder
+
+	// shard decide the nodes handled by this replica, nil if sharding is disabled
+	shard *shard.Shard
 }
 
 func (r *ReconcileNode) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if !r.shard.Owns(request.Name) {
+		return reconcile.Result{}, nil
+	}
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Controller: ControllerName, Node: request.Name})
 	defer node.Notify(ctx, request.Name)
 
 	k8sNode := &corev1.Node{}
@@ -162,6 +172,10 @@ func (r *ReconcileNode) createOrUpdate(ctx context.Context, k8sNode *corev1.Node
 		if err != nil {
 			return err
 		}
+		// the node cap is persisted, so the limits from the catalogue without the trunk or erdma quota is not used
+		if limit.TrunkUnknown || limit.ERdmaUnknown {
+			return fmt.Errorf("trunk or erdma quota of instance type %s is unknown", nodeInfo.InstanceType)
+		}
 
 		node.Spec.NodeCap = networkv1beta1.NodeCap{
 			InstanceBandwidthTx:   limit.InstanceBandwidthTx,
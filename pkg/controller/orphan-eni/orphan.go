@@ -0,0 +1,222 @@
+// Package orphaneni find the enis created by terway whose owner is gone
+package orphaneni
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	register "github.com/AliyunContainerService/terway/pkg/controller"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+const ControllerName = "orphan-eni"
+
+const (
+	ActionReport = "report"
+	ActionDelete = "delete"
+)
+
+var log = ctrl.Log.WithName(ControllerName)
+
+// OrphanENICount the orphan enis found in the last sync, by eni status
+var OrphanENICount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "orphan_eni_count",
+		Help: "controlplane orphan eni count",
+	},
+	[]string{"status"},
+)
+
+func init() {
+	register.Add(ControllerName, func(mgr manager.Manager, ctrlCtx *register.ControllerCtx) error {
+		cfg := ctrlCtx.Config
+		grace, err := time.ParseDuration(cfg.OrphanENIGracePeriod)
+		if err != nil {
+			return err
+		}
+		period, err := time.ParseDuration(cfg.OrphanENISyncPeriod)
+		if err != nil {
+			return err
+		}
+		metrics.Registry.MustRegister(OrphanENICount)
+
+		return mgr.Add(NewHunter(mgr.GetClient(), ctrlCtx.AliyunClient, Options{
+			VPCID:       cfg.VPCID,
+			ClusterID:   cfg.ClusterID,
+			Action:      cfg.OrphanENIAction,
+			GracePeriod: grace,
+			SyncPeriod:  period,
+		}))
+	}, false)
+}
+
+// API is the openAPI used by the hunter
+type API interface {
+	DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*aliyunClient.NetworkInterface, error)
+	DeleteNetworkInterface(ctx context.Context, eniID string) error
+}
+
+type Options struct {
+	VPCID     string
+	ClusterID string
+	// Action is report or delete
+	Action      string
+	GracePeriod time.Duration
+	SyncPeriod  time.Duration
+}
+
+// Hunter find the enis tagged by this cluster, but the Node or PodENI in the tags is not exist.
+// Enis without the cluster tag or the ownership tags are never touched.
+type Hunter struct {
+	client client.Client
+	api    API
+	opts   Options
+
+	// firstSeen is the time the eni is found orphan, reset if the owner comes back
+	firstSeen map[string]time.Time
+	now       func() time.Time
+}
+
+func NewHunter(c client.Client, api API, opts Options) *Hunter {
+	return &Hunter{
+		client:    c,
+		api:       api,
+		opts:      opts,
+		firstSeen: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+func (h *Hunter) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := h.sync(ctx)
+		if err != nil {
+			log.Error(err, "error hunt orphan enis")
+		}
+	}, h.opts.SyncPeriod)
+	return nil
+}
+
+// NeedLeaderElection need election
+func (h *Hunter) NeedLeaderElection() bool {
+	return true
+}
+
+func (h *Hunter) sync(ctx context.Context) error {
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Controller: ControllerName})
+
+	enis, err := h.api.DescribeNetworkInterface(ctx, h.opts.VPCID, nil, "", "", "", map[string]string{
+		types.TagKeyClusterID: h.opts.ClusterID,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := h.now()
+	seen := make(map[string]struct{}, len(enis))
+	count := map[string]int{}
+	for _, eni := range enis {
+		owner, err := h.owner(ctx, eni)
+		if err != nil {
+			return err
+		}
+		if owner == "" {
+			continue
+		}
+		seen[eni.NetworkInterfaceID] = struct{}{}
+
+		first, ok := h.firstSeen[eni.NetworkInterfaceID]
+		if !ok {
+			first = now
+			h.firstSeen[eni.NetworkInterfaceID] = now
+		}
+		if now.Sub(first) < h.opts.GracePeriod || now.Sub(creationTime(eni)) < h.opts.GracePeriod {
+			continue
+		}
+
+		count[eni.Status]++
+		l := log.WithValues("eni", eni.NetworkInterfaceID, "status", eni.Status, "owner", owner, "since", first)
+		if h.opts.Action != ActionDelete || eni.Status != aliyunClient.ENIStatusAvailable {
+			l.Info("orphan eni found")
+			continue
+		}
+		err = h.api.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+		if err != nil {
+			l.Error(err, "error delete orphan eni")
+			continue
+		}
+		l.Info("orphan eni deleted")
+		delete(h.firstSeen, eni.NetworkInterfaceID)
+	}
+
+	// the owner is back, or the eni is gone
+	for id := range h.firstSeen {
+		if _, ok := seen[id]; !ok {
+			delete(h.firstSeen, id)
+		}
+	}
+
+	OrphanENICount.Reset()
+	for status, n := range count {
+		OrphanENICount.WithLabelValues(status).Set(float64(n))
+	}
+	return nil
+}
+
+// owner return the missing owner of the eni, empty if the owner exists or the eni is not owned by a Node or PodENI
+func (h *Hunter) owner(ctx context.Context, eni *aliyunClient.NetworkInterface) (string, error) {
+	tags := make(map[string]string, len(eni.Tags))
+	for _, tag := range eni.Tags {
+		tags[tag.TagKey] = tag.TagValue
+	}
+	if _, ok := tags[enipool.TagENIPool]; ok {
+		return "", nil
+	}
+
+	var (
+		kind string
+		obj  client.Object
+		key  client.ObjectKey
+	)
+	switch {
+	case tags[types.TagKubernetesPodName] != "":
+		kind, obj = "PodENI", &v1beta1.PodENI{}
+		key = client.ObjectKey{Namespace: tags[types.TagKubernetesPodNamespace], Name: tags[types.TagKubernetesPodName]}
+	case tags[types.TagK8SNodeName] != "":
+		kind, obj = "Node", &corev1.Node{}
+		key = client.ObjectKey{Name: tags[types.TagK8SNodeName]}
+	default:
+		return "", nil
+	}
+
+	err := h.client.Get(ctx, key, obj)
+	if err == nil {
+		return "", nil
+	}
+	if k8sErr.IsNotFound(err) {
+		return fmt.Sprintf("%s %s", kind, key), nil
+	}
+	return "", err
+}
+
+// creationTime is the time reported by the openAPI, the time is not tagged so the create request is the same on retry
+func creationTime(eni *aliyunClient.NetworkInterface) time.Time {
+	t, err := time.Parse(time.RFC3339, eni.CreationTime)
+	if err == nil {
+		return t
+	}
+	return time.Time{}
+}
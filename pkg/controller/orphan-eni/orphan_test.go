@@ -0,0 +1,107 @@
+package orphaneni
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	return scheme
+}
+
+func newENI(id, status string, tags map[string]string) *aliyunClient.NetworkInterface {
+	eni := &aliyunClient.NetworkInterface{
+		NetworkInterfaceID: id,
+		Status:             status,
+		CreationTime:       "2024-01-01T00:00:00Z",
+	}
+	for k, v := range tags {
+		eni.Tags = append(eni.Tags, ecs.Tag{TagKey: k, TagValue: v})
+	}
+	return eni
+}
+
+func TestHunter_Sync(t *testing.T) {
+	enis := []*aliyunClient.NetworkInterface{
+		// node is gone
+		newENI("eni-node-gone", aliyunClient.ENIStatusAvailable, map[string]string{types.TagK8SNodeName: "node-gone"}),
+		// node exists
+		newENI("eni-node", aliyunClient.ENIStatusInUse, map[string]string{types.TagK8SNodeName: "node-1"}),
+		// podENI is gone, but still attached
+		newENI("eni-pod-gone", aliyunClient.ENIStatusInUse, map[string]string{types.TagKubernetesPodNamespace: "default", types.TagKubernetesPodName: "gone"}),
+		// podENI exists
+		newENI("eni-pod", aliyunClient.ENIStatusAvailable, map[string]string{types.TagKubernetesPodNamespace: "default", types.TagKubernetesPodName: "foo"}),
+		// kept by the pool
+		newENI("eni-pool", aliyunClient.ENIStatusAvailable, map[string]string{enipool.TagENIPool: "true", types.TagK8SNodeName: "node-gone"}),
+		// no ownership tags
+		newENI("eni-legacy", aliyunClient.ENIStatusAvailable, nil),
+	}
+
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DescribeNetworkInterface", mock.Anything, "vpc-1", mock.Anything, "", "", "", map[string]string{types.TagKeyClusterID: "c-1"}).Return(enis, nil)
+	openAPI.On("DeleteNetworkInterface", mock.Anything, "eni-node-gone").Return(nil).Once()
+
+	c := fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1beta1.PodENI{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}},
+	).Build()
+
+	h := NewHunter(c, openAPI, Options{VPCID: "vpc-1", ClusterID: "c-1", Action: ActionDelete, GracePeriod: time.Hour})
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	// within the grace period
+	assert.NoError(t, h.sync(context.Background()))
+	assert.Len(t, h.firstSeen, 2)
+	openAPI.AssertNotCalled(t, "DeleteNetworkInterface", mock.Anything, mock.Anything)
+
+	// only the available one is deleted
+	now = now.Add(2 * time.Hour)
+	assert.NoError(t, h.sync(context.Background()))
+	assert.Len(t, h.firstSeen, 1)
+	assert.Contains(t, h.firstSeen, "eni-pod-gone")
+}
+
+func TestHunter_SyncReport(t *testing.T) {
+	enis := []*aliyunClient.NetworkInterface{
+		newENI("eni-node-gone", aliyunClient.ENIStatusAvailable, map[string]string{types.TagK8SNodeName: "node-gone"}),
+	}
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("DescribeNetworkInterface", mock.Anything, "vpc-1", mock.Anything, "", "", "", mock.Anything).Return(enis, nil)
+
+	h := NewHunter(fake.NewClientBuilder().WithScheme(newScheme()).Build(), openAPI, Options{VPCID: "vpc-1", ClusterID: "c-1", Action: ActionReport, GracePeriod: time.Hour})
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	assert.NoError(t, h.sync(context.Background()))
+	now = now.Add(2 * time.Hour)
+	assert.NoError(t, h.sync(context.Background()))
+
+	// reported, not deleted
+	assert.Contains(t, h.firstSeen, "eni-node-gone")
+}
+
+func TestCreationTime(t *testing.T) {
+	eni := newENI("eni-1", "", nil)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), creationTime(eni))
+
+	eni.CreationTime = ""
+	assert.True(t, creationTime(eni).IsZero())
+}
@@ -21,9 +21,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	k8sErr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -48,6 +50,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/controlplane"
@@ -63,6 +66,7 @@ func init() {
 		ctrlCtx.RegisterResource = append(ctrlCtx.RegisterResource, &v1beta1.PodENI{})
 
 		r := NewReconcilePod(mgr, ctrlCtx.AliyunClient)
+		r.eniPool = ctrlCtx.ENIPool
 		c, err := controller.NewUnmanaged(controllerName, mgr, controller.Options{
 			Reconciler:              r,
 			MaxConcurrentReconciles: controlplane.GetConfig().PodENIMaxConcurrent,
@@ -90,8 +94,10 @@ func init() {
 }
 
 var (
-	leakedENICheckPeriod = 10 * time.Minute
-	podENICheckPeriod    = 1 * time.Minute
+	leakedENICheckPeriod    = 10 * time.Minute
+	podENICheckPeriod       = 1 * time.Minute
+	securityGroupSyncPeriod = 5 * time.Minute
+	memberENIPoolSyncPeriod = 1 * time.Minute
 )
 
 // ReconcilePodENI implements reconcile.Reconciler
@@ -109,6 +115,13 @@ type ReconcilePodENI struct {
 	trunkMode bool // use trunk mode or secondary eni mode
 	// deprecated remove after we deprecated eniOnly
 	crdMode bool
+
+	// sgLimiter limit the rate of updating security groups on exist enis, nil to disable
+	sgLimiter    *rate.Limiter
+	sgSyncPeriod time.Duration
+
+	// eniPool is the member eni pool, nil if disabled
+	eniPool *enipool.Pool
 }
 
 type Wrapper struct {
@@ -145,6 +158,15 @@ func NewReconcilePod(mgr manager.Manager, aliyunClient register.Interface) *Reco
 		trunkMode: *controlplane.GetConfig().EnableTrunk,
 		crdMode:   controlplane.GetConfig().IPAMType == types.IPAMTypeCRD,
 	}
+
+	if !controlplane.GetConfig().DisableSecurityGroupSync {
+		period, err := time.ParseDuration(controlplane.GetConfig().SecurityGroupSyncPeriod)
+		if err != nil || period <= 0 {
+			period = securityGroupSyncPeriod
+		}
+		r.sgSyncPeriod = period
+		r.sgLimiter = rate.NewLimiter(rate.Limit(controlplane.GetConfig().SecurityGroupSyncQPS), 1)
+	}
 	return r
 }
 
@@ -152,6 +174,7 @@ func NewReconcilePod(mgr manager.Manager, aliyunClient register.Interface) *Reco
 // podENI create -> do attach to node and update status
 // podENI delete -> detach podENI and delete
 func (m *ReconcilePodENI) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Controller: controllerName, Pod: request.String()})
 	l := log.FromContext(ctx)
 	l.Info("Reconcile")
 	start := time.Now()
@@ -213,6 +236,7 @@ func (m *ReconcilePodENI) NeedLeaderElection() bool {
 // gc will handle following circumstances
 // 1. cr podENI is leaked
 // 2. release fixed ip resource by strategy
+// 3. security groups changed for exist eni
 func (m *ReconcilePodENI) gc(ctx context.Context) {
 	go wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
 		m.gcSecondaryENI(ctx)
@@ -221,6 +245,18 @@ func (m *ReconcilePodENI) gc(ctx context.Context) {
 	}, leakedENICheckPeriod, 1.1, true)
 
 	go wait.JitterUntilWithContext(ctx, m.gcCRPodENIs, podENICheckPeriod, 1.1, true)
+
+	if m.sgLimiter != nil {
+		go wait.JitterUntilWithContext(ctx, m.syncSecurityGroups, m.sgSyncPeriod, 1.1, true)
+	}
+
+	if m.eniPool != nil {
+		period, err := time.ParseDuration(controlplane.GetConfig().MemberENIPoolSyncPeriod)
+		if err != nil || period <= 0 {
+			period = memberENIPoolSyncPeriod
+		}
+		go m.eniPool.Run(ctx, period)
+	}
 }
 
 func (m *ReconcilePodENI) podENICreate(ctx context.Context, namespacedName client.ObjectKey, podENI *v1beta1.PodENI) (result reconcile.Result, err error) {
@@ -447,6 +483,10 @@ func (m *ReconcilePodENI) gcENIs(ctx context.Context, enis []*aliyunClient.Netwo
 		if !m.eniFilter(eni, tagFilter) {
 			continue
 		}
+		// eni is kept by the pool
+		if m.eniPool != nil && m.eniPool.Owns(eni) {
+			continue
+		}
 		t, err := time.Parse(layout, eni.CreationTime)
 		if err != nil {
 			l.Error(err, "error parse eni create time")
@@ -639,6 +679,41 @@ func (m *ReconcilePodENI) detach(ctx context.Context, podENI *v1beta1.PodENI) (r
 	return reconcile.Result{}, err
 }
 
+// stepRecorder persist the eni step into podENI status, so the operation can be resumed after restart
+type stepRecorder struct {
+	lock   sync.Mutex
+	client client.Client
+	podENI *v1beta1.PodENI
+}
+
+func (r *stepRecorder) get(id string) v1beta1.ENIInfo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	info, ok := r.podENI.Status.ENIInfos[id]
+	if !ok {
+		return v1beta1.ENIInfo{ID: id}
+	}
+	return info
+}
+
+func (r *stepRecorder) record(ctx context.Context, info v1beta1.ENIInfo) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.podENI.Status.ENIInfos == nil {
+		r.podENI.Status.ENIInfos = make(map[string]v1beta1.ENIInfo)
+	}
+	r.podENI.Status.ENIInfos[info.ID] = info
+	err := r.client.Status().Update(ctx, r.podENI)
+	if err != nil {
+		return fmt.Errorf("error record eni %s step %s, %w", info.ID, info.Step, err)
+	}
+	return nil
+}
+
+// attachENI attach all enis to the instance.
+// Each step is recorded, an eni is attached only once even the controller is restarted during attaching.
 func (m *ReconcilePodENI) attachENI(ctx context.Context, podENI *v1beta1.PodENI) error {
 	var err error
 	if podENI.Status.InstanceID == "" {
@@ -655,26 +730,49 @@ func (m *ReconcilePodENI) attachENI(ctx context.Context, podENI *v1beta1.PodENI)
 		}
 	}()
 
-	ch := make(chan *v1beta1.ENIInfo)
-	done := make(chan struct{})
-	go func() {
-		// override the config
-		podENI.Status.ENIInfos = make(map[string]v1beta1.ENIInfo)
-		for info := range ch {
-			podENI.Status.ENIInfos[info.ID] = *info
-		}
-		done <- struct{}{}
-	}()
+	recorder := &stepRecorder{client: m.client, podENI: podENI}
+	// podENI is updated by the recorder, take a copy before attaching
+	allocs := podENI.DeepCopy().Spec.Allocations
+	instanceID, trunkENIID := podENI.Status.InstanceID, podENI.Status.TrunkENIID
 
 	g, _ := errgroup.WithContext(context.Background())
-	for i := range podENI.Spec.Allocations {
+	for i := range allocs {
 		ii := i
 		g.Go(func() error {
-			alloc := podENI.Spec.Allocations[ii]
+			alloc := allocs[ii]
 			ctx := common.WithCtx(ctx, &alloc)
-			err := m.aliyun.AttachNetworkInterface(ctx, alloc.ENI.ID, podENI.Status.InstanceID, podENI.Status.TrunkENIID)
-			if err != nil {
-				return err
+
+			info := recorder.get(alloc.ENI.ID)
+			switch info.Step {
+			case v1beta1.ENIStepAttached:
+				if info.Status == v1beta1.ENIStatusBind {
+					return nil
+				}
+			case v1beta1.ENIStepAttaching:
+				// attach is requested before, only re-attach if the eni is still available
+				enis, err := m.aliyun.DescribeNetworkInterface(ctx, "", []string{alloc.ENI.ID}, "", "", "", nil)
+				if err != nil {
+					return err
+				}
+				if len(enis) == 0 {
+					return fmt.Errorf("eni %s not found", alloc.ENI.ID)
+				}
+				if enis[0].Status == aliyunClient.ENIStatusAvailable {
+					err = m.aliyun.AttachNetworkInterface(ctx, alloc.ENI.ID, instanceID, trunkENIID)
+					if err != nil {
+						return err
+					}
+				}
+			default:
+				info.Step = v1beta1.ENIStepAttaching
+				err := recorder.record(ctx, info)
+				if err != nil {
+					return err
+				}
+				err = m.aliyun.AttachNetworkInterface(ctx, alloc.ENI.ID, instanceID, trunkENIID)
+				if err != nil {
+					return err
+				}
 			}
 
 			eni, err := m.aliyun.WaitForNetworkInterface(ctx, alloc.ENI.ID, aliyunClient.ENIStatusInUse, backoff.Backoff(backoff.WaitENIStatus), false)
@@ -682,21 +780,22 @@ func (m *ReconcilePodENI) attachENI(ctx context.Context, podENI *v1beta1.PodENI)
 				return err
 			}
 
-			ch <- &v1beta1.ENIInfo{
-				ID:     eni.NetworkInterfaceID,
-				Type:   v1beta1.ENIType(eni.Type),
-				Vid:    eni.DeviceIndex,
-				Status: v1beta1.ENIStatusBind,
-			}
-			return nil
+			return recorder.record(ctx, v1beta1.ENIInfo{
+				ID:          eni.NetworkInterfaceID,
+				Type:        v1beta1.ENIType(eni.Type),
+				Vid:         eni.DeviceIndex,
+				Status:      v1beta1.ENIStatusBind,
+				Step:        v1beta1.ENIStepAttached,
+				ClientToken: info.ClientToken,
+			})
 		})
 	}
 	err = g.Wait()
-	close(ch)
-	<-done
 	return err
 }
 
+// detachMemberENI detach all enis from the instance.
+// Each step is recorded, so the detaching can be resumed after restart.
 func (m *ReconcilePodENI) detachMemberENI(ctx context.Context, podENI *v1beta1.PodENI) error {
 	var err error
 	if podENI.Status.Phase == v1beta1.ENIPhaseUnbind {
@@ -710,43 +809,73 @@ func (m *ReconcilePodENI) detachMemberENI(ctx context.Context, podENI *v1beta1.P
 			m.record.Eventf(podENI, corev1.EventTypeNormal, types.EventDetachENISucceed, fmt.Sprintf("detach eni %s", strings.Join(allocIDs(podENI), ",")))
 		}
 	}()
+
+	recorder := &stepRecorder{client: m.client, podENI: podENI}
+
 	for _, alloc := range podENI.Spec.Allocations {
 		ctx := common.WithCtx(ctx, &alloc)
+
+		info := recorder.get(alloc.ENI.ID)
+		if info.Step == v1beta1.ENIStepDetached || info.Status == v1beta1.ENIStatusDeleted {
+			continue
+		}
+
 		instanceID := podENI.Status.InstanceID
 		trunkENIID := podENI.Status.TrunkENIID
-		if podENI.Status.InstanceID == "" {
-			enis, err := m.aliyun.DescribeNetworkInterface(ctx, "", []string{alloc.ENI.ID}, "", "", "", nil)
+		needDetach := true
+		if instanceID == "" || info.Step == v1beta1.ENIStepDetaching {
+			var enis []*aliyunClient.NetworkInterface
+			enis, err = m.aliyun.DescribeNetworkInterface(ctx, "", []string{alloc.ENI.ID}, "", "", "", nil)
 			if err != nil {
 				return err
 			}
-			if len(enis) == 0 {
-				continue
-			}
-			if enis[0].InstanceID == "" {
+			if len(enis) == 0 || enis[0].InstanceID == "" {
+				info.Step = v1beta1.ENIStepDetached
+				info.Status = v1beta1.ENIStatusUnBind
+				err = recorder.record(ctx, info)
+				if err != nil {
+					return err
+				}
 				continue
 			}
 			instanceID = enis[0].InstanceID
 			trunkENIID = enis[0].TrunkNetworkInterfaceID
+			// detach is requested before, wait it finish
+			needDetach = enis[0].Status == aliyunClient.ENIStatusInUse
 		}
 
-		err = m.aliyun.DetachNetworkInterface(ctx, alloc.ENI.ID, instanceID, trunkENIID)
-		if err != nil {
-			return err
+		if needDetach {
+			if info.Step != v1beta1.ENIStepDetaching {
+				info.Step = v1beta1.ENIStepDetaching
+				err = recorder.record(ctx, info)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = m.aliyun.DetachNetworkInterface(ctx, alloc.ENI.ID, instanceID, trunkENIID)
+			if err != nil {
+				return err
+			}
 		}
 
 		_, err = m.aliyun.WaitForNetworkInterface(ctx, alloc.ENI.ID, aliyunClient.ENIStatusAvailable, backoff.Backoff(backoff.WaitENIStatus), true)
-		if err == nil {
-			continue
+		if err != nil && !errors.Is(err, apiErr.ErrNotFound) {
+			return err
 		}
-		if errors.Is(err, apiErr.ErrNotFound) {
-			continue
+
+		info.Step = v1beta1.ENIStepDetached
+		info.Status = v1beta1.ENIStatusUnBind
+		err = recorder.record(ctx, info)
+		if err != nil {
+			return err
 		}
-		return err
 	}
 
 	return nil
 }
 
+// deleteMemberENI delete all enis, the deleted eni is recorded and skipped in next run
 func (m *ReconcilePodENI) deleteMemberENI(ctx context.Context, podENI *v1beta1.PodENI) (err error) {
 	defer func() {
 		if err != nil {
@@ -756,14 +885,26 @@ func (m *ReconcilePodENI) deleteMemberENI(ctx context.Context, podENI *v1beta1.P
 		}
 	}()
 
+	recorder := &stepRecorder{client: m.client, podENI: podENI}
+
 	for _, alloc := range podENI.Spec.Allocations {
 		if alloc.ENI.ID == "" {
 			continue
 		}
+		info := recorder.get(alloc.ENI.ID)
+		if info.Status == v1beta1.ENIStatusDeleted {
+			continue
+		}
 		err = m.aliyun.DeleteNetworkInterface(common.WithCtx(ctx, &alloc), alloc.ENI.ID)
 		if err != nil {
 			return err
 		}
+
+		info.Status = v1beta1.ENIStatusDeleted
+		err = recorder.record(ctx, info)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
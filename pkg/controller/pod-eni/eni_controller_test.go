@@ -0,0 +1,297 @@
+package podeni
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+var errCrash = errors.New("crash")
+
+// crasher fail the n-th step, steps are counted across openAPI calls and status updates
+type crasher struct {
+	lock    sync.Mutex
+	steps   int
+	crashAt int
+}
+
+func (c *crasher) step() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.steps++
+	return c.steps == c.crashAt
+}
+
+// fakeECS keep the eni status, the openAPI call take effect even when crashed
+type fakeECS struct {
+	*mocks.Interface
+	*crasher
+
+	lock     sync.Mutex
+	enis     map[string]*aliyunClient.NetworkInterface
+	attached map[string]int
+	detached map[string]int
+}
+
+func newFakeECS(t *testing.T, c *crasher, enis ...*aliyunClient.NetworkInterface) *fakeECS {
+	f := &fakeECS{
+		Interface: mocks.NewInterface(t),
+		crasher:   c,
+		enis:      make(map[string]*aliyunClient.NetworkInterface),
+		attached:  make(map[string]int),
+		detached:  make(map[string]int),
+	}
+	for _, eni := range enis {
+		f.enis[eni.NetworkInterfaceID] = eni
+	}
+	return f
+}
+
+func (f *fakeECS) AttachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
+	f.lock.Lock()
+	eni, ok := f.enis[eniID]
+	if !ok || eni.Status != aliyunClient.ENIStatusAvailable {
+		f.lock.Unlock()
+		return errors.New("IncorrectStatus")
+	}
+	eni.Status = aliyunClient.ENIStatusInUse
+	eni.InstanceID = instanceID
+	eni.TrunkNetworkInterfaceID = trunkENIID
+	f.attached[eniID]++
+	f.lock.Unlock()
+
+	if f.step() {
+		return errCrash
+	}
+	return nil
+}
+
+func (f *fakeECS) DetachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
+	f.lock.Lock()
+	eni, ok := f.enis[eniID]
+	if !ok || eni.Status != aliyunClient.ENIStatusInUse {
+		f.lock.Unlock()
+		return errors.New("IncorrectStatus")
+	}
+	eni.Status = aliyunClient.ENIStatusAvailable
+	eni.InstanceID = ""
+	eni.TrunkNetworkInterfaceID = ""
+	f.detached[eniID]++
+	f.lock.Unlock()
+
+	if f.step() {
+		return errCrash
+	}
+	return nil
+}
+
+func (f *fakeECS) DeleteNetworkInterface(ctx context.Context, eniID string) error {
+	f.lock.Lock()
+	delete(f.enis, eniID)
+	f.lock.Unlock()
+
+	if f.step() {
+		return errCrash
+	}
+	return nil
+}
+
+func (f *fakeECS) DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*aliyunClient.NetworkInterface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var result []*aliyunClient.NetworkInterface
+	for _, id := range eniID {
+		eni, ok := f.enis[id]
+		if !ok {
+			continue
+		}
+		cp := *eni
+		result = append(result, &cp)
+	}
+	return result, nil
+}
+
+func (f *fakeECS) WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*aliyunClient.NetworkInterface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	eni, ok := f.enis[eniID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if eni.Status != status {
+		return nil, errors.New("status mismatch")
+	}
+	cp := *eni
+	return &cp, nil
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1beta1.AddToScheme(scheme)
+	return scheme
+}
+
+func newClient(c *crasher, objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1beta1.PodENI{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, cl client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				if c.step() {
+					return errCrash
+				}
+				return cl.SubResource(subResourceName).Update(ctx, obj, opts...)
+			},
+		}).Build()
+}
+
+func newTestObjects() (*corev1.Node, *corev1.Pod) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				corev1.LabelTopologyRegion:     "cn-hangzhou",
+				corev1.LabelTopologyZone:       "cn-hangzhou-k",
+				corev1.LabelInstanceTypeStable: "ecs.g7.large",
+			},
+		},
+		Spec: corev1.NodeSpec{ProviderID: "cn-hangzhou.i-1"},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	return node, pod
+}
+
+func newTestPodENI() *v1beta1.PodENI {
+	return &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "pod-1",
+			Namespace:  "default",
+			Finalizers: []string{types.FinalizerPodENI},
+		},
+		Spec: v1beta1.PodENISpec{
+			Allocations: []v1beta1.Allocation{
+				{ENI: v1beta1.ENI{ID: "eni-1"}},
+				{ENI: v1beta1.ENI{ID: "eni-2"}},
+			},
+		},
+	}
+}
+
+func TestReconcilePodENI_AttachResumable(t *testing.T) {
+	key := k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}
+
+	for crashAt := 1; ; crashAt++ {
+		c := &crasher{crashAt: crashAt}
+		node, pod := newTestObjects()
+		podENI := newTestPodENI()
+		podENI.Status.ENIInfos = map[string]v1beta1.ENIInfo{
+			"eni-1": {ID: "eni-1", Step: v1beta1.ENIStepCreated, ClientToken: "token-1"},
+		}
+		openAPI := newFakeECS(t, c,
+			&aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-1", Status: aliyunClient.ENIStatusAvailable},
+			&aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-2", Status: aliyunClient.ENIStatusAvailable},
+		)
+		m := &ReconcilePodENI{
+			client: newClient(c, node, pod, podENI),
+			aliyun: openAPI,
+			record: record.NewFakeRecorder(100),
+		}
+
+		// restart the reconcile until it succeed
+		var err error
+		for i := 0; i < 3; i++ {
+			_, err = m.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			if err == nil {
+				break
+			}
+		}
+		assert.NoError(t, err, "crash at %d", crashAt)
+
+		result := &v1beta1.PodENI{}
+		assert.NoError(t, m.client.Get(context.Background(), key, result))
+		assert.EqualValues(t, v1beta1.ENIPhaseBind, result.Status.Phase, "crash at %d", crashAt)
+		assert.Equal(t, "i-1", result.Status.InstanceID)
+		for _, id := range []string{"eni-1", "eni-2"} {
+			assert.Equal(t, 1, openAPI.attached[id], "crash at %d, eni %s", crashAt, id)
+			assert.Equal(t, v1beta1.ENIStepAttached, result.Status.ENIInfos[id].Step)
+			assert.Equal(t, v1beta1.ENIStatusBind, result.Status.ENIInfos[id].Status)
+		}
+		assert.Equal(t, "token-1", result.Status.ENIInfos["eni-1"].ClientToken)
+
+		if c.steps < crashAt {
+			break
+		}
+	}
+}
+
+func TestReconcilePodENI_DeleteResumable(t *testing.T) {
+	key := k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}
+
+	for crashAt := 1; ; crashAt++ {
+		c := &crasher{crashAt: crashAt}
+		podENI := newTestPodENI()
+		podENI.Status = v1beta1.PodENIStatus{
+			Phase:      v1beta1.ENIPhaseBind,
+			InstanceID: "i-1",
+			ENIInfos: map[string]v1beta1.ENIInfo{
+				"eni-1": {ID: "eni-1", Step: v1beta1.ENIStepAttached, Status: v1beta1.ENIStatusBind},
+				"eni-2": {ID: "eni-2", Step: v1beta1.ENIStepAttached, Status: v1beta1.ENIStatusBind},
+			},
+		}
+		openAPI := newFakeECS(t, c,
+			&aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-1", Status: aliyunClient.ENIStatusInUse, InstanceID: "i-1"},
+			&aliyunClient.NetworkInterface{NetworkInterfaceID: "eni-2", Status: aliyunClient.ENIStatusInUse, InstanceID: "i-1"},
+		)
+		m := &ReconcilePodENI{
+			client: newClient(c, podENI),
+			aliyun: openAPI,
+			record: record.NewFakeRecorder(100),
+		}
+		assert.NoError(t, m.client.Delete(context.Background(), podENI))
+
+		var err error
+		for i := 0; i < 3; i++ {
+			_, err = m.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			if err == nil {
+				break
+			}
+		}
+		assert.NoError(t, err, "crash at %d", crashAt)
+
+		for _, id := range []string{"eni-1", "eni-2"} {
+			assert.Equal(t, 1, openAPI.detached[id], "crash at %d, eni %s", crashAt, id)
+			assert.NotContains(t, openAPI.enis, id)
+		}
+		err = m.client.Get(context.Background(), key, &v1beta1.PodENI{})
+		assert.True(t, client.IgnoreNotFound(err) == nil && err != nil, "crash at %d", crashAt)
+
+		if c.steps < crashAt {
+			break
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podeni
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+// ConditionSecurityGroupMismatch is set when the eni security groups is different from the desired one
+const ConditionSecurityGroupMismatch = "SecurityGroupMismatch"
+
+const eth0 = "eth0"
+
+// syncSecurityGroups apply the latest security groups to the exist enis.
+// The desired security groups come from the PodNetworking, the pod annotation or the eni-config.
+func (m *ReconcilePodENI) syncSecurityGroups(ctx context.Context) {
+	l := ctrl.Log.WithName("sync-security-groups")
+
+	podENIs := &v1beta1.PodENIList{}
+	err := m.client.List(ctx, podENIs)
+	if err != nil {
+		l.Error(err, "error list cr pod enis")
+		return
+	}
+
+	for i := range podENIs.Items {
+		podENI := &podENIs.Items[i]
+		switch podENI.Status.Phase {
+		case v1beta1.ENIPhaseBind, v1beta1.ENIPhaseUnbind:
+		default:
+			// eni is processing, wait next time
+			continue
+		}
+		if !podENI.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		pod := &corev1.Pod{}
+		err = m.client.Get(ctx, k8stypes.NamespacedName{Namespace: podENI.Namespace, Name: podENI.Name}, pod)
+		if err != nil {
+			if !k8sErr.IsNotFound(err) {
+				l.Error(err, "error get pod", "pod", client.ObjectKeyFromObject(podENI).String())
+			}
+			continue
+		}
+
+		expected, err := m.expectSecurityGroups(ctx, pod)
+		if err != nil {
+			l.Error(err, "error get security groups", "pod", client.ObjectKeyFromObject(podENI).String())
+			continue
+		}
+
+		err = m.syncPodENISecurityGroups(ctx, podENI, expected)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			l.Error(err, "error sync security groups", "pod", client.ObjectKeyFromObject(podENI).String())
+		}
+	}
+}
+
+// syncPodENISecurityGroups modify the eni one by one, the rate is limited by sgLimiter.
+// The result is recorded in the spec and the condition of podENI.
+func (m *ReconcilePodENI) syncPodENISecurityGroups(ctx context.Context, podENI *v1beta1.PodENI, expected map[string][]string) error {
+	update := podENI.DeepCopy()
+
+	var errs []error
+	for i := range update.Spec.Allocations {
+		alloc := &update.Spec.Allocations[i]
+		sgs, ok := expected[alloc.Interface]
+		if !ok || len(sgs) == 0 || alloc.ENI.ID == "" {
+			continue
+		}
+		if common.SecurityGroupsEqual(alloc.ENI.SecurityGroupIDs, sgs) {
+			continue
+		}
+
+		err := m.sgLimiter.Wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = m.aliyun.ModifyNetworkInterfaceAttribute(common.WithCtx(ctx, alloc), alloc.ENI.ID, sgs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("eni %s, %w", alloc.ENI.ID, err))
+			continue
+		}
+		m.record.Eventf(podENI, corev1.EventTypeNormal, types.EventUpdateENISecurityGroupSucceed, "eni %s security groups updated to %v", alloc.ENI.ID, sgs)
+
+		alloc.ENI.SecurityGroupIDs = append([]string{}, sgs...)
+	}
+
+	if !equalAllocations(podENI, update) {
+		err := m.client.Patch(ctx, update, client.MergeFrom(podENI))
+		if err != nil {
+			return err
+		}
+	}
+
+	// update conditions
+	_, hadCondition := update.Status.Conditions[ConditionSecurityGroupMismatch]
+	if len(errs) > 0 || hadCondition {
+		statusUpdate := update.DeepCopy()
+		if len(errs) > 0 {
+			str := errs[0].Error()
+			if statusUpdate.Status.Conditions == nil {
+				statusUpdate.Status.Conditions = make(map[string]v1beta1.Condition)
+			}
+			statusUpdate.Status.Conditions[ConditionSecurityGroupMismatch] = v1beta1.Condition{
+				ObservedTime: metav1.Now(),
+				Message:      str[:min(len(str), 256)],
+			}
+			m.record.Eventf(podENI, corev1.EventTypeWarning, types.EventUpdateENISecurityGroupFailed, "%s", str)
+		} else {
+			delete(statusUpdate.Status.Conditions, ConditionSecurityGroupMismatch)
+		}
+
+		err := m.client.Status().Patch(ctx, statusUpdate, client.MergeFrom(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// expectSecurityGroups return the desired security groups, indexed by the interface name.
+// The webhook freezes the security groups into the PodNetworks annotation at admission,
+// so the referenced PodNetworking is read first to pick up its latest security groups.
+func (m *ReconcilePodENI) expectSecurityGroups(ctx context.Context, pod *corev1.Pod) (map[string][]string, error) {
+	podNetworkingName := pod.Annotations[types.PodNetworking]
+	if podNetworkingName != "" {
+		podNetworking := &v1beta1.PodNetworking{}
+		err := m.client.Get(ctx, k8stypes.NamespacedName{Name: podNetworkingName}, podNetworking)
+		if err != nil && !k8sErr.IsNotFound(err) {
+			return nil, err
+		}
+		// pod created from PodNetworking has only eth0, empty security groups fallback to the eni-config as the webhook does.
+		// PodNetworking deleted fallback to the annotation
+		if err == nil {
+			sgs := podNetworking.Spec.SecurityGroupIDs
+			if len(sgs) == 0 {
+				cfg, err := daemon.ConfigFromConfigMap(ctx, m.client, "")
+				if err != nil {
+					return nil, err
+				}
+				sgs = cfg.GetSecurityGroups()
+			}
+			return map[string][]string{eth0: sgs}, nil
+		}
+	}
+
+	anno, err := controlplane.ParsePodNetworksFromAnnotation(pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(anno.PodNetworks) > 0 {
+		result := make(map[string][]string, len(anno.PodNetworks))
+		for _, c := range anno.PodNetworks {
+			ifName := c.Interface
+			if ifName == "" {
+				ifName = eth0
+			}
+			result[ifName] = c.SecurityGroupIDs
+		}
+		return result, nil
+	}
+
+	cfg, err := daemon.ConfigFromConfigMap(ctx, m.client, "")
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]string{eth0: cfg.GetSecurityGroups()}, nil
+}
+
+func equalAllocations(a, b *v1beta1.PodENI) bool {
+	if len(a.Spec.Allocations) != len(b.Spec.Allocations) {
+		return false
+	}
+	for i := range a.Spec.Allocations {
+		if !common.SecurityGroupsEqual(a.Spec.Allocations[i].ENI.SecurityGroupIDs, b.Spec.Allocations[i].ENI.SecurityGroupIDs) {
+			return false
+		}
+	}
+	return true
+}
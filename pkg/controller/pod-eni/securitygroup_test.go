@@ -0,0 +1,171 @@
+package podeni
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func newSGTestPodENI(phase v1beta1.Phase, sgs ...string) *v1beta1.PodENI {
+	return &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1beta1.PodENISpec{
+			Allocations: []v1beta1.Allocation{
+				{ENI: v1beta1.ENI{ID: "eni-1", SecurityGroupIDs: sgs}, Interface: "eth0"},
+			},
+		},
+		Status: v1beta1.PodENIStatus{Phase: phase},
+	}
+}
+
+func newSGTestPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				types.PodNetworks: `{"podNetworks":[{"interface":"eth0","securityGroupIDs":["sg-2","sg-1"]}]}`,
+			},
+		},
+	}
+}
+
+func newSGTestReconciler(openAPI *mocks.Interface, recorder record.EventRecorder, objs ...*v1beta1.PodENI) *ReconcilePodENI {
+	builder := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithStatusSubresource(&v1beta1.PodENI{}).
+		WithObjects(newSGTestPod())
+	for _, obj := range objs {
+		builder = builder.WithObjects(obj)
+	}
+	return &ReconcilePodENI{
+		client:    builder.Build(),
+		aliyun:    openAPI,
+		record:    recorder,
+		sgLimiter: rate.NewLimiter(rate.Inf, 1),
+	}
+}
+
+func TestSyncSecurityGroups_Update(t *testing.T) {
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-1", []string{"sg-2", "sg-1"}).Return(nil).Once()
+	recorder := record.NewFakeRecorder(10)
+	m := newSGTestReconciler(openAPI, recorder, newSGTestPodENI(v1beta1.ENIPhaseBind, "sg-1"))
+
+	m.syncSecurityGroups(context.Background())
+
+	result := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}, result))
+	assert.Equal(t, []string{"sg-2", "sg-1"}, result.Spec.Allocations[0].ENI.SecurityGroupIDs)
+	assert.NotContains(t, result.Status.Conditions, ConditionSecurityGroupMismatch)
+	assert.Contains(t, <-recorder.Events, types.EventUpdateENISecurityGroupSucceed)
+}
+
+func TestSyncSecurityGroups_Skip(t *testing.T) {
+	// mock fails the test on any unexpected call
+	openAPI := mocks.NewInterface(t)
+	m := newSGTestReconciler(openAPI, record.NewFakeRecorder(10),
+		newSGTestPodENI(v1beta1.ENIPhaseBind, "sg-1", "sg-2"))
+	m.syncSecurityGroups(context.Background())
+
+	// eni in processing is left to the next round
+	openAPI = mocks.NewInterface(t)
+	m = newSGTestReconciler(openAPI, record.NewFakeRecorder(10),
+		newSGTestPodENI(v1beta1.ENIPhaseBinding, "sg-1"))
+	m.syncSecurityGroups(context.Background())
+}
+
+func TestSyncSecurityGroups_Condition(t *testing.T) {
+	key := k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}
+
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-1", mock.Anything).Return(errors.New("InvalidSecurityGroupId.NotFound")).Once()
+	recorder := record.NewFakeRecorder(10)
+	m := newSGTestReconciler(openAPI, recorder, newSGTestPodENI(v1beta1.ENIPhaseBind, "sg-1"))
+
+	m.syncSecurityGroups(context.Background())
+
+	result := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), key, result))
+	assert.Equal(t, []string{"sg-1"}, result.Spec.Allocations[0].ENI.SecurityGroupIDs)
+	assert.Contains(t, result.Status.Conditions[ConditionSecurityGroupMismatch].Message, "InvalidSecurityGroupId.NotFound")
+	assert.Contains(t, <-recorder.Events, types.EventUpdateENISecurityGroupFailed)
+
+	// the condition is removed once the eni is updated
+	openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-1", mock.Anything).Return(nil).Once()
+	m.syncSecurityGroups(context.Background())
+
+	assert.NoError(t, m.client.Get(context.Background(), key, result))
+	assert.Equal(t, []string{"sg-2", "sg-1"}, result.Spec.Allocations[0].ENI.SecurityGroupIDs)
+	assert.NotContains(t, result.Status.Conditions, ConditionSecurityGroupMismatch)
+}
+
+func TestExpectSecurityGroups(t *testing.T) {
+	m := newSGTestReconciler(mocks.NewInterface(t), record.NewFakeRecorder(10))
+
+	sgs, err := m.expectSecurityGroups(context.Background(), newSGTestPod())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"eth0": {"sg-2", "sg-1"}}, sgs)
+
+	// interface in PodNetworks defaults to eth0
+	pod := newSGTestPod()
+	pod.Annotations[types.PodNetworks] = `{"podNetworks":[{"securityGroupIDs":["sg-3"]}]}`
+	sgs, err = m.expectSecurityGroups(context.Background(), pod)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"eth0": {"sg-3"}}, sgs)
+
+	// PodNetworking is preferred over the security groups frozen in the annotation
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec:       v1beta1.PodNetworkingSpec{SecurityGroupIDs: []string{"sg-4"}},
+	}
+	assert.NoError(t, m.client.Create(context.Background(), pn))
+	pod = newSGTestPod()
+	pod.Annotations[types.PodNetworking] = "pn"
+	sgs, err = m.expectSecurityGroups(context.Background(), pod)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"eth0": {"sg-4"}}, sgs)
+
+	// PodNetworking deleted, fallback to the annotation
+	pod.Annotations[types.PodNetworking] = "not-exist"
+	sgs, err = m.expectSecurityGroups(context.Background(), pod)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"eth0": {"sg-2", "sg-1"}}, sgs)
+}
+
+func TestSyncSecurityGroups_PodNetworking(t *testing.T) {
+	// the annotation is written by the webhook, the PodNetworking is updated later
+	openAPI := mocks.NewInterface(t)
+	openAPI.On("ModifyNetworkInterfaceAttribute", mock.Anything, "eni-1", []string{"sg-4"}).Return(nil).Once()
+	m := newSGTestReconciler(openAPI, record.NewFakeRecorder(10), newSGTestPodENI(v1beta1.ENIPhaseBind, "sg-2", "sg-1"))
+
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec:       v1beta1.PodNetworkingSpec{SecurityGroupIDs: []string{"sg-4"}},
+	}
+	assert.NoError(t, m.client.Create(context.Background(), pn))
+	pod := &corev1.Pod{}
+	key := k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}
+	assert.NoError(t, m.client.Get(context.Background(), key, pod))
+	pod.Annotations[types.PodNetworking] = "pn"
+	assert.NoError(t, m.client.Update(context.Background(), pod))
+
+	m.syncSecurityGroups(context.Background())
+
+	result := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), key, result))
+	assert.Equal(t, []string{"sg-4"}, result.Spec.Allocations[0].ENI.SecurityGroupIDs)
+}
@@ -103,13 +103,14 @@ func (m *ReconcilePodNetworking) Reconcile(ctx context.Context, request reconcil
 	}
 
 	if !changed(old) && old.Status.Status == v1beta1.NetworkingStatusReady {
-		return reconcile.Result{}, nil
+		return m.syncReservedIPs(ctx, old)
 	}
 
 	update := old.DeepCopy()
 	update.Status.UpdateAt = metav1.Now()
 
 	var statusVSW []v1beta1.VSwitch
+	var reservedIPs *v1beta1.ReservedIPsStatus
 	err = func() error {
 		for _, id := range old.Spec.VSwitchOptions {
 			sw, innerErr := m.swPool.GetByID(ctx, m.aliyunClient, id)
@@ -121,10 +122,13 @@ func (m *ReconcilePodNetworking) Reconcile(ctx context.Context, request reconcil
 				Zone: sw.Zone,
 			})
 		}
-		return nil
+		var innerErr error
+		reservedIPs, innerErr = m.reservedIPsStatus(ctx, old)
+		return innerErr
 	}()
 	if err == nil {
 		update.Status.VSwitches = statusVSW
+		update.Status.ReservedIPs = reservedIPs
 		update.Status.Status = v1beta1.NetworkingStatusReady
 		update.Status.Message = ""
 		m.record.Eventf(update, corev1.EventTypeNormal, types.EventSyncPodNetworkingSucceed, "Synced")
@@ -138,6 +142,9 @@ func (m *ReconcilePodNetworking) Reconcile(ctx context.Context, request reconcil
 	if err != nil {
 		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
 	}
+	if err2 == nil && reservedIPs != nil {
+		return reconcile.Result{RequeueAfter: reservedIPSyncPeriod}, nil
+	}
 	return reconcile.Result{}, err2
 }
 
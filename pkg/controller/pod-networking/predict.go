@@ -40,6 +40,11 @@ func (p *predicateForPodnetwokringEvent) Update(e event.UpdateEvent) bool {
 		return true
 	}
 
+	// spec changed, e.g. the reserved ips
+	if e.ObjectOld != nil && e.ObjectOld.GetGeneration() != newPodNetworking.GetGeneration() {
+		return true
+	}
+
 	return changed(newPodNetworking)
 }
 
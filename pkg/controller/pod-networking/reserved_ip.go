@@ -0,0 +1,77 @@
+package podnetworking
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+)
+
+// reservedIPSyncPeriod is the period to refresh the reserved ip usage
+var reservedIPSyncPeriod = time.Minute
+
+// reservedIPsStatus collect the ip in reserved ip pool used by podENIs, nil if no reserved ip configured
+func (m *ReconcilePodNetworking) reservedIPsStatus(ctx context.Context, pn *v1beta1.PodNetworking) (*v1beta1.ReservedIPsStatus, error) {
+	if len(pn.Spec.ReservedIPs) == 0 {
+		return nil, nil
+	}
+	pool, err := common.ParseReservedIPs(pn.Spec.ReservedIPs)
+	if err != nil {
+		return nil, err
+	}
+	ips := sets.New[string]()
+	for _, ip := range pool {
+		ips.Insert(ip.String())
+	}
+
+	podENIs := &v1beta1.PodENIList{}
+	err = m.client.List(ctx, podENIs)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &v1beta1.ReservedIPsStatus{Total: len(pool)}
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			if !ips.Has(alloc.IPv4) {
+				continue
+			}
+			if status.InUse == nil {
+				status.InUse = make(map[string]string)
+			}
+			status.InUse[alloc.IPv4] = podENI.Namespace + "/" + podENI.Name
+		}
+	}
+	return status, nil
+}
+
+// syncReservedIPs update the reserved ip usage, and requeue for next sync
+func (m *ReconcilePodNetworking) syncReservedIPs(ctx context.Context, pn *v1beta1.PodNetworking) (reconcile.Result, error) {
+	if len(pn.Spec.ReservedIPs) == 0 && pn.Status.ReservedIPs == nil {
+		return reconcile.Result{}, nil
+	}
+
+	status, err := m.reservedIPsStatus(ctx, pn)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !reflect.DeepEqual(status, pn.Status.ReservedIPs) {
+		update := pn.DeepCopy()
+		update.Status.ReservedIPs = status
+		update.Status.UpdateAt = metav1.Now()
+		err = m.client.Status().Update(ctx, update)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if status == nil {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: reservedIPSyncPeriod}, nil
+}
@@ -0,0 +1,56 @@
+package podnetworking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+)
+
+func TestReconcilePodNetworking_syncReservedIPs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec: v1beta1.PodNetworkingSpec{
+			ReservedIPs: []string{"192.168.0.10-192.168.0.12"},
+		},
+	}
+	podENIs := []*v1beta1.PodENI{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+			Spec: v1beta1.PodENISpec{
+				Allocations: []v1beta1.Allocation{{IPv4: "192.168.0.11"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default"},
+			Spec: v1beta1.PodENISpec{
+				Allocations: []v1beta1.Allocation{{IPv4: "192.168.1.11"}},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(pn, podENIs[0], podENIs[1]).
+		WithStatusSubresource(&v1beta1.PodNetworking{}).Build()
+	m := &ReconcilePodNetworking{client: c}
+
+	result, err := m.syncReservedIPs(context.Background(), pn)
+	assert.NoError(t, err)
+	assert.Equal(t, reservedIPSyncPeriod, result.RequeueAfter)
+
+	got := &v1beta1.PodNetworking{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "pn"}, got))
+	assert.Equal(t, &v1beta1.ReservedIPsStatus{
+		Total: 3,
+		InUse: map[string]string{"192.168.0.11": "default/pod-1"},
+	}, got.Status.ReservedIPs)
+}
@@ -19,6 +19,7 @@ package pod
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	register "github.com/AliyunContainerService/terway/pkg/controller"
 	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/pkg/vswitch"
 	"github.com/AliyunContainerService/terway/types"
@@ -63,8 +65,11 @@ func init() {
 
 		crdMode := controlplane.GetConfig().IPAMType == types.IPAMTypeCRD
 
+		r := NewReconcilePod(mgr, ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool, crdMode)
+		r.eniPool = ctrlCtx.ENIPool
+
 		c, err := controller.NewUnmanaged(controllerName, mgr, controller.Options{
-			Reconciler:              NewReconcilePod(mgr, ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool, crdMode),
+			Reconciler:              r,
 			MaxConcurrentReconciles: controlplane.GetConfig().PodMaxConcurrent,
 		})
 		if err != nil {
@@ -98,6 +103,10 @@ type ReconcilePod struct {
 	aliyun register.Interface
 
 	swPool *vswitch.SwitchPool
+	// eniPool is the member eni pool, nil if disabled
+	eniPool *enipool.Pool
+	// reservedIPs pick the eni primary ip for podNetworking with reserved ips
+	reservedIPs *reservedIPAllocator
 
 	//record event recorder
 	record record.EventRecorder
@@ -138,6 +147,7 @@ func NewReconcilePod(mgr manager.Manager, aliyunClient register.Interface, swPoo
 		trunkMode: *controlplane.GetConfig().EnableTrunk,
 		crdMode:   crdMode,
 	}
+	r.reservedIPs = newReservedIPAllocator(r.client)
 	return r
 }
 
@@ -147,6 +157,7 @@ func NewReconcilePod(mgr manager.Manager, aliyunClient register.Interface, swPoo
 // Fixed IP Pod delete -> mark PodENI status v1beta1.ENIPhaseDetaching
 // before delete event is trigger will check pod phase make sure sandbox is terminated
 func (m *ReconcilePod) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Controller: controllerName, Pod: request.String()})
 	l := log.FromContext(ctx)
 	l.V(5).Info("Reconcile")
 	start := time.Now()
@@ -160,6 +171,7 @@ func (m *ReconcilePod) Reconcile(ctx context.Context, request reconcile.Request)
 		}
 		return reconcile.Result{}, err
 	}
+	ctx = aliyunClient.WithAuditCaller(ctx, aliyunClient.AuditCaller{Node: pod.Spec.NodeName})
 
 	if utils.PodSandboxExited(pod) {
 		result, err := m.podDelete(ctx, request.NamespacedName)
@@ -240,12 +252,26 @@ func (m *ReconcilePod) podCreate(ctx context.Context, pod *corev1.Pod) (reconcil
 		return reconcile.Result{Requeue: true}, nil
 	}
 
+	// the picked reserved ip is released after the cr is created or rolled back, or the config is failed to parse
+	var picked []string
+	defer func() {
+		m.reservedIPs.release(picked...)
+	}()
+
 	// 2. cr is not found , so we will create new
 	nodeInfo, allocType, allocs, err := m.parse(ctx, pod, node)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("error parse config, %w", err)
 	}
 
+	// the webhook copy the podNetworking config to the annotation, the reserved ips are picked here
+	if name := pod.Annotations[types.PodNetworking]; name != "" {
+		picked, err = m.pickReservedIPs(ctx, name, allocs)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("error parse config, %w", err)
+		}
+	}
+
 	l.Info("creating eni")
 
 	podENI := &v1beta1.PodENI{
@@ -267,7 +293,14 @@ func (m *ReconcilePod) podCreate(ctx context.Context, pod *corev1.Pod) (reconcil
 	defer func() {
 		if err != nil {
 			l.Error(err, "error ,will roll back all created eni")
-			innerErr := m.deleteAllENI(ctx, podENI)
+			// the enis are deleted, so the token can not be reused. Keep the enis if the attempt is not persisted,
+			// the retry with the same token get the same enis back.
+			innerErr := m.bumpCreateAttempt(ctx, pod)
+			if innerErr != nil {
+				l.Error(innerErr, "error record eni create attempt, skip roll back")
+				return
+			}
+			innerErr = m.deleteAllENI(ctx, podENI)
 			if innerErr != nil {
 				l.Error(innerErr, "error delete eni")
 			}
@@ -283,12 +316,24 @@ func (m *ReconcilePod) podCreate(ctx context.Context, pod *corev1.Pod) (reconcil
 	}
 
 	// 2.3 create cr
+	eniInfos := podENI.Status.ENIInfos
 	err = m.client.Create(ctx, podENI)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("error create cr, %s", err)
 	}
 
-	// 2.4 wait cr created
+	// 2.4 record the created step, podENI controller will continue from it
+	if len(eniInfos) > 0 {
+		base := podENI.DeepCopy()
+		podENI.Status.ENIInfos = eniInfos
+		innerErr := m.client.Status().Patch(ctx, podENI, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+		if innerErr != nil {
+			// podENI controller may already take over
+			l.Error(innerErr, "error record eni step")
+		}
+	}
+
+	// 2.5 wait cr created
 	_ = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, 2*time.Second, true, func(ctx context.Context) (bool, error) {
 		podENI := &v1beta1.PodENI{}
 		err := m.client.Get(ctx, k8stypes.NamespacedName{
@@ -538,6 +583,34 @@ func (m *ReconcilePod) reConfig(ctx context.Context, pod *corev1.Pod, prePodENI
 	return reconcile.Result{Requeue: true}, err
 }
 
+// createAttempt is the count of eni creation rolled back for the pod
+func createAttempt(pod *corev1.Pod) int {
+	attempt, err := strconv.Atoi(pod.Annotations[types.PodENICreateAttempt])
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// bumpCreateAttempt persist the next attempt on the pod, so the token is not reused after controller restart
+func (m *ReconcilePod) bumpCreateAttempt(ctx context.Context, pod *corev1.Pod) error {
+	update := pod.DeepCopy()
+	if update.Annotations == nil {
+		update.Annotations = make(map[string]string)
+	}
+	update.Annotations[types.PodENICreateAttempt] = strconv.Itoa(createAttempt(pod) + 1)
+	return m.client.Patch(ctx, update, client.MergeFrom(pod))
+}
+
+// eniClientToken is the idempotency token for create eni, so the retry of same allocation will not create another eni.
+// The reserved ip picked may change on retry, the token is not reused with another primary ip.
+func eniClientToken(uid k8stypes.UID, index, attempt int, primaryIP string) string {
+	if primaryIP == "" {
+		return fmt.Sprintf("%s-%d-%d", uid, index, attempt)
+	}
+	return fmt.Sprintf("%s-%d-%d-%s", uid, index, attempt, primaryIP)
+}
+
 func (m *ReconcilePod) createENI(ctx context.Context, allocs *[]*v1beta1.Allocation, allocType *v1beta1.AllocationType, pod *corev1.Pod, podENI *v1beta1.PodENI) error {
 	if allocs == nil || len(*allocs) == 0 {
 		return nil
@@ -567,11 +640,25 @@ func (m *ReconcilePod) createENI(ctx context.Context, allocs *[]*v1beta1.Allocat
 		ipv6Count = 1
 	}
 
-	ch := make(chan *v1beta1.Allocation)
+	attempt := createAttempt(pod)
+
+	type created struct {
+		alloc       *v1beta1.Allocation
+		clientToken string
+	}
+	ch := make(chan created)
 	done := make(chan struct{})
 	go func() {
-		for alloc := range ch {
-			podENI.Spec.Allocations = append(podENI.Spec.Allocations, *alloc)
+		for c := range ch {
+			podENI.Spec.Allocations = append(podENI.Spec.Allocations, *c.alloc)
+			if podENI.Status.ENIInfos == nil {
+				podENI.Status.ENIInfos = make(map[string]v1beta1.ENIInfo)
+			}
+			podENI.Status.ENIInfos[c.alloc.ENI.ID] = v1beta1.ENIInfo{
+				ID:          c.alloc.ENI.ID,
+				Step:        v1beta1.ENIStepCreated,
+				ClientToken: c.clientToken,
+			}
 		}
 		done <- struct{}{}
 	}()
@@ -602,17 +689,46 @@ func (m *ReconcilePod) createENI(ctx context.Context, allocs *[]*v1beta1.Allocat
 						types.NetworkInterfaceTagCreatorKey: types.TagTerwayController,
 					},
 					DeleteENIOnECSRelease: &deleteENIOnECSRelease,
+					ClientToken:           eniClientToken(pod.UID, ii, attempt, alloc.IPv4),
+					PrimaryIP:             alloc.IPv4,
+					Owner: &aliyunClient.ENIOwner{
+						ClusterID:    clusterID,
+						NodeName:     pod.Spec.NodeName,
+						PodNamespace: pod.Namespace,
+						PodName:      pod.Name,
+						Component:    controllerName,
+					},
 				},
 				Backoff: &bo,
 			}
-			eni, err := m.aliyun.CreateNetworkInterface(ctx, option)
-			if err != nil {
+			var eni *aliyunClient.NetworkInterface
+			// member eni is taken from the pool first, unless the ip is specified
+			if m.eniPool != nil && deleteENIOnECSRelease && alloc.IPv4 == "" &&
+				alloc.ENI.AttachmentOptions.Trunk != nil && *alloc.ENI.AttachmentOptions.Trunk {
+				eni = m.eniPool.Acquire(enipool.Request{
+					VSwitchID:        alloc.ENI.VSwitchID,
+					SecurityGroupIDs: alloc.ENI.SecurityGroupIDs,
+					ResourceGroupID:  alloc.ENI.ResourceGroupID,
+					IPv6Count:        ipv6Count,
+					ERDMA:            option.NetworkInterfaceOptions.ERDMA,
+				})
+			}
+			token := ""
+			if eni == nil {
+				var err error
+				token = option.NetworkInterfaceOptions.ClientToken
+				eni, err = m.aliyun.CreateNetworkInterface(ctx, option)
+				if err != nil {
 
-				if apiErr.ErrorCodeIs(err, apiErr.InvalidVSwitchIDIPNotEnough, apiErr.QuotaExceededPrivateIPAddress) {
-					m.swPool.Block(alloc.ENI.VSwitchID)
-				}
+					if apiErr.ErrorCodeIs(err, apiErr.InvalidVSwitchIDIPNotEnough, apiErr.QuotaExceededPrivateIPAddress) {
+						m.swPool.Block(alloc.ENI.VSwitchID)
+					}
+					if alloc.IPv4 != "" && apiErr.ErrorCodeIs(err, apiErr.ErrPrivateIPAddressDuplicated) {
+						m.reservedIPs.conflict(alloc.IPv4)
+					}
 
-				return fmt.Errorf("create eni with openAPI err, %w", err)
+					return fmt.Errorf("create eni with openAPI err, %w", err)
+				}
 			}
 
 			v6 := ""
@@ -626,12 +742,18 @@ func (m *ReconcilePod) createENI(ctx context.Context, allocs *[]*v1beta1.Allocat
 				VSwitchID:        eni.VSwitchID,
 				SecurityGroupIDs: eni.SecurityGroupIDs,
 				ResourceGroupID:  eni.ResourceGroupID,
+
+				NetworkInterfaceTrafficMode: v1beta1.NetworkInterfaceTrafficModeStandard,
+			}
+			// the traffic mode is not in the create response
+			if option.NetworkInterfaceOptions.ERDMA {
+				alloc.ENI.NetworkInterfaceTrafficMode = v1beta1.NetworkInterfaceTrafficModeHighPerformance
 			}
 			alloc.IPv4 = eni.PrivateIPAddress
 			alloc.IPv6 = v6
 			alloc.AllocationType = *allocType
 
-			ch <- alloc
+			ch <- created{alloc: alloc, clientToken: token}
 			return nil
 		})
 	}
@@ -59,9 +59,27 @@ func (m *ReconcilePod) ParsePodNetworksFromAnnotation(ctx context.Context, zoneI
 		alloc.IPv6CIDR = sw.IPv6CIDR
 		var routes []v1beta1.Route
 		for _, r := range c.ExtraRoutes {
-			routes = append(routes, v1beta1.Route{Dst: r.Dst})
+			routes = append(routes, v1beta1.Route{
+				Dst:     r.Dst,
+				Gateway: r.Gateway,
+				Metric:  r.Metric,
+				Table:   r.Table,
+				Src:     r.Src,
+				MTU:     r.MTU,
+			})
 		}
 		alloc.ExtraRoutes = routes
+		var rules []v1beta1.Rule
+		for _, r := range c.ExtraRules {
+			rules = append(rules, v1beta1.Rule{
+				From:     r.From,
+				To:       r.To,
+				FwMark:   r.FwMark,
+				Priority: r.Priority,
+				Table:    r.Table,
+			})
+		}
+		alloc.ExtraRules = rules
 
 		// for default , leave it blank
 		trunk := false
@@ -0,0 +1,286 @@
+package pod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	apiErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+// fakeENIs create eni idempotently by the client token, same as the openAPI
+type fakeENIs struct {
+	lock   sync.Mutex
+	tokens []string
+	enis   map[string]string
+}
+
+func (f *fakeENIs) create(ctx context.Context, opts ...aliyunClient.CreateNetworkInterfaceOption) (*aliyunClient.NetworkInterface, error) {
+	option := &aliyunClient.CreateNetworkInterfaceOptions{}
+	for _, opt := range opts {
+		opt.ApplyCreateNetworkInterface(option)
+	}
+	token := option.NetworkInterfaceOptions.ClientToken
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.tokens = append(f.tokens, token)
+	id, ok := f.enis[token]
+	if !ok {
+		id = fmt.Sprintf("eni-%d", len(f.enis)+1)
+		f.enis[token] = id
+	}
+	ip := option.NetworkInterfaceOptions.PrimaryIP
+	if ip == "" {
+		ip = "192.168.0.10"
+	}
+	return &aliyunClient.NetworkInterface{
+		NetworkInterfaceID: id,
+		VSwitchID:          option.NetworkInterfaceOptions.VSwitchID,
+		SecurityGroupIDs:   option.NetworkInterfaceOptions.SecurityGroupIDs,
+		PrivateIPAddress:   ip,
+		ZoneID:             "cn-hangzhou-a",
+	}, nil
+}
+
+func newCreateTestObjects() (*corev1.Node, *corev1.Pod) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				corev1.LabelTopologyRegion:     "cn-hangzhou",
+				corev1.LabelTopologyZone:       "cn-hangzhou-a",
+				corev1.LabelInstanceTypeStable: "ecs.g7.large",
+			},
+		},
+		Spec: corev1.NodeSpec{ProviderID: "cn-hangzhou.i-xx"},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			UID:       "uid-1",
+			Annotations: map[string]string{
+				types.PodENI:      "true",
+				types.PodNetworks: `{"podNetworks":[{"vSwitchOptions":["vsw-1"],"securityGroupIDs":["sg-1"],"interface":"eth0"}]}`,
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+	}
+	return node, pod
+}
+
+// newCreateTestReconciler return a new reconciler, as the controller is restarted
+func newCreateTestReconciler(t *testing.T, aliyun *mocks.Interface, funcs interceptor.Funcs, objs ...client.Object) *ReconcilePod {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1beta1.AddToScheme(scheme)
+
+	swPool, err := vswitch.NewSwitchPool(10, "10m")
+	assert.NoError(t, err)
+	swPool.Add(&vswitch.Switch{ID: "vsw-1", Zone: "cn-hangzhou-a", AvailableIPCount: 10, IPv4CIDR: "192.168.0.0/24"})
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1beta1.PodENI{}).
+		WithInterceptorFuncs(funcs).Build()
+	return &ReconcilePod{
+		client:      c,
+		scheme:      scheme,
+		aliyun:      aliyun,
+		swPool:      swPool,
+		reservedIPs: newReservedIPAllocator(c),
+		record:      record.NewFakeRecorder(100),
+	}
+}
+
+func getTestPod(t *testing.T, c client.Client) *corev1.Pod {
+	pod := &corev1.Pod{}
+	assert.NoError(t, c.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}, pod))
+	return pod
+}
+
+func TestReconcilePod_podCreateCrash(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1", IPStack: "ipv4"})
+	node, pod := newCreateTestObjects()
+
+	enis := &fakeENIs{enis: make(map[string]string)}
+	aliyun := mocks.NewInterface(t)
+	aliyun.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(enis.create)
+
+	// crash after the eni is created, before the cr is created
+	m := newCreateTestReconciler(t, aliyun, interceptor.Funcs{
+		Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*v1beta1.PodENI); ok {
+				panic("crash")
+			}
+			return cl.Create(ctx, obj, opts...)
+		},
+	}, node, pod)
+	assert.Panics(t, func() {
+		_, _ = m.podCreate(context.Background(), getTestPod(t, m.client))
+	})
+
+	// restart, the same eni is got back by the token
+	m = newCreateTestReconciler(t, aliyun, interceptor.Funcs{}, node, pod)
+	_, err := m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"uid-1-0-0", "uid-1-0-0"}, enis.tokens)
+	podENI := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}, podENI))
+	if assert.Len(t, podENI.Spec.Allocations, 1) {
+		assert.Equal(t, "eni-1", podENI.Spec.Allocations[0].ENI.ID)
+	}
+	assert.Equal(t, "uid-1-0-0", podENI.Status.ENIInfos["eni-1"].ClientToken)
+}
+
+func TestReconcilePod_podCreateRollback(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1", IPStack: "ipv4"})
+	node, pod := newCreateTestObjects()
+
+	enis := &fakeENIs{enis: make(map[string]string)}
+	aliyun := mocks.NewInterface(t)
+	aliyun.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(enis.create)
+	aliyun.On("DeleteNetworkInterface", mock.Anything, "eni-1").Return(nil).Once()
+
+	// the cr is failed to create, the eni is deleted and the attempt is persisted
+	m := newCreateTestReconciler(t, aliyun, interceptor.Funcs{
+		Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*v1beta1.PodENI); ok {
+				return errors.New("create failed")
+			}
+			return cl.Create(ctx, obj, opts...)
+		},
+	}, node, pod)
+	_, err := m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.Error(t, err)
+
+	updated := getTestPod(t, m.client)
+	assert.Equal(t, "1", updated.Annotations[types.PodENICreateAttempt])
+
+	// restart, the deleted eni is not reused
+	m = newCreateTestReconciler(t, aliyun, interceptor.Funcs{}, node, updated)
+	_, err = m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"uid-1-0-0", "uid-1-0-1"}, enis.tokens)
+	podENI := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}, podENI))
+	if assert.Len(t, podENI.Spec.Allocations, 1) {
+		assert.Equal(t, "eni-2", podENI.Spec.Allocations[0].ENI.ID)
+	}
+}
+
+func TestReconcilePod_podCreateRollbackNotPersisted(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1", IPStack: "ipv4"})
+	node, pod := newCreateTestObjects()
+
+	enis := &fakeENIs{enis: make(map[string]string)}
+	aliyun := mocks.NewInterface(t)
+	aliyun.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(enis.create)
+
+	// the attempt is failed to persist, the eni is kept for the retry with the same token
+	m := newCreateTestReconciler(t, aliyun, interceptor.Funcs{
+		Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*v1beta1.PodENI); ok {
+				return errors.New("create failed")
+			}
+			return cl.Create(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, cl client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			return errors.New("patch failed")
+		},
+	}, node, pod)
+	_, err := m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.Error(t, err)
+	aliyun.AssertNotCalled(t, "DeleteNetworkInterface", mock.Anything, mock.Anything)
+
+	m = newCreateTestReconciler(t, aliyun, interceptor.Funcs{}, node, pod)
+	_, err = m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"uid-1-0-0", "uid-1-0-0"}, enis.tokens)
+}
+
+func TestReconcilePod_podCreateReservedIP(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1", IPStack: "ipv4"})
+	node, pod := newCreateTestObjects()
+	pod.Annotations[types.PodNetworking] = "pn"
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec: v1beta1.PodNetworkingSpec{
+			ReservedIPs: []string{"192.168.0.10-192.168.0.11"},
+		},
+	}
+
+	enis := &fakeENIs{enis: make(map[string]string)}
+	aliyun := mocks.NewInterface(t)
+	// the first ip is used outside the cluster
+	aliyun.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(func(ctx context.Context, opts ...aliyunClient.CreateNetworkInterfaceOption) (*aliyunClient.NetworkInterface, error) {
+		_, _ = enis.create(ctx, opts...)
+		return nil, apiErr.NewServerError(400, `{"Code": "InvalidPrivateIpAddress.Duplicated"}`, "")
+	}).Once()
+	aliyun.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(enis.create)
+
+	// the attempt is failed to persist, the retry is with the same attempt
+	m := newCreateTestReconciler(t, aliyun, interceptor.Funcs{
+		Patch: func(ctx context.Context, cl client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			return errors.New("patch failed")
+		},
+	}, node, pod, pn)
+	_, err := m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.Error(t, err)
+	assert.Empty(t, m.reservedIPs.pending)
+
+	_, err = m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.NoError(t, err)
+	assert.Empty(t, m.reservedIPs.pending)
+
+	// the token is not reused with another primary ip
+	assert.Equal(t, []string{"uid-1-0-0-192.168.0.10", "uid-1-0-0-192.168.0.11"}, enis.tokens)
+	podENI := &v1beta1.PodENI{}
+	assert.NoError(t, m.client.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "pod-1"}, podENI))
+	if assert.Len(t, podENI.Spec.Allocations, 1) {
+		assert.Equal(t, "192.168.0.11", podENI.Spec.Allocations[0].IPv4)
+	}
+}
+
+func TestReconcilePod_podCreateReleasePicked(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1", IPStack: "ipv4"})
+	node, pod := newCreateTestObjects()
+	pod.Annotations[types.PodNetworking] = "pn"
+	// both interfaces pick from the pool, the second is failed to pick
+	pod.Annotations[types.PodNetworks] = `{"podNetworks":[{"vSwitchOptions":["vsw-1"],"securityGroupIDs":["sg-1"],"interface":"eth0"},` +
+		`{"vSwitchOptions":["vsw-1"],"securityGroupIDs":["sg-1"]}]}`
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec: v1beta1.PodNetworkingSpec{
+			ReservedIPs: []string{"192.168.0.10"},
+		},
+	}
+
+	m := newCreateTestReconciler(t, mocks.NewInterface(t), interceptor.Funcs{}, node, pod, pn)
+	_, err := m.podCreate(context.Background(), getTestPod(t, m.client))
+	assert.ErrorContains(t, err, "error pick reserved ip")
+	assert.Empty(t, m.reservedIPs.pending)
+}
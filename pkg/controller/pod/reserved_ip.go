@@ -0,0 +1,133 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+)
+
+// conflictTTL is how long an ip used outside the cluster is skipped
+var conflictTTL = 5 * time.Minute
+
+// reservedIPAllocator pick ip from the reserved ip pool of podNetworking
+type reservedIPAllocator struct {
+	client client.Client
+
+	lock sync.Mutex
+	// pending is the ip picked, but the podENI is not created yet
+	pending sets.Set[string]
+	// conflicts is the ip used outside the cluster
+	conflicts map[string]time.Time
+}
+
+func newReservedIPAllocator(c client.Client) *reservedIPAllocator {
+	return &reservedIPAllocator{
+		client:    c,
+		pending:   sets.New[string](),
+		conflicts: make(map[string]time.Time),
+	}
+}
+
+// pick return a free ip in both the reserved ip pool and the vSwitch cidr
+func (r *reservedIPAllocator) pick(ctx context.Context, podNetworking *v1beta1.PodNetworking, vSwitchCIDR string) (string, error) {
+	pool, err := common.ParseReservedIPs(podNetworking.Spec.ReservedIPs)
+	if err != nil {
+		return "", err
+	}
+	prefix, err := netip.ParsePrefix(vSwitchCIDR)
+	if err != nil {
+		return "", fmt.Errorf("error parse vSwitch cidr %s, %w", vSwitchCIDR, err)
+	}
+
+	podENIs := &v1beta1.PodENIList{}
+	err = r.client.List(ctx, podENIs)
+	if err != nil {
+		return "", err
+	}
+	used := sets.New[string]()
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			used.Insert(alloc.IPv4)
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for ip, expireAt := range r.conflicts {
+		if now.After(expireAt) {
+			delete(r.conflicts, ip)
+		}
+	}
+
+	for _, addr := range pool {
+		if !prefix.Contains(addr) {
+			continue
+		}
+		ip := addr.String()
+		if used.Has(ip) || r.pending.Has(ip) {
+			continue
+		}
+		if _, ok := r.conflicts[ip]; ok {
+			continue
+		}
+		r.pending.Insert(ip)
+		return ip, nil
+	}
+	return "", fmt.Errorf("no free ip in reserved ip pool of podNetworking %s for vSwitch cidr %s", podNetworking.Name, vSwitchCIDR)
+}
+
+// release the picked ip, should be called after the podENI is seen or the allocation is abandoned
+func (r *reservedIPAllocator) release(ips ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pending.Delete(ips...)
+}
+
+// conflict mark the ip is used outside the cluster
+func (r *reservedIPAllocator) conflict(ip string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.conflicts[ip] = time.Now().Add(conflictTTL)
+}
+
+// pickReservedIPs pick the eni primary ip from the reserved ip pool of the podNetworking, the podNetworking only
+// configures the default interface. The picked ips are returned on error as well, the caller should release them.
+func (m *ReconcilePod) pickReservedIPs(ctx context.Context, podNetworkingName string, allocs []*v1beta1.Allocation) ([]string, error) {
+	podNetworking := &v1beta1.PodNetworking{}
+	err := m.client.Get(ctx, client.ObjectKey{Name: podNetworkingName}, podNetworking)
+	if err != nil {
+		return nil, fmt.Errorf("error get podNetworking %s, %w", podNetworkingName, err)
+	}
+	if len(podNetworking.Spec.ReservedIPs) == 0 {
+		return nil, nil
+	}
+
+	var picked []string
+	for _, alloc := range allocs {
+		if alloc.Interface != "" && alloc.Interface != defaultInterface {
+			continue
+		}
+		if alloc.IPv4 != "" {
+			continue
+		}
+		ip, err := m.reservedIPs.pick(ctx, podNetworking, alloc.IPv4CIDR)
+		if err != nil {
+			return picked, fmt.Errorf("error pick reserved ip, %w", err)
+		}
+		alloc.IPv4 = ip
+		picked = append(picked, ip)
+	}
+	return picked, nil
+}
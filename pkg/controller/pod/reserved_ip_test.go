@@ -0,0 +1,131 @@
+package pod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func Test_reservedIPAllocator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+
+	podENI := &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: v1beta1.PodENISpec{
+			Allocations: []v1beta1.Allocation{{IPv4: "192.168.0.10"}},
+		},
+	}
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec: v1beta1.PodNetworkingSpec{
+			ReservedIPs: []string{"192.168.0.10-192.168.0.13", "192.168.1.10"},
+		},
+	}
+
+	r := newReservedIPAllocator(fake.NewClientBuilder().WithScheme(scheme).WithObjects(podENI).Build())
+
+	// used by podENI
+	ip, err := r.pick(context.Background(), pn, "192.168.0.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.11", ip)
+
+	// pending
+	ip, err = r.pick(context.Background(), pn, "192.168.0.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.12", ip)
+
+	// used outside the cluster
+	r.conflict("192.168.0.13")
+	_, err = r.pick(context.Background(), pn, "192.168.0.0/24")
+	assert.Error(t, err)
+
+	r.release("192.168.0.11")
+	ip, err = r.pick(context.Background(), pn, "192.168.0.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.11", ip)
+
+	// only the ip in vSwitch cidr is picked
+	ip, err = r.pick(context.Background(), pn, "192.168.1.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.1.10", ip)
+}
+
+func TestReconcilePod_parseReservedIPs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec: v1beta1.PodNetworkingSpec{
+			ReservedIPs: []string{"192.168.0.10-192.168.0.11"},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				corev1.LabelTopologyRegion:     "cn-hangzhou",
+				corev1.LabelTopologyZone:       "cn-hangzhou-a",
+				corev1.LabelInstanceTypeStable: "ecs.g7.large",
+			},
+		},
+		Spec: corev1.NodeSpec{ProviderID: "cn-hangzhou.i-xx"},
+	}
+	swPool, err := vswitch.NewSwitchPool(10, "10m")
+	assert.NoError(t, err)
+	swPool.Add(&vswitch.Switch{ID: "vsw-1", Zone: "cn-hangzhou-a", AvailableIPCount: 10, IPv4CIDR: "192.168.0.0/24"})
+
+	m := &ReconcilePod{
+		client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(pn).Build(),
+		swPool:      swPool,
+		reservedIPs: newReservedIPAllocator(fake.NewClientBuilder().WithScheme(scheme).Build()),
+	}
+
+	// the podNetworks annotation set by the webhook from the podNetworking
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				types.PodNetworking: "pn",
+				types.PodNetworks:   `{"podNetworks":[{"vSwitchOptions":["vsw-1"],"securityGroupIDs":["sg-1"],"interface":"eth0"}]}`,
+			},
+		},
+	}
+	_, _, allocs, err := m.parse(context.Background(), pod, node)
+	assert.NoError(t, err)
+	assert.Len(t, allocs, 1)
+	picked, err := m.pickReservedIPs(context.Background(), "pn", allocs)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.168.0.10"}, picked)
+	assert.Equal(t, "192.168.0.10", allocs[0].IPv4)
+
+	_, _, allocs, err = m.parse(context.Background(), pod, node)
+	assert.NoError(t, err)
+	_, err = m.pickReservedIPs(context.Background(), "pn", allocs)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.11", allocs[0].IPv4)
+
+	// the pool is exhausted
+	_, _, allocs, err = m.parse(context.Background(), pod, node)
+	assert.NoError(t, err)
+	picked, err = m.pickReservedIPs(context.Background(), "pn", allocs)
+	assert.Error(t, err)
+	assert.Empty(t, picked)
+
+	// the podNetworks annotation without podNetworking is not changed
+	delete(pod.Annotations, types.PodNetworking)
+	_, _, allocs, err = m.parse(context.Background(), pod, node)
+	assert.NoError(t, err)
+	assert.Empty(t, allocs[0].IPv4)
+}
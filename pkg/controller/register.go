@@ -26,6 +26,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	enipool "github.com/AliyunContainerService/terway/pkg/controller/eni-pool"
+	"github.com/AliyunContainerService/terway/pkg/controller/shard"
 	"github.com/AliyunContainerService/terway/pkg/vswitch"
 	"github.com/AliyunContainerService/terway/types/controlplane"
 
@@ -45,6 +47,12 @@ type ControllerCtx struct {
 	VSwitchPool  *vswitch.SwitchPool
 	AliyunClient Interface
 
+	// ENIPool is the member eni pool for trunk pods, nil if disabled
+	ENIPool *enipool.Pool
+
+	// Shard decide the nodes handled by this replica, nil if sharding is disabled
+	Shard *shard.Shard
+
 	Wg *wait.Group
 
 	TracerProvider trace.TracerProvider
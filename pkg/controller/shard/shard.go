@@ -0,0 +1,281 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shard split the nodes between controlplane replicas without leader election.
+// Each replica keeps its own Lease alive, the live replicas sorted by identity split the hash space of node names into equal ranges.
+package shard
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// LabelShardGroup is set on the shard leases, value is the group name
+const LabelShardGroup = "k8s.aliyun.com/terway-shard"
+
+var log = logf.Log.WithName("shard")
+
+// ShardMembers the live replicas seen by this replica
+var ShardMembers = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "shard_members",
+		Help: "controlplane shard live members",
+	},
+)
+
+type Options struct {
+	Namespace string
+	// Group is the prefix of the lease name, replicas in the same group share the nodes
+	Group    string
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewPeriod   time.Duration
+}
+
+// Shard decide which nodes are handled by this replica.
+// When the members changed, the nodes still owned under the previous members are kept, the new ones are taken
+// after all replicas have seen the change, so a node is never handled by two replicas at the same time.
+type Shard struct {
+	client kubernetes.Interface
+	opts   Options
+
+	lock sync.RWMutex
+	// members is the sorted identities of live replicas
+	members []string
+	prev    []string
+	// changedAt the time members changed, the new ranges are taken after settle
+	changedAt time.Time
+	settled   bool
+	// renewedAt the last time the lease is renewed, others take the nodes once the lease expired
+	renewedAt time.Time
+
+	handlers []func(ctx context.Context)
+
+	now func() time.Time
+}
+
+func New(c kubernetes.Interface, opts Options) *Shard {
+	return &Shard{
+		client:  c,
+		opts:    opts,
+		settled: true,
+		now:     time.Now,
+	}
+}
+
+// Owns whether the node is handled by this replica, always true if sharding is disabled.
+// Nothing is owned once the lease is not renewed in the lease duration, as others may have taken the nodes.
+func (s *Shard) Owns(name string) bool {
+	if s == nil {
+		return true
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.now().Sub(s.renewedAt) > s.opts.LeaseDuration {
+		return false
+	}
+	if !owns(s.members, s.opts.Identity, name) {
+		return false
+	}
+	return s.settled || owns(s.prev, s.opts.Identity, name)
+}
+
+// AddHandler register the func called once the new ranges are taken, nodes newly owned should be re-queued
+func (s *Shard) AddHandler(fn func(ctx context.Context)) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers = append(s.handlers, fn)
+}
+
+// NeedLeaderElection every replica keeps its own lease
+func (s *Shard) NeedLeaderElection() bool {
+	return false
+}
+
+// ControllerNeedLeaderElection return the option for the sharded controllers, nil to keep the manager default
+func (s *Shard) ControllerNeedLeaderElection() *bool {
+	if s == nil {
+		return nil
+	}
+	need := false
+	return &need
+}
+
+// Start keep the lease alive until ctx is done, the lease is deleted on exit so others take over quickly
+func (s *Shard) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := s.sync(ctx)
+		if err != nil {
+			log.Error(err, "failed to sync shard")
+		}
+	}, s.opts.RenewPeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.RenewPeriod)
+	defer cancel()
+	err := s.client.CoordinationV1().Leases(s.opts.Namespace).Delete(ctx, s.leaseName(), metav1.DeleteOptions{})
+	if err != nil && !k8sErr.IsNotFound(err) {
+		log.Error(err, "failed to release shard lease")
+	}
+	return nil
+}
+
+func (s *Shard) leaseName() string {
+	return s.opts.Group + "-" + s.opts.Identity
+}
+
+func (s *Shard) sync(ctx context.Context) error {
+	renewedAt := s.now()
+	err := s.renew(ctx)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	if renewedAt.Sub(s.renewedAt) > s.opts.LeaseDuration && s.settled {
+		// the nodes may be taken by others while the lease expired, take them again after all replicas see the lease
+		s.prev = nil
+		s.changedAt = renewedAt
+		s.settled = false
+	}
+	s.renewedAt = renewedAt
+	s.lock.Unlock()
+
+	members, err := s.liveMembers(ctx)
+	if err != nil {
+		return err
+	}
+	ShardMembers.Set(float64(len(members)))
+
+	now := s.now()
+
+	s.lock.Lock()
+	if !slices.Equal(s.members, members) {
+		log.Info("shard members changed", "from", s.members, "to", members)
+		s.prev = s.members
+		s.members = members
+		s.changedAt = now
+		s.settled = false
+	}
+	// every replica see the change in a renew period
+	var handlers []func(ctx context.Context)
+	if !s.settled && now.Sub(s.changedAt) >= 2*s.opts.RenewPeriod {
+		s.settled = true
+		handlers = s.handlers
+	}
+	s.lock.Unlock()
+
+	for _, fn := range handlers {
+		fn(ctx)
+	}
+	return nil
+}
+
+func (s *Shard) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(s.now())
+	duration := int32(s.opts.LeaseDuration / time.Second)
+	holder := s.opts.Identity
+
+	leases := s.client.CoordinationV1().Leases(s.opts.Namespace)
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8sErr.IsNotFound(err) {
+			return err
+		}
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.opts.Namespace,
+				Labels:    map[string]string{LabelShardGroup: s.opts.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// liveMembers list the replicas renewed in the lease duration, expired leases are removed
+func (s *Shard) liveMembers(ctx context.Context) ([]string, error) {
+	leases := s.client.CoordinationV1().Leases(s.opts.Namespace)
+	list, err := leases.List(ctx, metav1.ListOptions{
+		LabelSelector: LabelShardGroup + "=" + s.opts.Group,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var members []string
+	for _, lease := range list.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expire := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expire) {
+			log.Info("shard member expired", "member", *lease.Spec.HolderIdentity)
+			err = leases.Delete(ctx, lease.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sErr.IsNotFound(err) {
+				log.Error(err, "failed to delete expired lease", "lease", lease.Name)
+			}
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// owns whether the node name falls in the hash range of the identity
+func owns(members []string, identity, name string) bool {
+	index := sort.SearchStrings(members, identity)
+	if index >= len(members) || members[index] != identity {
+		return false
+	}
+	return rangeOf(name, len(members)) == index
+}
+
+// rangeOf split the 32 bits hash space into n equal ranges
+func rangeOf(name string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int(uint64(h.Sum32()) * uint64(n) >> 32)
+}
@@ -0,0 +1,149 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newShard(c kubernetes.Interface, identity string, now *time.Time) *Shard {
+	s := New(c, Options{
+		Namespace:     "kube-system",
+		Group:         "terway-shard",
+		Identity:      identity,
+		LeaseDuration: 30 * time.Second,
+		RenewPeriod:   10 * time.Second,
+	})
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func owners(nodes []string, shards ...*Shard) map[string]int {
+	result := make(map[string]int)
+	for _, name := range nodes {
+		for _, s := range shards {
+			if s.Owns(name) {
+				result[name]++
+			}
+		}
+	}
+	return result
+}
+
+func TestShard_Rebalance(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewSimpleClientset()
+	now := time.Now()
+
+	var nodes []string
+	for i := 0; i < 100; i++ {
+		nodes = append(nodes, fmt.Sprintf("node-%d", i))
+	}
+
+	a := newShard(c, "a", &now)
+	notified := 0
+	a.AddHandler(func(ctx context.Context) {
+		notified++
+	})
+	assert.NoError(t, a.sync(ctx))
+	// wait all replicas see the change
+	assert.Empty(t, owners(nodes, a))
+
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, a.sync(ctx))
+	assert.Equal(t, 1, notified)
+	for _, name := range nodes {
+		assert.True(t, a.Owns(name))
+	}
+
+	// b joins, a give up part of the nodes at once, b take them later
+	b := newShard(c, "b", &now)
+	assert.NoError(t, b.sync(ctx))
+	assert.NoError(t, a.sync(ctx))
+	for name, n := range owners(nodes, a, b) {
+		assert.LessOrEqual(t, n, 1, name)
+	}
+
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, a.sync(ctx))
+	assert.NoError(t, b.sync(ctx))
+	result := owners(nodes, a, b)
+	assert.Len(t, result, len(nodes))
+	for name, n := range result {
+		assert.Equal(t, 1, n, name)
+	}
+	assert.Equal(t, 2, notified)
+
+	// b is gone, the lease expired
+	now = now.Add(time.Minute)
+	assert.NoError(t, a.sync(ctx))
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, a.sync(ctx))
+	for _, name := range nodes {
+		assert.True(t, a.Owns(name))
+	}
+	leases, err := c.CoordinationV1().Leases("kube-system").List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, leases.Items, 1)
+}
+
+func TestShard_RenewFailed(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewSimpleClientset()
+	now := time.Now()
+
+	a := newShard(c, "a", &now)
+	assert.NoError(t, a.sync(ctx))
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, a.sync(ctx))
+	assert.True(t, a.Owns("node-1"))
+
+	c.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("apiserver unavailable")
+	})
+
+	// still owned in the lease duration
+	now = now.Add(20 * time.Second)
+	assert.Error(t, a.sync(ctx))
+	assert.True(t, a.Owns("node-1"))
+
+	// the lease may be taken by others
+	now = now.Add(20 * time.Second)
+	assert.Error(t, a.sync(ctx))
+	assert.False(t, a.Owns("node-1"))
+
+	// renewed, the nodes are taken after all replicas see the lease
+	c.ReactionChain = c.ReactionChain[1:]
+	now = now.Add(time.Second)
+	assert.NoError(t, a.sync(ctx))
+	assert.False(t, a.Owns("node-1"))
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, a.sync(ctx))
+	assert.True(t, a.Owns("node-1"))
+}
+
+func TestShard_Disabled(t *testing.T) {
+	var s *Shard
+	assert.True(t, s.Owns("node-1"))
+	assert.Nil(t, s.ControllerNeedLeaderElection())
+	s.AddHandler(func(ctx context.Context) {})
+}
+
+func TestRangeOf(t *testing.T) {
+	count := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		r := rangeOf(fmt.Sprintf("node-%d", i), 3)
+		count[r]++
+	}
+	for _, n := range count {
+		assert.InDelta(t, 1000, n, 200)
+	}
+}
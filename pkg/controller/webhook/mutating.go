@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/go-logr/logr"
 	"github.com/samber/lo"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -33,6 +35,7 @@ import (
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/controlplane"
 	"github.com/AliyunContainerService/terway/types/daemon"
+	"github.com/AliyunContainerService/terway/types/route"
 
 	"gomodules.xyz/jsonpatch/v2"
 	corev1 "k8s.io/api/core/v1"
@@ -110,6 +113,21 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 		return webhook.Denied("can not use pod annotation and podNetworking at same time")
 	}
 
+	// pod use ip from the shared eni, with its own security groups
+	if pod.Annotations[types.PodSecurityGroups] != "" && pod.Annotations[types.PodNetworks] == "" && !types.PodUseENI(pod) {
+		if len(types.PodSecurityGroupIDs(pod)) > 5 {
+			return admission.Denied("security group can not more than 5")
+		}
+		changed, err := setEgressGateway(ctx, client, req.Namespace, pod, nil)
+		if err != nil {
+			return webhook.Denied(err.Error())
+		}
+		if changed {
+			return patchPod(l, original, pod)
+		}
+		return webhook.Allowed("use shared eni")
+	}
+
 	// 1. pod annotation config
 	// 2. pod match podNetworking
 	// 3. write default config from eni-config
@@ -131,6 +149,13 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 		if podNetworking == nil {
 			if config.IPAMType != types.IPAMTypeCRD {
 				if !types.PodUseENI(pod) {
+					changed, err := setEgressGateway(ctx, client, req.Namespace, pod, nil)
+					if err != nil {
+						return webhook.Denied(err.Error())
+					}
+					if changed {
+						return patchPod(l, original, pod)
+					}
 					l.V(5).Info("no selector is matched or CRD is not ready")
 					return webhook.Allowed("not match")
 				}
@@ -138,15 +163,33 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 			}
 
 			networks.PodNetworks = append(networks.PodNetworks, controlplane.PodNetworks{Interface: eth0})
+		} else if podNetworking.Spec.ENIOptions.ENIAttachType == v1beta1.ENIOptionTypeShared {
+			// only security groups is used, the ip is allocated from the shared eni
+			if len(podNetworking.Spec.SecurityGroupIDs) > 5 {
+				return admission.Denied("security group can not more than 5")
+			}
+			pod.Annotations[types.PodNetworking] = podNetworking.Name
+			setNAT64(pod, podNetworking)
+			_, err = setEgressGateway(ctx, client, req.Namespace, pod, podNetworking)
+			if err != nil {
+				return webhook.Denied(err.Error())
+			}
+			if len(podNetworking.Spec.SecurityGroupIDs) > 0 {
+				pod.Annotations[types.PodSecurityGroups] = strings.Join(podNetworking.Spec.SecurityGroupIDs, ",")
+			}
+			return patchPod(l, original, pod)
 		} else {
 			// use config from pn
 			pod.Annotations[types.PodNetworking] = podNetworking.Name
+			setNAT64(pod, podNetworking)
 			networks.PodNetworks = append(networks.PodNetworks, controlplane.PodNetworks{
 				Interface:            eth0,
 				VSwitchOptions:       podNetworking.Spec.VSwitchOptions,
 				SecurityGroupIDs:     podNetworking.Spec.SecurityGroupIDs,
 				ENIOptions:           podNetworking.Spec.ENIOptions,
 				VSwitchSelectOptions: podNetworking.Spec.VSwitchSelectOptions,
+				ExtraRoutes:          toRoutes(podNetworking.Spec.ExtraRoutes),
+				ExtraRules:           toRules(podNetworking.Spec.ExtraRules),
 			})
 
 			for _, vsw := range podNetworking.Status.VSwitches {
@@ -184,6 +227,10 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 		if iF.Has(n.Interface) {
 			return admission.Denied("duplicated interface")
 		}
+		err = validateRoutes(n.ExtraRoutes, n.ExtraRules)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
 		iF.Insert(n.Interface)
 
 		// only set prev zone for fixed ip
@@ -220,6 +267,8 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 	}
 	pod.Annotations[types.PodNetworks] = string(pnaBytes)
 	pod.Annotations[types.PodENI] = "true"
+	// egress gateway is only for the shared eni
+	delete(pod.Annotations, types.PodEgressGateway)
 
 	setResourceRequest(pod, networks.PodNetworks, *config.EnableTrunk)
 
@@ -338,6 +387,21 @@ func matchOnePodNetworking(ctx context.Context, namespace string, client client.
 	return nil, nil
 }
 
+func patchPod(l logr.Logger, original []byte, pod *corev1.Pod) webhook.AdmissionResponse {
+	podPatched, err := json.Marshal(pod)
+	if err != nil {
+		l.Error(err, "error marshal pod")
+		return webhook.Errored(1, err)
+	}
+	patches, err := jsonpatch.CreatePatch(original, podPatched)
+	if err != nil {
+		l.Error(err, "error create patch")
+		return webhook.Errored(1, err)
+	}
+	l.Info("patch pod", "patch", patches)
+	return webhook.Patched("ok", patches...)
+}
+
 func getPreviousZone(ctx context.Context, client client.Client, pod *corev1.Pod) (string, error) {
 	if !utils.IsFixedNamePod(pod) {
 		return "", nil
@@ -363,6 +427,54 @@ func getPreviousZone(ctx context.Context, client client.Client, pod *corev1.Pod)
 	return podENI.Spec.Zone, nil
 }
 
+// setNAT64 mark the pod to use nat64, the daemon pass it to the datapath
+func setNAT64(pod *corev1.Pod, podNetworking *v1beta1.PodNetworking) {
+	if !podNetworking.Spec.EnableNAT64 {
+		return
+	}
+	pod.Annotations[types.PodNAT64] = "true"
+}
+
+// setEgressGateway set the egress gateway for pod using the shared eni, the podNetworking takes precedence over the namespace.
+// The annotation is always derived here, the one supplied by the pod is overwritten, so a pod can not pick other's gateway.
+// Return true if the pod is changed.
+func setEgressGateway(ctx context.Context, client client.Client, namespace string, pod *corev1.Pod, podNetworking *v1beta1.PodNetworking) (bool, error) {
+	prev, hadPrev := pod.Annotations[types.PodEgressGateway]
+	delete(pod.Annotations, types.PodEgressGateway)
+
+	var gw *types.EgressGateway
+	if podNetworking != nil && podNetworking.Spec.EgressGateway != nil {
+		gw = &types.EgressGateway{
+			Name:             podNetworking.Spec.EgressGateway.Name,
+			DestinationCIDRs: podNetworking.Spec.EgressGateway.DestinationCIDRs,
+			VSwitchID:        podNetworking.Spec.EgressGateway.VSwitchID,
+		}
+	} else {
+		ns := &corev1.Namespace{}
+		err := client.Get(ctx, k8stypes.NamespacedName{Name: namespace}, ns)
+		if err != nil {
+			return false, fmt.Errorf("error get namespace, %w", err)
+		}
+		gw, err = types.ParseEgressGateway(ns.Annotations[types.NamespaceEgressGateway])
+		if err != nil {
+			return false, fmt.Errorf("invalid egress gateway of namespace %s, %w", namespace, err)
+		}
+	}
+	if gw == nil {
+		return hadPrev, nil
+	}
+	err := gw.Validate()
+	if err != nil {
+		return false, err
+	}
+	out, err := json.Marshal(gw)
+	if err != nil {
+		return false, err
+	}
+	pod.Annotations[types.PodEgressGateway] = string(out)
+	return !hadPrev || prev != string(out), nil
+}
+
 func setResourceRequest(pod *corev1.Pod, podNetworks []controlplane.PodNetworks, enableTrunk bool) {
 	count := len(podNetworks)
 	if count == 0 {
@@ -445,3 +557,49 @@ func PodMatchSelector(labelSelector *metav1.LabelSelector, l labels.Set) (bool,
 	}
 	return selector.Matches(l), nil
 }
+
+func toRoutes(routes []v1beta1.Route) []route.Route {
+	var res []route.Route
+	for _, r := range routes {
+		res = append(res, route.Route{
+			Dst:     r.Dst,
+			Gateway: r.Gateway,
+			Metric:  r.Metric,
+			Table:   r.Table,
+			Src:     r.Src,
+			MTU:     r.MTU,
+		})
+	}
+	return res
+}
+
+func toRules(rules []v1beta1.Rule) []route.Rule {
+	var res []route.Rule
+	for _, r := range rules {
+		res = append(res, route.Rule{
+			From:     r.From,
+			To:       r.To,
+			FwMark:   r.FwMark,
+			Priority: r.Priority,
+			Table:    r.Table,
+		})
+	}
+	return res
+}
+
+// validateRoutes check the extra routes and rules rendered in the pod net ns
+func validateRoutes(routes []route.Route, rules []route.Rule) error {
+	for i := range routes {
+		err := routes[i].Validate()
+		if err != nil {
+			return fmt.Errorf("invalid extraRoutes, %w", err)
+		}
+	}
+	for i := range rules {
+		err := rules[i].Validate()
+		if err != nil {
+			return fmt.Errorf("invalid extraRules, %w", err)
+		}
+	}
+	return nil
+}
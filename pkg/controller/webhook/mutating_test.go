@@ -16,6 +16,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/controlplane"
 )
 
@@ -391,6 +392,95 @@ func Test_setResourceRequest1(t *testing.T) {
 	}
 }
 
+func Test_setNAT64(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+	pn := &v1beta1.PodNetworking{}
+
+	setNAT64(pod, pn)
+	assert.NotContains(t, pod.Annotations, types.PodNAT64)
+
+	pn.Spec.EnableNAT64 = true
+	setNAT64(pod, pn)
+	assert.Equal(t, "true", pod.Annotations[types.PodNAT64])
+}
+
+func Test_setEgressGateway(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default",
+			},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "partner",
+				Annotations: map[string]string{types.NamespaceEgressGateway: `{"name":"ns","destinationCIDRs":["203.0.113.0/24"]}`},
+			},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "invalid",
+				Annotations: map[string]string{types.NamespaceEgressGateway: `{"name":"ns"}`},
+			},
+		},
+	).Build()
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+	}
+
+	pod := newPod()
+	changed, err := setEgressGateway(context.Background(), fakeClient, "default", pod, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.NotContains(t, pod.Annotations, types.PodEgressGateway)
+
+	pod = newPod()
+	changed, err = setEgressGateway(context.Background(), fakeClient, "partner", pod, nil)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.JSONEq(t, `{"name":"ns","destinationCIDRs":["203.0.113.0/24"]}`, pod.Annotations[types.PodEgressGateway])
+
+	// podNetworking takes precedence
+	pn := &v1beta1.PodNetworking{Spec: v1beta1.PodNetworkingSpec{EgressGateway: &v1beta1.EgressGateway{
+		Name:             "pn",
+		DestinationCIDRs: []string{"198.51.100.0/24"},
+		VSwitchID:        "vsw-1",
+	}}}
+	pod = newPod()
+	changed, err = setEgressGateway(context.Background(), fakeClient, "partner", pod, pn)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.JSONEq(t, `{"name":"pn","destinationCIDRs":["198.51.100.0/24"],"vSwitchID":"vsw-1"}`, pod.Annotations[types.PodEgressGateway])
+
+	// set by user is overwritten
+	pod = newPod()
+	pod.Annotations[types.PodEgressGateway] = `{"name":"user","destinationCIDRs":["192.0.2.0/24"]}`
+	changed, err = setEgressGateway(context.Background(), fakeClient, "partner", pod, pn)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.JSONEq(t, `{"name":"pn","destinationCIDRs":["198.51.100.0/24"],"vSwitchID":"vsw-1"}`, pod.Annotations[types.PodEgressGateway])
+
+	// set by user is removed if no gateway is configured
+	pod = newPod()
+	pod.Annotations[types.PodEgressGateway] = `{"name":"ns","destinationCIDRs":["203.0.113.0/24"]}`
+	changed, err = setEgressGateway(context.Background(), fakeClient, "default", pod, nil)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, pod.Annotations, types.PodEgressGateway)
+
+	// reinvoked with the annotation set by the webhook
+	pod = newPod()
+	pod.Annotations[types.PodEgressGateway] = `{"name":"ns","destinationCIDRs":["203.0.113.0/24"]}`
+	changed, err = setEgressGateway(context.Background(), fakeClient, "partner", pod, nil)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	_, err = setEgressGateway(context.Background(), fakeClient, "invalid", newPod(), nil)
+	assert.Error(t, err)
+}
+
 func TestPodMatchSelectorReturnsTrueWhenLabelsMatch(t *testing.T) {
 	labelSelector := &metav1.LabelSelector{
 		MatchLabels: map[string]string{"key": "value"},
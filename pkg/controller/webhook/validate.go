@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
 
 	"k8s.io/apimachinery/pkg/util/json"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -47,6 +48,18 @@ func ValidateHook() *webhook.Admission {
 				return admission.Denied("security group can not more than 5")
 			}
 
+			if len(podNetworking.Spec.ReservedIPs) > 0 {
+				_, err = common.ParseReservedIPs(podNetworking.Spec.ReservedIPs)
+				if err != nil {
+					return webhook.Denied(fmt.Sprintf("invalid reservedIPs, %s", err))
+				}
+			}
+
+			err = validateRoutes(toRoutes(podNetworking.Spec.ExtraRoutes), toRules(podNetworking.Spec.ExtraRules))
+			if err != nil {
+				return webhook.Denied(err.Error())
+			}
+
 			if podNetworking.Spec.AllocationType.ReleaseStrategy == v1beta1.ReleaseStrategyTTL {
 				_, err = time.ParseDuration(podNetworking.Spec.AllocationType.ReleaseAfter)
 				if err != nil {
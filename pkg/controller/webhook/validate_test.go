@@ -165,6 +165,69 @@ func TestValidateHookDeniesWhenReleaseAfterIsInvalid(t *testing.T) {
 	assert.False(t, resp.Allowed)
 }
 
+func TestValidateHookDeniesWhenReservedIPsIsInvalid(t *testing.T) {
+	podNetworking := &v1beta1.PodNetworking{
+		Spec: v1beta1.PodNetworkingSpec{
+			Selector: v1beta1.Selector{
+				PodSelector: &metav1.LabelSelector{},
+			},
+			VSwitchOptions:   []string{"vsw-123"},
+			SecurityGroupIDs: []string{"sg-1"},
+			ReservedIPs:      []string{"192.168.0.20-192.168.0.10"},
+		},
+	}
+	raw, _ := json.Marshal(podNetworking)
+	req := webhook.AdmissionRequest{
+		AdmissionRequest: v1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{
+				Group:   "",
+				Version: "",
+				Kind:    "PodNetworking",
+			},
+			Object: runtime.RawExtension{
+				Raw: raw,
+			},
+		},
+	}
+	resp := ValidateHook().Handle(context.Background(), req)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "invalid reservedIPs")
+}
+
+func TestValidateHookDeniesWhenExtraRulesIsInvalid(t *testing.T) {
+	podNetworking := &v1beta1.PodNetworking{
+		Spec: v1beta1.PodNetworkingSpec{
+			Selector: v1beta1.Selector{
+				PodSelector: &metav1.LabelSelector{},
+			},
+			VSwitchOptions:   []string{"vsw-123"},
+			SecurityGroupIDs: []string{"sg-1"},
+			ExtraRoutes: []v1beta1.Route{
+				{Dst: "192.168.100.0/24", Gateway: "192.168.0.253", Table: 100},
+			},
+			ExtraRules: []v1beta1.Rule{
+				{From: "192.168.0.0/24"},
+			},
+		},
+	}
+	raw, _ := json.Marshal(podNetworking)
+	req := webhook.AdmissionRequest{
+		AdmissionRequest: v1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{
+				Group:   "",
+				Version: "",
+				Kind:    "PodNetworking",
+			},
+			Object: runtime.RawExtension{
+				Raw: raw,
+			},
+		},
+	}
+	resp := ValidateHook().Handle(context.Background(), req)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "invalid extraRules")
+}
+
 func TestValidateHookAllowsWhenAllConditionsAreMet(t *testing.T) {
 	podNetworking := &v1beta1.PodNetworking{
 		Spec: v1beta1.PodNetworkingSpec{
@@ -177,6 +240,12 @@ func TestValidateHookAllowsWhenAllConditionsAreMet(t *testing.T) {
 				ReleaseStrategy: v1beta1.ReleaseStrategyTTL,
 				ReleaseAfter:    "1h",
 			},
+			ExtraRoutes: []v1beta1.Route{
+				{Dst: "192.168.100.0/24", Gateway: "192.168.0.253", Table: 100},
+			},
+			ExtraRules: []v1beta1.Rule{
+				{From: "192.168.0.0/24", Table: 100},
+			},
 		},
 	}
 	raw, _ := json.Marshal(podNetworking)
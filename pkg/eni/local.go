@@ -209,10 +209,13 @@ func (l *Local) load(podResources []daemon.PodResources) error {
 		return err
 	}
 
-	for _, v := range ipv4 {
-		l.ipv4.Add(NewValidIP(v, netip.MustParseAddr(v.String()) == primary))
-		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
-		metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+	// in ipv6 only stack, the primary ipv4 is kept by the eni and not used by pods
+	if l.enableIPv4 {
+		for _, v := range ipv4 {
+			l.ipv4.Add(NewValidIP(v, netip.MustParseAddr(v.String()) == primary))
+			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+			metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+		}
 	}
 	l.ipv6.PutValid(ipv6...)
 	metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6)))
@@ -676,7 +679,7 @@ func (l *Local) factoryAllocWorker(ctx context.Context) {
 			l.allocatingV6 = max(l.allocatingV6, 0)
 
 			primary, err := netip.ParseAddr(eni.PrimaryIP.IPv4.String())
-			if err == nil {
+			if err == nil && l.enableIPv4 {
 				for _, v := range ipv4Set {
 					l.ipv4.Add(NewValidIP(v, netip.MustParseAddr(v.String()) == primary))
 
@@ -980,6 +983,7 @@ func (l *Local) Status() Status {
 
 	s.MAC = l.eni.MAC
 	s.NetworkInterfaceID = l.eni.ID
+	s.VSwitchID = l.eni.VSwitchID
 
 	usage := make([][]string, 0, len(l.ipv4)+len(l.ipv6))
 	for _, v := range l.ipv4 {
@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/AliyunContainerService/terway/pkg/factory"
+	factorymocks "github.com/AliyunContainerService/terway/pkg/factory/mocks"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
@@ -211,6 +212,35 @@ func TestLocal_DisposeWholeENI(t *testing.T) {
 	assert.Equal(t, statusDeleting, local.status)
 }
 
+func TestLocal_load_IPv6Only(t *testing.T) {
+	f := factorymocks.NewFactory(t)
+	f.On("LoadNetworkInterface", "00:00:00:00:00:01").Return(
+		[]netip.Addr{netip.MustParseAddr("192.0.2.1")},
+		[]netip.Addr{netip.MustParseAddr("fd00:46dd:e::1"), netip.MustParseAddr("fd00:46dd:e::2")}, nil)
+
+	eni := &daemon.ENI{ID: "eni-1", MAC: "00:00:00:00:00:01"}
+	eni.PrimaryIP.SetIP("192.0.2.1")
+	local := NewLocalTest(eni, f, &types.PoolConfig{MaxIPPerENI: 10, EnableIPv6: true}, "")
+
+	err := local.load(nil)
+	assert.NoError(t, err)
+
+	// the primary ipv4 is not put into the pool
+	assert.Equal(t, 0, len(local.ipv4))
+	assert.Equal(t, 2, len(local.ipv6))
+
+	idles, inUse, err := local.Usage()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, idles)
+	assert.Equal(t, 0, inUse)
+
+	// only the ipv6 is released
+	n := local.Dispose(1)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, statusInUse, local.status)
+	assert.Equal(t, 1, len(local.ipv6.Deleting()))
+}
+
 func TestLocal_Allocate_NoCache(t *testing.T) {
 	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "")
 
@@ -88,6 +88,7 @@ func (l *RemoteIPResource) ToRPC() []*rpc.NetConf {
 			ENIInfo:      eniInfo,
 			IfName:       alloc.Interface,
 			ExtraRoutes:  parseExtraRoute(alloc.ExtraRoutes),
+			ExtraRules:   parseExtraRule(alloc.ExtraRules),
 			DefaultRoute: alloc.DefaultRoute,
 		})
 	}
@@ -229,7 +230,29 @@ func parseExtraRoute(routes []podENITypes.Route) []*rpc.Route {
 	var res []*rpc.Route
 	for _, r := range routes {
 		res = append(res, &rpc.Route{
-			Dst: r.Dst,
+			Dst:     r.Dst,
+			Gateway: r.Gateway,
+			Metric:  int32(r.Metric),
+			Table:   int32(r.Table),
+			Src:     r.Src,
+			MTU:     int32(r.MTU),
+		})
+	}
+	return res
+}
+
+func parseExtraRule(rules []podENITypes.Rule) []*rpc.Rule {
+	if rules == nil {
+		return nil
+	}
+	var res []*rpc.Rule
+	for _, r := range rules {
+		res = append(res, &rpc.Rule{
+			From:     r.From,
+			To:       r.To,
+			FwMark:   r.FwMark,
+			Priority: int32(r.Priority),
+			Table:    int32(r.Table),
 		})
 	}
 	return res
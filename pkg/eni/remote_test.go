@@ -68,3 +68,17 @@ func TestToRPC(t *testing.T) {
 		assert.Equal(t, true, result[0].DefaultRoute)
 	})
 }
+
+func Test_parseExtraRoute(t *testing.T) {
+	routes := parseExtraRoute([]podENITypes.Route{{Dst: "192.168.100.0/24", Gateway: "192.168.0.253", Metric: 100, Table: 100, Src: "192.168.0.10", MTU: 1400}})
+	assert.Len(t, routes, 1)
+	assert.Equal(t, "192.168.0.253", routes[0].Gateway)
+	assert.Equal(t, int32(100), routes[0].Table)
+	assert.Equal(t, int32(1400), routes[0].MTU)
+
+	rules := parseExtraRule([]podENITypes.Rule{{From: "192.168.0.0/24", FwMark: 0x10, Priority: 300, Table: 100}})
+	assert.Len(t, rules, 1)
+	assert.Equal(t, uint32(0x10), rules[0].FwMark)
+	assert.Equal(t, int32(300), rules[0].Priority)
+	assert.Nil(t, parseExtraRule(nil))
+}
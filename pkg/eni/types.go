@@ -231,6 +231,7 @@ type NetworkResource interface {
 type Status struct {
 	NetworkInterfaceID   string
 	MAC                  string
+	VSwitchID            string
 	Type                 string
 	AllocInhibitExpireAt string
 
@@ -36,6 +36,7 @@ type Aliyun struct {
 	enableIPv4, enableIPv6 bool
 
 	instanceID string
+	nodeName   string
 	zoneID     string
 
 	openAPI interface {
@@ -67,6 +68,7 @@ func NewAliyun(ctx context.Context, openAPI *client.OpenAPI, getter eni.ENIInfoG
 		enableIPv4:       cfg.EnableIPv4,
 		enableIPv6:       cfg.EnableIPv6,
 		instanceID:       cfg.InstanceID,
+		nodeName:         cfg.NodeName,
 		zoneID:           cfg.ZoneID,
 		vSwitchOptions:   cfg.VSwitchOptions,
 		securityGroupIDs: cfg.SecurityGroupIDs,
@@ -116,6 +118,7 @@ func (a *Aliyun) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon
 				InstanceID:       a.instanceID,
 				Tags:             a.eniTags,
 				ResourceGroupID:  a.resourceGroupID,
+				Owner:            &client.ENIOwner{NodeName: a.nodeName, Component: types.ComponentTerwayDaemon},
 			},
 			Backoff: &bo,
 		}
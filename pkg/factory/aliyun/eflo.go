@@ -66,7 +66,7 @@ func (p *Eflo) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.E
 
 	klog.Infof("CreateNetworkInterface %s %s %s %s", p.zoneID, p.instanceID, vsw.ID, p.securityGroupIDs[0])
 
-	_, eniID, err := p.api.CreateElasticNetworkInterface(p.zoneID, p.instanceID, vsw.ID, p.securityGroupIDs[0])
+	_, eniID, err := p.api.CreateElasticNetworkInterface(ctx, p.zoneID, p.instanceID, vsw.ID, p.securityGroupIDs[0])
 	if err != nil {
 		return nil, nil, nil, err
 	}
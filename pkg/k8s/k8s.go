@@ -56,7 +56,8 @@ const (
 	eventTypeNormal  = corev1.EventTypeNormal
 	eventTypeWarning = corev1.EventTypeWarning
 
-	labelDynamicConfig = "terway-config"
+	// LabelDynamicConfig node label, the name of the configmap merged to the eni-config
+	LabelDynamicConfig = "terway-config"
 
 	ConditionFalse = "false"
 	conditionTrue  = "true"
@@ -71,6 +72,9 @@ const (
 	TERABYTE
 )
 
+// QoSAnnotations the pod annotations take effect on running pods
+var QoSAnnotations = []string{podIngressBandwidth, podEgressBandwidth, types.NetworkPriority}
+
 var (
 	storageCleanTimeout = 1 * time.Hour
 	storageCleanPeriod  = 5 * time.Minute
@@ -494,7 +498,7 @@ func (k *k8s) RecordPodEvent(podName, podNamespace, eventType, reason, message s
 // GetNodeDynamicConfigLabel returns value with label config
 func (k *k8s) GetNodeDynamicConfigLabel() string {
 	// use node cached in newK8s()
-	cfgName, ok := k.node.Labels[labelDynamicConfig]
+	cfgName, ok := k.node.Labels[LabelDynamicConfig]
 	if !ok {
 		return ""
 	}
@@ -590,6 +594,15 @@ func convertPod(daemonMode string, enableErdma bool, statefulWorkloadKindSet set
 		}
 	}
 
+	pi.NAT64 = parseBool(podAnnotation[types.PodNAT64])
+
+	if gw, err := types.ParseEgressGateway(podAnnotation[types.PodEgressGateway]); err == nil {
+		pi.EgressGateway = gw
+	} else {
+		_ = tracing.RecordPodEvent(pod.Name, pod.Namespace, eventTypeWarning,
+			"ParseFailed", fmt.Sprintf("Parse pod annotation %s failed.", types.PodEgressGateway))
+	}
+
 	if enableErdma {
 		pi.ERdma = isERDMA(pod)
 	}
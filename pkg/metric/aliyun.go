@@ -30,4 +30,39 @@ var (
 		},
 		[]string{"api"},
 	)
+
+	// OpenAPIThrottleTotal the count of openAPI request rejected by flow control
+	OpenAPIThrottleTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aliyun_openapi_throttle_total",
+			Help: "count of aliyun openapi request throttled",
+		},
+		[]string{"api"},
+	)
+
+	// OpenAPIEffectiveQPS the current rate of the adaptive rate limiter
+	OpenAPIEffectiveQPS = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aliyun_openapi_effective_qps",
+			Help: "current qps allowed by the adaptive rate limiter",
+		},
+		[]string{"api"},
+	)
+
+	// CredentialProvider the credential provider in use, 1 for the active one
+	CredentialProvider = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aliyun_credential_provider",
+			Help: "credential provider in use, 1 for the active one",
+		},
+		[]string{"provider"},
+	)
+
+	// CredentialExpiration the expiration of the credential in use
+	CredentialExpiration = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "aliyun_credential_expiration_timestamp_seconds",
+			Help: "expiration of the credential in use, in unix seconds",
+		},
+	)
 )
@@ -0,0 +1,355 @@
+package nat64
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+// the programs are tc direct action programs, addresses of the pod are compiled in as constants,
+// so no map is required and the translation is stateless.
+// Only tcp and udp are translated, other packets to the prefix are dropped.
+
+const (
+	tcActOK   = 0
+	tcActShot = 2
+
+	ethHLen  = 14
+	ipv4HLen = 20
+	ipv6HLen = 40
+
+	protoTCP = 6
+	protoUDP = 17
+
+	tcpCsumOff = 16
+	udpCsumOff = 6
+
+	// bpf_l4_csum_replace flags
+	fPseudoHdr    = 1 << 4
+	fMarkMangled0 = 1 << 5
+
+	// offset of protocol in struct __sk_buff
+	skbProtocolOff = 16
+
+	ethProtoOff = 12
+)
+
+var (
+	ethPIP   = be16(0x0800)
+	ethPIPv6 = be16(0x86dd)
+)
+
+// be16 return the value which has the network byte order layout in memory
+func be16(v uint16) int32 {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return int32(binary.NativeEndian.Uint16(b))
+}
+
+// word return the 32 bit value read from b in native byte order
+func word(b []byte) int32 {
+	return int32(binary.NativeEndian.Uint32(b))
+}
+
+// NewIngressProgram create the program translate ipv6 packets from the pod to ipv4, attach it on tc ingress of the host side link
+func NewIngressProgram(cfg *Config) (*ebpf.Program, error) {
+	insns, err := ingressInstructions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newProgram("terway_nat64_in", insns)
+}
+
+// NewEgressProgram create the program translate ipv4 packets to the pod to ipv6, attach it on tc egress of the host side link
+func NewEgressProgram(cfg *Config) (*ebpf.Program, error) {
+	insns, err := egressInstructions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newProgram("terway_nat64_eg", insns)
+}
+
+func newProgram(name string, insns asm.Instructions) (*ebpf.Program, error) {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         name,
+		Type:         ebpf.SchedCLS,
+		License:      "GPL",
+		Instructions: insns,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error load nat64 program %s, %w", name, err)
+	}
+	return prog, nil
+}
+
+// ingressInstructions ipv6 -> ipv4
+// stack: fp-40 ipv6 header, fp-64 ipv4 header, fp-72 eth proto
+func ingressInstructions(cfg *Config) (asm.Instructions, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	prefix := cfg.Prefix.IP.To16()
+	podIPv6 := cfg.PodIPv6.To16()
+	podIPv4 := cfg.PodIPv4.To4()
+
+	const (
+		v6     = -40
+		v4     = -64
+		ethOff = -72
+	)
+
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.LoadMem(asm.R2, asm.R6, skbProtocolOff, asm.Word),
+		asm.JNE.Imm32(asm.R2, ethPIPv6, "ok"),
+
+		// load the ipv6 header
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethHLen),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, v6),
+		asm.Mov.Imm(asm.R4, ipv6HLen),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "ok"),
+	}
+
+	// only packets to the prefix is translated
+	for i := 0; i < 3; i++ {
+		insns = append(insns,
+			asm.LoadMem(asm.R2, asm.RFP, int16(v6+24+i*4), asm.Word),
+			asm.JNE.Imm32(asm.R2, word(prefix[i*4:]), "ok"),
+		)
+	}
+	// and must be sent by the pod
+	for i := 0; i < 4; i++ {
+		insns = append(insns,
+			asm.LoadMem(asm.R2, asm.RFP, int16(v6+8+i*4), asm.Word),
+			asm.JNE.Imm32(asm.R2, word(podIPv6[i*4:]), "drop"),
+		)
+	}
+
+	insns = append(insns,
+		asm.LoadMem(asm.R8, asm.RFP, v6+6, asm.Byte),
+		asm.JEq.Imm(asm.R8, protoTCP, "l4"),
+		asm.JNE.Imm(asm.R8, protoUDP, "drop"),
+
+		// build the ipv4 header
+		asm.StoreImm(asm.RFP, v4, 0x45, asm.Byte).WithSymbol("l4"),
+		asm.StoreImm(asm.RFP, v4+1, 0, asm.Byte),
+		asm.LoadMem(asm.R2, asm.RFP, v6+4, asm.Half),
+		asm.HostTo(asm.BE, asm.R2, asm.Half),
+		asm.Add.Imm(asm.R2, ipv4HLen),
+		asm.HostTo(asm.BE, asm.R2, asm.Half),
+		asm.StoreMem(asm.RFP, v4+2, asm.R2, asm.Half),
+		asm.StoreImm(asm.RFP, v4+4, 0, asm.Half),
+		// don't fragment
+		asm.StoreImm(asm.RFP, v4+6, int64(be16(0x4000)), asm.Half),
+		asm.LoadMem(asm.R2, asm.RFP, v6+7, asm.Byte),
+		asm.StoreMem(asm.RFP, v4+8, asm.R2, asm.Byte),
+		asm.StoreMem(asm.RFP, v4+9, asm.R8, asm.Byte),
+		asm.StoreImm(asm.RFP, v4+10, 0, asm.Half),
+		asm.StoreImm(asm.RFP, v4+12, int64(word(podIPv4)), asm.Word),
+		asm.LoadMem(asm.R2, asm.RFP, v6+36, asm.Word),
+		asm.StoreMem(asm.RFP, v4+16, asm.R2, asm.Word),
+
+		// ipv4 header checksum
+		asm.Mov.Imm(asm.R1, 0),
+		asm.Mov.Imm(asm.R2, 0),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, v4),
+		asm.Mov.Imm(asm.R4, ipv4HLen),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnCsumDiff.Call(),
+	)
+	insns = append(insns, csumFold(asm.R0)...)
+	insns = append(insns,
+		asm.StoreMem(asm.RFP, v4+10, asm.R0, asm.Half),
+
+		// the l4 checksum diff of the pseudo header, length and protocol are the same
+		asm.Mov.Reg(asm.R1, asm.RFP),
+		asm.Add.Imm(asm.R1, v6+8),
+		asm.Mov.Imm(asm.R2, 32),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, v4+12),
+		asm.Mov.Imm(asm.R4, 8),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnCsumDiff.Call(),
+		asm.Mov.Reg(asm.R7, asm.R0),
+	)
+	insns = append(insns, rewrite(ethPIP, ethOff, v4, ipv4HLen)...)
+	insns = append(insns, l4CsumReplace(ethHLen+ipv4HLen)...)
+	insns = append(insns, exits()...)
+	return insns, nil
+}
+
+// egressInstructions ipv4 -> ipv6
+// stack: fp-24 ipv4 header, fp-64 ipv6 header, fp-72 eth proto, fp-80 udp checksum
+func egressInstructions(cfg *Config) (asm.Instructions, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	prefix := cfg.Prefix.IP.To16()
+	podIPv6 := cfg.PodIPv6.To16()
+	podIPv4 := cfg.PodIPv4.To4()
+
+	const (
+		v4      = -24
+		v6      = -64
+		ethOff  = -72
+		udpCsum = -80
+	)
+
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.LoadMem(asm.R2, asm.R6, skbProtocolOff, asm.Word),
+		asm.JNE.Imm32(asm.R2, ethPIP, "ok"),
+
+		// load the ipv4 header
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethHLen),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, v4),
+		asm.Mov.Imm(asm.R4, ipv4HLen),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "ok"),
+
+		// only packets to the pod is translated
+		asm.LoadMem(asm.R2, asm.RFP, v4+16, asm.Word),
+		asm.JNE.Imm32(asm.R2, word(podIPv4), "ok"),
+
+		// ipv4 options and fragments are not supported
+		asm.LoadMem(asm.R2, asm.RFP, v4, asm.Byte),
+		asm.JNE.Imm(asm.R2, 0x45, "drop"),
+		asm.LoadMem(asm.R2, asm.RFP, v4+6, asm.Half),
+		asm.HostTo(asm.BE, asm.R2, asm.Half),
+		asm.And.Imm(asm.R2, 0x3fff),
+		asm.JNE.Imm(asm.R2, 0, "drop"),
+
+		asm.LoadMem(asm.R8, asm.RFP, v4+9, asm.Byte),
+		asm.JEq.Imm(asm.R8, protoTCP, "l4"),
+		asm.JNE.Imm(asm.R8, protoUDP, "drop"),
+
+		// udp without checksum is not allowed in ipv6
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethHLen+ipv4HLen+udpCsumOff),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, udpCsum),
+		asm.Mov.Imm(asm.R4, 2),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "drop"),
+		asm.LoadMem(asm.R2, asm.RFP, udpCsum, asm.Half),
+		asm.JEq.Imm(asm.R2, 0, "drop"),
+
+		// build the ipv6 header
+		asm.StoreImm(asm.RFP, v6, int64(word([]byte{0x60, 0, 0, 0})), asm.Word).WithSymbol("l4"),
+		asm.LoadMem(asm.R2, asm.RFP, v4+2, asm.Half),
+		asm.HostTo(asm.BE, asm.R2, asm.Half),
+		asm.Sub.Imm(asm.R2, ipv4HLen),
+		asm.HostTo(asm.BE, asm.R2, asm.Half),
+		asm.StoreMem(asm.RFP, v6+4, asm.R2, asm.Half),
+		asm.StoreMem(asm.RFP, v6+6, asm.R8, asm.Byte),
+		asm.LoadMem(asm.R2, asm.RFP, v4+8, asm.Byte),
+		asm.StoreMem(asm.RFP, v6+7, asm.R2, asm.Byte),
+	}
+	for i := 0; i < 3; i++ {
+		insns = append(insns, asm.StoreImm(asm.RFP, int16(v6+8+i*4), int64(word(prefix[i*4:])), asm.Word))
+	}
+	insns = append(insns,
+		asm.LoadMem(asm.R2, asm.RFP, v4+12, asm.Word),
+		asm.StoreMem(asm.RFP, v6+20, asm.R2, asm.Word),
+	)
+	for i := 0; i < 4; i++ {
+		insns = append(insns, asm.StoreImm(asm.RFP, int16(v6+24+i*4), int64(word(podIPv6[i*4:])), asm.Word))
+	}
+
+	insns = append(insns,
+		// the l4 checksum diff of the pseudo header
+		asm.Mov.Reg(asm.R1, asm.RFP),
+		asm.Add.Imm(asm.R1, v4+12),
+		asm.Mov.Imm(asm.R2, 8),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, v6+8),
+		asm.Mov.Imm(asm.R4, 32),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnCsumDiff.Call(),
+		asm.Mov.Reg(asm.R7, asm.R0),
+	)
+	insns = append(insns, rewrite(ethPIPv6, ethOff, v6, ipv6HLen)...)
+	insns = append(insns, l4CsumReplace(ethHLen+ipv6HLen)...)
+	insns = append(insns, exits()...)
+	return insns, nil
+}
+
+// csumFold fold the 32 bit sum in reg to the 16 bit checksum
+func csumFold(reg asm.Register) asm.Instructions {
+	var insns asm.Instructions
+	for i := 0; i < 2; i++ {
+		insns = append(insns,
+			asm.Mov.Reg(asm.R2, reg),
+			asm.RSh.Imm(asm.R2, 16),
+			asm.And.Imm(reg, 0xffff),
+			asm.Add.Reg(reg, asm.R2),
+		)
+	}
+	return append(insns,
+		asm.Xor.Imm(reg, 0xffff),
+		asm.And.Imm(reg, 0xffff),
+	)
+}
+
+// rewrite change the protocol of skb and write the l3 header in stack to it
+func rewrite(ethProto int32, ethOff, hdrOff int16, hdrLen int32) asm.Instructions {
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethProto),
+		asm.Mov.Imm(asm.R3, 0),
+		asm.FnSkbChangeProto.Call(),
+		asm.JNE.Imm(asm.R0, 0, "drop"),
+
+		asm.StoreImm(asm.RFP, ethOff, int64(ethProto), asm.Half),
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethProtoOff),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, int32(ethOff)),
+		asm.Mov.Imm(asm.R4, 2),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnSkbStoreBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "drop"),
+
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, ethHLen),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, int32(hdrOff)),
+		asm.Mov.Imm(asm.R4, hdrLen),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnSkbStoreBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "drop"),
+	}
+}
+
+// l4CsumReplace apply the checksum diff in R7 to the l4 header at l4Off, R8 is the l4 protocol
+func l4CsumReplace(l4Off int32) asm.Instructions {
+	return asm.Instructions{
+		asm.Mov.Imm(asm.R2, l4Off+tcpCsumOff),
+		asm.Mov.Imm(asm.R5, fPseudoHdr),
+		asm.JEq.Imm(asm.R8, protoTCP, "csum"),
+		asm.Mov.Imm(asm.R2, l4Off+udpCsumOff),
+		asm.Mov.Imm(asm.R5, fPseudoHdr|fMarkMangled0),
+		asm.Mov.Reg(asm.R1, asm.R6).WithSymbol("csum"),
+		asm.Mov.Imm(asm.R3, 0),
+		asm.Mov.Reg(asm.R4, asm.R7),
+		asm.FnL4CsumReplace.Call(),
+		asm.JNE.Imm(asm.R0, 0, "drop"),
+	}
+}
+
+func exits() asm.Instructions {
+	return asm.Instructions{
+		asm.Mov.Imm(asm.R0, tcActOK).WithSymbol("ok"),
+		asm.Return(),
+		asm.Mov.Imm(asm.R0, tcActShot).WithSymbol("drop"),
+		asm.Return(),
+	}
+}
@@ -0,0 +1,254 @@
+//go:build privileged
+
+package nat64
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	podIPv6  = net.ParseIP("fd00::10")
+	podIPv4  = net.ParseIP("100.64.0.10").To4()
+	remoteV4 = net.ParseIP("192.0.2.1").To4()
+)
+
+func testConfig(t *testing.T) *Config {
+	prefix, err := ParsePrefix("")
+	require.NoError(t, err)
+	return &Config{Prefix: prefix, PodIPv6: podIPv6, PodIPv4: podIPv4}
+}
+
+func csum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+func fold(sum uint32) uint16 {
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func l4Csum(src, dst net.IP, proto byte, l4 []byte) uint16 {
+	pseudo := append(append([]byte{}, src...), dst...)
+	pseudo = append(pseudo, 0, proto, byte(len(l4)>>8), byte(len(l4)))
+	return fold(csum(pseudo) + csum(l4))
+}
+
+// l4Packet return tcp or udp header with payload, checksum is filled
+func l4Packet(proto byte, src, dst net.IP, sport, dport uint16) []byte {
+	var l4 []byte
+	payload := []byte("hello nat64")
+	csumOff := udpCsumOff
+	if proto == protoTCP {
+		l4 = make([]byte, 20)
+		l4[12] = 5 << 4
+		l4[13] = 0x02 // syn
+		binary.BigEndian.PutUint16(l4[14:], 1024)
+		csumOff = tcpCsumOff
+	} else {
+		l4 = make([]byte, 8)
+		binary.BigEndian.PutUint16(l4[4:], uint16(8+len(payload)))
+	}
+	binary.BigEndian.PutUint16(l4[0:], sport)
+	binary.BigEndian.PutUint16(l4[2:], dport)
+	l4 = append(l4, payload...)
+	binary.BigEndian.PutUint16(l4[csumOff:], l4Csum(src, dst, proto, l4))
+	return l4
+}
+
+func ipv6Packet(src, dst net.IP, proto byte, l4 []byte) []byte {
+	pkt := make([]byte, ethHLen+ipv6HLen)
+	binary.BigEndian.PutUint16(pkt[ethProtoOff:], 0x86dd)
+	h := pkt[ethHLen:]
+	h[0] = 0x60
+	binary.BigEndian.PutUint16(h[4:], uint16(len(l4)))
+	h[6] = proto
+	h[7] = 64
+	copy(h[8:], src.To16())
+	copy(h[24:], dst.To16())
+	return append(pkt, l4...)
+}
+
+func ipv4Packet(src, dst net.IP, proto byte, l4 []byte) []byte {
+	pkt := make([]byte, ethHLen+ipv4HLen)
+	binary.BigEndian.PutUint16(pkt[ethProtoOff:], 0x0800)
+	h := pkt[ethHLen:]
+	h[0] = 0x45
+	binary.BigEndian.PutUint16(h[2:], uint16(ipv4HLen+len(l4)))
+	h[8] = 63
+	h[9] = proto
+	copy(h[12:], src.To4())
+	copy(h[16:], dst.To4())
+	binary.BigEndian.PutUint16(h[10:], fold(csum(h[:ipv4HLen])))
+	return append(pkt, l4...)
+}
+
+func TestIngressProgram(t *testing.T) {
+	cfg := testConfig(t)
+	prog, err := NewIngressProgram(cfg)
+	require.NoError(t, err)
+	defer prog.Close()
+
+	dst := Embed(cfg.Prefix, remoteV4)
+	for _, proto := range []byte{protoTCP, protoUDP} {
+		l4 := l4Packet(proto, podIPv6, dst, 40000, 80)
+		ret, out, err := prog.Test(ipv6Packet(podIPv6, dst, proto, l4))
+		require.NoError(t, err)
+		assert.Equal(t, uint32(tcActOK), ret)
+
+		require.Equal(t, ethHLen+ipv4HLen+len(l4), len(out))
+		assert.Equal(t, uint16(0x0800), binary.BigEndian.Uint16(out[ethProtoOff:]))
+		h := out[ethHLen : ethHLen+ipv4HLen]
+		assert.Equal(t, byte(0x45), h[0])
+		assert.Equal(t, uint16(ipv4HLen+len(l4)), binary.BigEndian.Uint16(h[2:]))
+		assert.Equal(t, uint16(0x4000), binary.BigEndian.Uint16(h[6:]))
+		assert.Equal(t, byte(64), h[8])
+		assert.Equal(t, proto, h[9])
+		assert.Equal(t, podIPv4, net.IP(h[12:16]))
+		assert.Equal(t, remoteV4, net.IP(h[16:20]))
+		assert.Equal(t, uint16(0), fold(csum(h)), "ipv4 header checksum")
+
+		outL4 := out[ethHLen+ipv4HLen:]
+		assert.Equal(t, zeroCsum(proto, l4), zeroCsum(proto, outL4))
+		assert.Equal(t, l4Csum(podIPv4, remoteV4, proto, zeroCsum(proto, outL4)), binary.BigEndian.Uint16(outL4[csumOffOf(proto):]), "l4 checksum")
+	}
+}
+
+func TestIngressProgramSkip(t *testing.T) {
+	cfg := testConfig(t)
+	prog, err := NewIngressProgram(cfg)
+	require.NoError(t, err)
+	defer prog.Close()
+
+	// not to the prefix
+	other := net.ParseIP("fd01::1")
+	pkt := ipv6Packet(podIPv6, other, protoTCP, l4Packet(protoTCP, podIPv6, other, 40000, 80))
+	ret, out, err := prog.Test(pkt)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActOK), ret)
+	assert.Equal(t, pkt, out)
+
+	// ipv4 is not touched
+	pkt = ipv4Packet(podIPv4, remoteV4, protoUDP, l4Packet(protoUDP, podIPv4, remoteV4, 40000, 53))
+	ret, out, err = prog.Test(pkt)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActOK), ret)
+	assert.Equal(t, pkt, out)
+
+	dst := Embed(cfg.Prefix, remoteV4)
+	// spoofed source
+	spoofed := net.ParseIP("fd00::11")
+	ret, _, err = prog.Test(ipv6Packet(spoofed, dst, protoTCP, l4Packet(protoTCP, spoofed, dst, 40000, 80)))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActShot), ret)
+
+	// icmpv6 is not translated
+	ret, _, err = prog.Test(ipv6Packet(podIPv6, dst, 58, make([]byte, 16)))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActShot), ret)
+}
+
+func TestEgressProgram(t *testing.T) {
+	cfg := testConfig(t)
+	prog, err := NewEgressProgram(cfg)
+	require.NoError(t, err)
+	defer prog.Close()
+
+	src := Embed(cfg.Prefix, remoteV4)
+	for _, proto := range []byte{protoTCP, protoUDP} {
+		l4 := l4Packet(proto, remoteV4, podIPv4, 80, 40000)
+		ret, out, err := prog.Test(ipv4Packet(remoteV4, podIPv4, proto, l4))
+		require.NoError(t, err)
+		assert.Equal(t, uint32(tcActOK), ret)
+
+		require.Equal(t, ethHLen+ipv6HLen+len(l4), len(out))
+		assert.Equal(t, uint16(0x86dd), binary.BigEndian.Uint16(out[ethProtoOff:]))
+		h := out[ethHLen : ethHLen+ipv6HLen]
+		assert.Equal(t, byte(0x60), h[0])
+		assert.Equal(t, uint16(len(l4)), binary.BigEndian.Uint16(h[4:]))
+		assert.Equal(t, proto, h[6])
+		assert.Equal(t, byte(63), h[7])
+		assert.Equal(t, src, net.IP(h[8:24]))
+		assert.Equal(t, podIPv6.To16(), net.IP(h[24:40]))
+
+		outL4 := out[ethHLen+ipv6HLen:]
+		assert.Equal(t, zeroCsum(proto, l4), zeroCsum(proto, outL4))
+		assert.Equal(t, l4Csum(src, podIPv6.To16(), proto, zeroCsum(proto, outL4)), binary.BigEndian.Uint16(outL4[csumOffOf(proto):]), "l4 checksum")
+	}
+}
+
+func TestEgressProgramSkip(t *testing.T) {
+	cfg := testConfig(t)
+	prog, err := NewEgressProgram(cfg)
+	require.NoError(t, err)
+	defer prog.Close()
+
+	// not to the pod
+	other := net.ParseIP("100.64.0.11").To4()
+	pkt := ipv4Packet(remoteV4, other, protoTCP, l4Packet(protoTCP, remoteV4, other, 80, 40000))
+	ret, out, err := prog.Test(pkt)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActOK), ret)
+	assert.Equal(t, pkt, out)
+
+	// udp without checksum
+	l4 := l4Packet(protoUDP, remoteV4, podIPv4, 53, 40000)
+	binary.BigEndian.PutUint16(l4[udpCsumOff:], 0)
+	ret, _, err = prog.Test(ipv4Packet(remoteV4, podIPv4, protoUDP, l4))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActShot), ret)
+
+	// fragment
+	pkt = ipv4Packet(remoteV4, podIPv4, protoTCP, l4Packet(protoTCP, remoteV4, podIPv4, 80, 40000))
+	pkt[ethHLen+6] = 0x20
+	ret, _, err = prog.Test(pkt)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(tcActShot), ret)
+}
+
+func TestRoundTrip(t *testing.T) {
+	cfg := testConfig(t)
+	in, err := NewIngressProgram(cfg)
+	require.NoError(t, err)
+	defer in.Close()
+	eg, err := NewEgressProgram(&Config{Prefix: cfg.Prefix, PodIPv6: podIPv6, PodIPv4: remoteV4})
+	require.NoError(t, err)
+	defer eg.Close()
+
+	dst := Embed(cfg.Prefix, remoteV4)
+	pkt := ipv6Packet(podIPv6, dst, protoUDP, l4Packet(protoUDP, podIPv6, dst, 40000, 53))
+	_, v4, err := in.Test(pkt)
+	require.NoError(t, err)
+	// translate back with the remote as the pod, the l4 header is kept
+	_, v6, err := eg.Test(v4)
+	require.NoError(t, err)
+
+	assert.Equal(t, Embed(cfg.Prefix, podIPv4), net.IP(v6[ethHLen+8:ethHLen+24]))
+	assert.Equal(t, pkt[ethHLen+ipv6HLen:ethHLen+ipv6HLen+udpCsumOff], v6[ethHLen+ipv6HLen:ethHLen+ipv6HLen+udpCsumOff])
+}
+
+func csumOffOf(proto byte) int {
+	if proto == protoTCP {
+		return tcpCsumOff
+	}
+	return udpCsumOff
+}
+
+func zeroCsum(proto byte, l4 []byte) []byte {
+	b := append([]byte{}, l4...)
+	binary.BigEndian.PutUint16(b[csumOffOf(proto):], 0)
+	return b
+}
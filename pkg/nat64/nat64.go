@@ -0,0 +1,74 @@
+package nat64
+
+import (
+	"fmt"
+	"net"
+)
+
+// DefaultPrefix is the well-known prefix defined in rfc6052
+const DefaultPrefix = "64:ff9b::/96"
+
+// Config is the stateless translation of one pod
+type Config struct {
+	// Prefix the /96 prefix the ipv4 address is embedded in
+	Prefix *net.IPNet
+	// PodIPv6 the ipv6 address of the pod
+	PodIPv6 net.IP
+	// PodIPv4 the ipv4 address the pod is translated to
+	PodIPv4 net.IP
+}
+
+// ParsePrefix parse the nat64 prefix, default to the well-known prefix. Only /96 prefix is supported.
+func ParsePrefix(s string) (*net.IPNet, error) {
+	if s == "" {
+		s = DefaultPrefix
+	}
+	ip, prefix, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("nat64 prefix %s is invalid, %w", s, err)
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("nat64 prefix %s is not ipv6", s)
+	}
+	ones, _ := prefix.Mask.Size()
+	if ones != 96 {
+		return nil, fmt.Errorf("nat64 prefix %s is not /96", s)
+	}
+	return prefix, nil
+}
+
+// Embed return the ipv6 address of the ipv4 address under the prefix
+func Embed(prefix *net.IPNet, ipv4 net.IP) net.IP {
+	v4 := ipv4.To4()
+	if v4 == nil {
+		return nil
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, prefix.IP.To16()[:12])
+	copy(ip[12:], v4)
+	return ip
+}
+
+// Extract return the ipv4 address embedded in the ipv6 address, nil if the address is not under the prefix
+func Extract(prefix *net.IPNet, ipv6 net.IP) net.IP {
+	if ipv6.To4() != nil || !prefix.Contains(ipv6) {
+		return nil
+	}
+	return net.IPv4(ipv6[12], ipv6[13], ipv6[14], ipv6[15]).To4()
+}
+
+func (c *Config) validate() error {
+	if c.Prefix == nil || c.PodIPv6 == nil || c.PodIPv4 == nil {
+		return fmt.Errorf("nat64 config is incomplete")
+	}
+	if ones, bits := c.Prefix.Mask.Size(); ones != 96 || bits != 128 {
+		return fmt.Errorf("nat64 prefix %s is not /96", c.Prefix)
+	}
+	if c.PodIPv6.To4() != nil || c.PodIPv6.To16() == nil {
+		return fmt.Errorf("pod ipv6 %s is invalid", c.PodIPv6)
+	}
+	if c.PodIPv4.To4() == nil {
+		return fmt.Errorf("pod ipv4 %s is invalid", c.PodIPv4)
+	}
+	return nil
+}
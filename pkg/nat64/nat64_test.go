@@ -0,0 +1,49 @@
+package nat64
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrefix(t *testing.T) {
+	prefix, err := ParsePrefix("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPrefix, prefix.String())
+
+	prefix, err = ParsePrefix("fd64::/96")
+	assert.NoError(t, err)
+	assert.Equal(t, "fd64::/96", prefix.String())
+
+	_, err = ParsePrefix("64:ff9b::/64")
+	assert.Error(t, err)
+	_, err = ParsePrefix("10.0.0.0/8")
+	assert.Error(t, err)
+	_, err = ParsePrefix("foo")
+	assert.Error(t, err)
+}
+
+func TestEmbedExtract(t *testing.T) {
+	prefix, _ := ParsePrefix("")
+
+	ip := Embed(prefix, net.ParseIP("192.0.2.33"))
+	assert.Equal(t, "64:ff9b::c000:221", ip.String())
+	assert.Equal(t, "192.0.2.33", Extract(prefix, ip).String())
+
+	assert.Nil(t, Embed(prefix, net.ParseIP("fd00::1")))
+	assert.Nil(t, Extract(prefix, net.ParseIP("fd00::1")))
+	assert.Nil(t, Extract(prefix, net.ParseIP("192.0.2.33")))
+}
+
+func TestConfig_validate(t *testing.T) {
+	prefix, _ := ParsePrefix("")
+	cfg := &Config{Prefix: prefix, PodIPv6: net.ParseIP("fd00::1"), PodIPv4: net.ParseIP("100.64.0.1")}
+	assert.NoError(t, cfg.validate())
+
+	assert.Error(t, (&Config{Prefix: prefix, PodIPv6: net.ParseIP("fd00::1")}).validate())
+	assert.Error(t, (&Config{Prefix: prefix, PodIPv6: net.ParseIP("10.0.0.1"), PodIPv4: net.ParseIP("100.64.0.1")}).validate())
+	assert.Error(t, (&Config{Prefix: prefix, PodIPv6: net.ParseIP("fd00::1"), PodIPv4: net.ParseIP("fd00::2")}).validate())
+	_, wide, _ := net.ParseCIDR("64:ff9b::/64")
+	assert.Error(t, (&Config{Prefix: wide, PodIPv6: net.ParseIP("fd00::1"), PodIPv4: net.ParseIP("100.64.0.1")}).validate())
+}
@@ -0,0 +1,116 @@
+// Package probe detects the kernel features used by the datapaths.
+// The results are recorded in the node capabilities by terway-cli when the node is initialized.
+package probe
+
+import (
+	"strconv"
+
+	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
+)
+
+// Feature is a kernel feature, the name is used in the node capability and node label keys
+type Feature string
+
+const (
+	IPVlanL2  Feature = "ipvlan_l2"
+	IPVlanL3S Feature = "ipvlan_l3s"
+
+	ClsAct Feature = "clsact"
+	FQ     Feature = "fq"
+	MQ     Feature = "mq"
+	// EDT earliest departure time bandwidth limit, requires fq and the bpf helpers setting the skb tstamp
+	EDT Feature = "edt"
+
+	BPFProgSchedCLS       Feature = "bpf_prog_sched_cls"
+	BPFProgSchedACT       Feature = "bpf_prog_sched_act"
+	BPFProgCGroupSockAddr Feature = "bpf_prog_cgroup_sock_addr"
+
+	BPFMapHash           Feature = "bpf_map_hash"
+	BPFMapLRUHash        Feature = "bpf_map_lru_hash"
+	BPFMapLPMTrie        Feature = "bpf_map_lpm_trie"
+	BPFMapArray          Feature = "bpf_map_array"
+	BPFMapProgArray      Feature = "bpf_map_prog_array"
+	BPFMapPerfEventArray Feature = "bpf_map_perf_event_array"
+
+	BPFRedirectPeer Feature = "bpf_redirect_peer"
+
+	// RDMA a rdma device is present when probed, the erdma eni is hot attached later, so check it live when used
+	RDMA Feature = "rdma"
+	// SMCR the smc module is available, the rdma device is required to use smc-r
+	SMCR Feature = "smc_r"
+)
+
+// Features all the features probed, in order
+var Features = []Feature{
+	IPVlanL2, IPVlanL3S,
+	ClsAct, FQ, MQ, EDT,
+	BPFProgSchedCLS, BPFProgSchedACT, BPFProgCGroupSockAddr,
+	BPFMapHash, BPFMapLRUHash, BPFMapLPMTrie, BPFMapArray, BPFMapProgArray, BPFMapPerfEventArray,
+	BPFRedirectPeer,
+	RDMA, SMCR,
+}
+
+// ebpfFeatures required by the cilium chainer
+var ebpfFeatures = []Feature{
+	ClsAct,
+	BPFProgSchedCLS,
+	BPFMapHash, BPFMapLRUHash, BPFMapLPMTrie, BPFMapArray, BPFMapProgArray, BPFMapPerfEventArray,
+}
+
+// Results the probed features, a feature not probed is absent
+type Results map[Feature]bool
+
+// EBPF whether the ebpf datapath (cilium chainer) is supported
+func (r Results) EBPF() bool {
+	for _, f := range ebpfFeatures {
+		if !r[f] {
+			return false
+		}
+	}
+	return true
+}
+
+// IPVlan whether the ipvlan datapath is supported, the ipvlan datapath relies on the cilium chainer
+func (r Results) IPVlan() bool {
+	return r[IPVlanL2] && r.EBPF()
+}
+
+// Store record the results in the node capabilities
+func (r Results) Store(store nodecap.NodeCapabilitiesStore) {
+	for f, ok := range r {
+		store.Set(nodeCapability(f), strconv.FormatBool(ok))
+	}
+}
+
+// Load read the results recorded in the node capabilities
+func Load(store nodecap.NodeCapabilitiesStore) Results {
+	return load(store.Get)
+}
+
+// Recorded return the results recorded in the node capabilities of this node
+func Recorded() Results {
+	return load(nodecap.GetNodeCapabilities)
+}
+
+func load(get func(capName string) string) Results {
+	r := Results{}
+	for _, f := range Features {
+		ok, err := strconv.ParseBool(get(nodeCapability(f)))
+		if err != nil {
+			continue
+		}
+		r[f] = ok
+	}
+	return r
+}
+
+// Get return whether the feature is recorded as supported in the node capabilities.
+// Nothing is probed here, a feature not recorded is treated as unsupported, the probe is done by terway-cli.
+func Get(f Feature) bool {
+	ok, err := strconv.ParseBool(nodecap.GetNodeCapabilities(nodeCapability(f)))
+	return err == nil && ok
+}
+
+func nodeCapability(f Feature) string {
+	return nodecap.NodeCapabilityFeaturePrefix + string(f)
+}
@@ -0,0 +1,212 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/features"
+	"github.com/go-logr/logr"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+const (
+	probeLinkName = "probe0"
+	probePeerName = "probe1"
+	probeIPVlan   = "probe-ipvl"
+)
+
+type prober struct {
+	// netns the feature is probed with the link created in a temporary net ns
+	netns bool
+	probe func(link netlink.Link) error
+}
+
+var probers = map[Feature]prober{
+	IPVlanL2:  {netns: true, probe: ipvlanProbe(netlink.IPVLAN_MODE_L2)},
+	IPVlanL3S: {netns: true, probe: ipvlanProbe(netlink.IPVLAN_MODE_L3S)},
+
+	ClsAct: {netns: true, probe: qdiscProbe("clsact")},
+	FQ:     {netns: true, probe: qdiscProbe("fq")},
+	MQ:     {netns: true, probe: qdiscProbe("mq")},
+	EDT: {netns: true, probe: func(link netlink.Link) error {
+		err := qdiscProbe("fq")(link)
+		if err != nil {
+			return err
+		}
+		// writing skb->tstamp is a ctx access, which has no helper to probe.
+		// bpf_skb_ecn_set_ce (kernel 5.1) is used as a proxy for the kernel version, same as the cilium bandwidth manager.
+		return features.HaveProgramHelper(ebpf.SchedCLS, asm.FnSkbEcnSetCe)
+	}},
+
+	BPFProgSchedCLS:       {probe: progProbe(ebpf.SchedCLS)},
+	BPFProgSchedACT:       {probe: progProbe(ebpf.SchedACT)},
+	BPFProgCGroupSockAddr: {probe: progProbe(ebpf.CGroupSockAddr)},
+
+	BPFMapHash:           {probe: mapProbe(ebpf.Hash)},
+	BPFMapLRUHash:        {probe: mapProbe(ebpf.LRUHash)},
+	BPFMapLPMTrie:        {probe: mapProbe(ebpf.LPMTrie)},
+	BPFMapArray:          {probe: mapProbe(ebpf.Array)},
+	BPFMapProgArray:      {probe: mapProbe(ebpf.ProgramArray)},
+	BPFMapPerfEventArray: {probe: mapProbe(ebpf.PerfEventArray)},
+
+	BPFRedirectPeer: {probe: func(link netlink.Link) error {
+		return features.HaveProgramHelper(ebpf.SchedCLS, asm.FnRedirectPeer)
+	}},
+
+	RDMA: {probe: rdmaProbe},
+	SMCR: {probe: smcrProbe},
+}
+
+// Probe the features, all features are probed if none is specified.
+// A feature is unsupported if failed to probe, the reason is logged.
+func Probe(ctx context.Context, fs ...Feature) Results {
+	log := logr.FromContextOrDiscard(ctx)
+	if len(fs) == 0 {
+		fs = Features
+	}
+
+	r := Results{}
+	probe := func(f Feature, link netlink.Link) {
+		err := probers[f].probe(link)
+		if err != nil {
+			log.Info("feature not supported", "feature", f, "reason", err.Error())
+		}
+		r[f] = err == nil
+	}
+
+	var nsFeatures []Feature
+	for _, f := range fs {
+		if probers[f].netns {
+			nsFeatures = append(nsFeatures, f)
+			continue
+		}
+		probe(f, nil)
+	}
+	if len(nsFeatures) == 0 {
+		return r
+	}
+
+	err := inTempNetNS(func() error {
+		link, err := setupProbeLink()
+		if err != nil {
+			return err
+		}
+		for _, f := range nsFeatures {
+			probe(f, link)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "error probe features in net ns")
+		for _, f := range nsFeatures {
+			r[f] = false
+		}
+	}
+	return r
+}
+
+// inTempNetNS run f in a new net ns, the net ns is released when f returns
+func inTempNetNS(f func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		// the thread is not unlocked, so it is terminated with the goroutine and never back to the host net ns
+		runtime.LockOSThread()
+
+		ns, err := netns.New()
+		if err != nil {
+			errCh <- fmt.Errorf("error create net ns, %w", err)
+			return
+		}
+		defer ns.Close()
+
+		errCh <- f()
+	}()
+	return <-errCh
+}
+
+// setupProbeLink create a multi queue veth pair as the link to probe on
+func setupProbeLink() (netlink.Link, error) {
+	err := netlink.LinkAdd(&netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        probeLinkName,
+			NumTxQueues: 2,
+			NumRxQueues: 2,
+		},
+		PeerName: probePeerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error add veth, %w", err)
+	}
+	return netlink.LinkByName(probeLinkName)
+}
+
+func ipvlanProbe(mode netlink.IPVlanMode) func(link netlink.Link) error {
+	return func(link netlink.Link) error {
+		ipvl := &netlink.IPVlan{
+			LinkAttrs: netlink.LinkAttrs{
+				Name:        probeIPVlan,
+				ParentIndex: link.Attrs().Index,
+			},
+			Mode: mode,
+		}
+		err := netlink.LinkAdd(ipvl)
+		if err != nil {
+			return err
+		}
+		// slaves on the same parent share the mode, so the slave is removed for the next probe
+		return netlink.LinkDel(ipvl)
+	}
+}
+
+func qdiscProbe(qdiscType string) func(link netlink.Link) error {
+	return func(link netlink.Link) error {
+		attrs := netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_ROOT,
+			Handle:    netlink.MakeHandle(1, 0),
+		}
+		if qdiscType == "clsact" {
+			attrs.Parent = netlink.HANDLE_CLSACT
+			attrs.Handle = netlink.MakeHandle(0xffff, 0)
+		}
+		return netlink.QdiscReplace(&netlink.GenericQdisc{QdiscAttrs: attrs, QdiscType: qdiscType})
+	}
+}
+
+func progProbe(pt ebpf.ProgramType) func(link netlink.Link) error {
+	return func(link netlink.Link) error {
+		return features.HaveProgramType(pt)
+	}
+}
+
+func mapProbe(mt ebpf.MapType) func(link netlink.Link) error {
+	return func(link netlink.Link) error {
+		return features.HaveMapType(mt)
+	}
+}
+
+func rdmaProbe(link netlink.Link) error {
+	links, err := netlink.RdmaLinkList()
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return fmt.Errorf("no rdma device")
+	}
+	return nil
+}
+
+func smcrProbe(link netlink.Link) error {
+	out, err := exec.Command("modprobe", "smc").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error load smc module, %w, %s", err, out)
+	}
+	_, err = os.Stat("/proc/sys/net/smc/tcp2smc")
+	return err
+}
@@ -0,0 +1,27 @@
+//go:build privileged
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func TestProbe(t *testing.T) {
+	r := Probe(context.Background())
+	assert.Len(t, r, len(Features))
+	assert.True(t, r[ClsAct])
+	assert.True(t, r[BPFProgSchedCLS])
+	assert.Equal(t, r[EDT], r[EDT] && r[FQ])
+
+	// the links are probed in a temporary net ns
+	_, err := netlink.LinkByName(probeLinkName)
+	assert.True(t, errors.As(err, &netlink.LinkNotFoundError{}))
+
+	r = Probe(context.Background(), ClsAct, BPFMapHash)
+	assert.Equal(t, Results{ClsAct: true, BPFMapHash: true}, r)
+}
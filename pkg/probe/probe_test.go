@@ -0,0 +1,51 @@
+package probe
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
+)
+
+func TestResults_StoreAndLoad(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "test_node_capabilities")
+	assert.NoError(t, err)
+	defer os.Remove(tempFile.Name())
+
+	store := nodecap.NewFileNodeCapabilities(tempFile.Name())
+	Results{IPVlanL2: true, FQ: false}.Store(store)
+	assert.NoError(t, store.Save())
+	assert.Equal(t, "true", store.Get("feature_ipvlan_l2"))
+
+	store = nodecap.NewFileNodeCapabilities(tempFile.Name())
+	assert.NoError(t, store.Load())
+	// features not recorded are absent
+	assert.Equal(t, Results{IPVlanL2: true, FQ: false}, Load(store))
+}
+
+func TestResults_Datapath(t *testing.T) {
+	r := Results{}
+	for _, f := range ebpfFeatures {
+		r[f] = true
+	}
+	assert.True(t, r.EBPF())
+	assert.False(t, r.IPVlan())
+
+	r[IPVlanL2] = true
+	assert.True(t, r.IPVlan())
+
+	r[BPFMapLPMTrie] = false
+	assert.False(t, r.EBPF())
+	assert.False(t, r.IPVlan())
+}
+
+func TestGet(t *testing.T) {
+	nodecap.SetNodeCapabilities(nodeCapability(IPVlanL2), "true")
+	nodecap.SetNodeCapabilities(nodeCapability(IPVlanL3S), "false")
+	assert.True(t, Get(IPVlanL2))
+	assert.False(t, Get(IPVlanL3S))
+	// not recorded
+	assert.False(t, Get(EDT))
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package probe
+
+import "context"
+
+// Probe the features, no feature is supported on this platform
+func Probe(ctx context.Context, fs ...Feature) Results {
+	if len(fs) == 0 {
+		fs = Features
+	}
+	r := Results{}
+	for _, f := range fs {
+		r[f] = false
+	}
+	return r
+}
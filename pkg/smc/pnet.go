@@ -2,41 +2,32 @@ package smc
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
 
 	"github.com/containernetworking/plugins/pkg/ns"
-	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/probe"
 )
 
 const (
 	smcPnet = "smc_pnet"
 )
 
-func ensureSMCR() bool {
-	_, err := exec.Command("modprobe", "smc").CombinedOutput()
-	if err != nil {
-		return false
-	}
-	// smc module is load
-	_, err = os.Stat("/proc/sys/net/smc/tcp2smc")
-	return err == nil
-}
-
 func supportSMCR() bool {
-	// rdma device attached
-	rdmaLink, err := netlink.RdmaLinkList()
-	if err != nil || len(rdmaLink) == 0 {
+	// smc module is load
+	if !probe.Get(probe.SMCR) {
 		return false
 	}
-	// smc-tools installed
-	_, err = exec.LookPath(smcPnet)
-	if err != nil {
+	// rdma device attached, the erdma eni may be attached after the node is initialized, so probe it every time
+	if !probe.Probe(context.Background(), probe.RDMA)[probe.RDMA] {
 		return false
 	}
-	return ensureSMCR()
+	// smc-tools installed
+	_, err := exec.LookPath(smcPnet)
+	return err == nil
 }
 
 func pnetID(name string) string {
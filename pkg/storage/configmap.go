@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const configMapTimeout = 10 * time.Second
+
+// ConfigMapStorage persistent storage in a ConfigMap, each key is an item in the data.
+// Items are always read from the apiserver and written with conflict retries, so it can be shared by replicas writing different keys.
+// The writes made while an update is in flight are batched into the next update, so concurrent writers do not conflict with each other.
+type ConfigMapStorage struct {
+	client       kubernetes.Interface
+	namespace    string
+	name         string
+	serializer   Serializer
+	deserializer Deserializer
+
+	lock     sync.Mutex
+	pending  []*configMapWrite
+	flushing bool
+}
+
+// configMapWrite is a write waiting for the update
+type configMapWrite struct {
+	mutate func(cm *corev1.ConfigMap) bool
+	done   chan error
+}
+
+// NewConfigMapStorage return new ConfigMap storage, the ConfigMap is created on first write
+func NewConfigMapStorage(client kubernetes.Interface, namespace, name string, serializer Serializer, deserializer Deserializer) *ConfigMapStorage {
+	return &ConfigMapStorage{
+		client:       client,
+		namespace:    namespace,
+		name:         name,
+		serializer:   serializer,
+		deserializer: deserializer,
+	}
+}
+
+// Put somethings into ConfigMap storage
+func (c *ConfigMapStorage) Put(key string, value interface{}) error {
+	data, err := c.serializer(value)
+	if err != nil {
+		return err
+	}
+	return c.update(func(cm *corev1.ConfigMap) bool {
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = string(data)
+		return true
+	})
+}
+
+// Get value in ConfigMap storage
+func (c *ConfigMapStorage) Get(key string) (interface{}, error) {
+	cm, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c.deserializer([]byte(data))
+}
+
+// List values in ConfigMap storage
+func (c *ConfigMapStorage) List() ([]interface{}, error) {
+	cm, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	var ret []interface{}
+	for _, data := range cm.Data {
+		obj, err := c.deserializer([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, obj)
+	}
+	return ret, nil
+}
+
+// Delete key in ConfigMap storage
+func (c *ConfigMapStorage) Delete(key string) error {
+	return c.update(func(cm *corev1.ConfigMap) bool {
+		_, ok := cm.Data[key]
+		delete(cm.Data, key)
+		return ok
+	})
+}
+
+// get return the ConfigMap, an empty one is returned if not exist
+func (c *ConfigMapStorage) get() (*corev1.ConfigMap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), configMapTimeout)
+	defer cancel()
+
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if err != nil {
+		if k8sErr.IsNotFound(err) {
+			return &corev1.ConfigMap{}, nil
+		}
+		return nil, err
+	}
+	return cm, nil
+}
+
+// update apply the mutate func to the ConfigMap, mutate return false if nothing changed.
+// The write is batched with the others pending, the first writer flushes the batch.
+func (c *ConfigMapStorage) update(mutate func(cm *corev1.ConfigMap) bool) error {
+	w := &configMapWrite{mutate: mutate, done: make(chan error, 1)}
+
+	c.lock.Lock()
+	c.pending = append(c.pending, w)
+	leader := !c.flushing
+	c.flushing = true
+	c.lock.Unlock()
+
+	if leader {
+		c.flush()
+	}
+	return <-w.done
+}
+
+// flush write the pending writes in one update, the writes queued meanwhile are flushed in background
+func (c *ConfigMapStorage) flush() {
+	c.lock.Lock()
+	batch := c.pending
+	c.pending = nil
+	c.lock.Unlock()
+
+	err := c.apply(func(cm *corev1.ConfigMap) bool {
+		changed := false
+		for _, w := range batch {
+			if w.mutate(cm) {
+				changed = true
+			}
+		}
+		return changed
+	})
+	for _, w := range batch {
+		w.done <- err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.pending) == 0 {
+		c.flushing = false
+		return
+	}
+	go c.flush()
+}
+
+// apply the mutate func to the ConfigMap, retried on conflict
+func (c *ConfigMapStorage) apply(mutate func(cm *corev1.ConfigMap) bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), configMapTimeout)
+	defer cancel()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cms := c.client.CoreV1().ConfigMaps(c.namespace)
+		cm, err := cms.Get(ctx, c.name, metav1.GetOptions{})
+		if err != nil {
+			if !k8sErr.IsNotFound(err) {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      c.name,
+					Namespace: c.namespace,
+				},
+			}
+			if !mutate(cm) {
+				return nil
+			}
+			_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+			if k8sErr.IsAlreadyExists(err) {
+				return k8sErr.NewConflict(corev1.Resource("configmaps"), c.name, err)
+			}
+			return err
+		}
+		if !mutate(cm) {
+			return nil
+		}
+		_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
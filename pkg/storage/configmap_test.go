@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestConfigMapStorage(t *testing.T) {
+	serializer := func(item interface{}) ([]byte, error) {
+		return json.Marshal(item)
+	}
+	deserializer := func(data []byte) (interface{}, error) {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	s := NewConfigMapStorage(fake.NewSimpleClientset(), "kube-system", "test", serializer, deserializer)
+
+	items, err := s.List()
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	// delete on a not exist ConfigMap
+	assert.NoError(t, s.Delete("a"))
+
+	assert.NoError(t, s.Put("a", "foo"))
+	assert.NoError(t, s.Put("b", "bar"))
+	v, err := s.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", v)
+
+	assert.NoError(t, s.Delete("a"))
+	_, err = s.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	items, err = s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"bar"}, items)
+}
+
+func TestConfigMapStorage_BatchWrites(t *testing.T) {
+	serializer := func(item interface{}) ([]byte, error) {
+		return json.Marshal(item)
+	}
+	deserializer := func(data []byte) (interface{}, error) {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	client := fake.NewSimpleClientset()
+	var writes atomic.Int32
+	client.PrependReactor("*", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetVerb() == "create" || action.GetVerb() == "update" {
+			writes.Add(1)
+			// slow down the write, so the writers are queued meanwhile
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false, nil, nil
+	})
+	s := NewConfigMapStorage(client, "kube-system", "test", serializer, deserializer)
+
+	// concurrent writers are batched, none of them fails on conflict
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, s.Put(fmt.Sprintf("key-%d", i), "foo"))
+		}(i)
+	}
+	wg.Wait()
+
+	items, err := s.List()
+	assert.NoError(t, err)
+	assert.Len(t, items, 50)
+	assert.Less(t, int(writes.Load()), 50)
+}
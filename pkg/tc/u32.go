@@ -65,6 +65,40 @@ OUT:
 	return nil, nil
 }
 
+// FilterByDstIP found u32 filter with the priority by pod ip as destination
+func FilterByDstIP(link netlink.Link, parent uint32, ipNet *net.IPNet, priority uint16) (*netlink.U32, error) {
+	filters, err := netlink.FilterList(link, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := U32MatchDst(ipNet)
+	for _, f := range filters {
+		u32, ok := f.(*netlink.U32)
+		if !ok {
+			continue
+		}
+		if u32.Attrs().LinkIndex != link.Attrs().Index ||
+			u32.Priority != priority ||
+			u32.Protocol != Protocol(ipNet) ||
+			u32.Sel == nil {
+			continue
+		}
+		if Contain(u32.Sel.Keys, matches) {
+			return u32, nil
+		}
+	}
+	return nil, nil
+}
+
+// Protocol return the ether type of the ip family
+func Protocol(ipNet *net.IPNet) uint16 {
+	if ipNet.IP.To4() == nil {
+		return unix.ETH_P_IPV6
+	}
+	return unix.ETH_P_IP
+}
+
 // MatchSrc add match for source ip
 func MatchSrc(u32 *netlink.U32, ipNet *net.IPNet) {
 	if u32.Sel == nil {
@@ -85,17 +119,47 @@ func U32MatchSrc(ipNet *net.IPNet) []netlink.TcU32Key {
 	return []netlink.TcU32Key{U32IPv4Src(ipNet)}
 }
 
+// MatchDst add match for destination ip
+func MatchDst(u32 *netlink.U32, ipNet *net.IPNet) {
+	if u32.Sel == nil {
+		u32.Sel = &netlink.TcU32Sel{
+			Flags: nl.TC_U32_TERMINAL,
+		}
+	}
+
+	u32.Sel.Keys = append(u32.Sel.Keys, U32MatchDst(ipNet)...)
+	u32.Sel.Nkeys = uint8(len(u32.Sel.Keys))
+}
+
+// U32MatchDst return u32 match key by dst ip
+func U32MatchDst(ipNet *net.IPNet) []netlink.TcU32Key {
+	if ipNet.IP.To4() == nil {
+		return u32IPv6(ipNet, 24)
+	}
+	return []netlink.TcU32Key{u32IPv4(ipNet, 16)}
+}
+
 func U32IPv4Src(ipNet *net.IPNet) netlink.TcU32Key {
+	return u32IPv4(ipNet, 12)
+}
+
+func U32IPv6Src(ipNet *net.IPNet) []netlink.TcU32Key {
+	return u32IPv6(ipNet, 8)
+}
+
+// u32IPv4 match the ipv4 address at the offset of ip header
+func u32IPv4(ipNet *net.IPNet, off int32) netlink.TcU32Key {
 	mask := net.IP(ipNet.Mask).To4()
 	val := ipNet.IP.Mask(ipNet.Mask).To4()
 	return netlink.TcU32Key{
 		Mask: binary.BigEndian.Uint32(mask),
 		Val:  binary.BigEndian.Uint32(val),
-		Off:  12,
+		Off:  off,
 	}
 }
 
-func U32IPv6Src(ipNet *net.IPNet) []netlink.TcU32Key {
+// u32IPv6 match the ipv6 address at the offset of ip header
+func u32IPv6(ipNet *net.IPNet, off int32) []netlink.TcU32Key {
 	mask := ipNet.Mask
 	val := ipNet.IP.Mask(ipNet.Mask)
 
@@ -106,7 +170,7 @@ func U32IPv6Src(ipNet *net.IPNet) []netlink.TcU32Key {
 			r = append(r, netlink.TcU32Key{
 				Mask: m,
 				Val:  binary.BigEndian.Uint32(val),
-				Off:  int32(8 + 4*i),
+				Off:  off + int32(4*i),
 			})
 		}
 		mask = mask[4:]
@@ -133,3 +133,50 @@ func TestU32IPv6Src(t *testing.T) {
 		})
 	}
 }
+
+func TestU32MatchDst(t *testing.T) {
+	tests := []struct {
+		name  string
+		ipNet *net.IPNet
+		want  []netlink.TcU32Key
+	}{
+		{
+			name: "192.168.0.1/32",
+			ipNet: &net.IPNet{
+				IP:   net.ParseIP("192.168.0.1"),
+				Mask: net.CIDRMask(32, 32),
+			},
+			want: []netlink.TcU32Key{
+				{
+					Mask: 0xffffffff,
+					Val:  0xc0a80001,
+					Off:  16,
+				},
+			},
+		}, {
+			name: "fd:aaaa::/64",
+			ipNet: &net.IPNet{
+				IP:   net.ParseIP("fd:aaaa::"),
+				Mask: net.CIDRMask(64, 128),
+			},
+			want: []netlink.TcU32Key{
+				{
+					Mask: 0xffffffff,
+					Val:  0x00fdaaaa,
+					Off:  24,
+				}, {
+					Mask: 0xffffffff,
+					Val:  0x00000000,
+					Off:  28,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := U32MatchDst(tt.ipNet); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("U32MatchDst() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ const (
 	NodeCapabilityDataPath              = "datapath"
 	NodeCapabilityNetworkPolicyProvider = "network_policy_provider"
 	NodeCapabilityHasCiliumChainer      = "has_cilium_chainer"
+	// NodeCapabilityFeaturePrefix the kernel features probed, e.g. feature_ipvlan_l2 = true
+	NodeCapabilityFeaturePrefix = "feature_"
 )
 
 // NodeCapabilitiesStore defines an interface for node capabilities operations
@@ -29,9 +29,14 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
 )
 
+// VPC is the openAPI used to look up the vSwitch, it is satisfied by client.VPC
+type VPC interface {
+	DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*vpc.VSwitch, error)
+}
+
 var ErrNoAvailableVSwitch = errors.New("no available vSwitch")
 var ErrIPNotEnough = errors.New("no ip left")
 
@@ -70,7 +75,7 @@ func NewSwitchPool(size int, ttl string) (*SwitchPool, error) {
 }
 
 // GetOne get one vSwitch by zone and limit in ids
-func (s *SwitchPool) GetOne(ctx context.Context, client client.VPC, zone string, ids []string, opts ...SelectOption) (*Switch, error) {
+func (s *SwitchPool) GetOne(ctx context.Context, client VPC, zone string, ids []string, opts ...SelectOption) (*Switch, error) {
 	var fallBackSwitches []*Switch
 
 	selectOptions := &SelectOptions{}
@@ -139,7 +144,7 @@ func (s *SwitchPool) GetOne(ctx context.Context, client client.VPC, zone string,
 }
 
 // GetByID will get vSwitch info from local store or openAPI
-func (s *SwitchPool) GetByID(ctx context.Context, client client.VPC, id string) (*Switch, error) {
+func (s *SwitchPool) GetByID(ctx context.Context, client VPC, id string) (*Switch, error) {
 	v, ok := s.cache.Get(id)
 	if !ok {
 		v, err, _ := s.g.Do(id, func() (interface{}, error) {
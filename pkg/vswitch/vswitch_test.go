@@ -14,7 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package vswitch
+package vswitch_test
 
 import (
 	"context"
@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/AliyunContainerService/terway/pkg/aliyun/client/mocks"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
 )
 
 func TestSwitchPool_GetOne(t *testing.T) {
@@ -52,13 +53,13 @@ func TestSwitchPool_GetOne(t *testing.T) {
 		AvailableIpAddressCount: 10,
 	}, nil).Maybe()
 
-	switchPool, err := NewSwitchPool(100, "100m")
+	switchPool, err := vswitch.NewSwitchPool(100, "100m")
 	assert.NoError(t, err)
 
 	for i := 0; i < 10; i++ {
-		sw, err := switchPool.GetOne(context.Background(), openAPI, "zone-2", []string{"vsw-2", "vsw-3"}, &SelectOptions{
+		sw, err := switchPool.GetOne(context.Background(), openAPI, "zone-2", []string{"vsw-2", "vsw-3"}, &vswitch.SelectOptions{
 			IgnoreZone:          false,
-			VSwitchSelectPolicy: VSwitchSelectionPolicyOrdered,
+			VSwitchSelectPolicy: vswitch.VSwitchSelectionPolicyOrdered,
 		})
 		assert.NoError(t, err)
 		assert.Equal(t, "vsw-2", sw.ID)
@@ -66,9 +67,9 @@ func TestSwitchPool_GetOne(t *testing.T) {
 
 	ids := make(map[string]struct{})
 	for i := 0; i < 10; i++ {
-		sw, err := switchPool.GetOne(context.Background(), openAPI, "zone-2", []string{"vsw-2", "vsw-3"}, &SelectOptions{
+		sw, err := switchPool.GetOne(context.Background(), openAPI, "zone-2", []string{"vsw-2", "vsw-3"}, &vswitch.SelectOptions{
 			IgnoreZone:          false,
-			VSwitchSelectPolicy: VSwitchSelectionPolicyRandom,
+			VSwitchSelectPolicy: vswitch.VSwitchSelectionPolicyRandom,
 		})
 		assert.NoError(t, err)
 		ids[sw.ID] = struct{}{}
@@ -76,21 +77,21 @@ func TestSwitchPool_GetOne(t *testing.T) {
 
 	assert.Equal(t, 2, len(ids))
 
-	_, err = switchPool.GetOne(context.Background(), openAPI, "zone-x", []string{"vsw-2", "vsw-3"}, &SelectOptions{
+	_, err = switchPool.GetOne(context.Background(), openAPI, "zone-x", []string{"vsw-2", "vsw-3"}, &vswitch.SelectOptions{
 		IgnoreZone:          false,
-		VSwitchSelectPolicy: VSwitchSelectionPolicyRandom,
+		VSwitchSelectPolicy: vswitch.VSwitchSelectionPolicyRandom,
 	})
 
-	assert.True(t, errors.Is(err, ErrNoAvailableVSwitch))
-	assert.False(t, errors.Is(err, ErrIPNotEnough))
+	assert.True(t, errors.Is(err, vswitch.ErrNoAvailableVSwitch))
+	assert.False(t, errors.Is(err, vswitch.ErrIPNotEnough))
 
-	_, err = switchPool.GetOne(context.Background(), openAPI, "zone-0", []string{"vsw-0"}, &SelectOptions{
+	_, err = switchPool.GetOne(context.Background(), openAPI, "zone-0", []string{"vsw-0"}, &vswitch.SelectOptions{
 		IgnoreZone:          false,
-		VSwitchSelectPolicy: VSwitchSelectionPolicyRandom,
+		VSwitchSelectPolicy: vswitch.VSwitchSelectionPolicyRandom,
 	})
 
-	assert.True(t, errors.Is(err, ErrNoAvailableVSwitch))
-	assert.True(t, errors.Is(err, ErrIPNotEnough))
+	assert.True(t, errors.Is(err, vswitch.ErrNoAvailableVSwitch))
+	assert.True(t, errors.Is(err, vswitch.ErrIPNotEnough))
 }
 
 func TestGetByID(t *testing.T) {
@@ -103,9 +104,9 @@ func TestGetByID(t *testing.T) {
 	}, nil).Maybe()
 	openAPI.On("DescribeVSwitchByID", mock.Anything, "vsw-3").Return(nil, fmt.Errorf("err")).Maybe()
 
-	switchPool, err := NewSwitchPool(100, "100m")
+	switchPool, err := vswitch.NewSwitchPool(100, "100m")
 	assert.NoError(t, err)
-	switchPool.Add(&Switch{
+	switchPool.Add(&vswitch.Switch{
 		ID:               "vsw-1",
 		Zone:             "zone-1",
 		AvailableIPCount: 10,
@@ -0,0 +1,398 @@
+package datapath
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/probe"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+// ParseSetupConf build the setup config from the allocation, ifName is the interface requested by the runtime
+func ParseSetupConf(ifName string, alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.SetupConfig, error) {
+	var (
+		err            error
+		containerIPNet *terwayTypes.IPNetSet
+		gatewayIP      *terwayTypes.IPSet
+		serviceCIDR    *terwayTypes.IPNetSet
+		eniGatewayIP   *terwayTypes.IPSet
+		deviceID       int32
+		trunkENI       bool
+		vid            uint32
+		erdma          bool
+
+		ingress         uint64
+		egress          uint64
+		networkPriority uint32
+		ingressPriority *types.IngressPriority
+		ingressClasses  []*types.IngressPriority
+
+		disableCreatePeer bool
+	)
+
+	serviceCIDR, err = terwayTypes.ToIPNetSet(alloc.GetBasicInfo().GetServiceCIDR())
+	if err != nil {
+		return nil, err
+	}
+
+	if ipType == rpc.IPType_TypeVPCIP {
+		subnetStr := alloc.GetBasicInfo().GetPodCIDR().GetIPv4()
+		_, subnet, err := net.ParseCIDR(subnetStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %s, %w", subnetStr, err)
+		}
+		containerIPNet = &terwayTypes.IPNetSet{
+			IPv4: subnet,
+			IPv6: nil,
+		}
+	} else if alloc.GetBasicInfo() != nil {
+		podIP := alloc.GetBasicInfo().GetPodIP()
+		subNet := alloc.GetBasicInfo().GetPodCIDR()
+		gw := alloc.GetBasicInfo().GetGatewayIP()
+
+		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
+		if err != nil {
+			return nil, err
+		}
+		gatewayIP, err = terwayTypes.ToIPSet(gw)
+		if err != nil {
+			return nil, err
+		}
+		disableCreatePeer = conf.DisableHostPeer
+	}
+
+	if alloc.GetENIInfo() != nil {
+		mac := alloc.GetENIInfo().GetMAC()
+		if mac != "" {
+			err = retry.OnError(wait.Backoff{
+				Steps:    10,
+				Duration: 1 * time.Second,
+				Factor:   1.0,
+				Jitter:   0,
+			}, func(err error) bool {
+				return errors.Is(err, link.ErrNotFound)
+			}, func() error {
+				deviceID, err = link.GetDeviceNumber(mac)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		trunkENI = alloc.GetENIInfo().GetTrunk()
+		vid = alloc.GetENIInfo().GetVid()
+		erdma = alloc.GetENIInfo().GetERDMA()
+		if alloc.GetENIInfo().GetGatewayIP() != nil {
+			eniGatewayIP, err = terwayTypes.ToIPSet(alloc.GetENIInfo().GetGatewayIP())
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if alloc.GetPod() != nil {
+		ingress = alloc.GetPod().GetIngress()
+		egress = alloc.GetPod().GetEgress()
+		networkPriority = PrioMap[alloc.GetPod().GetNetworkPriority()]
+		ingressPriority = conf.IngressPriority(alloc.GetPod().GetNetworkPriority(), networkPriority)
+		if ingressPriority != nil {
+			ingressClasses = IngressPriorityClasses(conf)
+		}
+	}
+	if conf.RuntimeConfig.Bandwidth.EgressRate > 0 {
+		egress = uint64(conf.RuntimeConfig.Bandwidth.EgressRate / 8)
+	}
+	if conf.RuntimeConfig.Bandwidth.IngressRate > 0 {
+		ingress = uint64(conf.RuntimeConfig.Bandwidth.IngressRate / 8)
+	}
+
+	hostStackCIDRs, err := conf.GetHostStackCIDRs(alloc.GetBasicInfo().GetHostStackCIDRs())
+	if err != nil {
+		return nil, err
+	}
+
+	name := alloc.IfName
+	if name == "" {
+		name = ifName
+	}
+	routes, err := parseExtraRoutes(alloc.GetExtraRoutes(), gatewayIP)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseExtraRules(alloc.GetExtraRules())
+	if err != nil {
+		return nil, err
+	}
+
+	nat64, err := parseNAT64(alloc, conf, containerIPNet)
+	if err != nil {
+		return nil, err
+	}
+
+	// port mappings are served on the primary interface
+	var hostPorts []types.HostPort
+	if name == ifName {
+		hostPorts, err = conf.GetHostPorts()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dp := GetDatePath(ipType, conf, trunkENI)
+	return &types.SetupConfig{
+		DP:                    dp,
+		ContainerIfName:       name,
+		ContainerIPNet:        containerIPNet,
+		GatewayIP:             gatewayIP,
+		MTU:                   conf.MTU,
+		ENIIndex:              int(deviceID),
+		ERDMA:                 erdma,
+		ENIGatewayIP:          eniGatewayIP,
+		ServiceCIDR:           serviceCIDR,
+		HostStackCIDRs:        hostStackCIDRs,
+		BandwidthMode:         conf.BandwidthMode,
+		EnableNetworkPriority: conf.EnableNetworkPriority,
+		Ingress:               ingress,
+		Egress:                egress,
+		StripVlan:             trunkENI,
+		Vid:                   int(vid),
+		DefaultRoute:          alloc.GetDefaultRoute(),
+		ExtraRoutes:           routes,
+		ExtraRules:            rules,
+		NAT64:                 nat64,
+		HostPorts:             hostPorts,
+		DisableCreatePeer:     disableCreatePeer,
+		RuntimeConfig:         conf.RuntimeConfig,
+		NetworkPriority:       networkPriority,
+		IngressPriority:       ingressPriority,
+		IngressClasses:        ingressClasses,
+	}, nil
+}
+
+// parseExtraRoutes the route without gateway is via the gateway of the interface
+func parseExtraRoutes(extraRoutes []*rpc.Route, gatewayIP *terwayTypes.IPSet) ([]types.Route, error) {
+	var routes []types.Route
+	for _, r := range extraRoutes {
+		ip, n, err := net.ParseCIDR(r.Dst)
+		if err != nil {
+			return nil, fmt.Errorf("error parse extra routes, %w", err)
+		}
+		route := types.Route{
+			Dst:    *n,
+			Metric: int(r.Metric),
+			Table:  int(r.Table),
+			MTU:    int(r.MTU),
+		}
+		switch {
+		case r.Gateway != "":
+			route.GW = net.ParseIP(r.Gateway)
+			if route.GW == nil {
+				return nil, fmt.Errorf("error parse extra routes, invalid gateway %s", r.Gateway)
+			}
+		case gatewayIP == nil:
+		case ip.To4() != nil:
+			route.GW = gatewayIP.IPv4
+		default:
+			route.GW = gatewayIP.IPv6
+		}
+		if r.Src != "" {
+			route.Src = net.ParseIP(r.Src)
+			if route.Src == nil {
+				return nil, fmt.Errorf("error parse extra routes, invalid src %s", r.Src)
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func parseExtraRules(extraRules []*rpc.Rule) ([]types.Rule, error) {
+	var rules []types.Rule
+	for _, r := range extraRules {
+		rule := types.Rule{
+			Mark:     r.FwMark,
+			Priority: int(r.Priority),
+			Table:    int(r.Table),
+		}
+		if r.From != "" {
+			_, n, err := net.ParseCIDR(r.From)
+			if err != nil {
+				return nil, fmt.Errorf("error parse extra rules, %w", err)
+			}
+			rule.Src = n
+		}
+		if r.To != "" {
+			_, n, err := net.ParseCIDR(r.To)
+			if err != nil {
+				return nil, fmt.Errorf("error parse extra rules, %w", err)
+			}
+			rule.Dst = n
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseNAT64 nat64 is only for ipv6 only pod
+func parseNAT64(alloc *rpc.NetConf, conf *types.CNIConf, containerIPNet *terwayTypes.IPNetSet) (*types.NAT64, error) {
+	if !alloc.GetPod().GetNAT64() || containerIPNet == nil || containerIPNet.IPv4 != nil || containerIPNet.IPv6 == nil {
+		return nil, nil
+	}
+	nat64, err := conf.GetNAT64()
+	if err != nil {
+		return nil, err
+	}
+	if nat64 == nil {
+		return nil, fmt.Errorf("nat64 is required by pod, but nat64_ipv4_pool is not configured")
+	}
+	return nat64, nil
+}
+
+// ParseTearDownConf build the teardown config from the allocation
+func ParseTearDownConf(alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.TeardownCfg, error) {
+	if alloc.GetBasicInfo() == nil {
+		return nil, fmt.Errorf("return empty pod alloc info: %v", alloc)
+	}
+
+	var (
+		err            error
+		containerIPNet *terwayTypes.IPNetSet
+		serviceCIDR    *terwayTypes.IPNetSet
+		eniIndex       int32
+	)
+
+	serviceCIDR, err = terwayTypes.ToIPNetSet(alloc.GetBasicInfo().GetServiceCIDR())
+	if err != nil {
+		return nil, err
+	}
+
+	if ipType == rpc.IPType_TypeVPCIP {
+		subnetStr := alloc.GetBasicInfo().GetPodCIDR().GetIPv4()
+		_, subnet, err := net.ParseCIDR(subnetStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %s, %w", subnetStr, err)
+		}
+		containerIPNet = &terwayTypes.IPNetSet{
+			IPv4: subnet,
+			IPv6: nil,
+		}
+	} else if alloc.GetBasicInfo() != nil {
+		podIP := alloc.GetBasicInfo().GetPodIP()
+		subNet := alloc.GetBasicInfo().GetPodCIDR()
+
+		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if alloc.GetENIInfo() != nil {
+		mac := alloc.GetENIInfo().GetMAC()
+		if mac != "" {
+			eniIndex, _ = link.GetDeviceNumber(mac)
+		}
+	}
+
+	dp := GetDatePath(ipType, conf, false)
+	return &types.TeardownCfg{
+		DP:                    dp,
+		ContainerIPNet:        containerIPNet,
+		ServiceCIDR:           serviceCIDR,
+		ENIIndex:              int(eniIndex),
+		EnableNetworkPriority: conf.EnableNetworkPriority,
+	}, nil
+}
+
+// ParseCheckConf build the check config from the allocation, ifName is the interface requested by the runtime
+func ParseCheckConf(ifName string, alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.CheckConfig, error) {
+	var (
+		err            error
+		containerIPNet *terwayTypes.IPNetSet
+		gatewayIP      *terwayTypes.IPSet
+		deviceID       int32
+		trunkENI       bool
+	)
+
+	if alloc.GetBasicInfo() != nil {
+		podIP := alloc.GetBasicInfo().GetPodIP()
+		subNet := alloc.GetBasicInfo().GetPodCIDR()
+		gw := alloc.GetBasicInfo().GetGatewayIP()
+
+		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
+		if err != nil {
+			return nil, err
+		}
+		gatewayIP, err = terwayTypes.ToIPSet(gw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if alloc.GetENIInfo() != nil {
+		mac := alloc.GetENIInfo().GetMAC()
+		if mac != "" {
+			deviceID, err = link.GetDeviceNumber(mac)
+			if err != nil {
+				return nil, err
+			}
+		}
+		trunkENI = alloc.GetENIInfo().GetTrunk()
+	}
+
+	name := alloc.IfName
+	if name == "" {
+		name = ifName
+	}
+
+	dp := GetDatePath(ipType, conf, trunkENI)
+	return &types.CheckConfig{
+		DP:              dp,
+		ContainerIfName: name,
+		ContainerIPNet:  containerIPNet,
+		GatewayIP:       gatewayIP,
+		MTU:             conf.MTU,
+		ENIIndex:        deviceID,
+		TrunkENI:        trunkENI,
+		DefaultRoute:    alloc.GetDefaultRoute(),
+	}, nil
+}
+
+// IngressPriorityClasses the ingress shaping of all the configured network priorities
+func IngressPriorityClasses(conf *types.CNIConf) []*types.IngressPriority {
+	var r []*types.IngressPriority
+	for _, prio := range []terwayTypes.NetworkPrio{terwayTypes.NetworkPrioGuaranteed, terwayTypes.NetworkPrioBurstable, terwayTypes.NetworkPrioBestEffort} {
+		p := conf.IngressPriority(string(prio), PrioMap[string(prio)])
+		if p != nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// GetDatePath pick the datapath, the ipvlan datapath is used only if the ipvlan support is recorded by terway-cli
+func GetDatePath(ipType rpc.IPType, conf *types.CNIConf, trunk bool) types.DataPath {
+	switch ipType {
+	case rpc.IPType_TypeVPCIP:
+		return types.VPCRoute
+	case rpc.IPType_TypeVPCENI:
+		if trunk {
+			return types.Vlan
+		}
+		return types.ExclusiveENI
+	case rpc.IPType_TypeENIMultiIP:
+		if trunk && conf.VlanStripType == types.VlanStripTypeVlan {
+			return types.Vlan
+		}
+		if conf.IPVlan() && probe.Get(probe.IPVlanL2) {
+			return types.IPVlan
+		}
+		return types.PolicyRoute
+	default:
+		panic(fmt.Sprintf("unsupported ipType %s", ipType))
+	}
+}
@@ -0,0 +1,13 @@
+package datapath
+
+import (
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// PrioMap map the network priority to the prio qdisc class, the value is the tc handle 1:band+1
+var PrioMap = map[string]uint32{
+	string(types.NetworkPrioGuaranteed): 1<<16 | 1, // band 0
+	string(types.NetworkPrioBurstable):  1<<16 | 2, // band 1
+	string(types.NetworkPrioBestEffort): 1<<16 | 3, // band 2
+	"":                                  1<<16 | 2,
+}
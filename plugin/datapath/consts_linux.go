@@ -2,15 +2,17 @@ package datapath
 
 import (
 	"net"
-
-	"github.com/vishvananda/netlink"
-
-	"github.com/AliyunContainerService/terway/types"
 )
 
 const (
 	toContainerPriority   = 512
 	fromContainerPriority = 2048
+
+	// egressGatewayPriority route the pod traffic to the eni of the egress gateway, before the rule from container
+	egressGatewayPriority = 1536
+
+	// hostPortPriority route the replies of host ports to the host peer in the pod net ns, before the multi network rules
+	hostPortPriority = 256
 )
 
 // default addrs
@@ -28,10 +30,3 @@ var (
 		Mask: net.CIDRMask(128, 128),
 	}
 )
-
-var PrioMap = map[string]uint32{
-	string(types.NetworkPrioGuaranteed): netlink.MakeHandle(1, 1), // band 0
-	string(types.NetworkPrioBurstable):  netlink.MakeHandle(1, 2), // band 1
-	string(types.NetworkPrioBestEffort): netlink.MakeHandle(1, 3), // band 2
-	"":                                  netlink.MakeHandle(1, 2),
-}
@@ -0,0 +1,31 @@
+package datapath
+
+import (
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+)
+
+// PodDataPath the datapath the pod is set up with, detected by the type of the container interface
+func PodDataPath(netNS ns.NetNS, ifName string) (types.DataPath, error) {
+	var dp types.DataPath
+	err := netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(ifName)
+		if err != nil {
+			return err
+		}
+		switch contLink.Type() {
+		case "veth":
+			dp = types.PolicyRoute
+		case "ipvlan":
+			dp = types.IPVlan
+		default:
+			return fmt.Errorf("link type %s is not supported", contLink.Type())
+		}
+		return nil
+	})
+	return dp, err
+}
@@ -0,0 +1,49 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+)
+
+func TestPodDataPath(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, containerNS.Close())
+		assert.NoError(t, testutils.UnmountNS(containerNS))
+	}()
+
+	err = containerNS.Do(func(_ ns.NetNS) error {
+		err := netlink.LinkAdd(&netlink.Veth{
+			LinkAttrs: netlink.LinkAttrs{Name: "eth0"},
+			PeerName:  "veth1",
+		})
+		if err != nil {
+			return err
+		}
+		return netlink.LinkAdd(&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth1"}})
+	})
+	assert.NoError(t, err)
+
+	dp, err := PodDataPath(containerNS, "eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, types.PolicyRoute, dp)
+
+	_, err = PodDataPath(containerNS, "eth1")
+	assert.Error(t, err)
+
+	_, err = PodDataPath(containerNS, "eth2")
+	assert.Error(t, err)
+}
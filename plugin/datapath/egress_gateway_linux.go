@@ -0,0 +1,268 @@
+package datapath
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/go-logr/logr"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+const (
+	egressGatewayChain   = "TERWAY-EGRESS-GW"
+	egressGatewayComment = "terway egress gateway"
+)
+
+// EgressGateway is the ip reserved on the eni, the traffic is SNATed to the ip and leave from the eni
+type EgressGateway struct {
+	Name string
+	IP   net.IP
+	ENI  netlink.Link
+	// GatewayIP the vSwitch gateway of the eni
+	GatewayIP net.IP
+}
+
+// EgressGatewayPod the traffic from the pod to the destination cidrs goes through the gateway
+type EgressGatewayPod struct {
+	IP       net.IP
+	Gateway  *EgressGateway
+	DstCIDRs []*net.IPNet
+}
+
+// SetEgressGateways reconcile the policy routes and the SNAT rules of all pods using egress gateway on the node,
+// the rules of pods not in the list are removed.
+func SetEgressGateways(ctx context.Context, pods []*EgressGatewayPod) error {
+	var rules []*netlink.Rule
+	var snat [][]string
+	enis := make(map[int]*EgressGateway)
+	for _, pod := range pods {
+		enis[pod.Gateway.ENI.Attrs().Index] = pod.Gateway
+		for _, dst := range pod.DstCIDRs {
+			rules = append(rules, egressGatewayRule(pod, dst))
+			snat = append(snat, egressGatewaySNATSpec(pod, dst))
+		}
+	}
+
+	// the table of eni is not setup if no pod is using the eni
+	for index, gw := range enis {
+		err := nic.Setup(ctx, gw.ENI, &nic.Conf{
+			Routes: []*netlink.Route{
+				{
+					LinkIndex: index,
+					Scope:     netlink.SCOPE_UNIVERSE,
+					Table:     utils.GetRouteTableID(index),
+					Dst:       defaultRoute,
+					Gw:        gw.GatewayIP,
+					Flags:     int(netlink.FLAG_ONLINK),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("setup eni %s for egress gateway %s, %w", gw.ENI.Attrs().Name, gw.Name, err)
+		}
+	}
+
+	err := setEgressGatewayRules(ctx, rules)
+	if err != nil {
+		return err
+	}
+	return setEgressGatewaySNAT(ctx, snat)
+}
+
+func egressGatewayRule(pod *EgressGatewayPod, dst *net.IPNet) *netlink.Rule {
+	rule := netlink.NewRule()
+	rule.Src = utils.NewIPNetWithMaxMask(&net.IPNet{IP: pod.IP})
+	rule.Dst = dst
+	rule.Table = utils.GetRouteTableID(pod.Gateway.ENI.Attrs().Index)
+	rule.Priority = egressGatewayPriority
+	return rule
+}
+
+func egressGatewayRuleKey(rule *netlink.Rule) string {
+	return fmt.Sprintf("%s-%s-%d", rule.Src, rule.Dst, rule.Table)
+}
+
+func setEgressGatewayRules(ctx context.Context, expected []*netlink.Rule) error {
+	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("error list rules, %w", err)
+	}
+	exist := make(map[string]bool)
+	for i := range rules {
+		r := &rules[i]
+		if r.Priority != egressGatewayPriority {
+			continue
+		}
+		exist[egressGatewayRuleKey(r)] = true
+	}
+
+	want := make(map[string]bool)
+	for _, r := range expected {
+		key := egressGatewayRuleKey(r)
+		want[key] = true
+		if exist[key] {
+			continue
+		}
+		err = utils.RuleAdd(ctx, r)
+		if err != nil {
+			return err
+		}
+		exist[key] = true
+	}
+
+	for i := range rules {
+		r := &rules[i]
+		if r.Priority != egressGatewayPriority || want[egressGatewayRuleKey(r)] {
+			continue
+		}
+		err = utils.RuleDel(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func egressGatewaySNATSpec(pod *EgressGatewayPod, dst *net.IPNet) []string {
+	return []string{
+		"-s", utils.NewIPNetWithMaxMask(&net.IPNet{IP: pod.IP}).String(),
+		"-d", dst.String(),
+		"-m", "comment", "--comment", egressGatewayComment + " " + pod.Gateway.Name,
+		"-j", "SNAT", "--to-source", pod.Gateway.IP.String(),
+	}
+}
+
+// egressGatewaySNATKey return the key of the rule spec, the spec is generated or listed by iptables
+func egressGatewaySNATKey(spec []string) string {
+	var src, dst, to string
+	for i := 0; i+1 < len(spec); i++ {
+		switch spec[i] {
+		case "-s":
+			src = spec[i+1]
+		case "-d":
+			dst = spec[i+1]
+		case "--to-source":
+			to = spec[i+1]
+		}
+	}
+	return src + "-" + dst + "-" + to
+}
+
+// splitRuleSpec split the rule listed by iptables -S, the quoted comment is kept as one field
+func splitRuleSpec(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	quoted, inField := false, false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case c == ' ' && !quoted:
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(c)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+func setEgressGatewaySNAT(ctx context.Context, expected [][]string) error {
+	l := logr.FromContextOrDiscard(ctx)
+	ipt, err := iptables.NewWithProtocol(iptables.ProtocolIPv4)
+	if err != nil {
+		return err
+	}
+
+	ok, err := ipt.ChainExists("nat", egressGatewayChain)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if len(expected) == 0 {
+			return nil
+		}
+		err = ipt.NewChain("nat", egressGatewayChain)
+		if err != nil {
+			return err
+		}
+	}
+	// jump before the masquerade rules of others
+	jump := []string{"-m", "comment", "--comment", egressGatewayComment, "-j", egressGatewayChain}
+	ok, err = ipt.Exists("nat", "POSTROUTING", jump...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.Info("iptables insert", "table", "nat", "chain", "POSTROUTING", "rule", jump)
+		err = ipt.Insert("nat", "POSTROUTING", 1, jump...)
+		if err != nil {
+			return err
+		}
+	}
+
+	lines, err := ipt.List("nat", egressGatewayChain)
+	if err != nil {
+		return err
+	}
+	exist := make(map[string]bool)
+	var stale [][]string
+	for _, line := range lines {
+		fields := splitRuleSpec(line)
+		if len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+		spec := fields[2:]
+		key := egressGatewaySNATKey(spec)
+		if exist[key] {
+			stale = append(stale, spec)
+			continue
+		}
+		exist[key] = true
+	}
+
+	want := make(map[string]bool)
+	for _, spec := range expected {
+		key := egressGatewaySNATKey(spec)
+		want[key] = true
+		if exist[key] {
+			continue
+		}
+		l.Info("iptables append", "table", "nat", "chain", egressGatewayChain, "rule", spec)
+		err = ipt.Append("nat", egressGatewayChain, spec...)
+		if err != nil {
+			return err
+		}
+		exist[key] = true
+	}
+
+	for _, line := range lines {
+		fields := splitRuleSpec(line)
+		if len(fields) < 2 || fields[0] != "-A" || want[egressGatewaySNATKey(fields[2:])] {
+			continue
+		}
+		stale = append(stale, fields[2:])
+	}
+	for _, spec := range stale {
+		l.Info("iptables delete", "table", "nat", "chain", egressGatewayChain, "rule", spec)
+		err = ipt.DeleteIfExists("nat", egressGatewayChain, spec...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+func TestSetEgressGatewayRules(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	defer func() {
+		_ = hostNS.Close()
+		_ = testutils.UnmountNS(hostNS)
+	}()
+	require.NoError(t, hostNS.Set())
+
+	err = netlink.LinkAdd(&netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+		PeerName:  "eni-peer",
+	})
+	require.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+
+	gw := &EgressGateway{Name: "partner", IP: net.ParseIP("192.168.0.100"), ENI: eni, GatewayIP: net.ParseIP("192.168.0.253")}
+	_, dst1, _ := net.ParseCIDR("203.0.113.0/24")
+	_, dst2, _ := net.ParseCIDR("198.51.100.0/24")
+	pod1 := &EgressGatewayPod{IP: net.ParseIP("192.168.0.10"), Gateway: gw, DstCIDRs: []*net.IPNet{dst1, dst2}}
+	pod2 := &EgressGatewayPod{IP: net.ParseIP("192.168.0.11"), Gateway: gw, DstCIDRs: []*net.IPNet{dst1}}
+
+	// rule of others is kept
+	other := netlink.NewRule()
+	other.Src = utils.NewIPNetWithMaxMask(&net.IPNet{IP: pod1.IP})
+	other.Table = utils.GetRouteTableID(eni.Attrs().Index)
+	other.Priority = fromContainerPriority
+	require.NoError(t, netlink.RuleAdd(other))
+
+	list := func() []string {
+		rules, err := netlink.RuleList(netlink.FAMILY_V4)
+		require.NoError(t, err)
+		var keys []string
+		for i := range rules {
+			if rules[i].Priority == egressGatewayPriority {
+				keys = append(keys, egressGatewayRuleKey(&rules[i]))
+			}
+		}
+		return keys
+	}
+
+	ctx := context.Background()
+	rules := []*netlink.Rule{egressGatewayRule(pod1, dst1), egressGatewayRule(pod1, dst2), egressGatewayRule(pod2, dst1)}
+	require.NoError(t, setEgressGatewayRules(ctx, rules))
+	assert.ElementsMatch(t, []string{
+		egressGatewayRuleKey(rules[0]), egressGatewayRuleKey(rules[1]), egressGatewayRuleKey(rules[2]),
+	}, list())
+
+	// idempotent
+	require.NoError(t, setEgressGatewayRules(ctx, rules))
+	assert.Len(t, list(), 3)
+
+	// pod1 is removed
+	require.NoError(t, setEgressGatewayRules(ctx, rules[2:]))
+	assert.Equal(t, []string{egressGatewayRuleKey(rules[2])}, list())
+
+	found, err := utils.FindIPRule(other)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	require.NoError(t, setEgressGatewayRules(ctx, nil))
+	assert.Empty(t, list())
+}
+
+func TestEgressGatewaySNATSpec(t *testing.T) {
+	gw := &EgressGateway{Name: "partner", IP: net.ParseIP("192.168.0.100")}
+	_, dst, _ := net.ParseCIDR("203.0.113.0/24")
+	spec := egressGatewaySNATSpec(&EgressGatewayPod{IP: net.ParseIP("192.168.0.10"), Gateway: gw}, dst)
+
+	// as listed by iptables -S
+	line := `-A TERWAY-EGRESS-GW -s 192.168.0.10/32 -d 203.0.113.0/24 -m comment --comment "terway egress gateway partner" -j SNAT --to-source 192.168.0.100`
+	fields := splitRuleSpec(line)
+	assert.Equal(t, append([]string{"-A", egressGatewayChain}, spec...), fields)
+	assert.Equal(t, "192.168.0.10/32-203.0.113.0/24-192.168.0.100", egressGatewaySNATKey(fields[2:]))
+	assert.Equal(t, egressGatewaySNATKey(spec), egressGatewaySNATKey(fields[2:]))
+
+	assert.Equal(t, []string{"-N", egressGatewayChain}, splitRuleSpec("-N TERWAY-EGRESS-GW"))
+}
@@ -127,23 +127,8 @@ func generateContCfgForExclusiveENI(cfg *types.SetupConfig, link netlink.Link) *
 		sysctl = utils.GenerateIPv6Sysctl(cfg.ContainerIfName, true, false)
 	}
 
-	for i := range cfg.ExtraRoutes {
-		if cfg.ExtraRoutes[i].GW != nil {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_UNIVERSE,
-				Flags:     int(netlink.FLAG_ONLINK),
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-				Gw:        cfg.ExtraRoutes[i].GW,
-			})
-		} else {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_LINK,
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-			})
-		}
-	}
+	routes = append(routes, generateExtraRoutes(link, cfg.ExtraRoutes)...)
+	rules = append(rules, generateExtraRules(cfg)...)
 
 	contCfg := &nic.Conf{
 		IfName: cfg.ContainerIfName,
@@ -205,7 +190,10 @@ func generateVeth1Cfg(cfg *types.SetupConfig, link netlink.Link, peerMAC net.Har
 			HardwareAddr: peerMAC,
 			State:        netlink.NUD_PERMANENT,
 		})
-		sysctl = utils.GenerateIPv6Sysctl(cfg.ContainerIfName, true, false)
+		if cfg.NAT64 != nil {
+			routes = append(routes, nat64Route(link, cfg.NAT64.Prefix))
+		}
+		sysctl = utils.GenerateIPv6Sysctl(defaultVethForENI, true, false)
 	}
 
 	var extraRoutes []cniTypes.Route
@@ -215,14 +203,19 @@ func generateVeth1Cfg(cfg *types.SetupConfig, link netlink.Link, peerMAC net.Har
 			GW:  LinkIP,
 		}
 		if terwayIP.IPv6(cfg.HostStackCIDRs[i].IP) {
+			if cfg.ContainerIPNet.IPv6 == nil {
+				continue
+			}
 			r.GW = LinkIPv6
+		} else if cfg.ContainerIPNet.IPv4 == nil {
+			continue
 		}
 		extraRoutes = append(extraRoutes, r)
 	}
 
 	//  add eth0 ip to the route
 	if cfg.HostIPSet != nil {
-		if cfg.HostIPSet.IPv4 != nil {
+		if cfg.HostIPSet.IPv4 != nil && cfg.ContainerIPNet.IPv4 != nil {
 			routes = append(routes, &netlink.Route{
 				LinkIndex: link.Attrs().Index,
 				Dst: &net.IPNet{
@@ -232,7 +225,7 @@ func generateVeth1Cfg(cfg *types.SetupConfig, link netlink.Link, peerMAC net.Har
 				Gw: LinkIP,
 			})
 		}
-		if cfg.HostIPSet.IPv6 != nil {
+		if cfg.HostIPSet.IPv6 != nil && cfg.ContainerIPNet.IPv6 != nil {
 			routes = append(routes, &netlink.Route{
 				LinkIndex: link.Attrs().Index,
 				Dst: &net.IPNet{
@@ -273,6 +266,50 @@ func generateVeth1Cfg(cfg *types.SetupConfig, link netlink.Link, peerMAC net.Har
 	return contCfg
 }
 
+// SetHostStackRoutes update the routes to the host stack cidrs in the pod net ns, as the veth1 routes added in setup
+func SetHostStackRoutes(ctx context.Context, netNS ns.NetNS, add, del []*net.IPNet) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		veth1, err := netlink.LinkByName(defaultVethForENI)
+		if err != nil {
+			return fmt.Errorf("error get link %s, %w", defaultVethForENI, err)
+		}
+
+		for _, dst := range del {
+			routes, err := utils.FoundRoutes(hostStackRoute(veth1, dst))
+			if err != nil {
+				return err
+			}
+			for i := range routes {
+				err = utils.RouteDel(ctx, &routes[i])
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, dst := range add {
+			err = utils.RouteReplace(ctx, hostStackRoute(veth1, dst))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func hostStackRoute(link netlink.Link, dst *net.IPNet) *netlink.Route {
+	gw := LinkIP
+	if terwayIP.IPv6(dst.IP) {
+		gw = LinkIPv6
+	}
+	return &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Flags:     int(netlink.FLAG_ONLINK),
+		Dst:       dst,
+		Gw:        gw,
+	}
+}
+
 func generateHostSlaveCfg(cfg *types.SetupConfig, link netlink.Link) *nic.Conf {
 	var addrs []*netlink.Addr
 	var routes []*netlink.Route
@@ -315,6 +352,15 @@ func generateHostSlaveCfg(cfg *types.SetupConfig, link netlink.Link) *nic.Conf {
 }
 
 func (r *ExclusiveENI) Setup(ctx context.Context, cfg *types.SetupConfig, netNS ns.NetNS) error {
+	// nat64 is done on the host side of veth1
+	if cfg.NAT64 != nil && (cfg.DisableCreatePeer || cfg.ContainerIfName != "eth0") {
+		return fmt.Errorf("nat64 requires the host peer of eth0")
+	}
+	// the replies are routed to veth1
+	if len(cfg.HostPorts) > 0 && (cfg.DisableCreatePeer || cfg.ContainerIfName != "eth0") {
+		return fmt.Errorf("host port requires the host peer of eth0")
+	}
+
 	// 1. move link in
 	nicLink, err := netlink.LinkByIndex(cfg.ENIIndex)
 	if err != nil {
@@ -359,6 +405,7 @@ func (r *ExclusiveENI) Setup(ctx context.Context, cfg *types.SetupConfig, netNS
 		}
 	}()
 	// 2. setup addr and default route
+	var veth1MAC net.HardwareAddr
 	err = netNS.Do(func(netNS ns.NetNS) error {
 		// 2.1 setup addr
 		contLink, err := netlink.LinkByName(nicLink.Attrs().Name)
@@ -406,6 +453,7 @@ func (r *ExclusiveENI) Setup(ctx context.Context, cfg *types.SetupConfig, netNS
 			if err != nil {
 				return err
 			}
+			veth1MAC = veth1.Attrs().HardwareAddr
 			veth1Cfg := generateVeth1Cfg(cfg, veth1, mac)
 			return nic.Setup(ctx, veth1, veth1Cfg)
 		}
@@ -429,6 +477,24 @@ func (r *ExclusiveENI) Setup(ctx context.Context, cfg *types.SetupConfig, netNS
 		return fmt.Errorf("error set up hostpeer, %w", err)
 	}
 
+	if cfg.NAT64 != nil {
+		err = setupNAT64(ctx, hostPeer, veth1MAC, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.HostPorts) > 0 {
+		err = setupHostPortReplyRoutes(ctx, netNS, cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+		err = setupHostPorts(ctx, cfg.ContainerIPNet, cfg.HostPorts)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
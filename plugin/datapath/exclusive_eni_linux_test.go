@@ -171,3 +171,209 @@ func TestDataPathExclusiveENI(t *testing.T) {
 	_, ok = err.(netlink.LinkNotFoundError)
 	assert.True(t, ok)
 }
+
+func TestDataPathExclusiveENIHostPort(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	// the client owns the gateway of the eni, and accesses the host ip by the primary link of the host
+	clientNS := newHostPortClient(t)
+	eni := connectHostPortClient(t, clientNS, "eni", "wire", "169.10.0.253/24")
+	primary := connectHostPortClient(t, clientNS, "primary", "wire0", "169.20.0.1/24")
+	err = netlink.AddrAdd(primary, &netlink.Addr{IPNet: eth0IPNet})
+	assert.NoError(t, err)
+
+	cfg := &types2.SetupConfig{
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+		},
+		DefaultRoute: true,
+		HostPorts:    hostPortTestPorts,
+	}
+
+	d := NewExclusiveENIDriver()
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	// without the fwmark rule the replies are sent to the client from the pod ip by the eni
+	assertHostPortReply(t, clientNS, containerNS, eth0IPNet.IP)
+}
+
+func TestDataPathExclusiveENIIPv6Only(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var err error
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	_, hostStackV4, _ := net.ParseCIDR("169.254.20.10/32")
+	_, hostStackV6, _ := net.ParseCIDR("fd00:40::10/128")
+
+	cfg := &types2.SetupConfig{
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		ServiceCIDR: &terwayTypes.IPNetSet{
+			IPv6: serviceCIDRIPv6,
+		},
+		HostStackCIDRs: []*net.IPNet{hostStackV4, hostStackV6},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := NewExclusiveENIDriver()
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		containerLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if assert.NoError(t, err) {
+			ok, err := FindIP(containerLink, utils.NewIPNet(cfg.ContainerIPNet))
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}
+
+		// default via gw dev eth0
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+			Dst: nil,
+		}, netlink.RT_FILTER_DST)
+		if assert.NoError(t, err) && assert.Equal(t, 1, len(routes)) {
+			assert.Equal(t, ipv6GW.String(), routes[0].Gw.String())
+		}
+
+		vethLink, err := netlink.LinkByName(defaultVethForENI)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		// service and host stack cidrs via fe80::1 dev veth1
+		for _, dst := range []*net.IPNet{serviceCIDRIPv6, hostStackV6, utils.NewIPNetWithMaxMask(eth0IPNetIPv6)} {
+			routes, err = netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+				Dst:       dst,
+				LinkIndex: vethLink.Attrs().Index,
+			}, netlink.RT_FILTER_DST|netlink.RT_FILTER_OIF)
+			if assert.NoError(t, err) && assert.Equal(t, 1, len(routes), "expect route to %s", dst) {
+				assert.Equal(t, LinkIPv6.String(), routes[0].Gw.String())
+			}
+		}
+
+		// ipv4 is not used
+		addrs, err := netlink.AddrList(vethLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(addrs))
+
+		routes, err = netlink.RouteList(vethLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(routes))
+		return nil
+	})
+
+	hostVETHLink, err := netlink.LinkByName(cfg.HostVETHName)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ok, err := FindIP(hostVETHLink, &terwayTypes.IPNetSet{
+		IPv6: LinkIPNetv6,
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	addrs, err := netlink.AddrList(hostVETHLink, netlink.FAMILY_V4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(addrs))
+
+	// route fd00:10::10 dev hostVETH
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+		Dst:       utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6),
+		LinkIndex: hostVETHLink.Attrs().Index,
+	}, netlink.RT_FILTER_DST|netlink.RT_FILTER_OIF)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(routes))
+
+	// remove the host stack cidr
+	err = SetHostStackRoutes(context.Background(), containerNS, nil, []*net.IPNet{hostStackV6})
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+			Dst: hostStackV6,
+		}, netlink.RT_FILTER_DST)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(routes))
+		return nil
+	})
+
+	err = utils.GenericTearDown(context.Background(), containerNS)
+	assert.NoError(t, err)
+}
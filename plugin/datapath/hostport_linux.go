@@ -0,0 +1,436 @@
+package datapath
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/go-logr/logr"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/pkg/tc"
+	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+// the host ports are DNATed by the host stack, so the conntrack is shared with the kube-proxy.
+// The DNATed connections are marked in the conntrack, datapaths which bypass the host stack
+// steer the replies of the marked connections back to the host peer.
+
+const (
+	hostPortChain   = "TERWAY-HOSTPORT"
+	hostPortComment = "terway hostport"
+
+	// hostPortMark the conntrack mark of the connections DNATed to the pods
+	hostPortMark = 0x80000
+
+	// hostPortFilterPriority the filters restore the conntrack mark on the eni egress, before the filters of host stack cidrs
+	hostPortFilterPriority = 30000
+	// hostPortRedirectPriority the filter redirect the marked replies to the host stack
+	hostPortRedirectPriority = 30001
+
+	// offset of mark in struct __sk_buff
+	skbMarkOff = 8
+	// BPF_F_INGRESS of bpf_redirect
+	bpfFIngress = 1
+	tcActUnspec = -1
+)
+
+var hostPortMarkSpec = fmt.Sprintf("%#x/%#x", hostPortMark, hostPortMark)
+
+// setupHostPorts DNAT the host ports to the pod ips, the rules of the pod ips are replaced
+func setupHostPorts(ctx context.Context, podIPNet *terwayTypes.IPNetSet, hostPorts []types.HostPort) error {
+	for _, podIP := range podIPs(podIPNet) {
+		var specs [][]string
+		for _, hp := range hostPortsOfFamily(hostPorts, podIP) {
+			specs = append(specs, hostPortSpecs(hp, podIP)...)
+		}
+		err := setHostPortDNAT(ctx, podIP, specs)
+		if err != nil {
+			return fmt.Errorf("error set host ports of %s, %w", podIP, err)
+		}
+	}
+	return nil
+}
+
+// TeardownHostPorts remove the DNAT rules to the pod ips
+func TeardownHostPorts(ctx context.Context, podIPNet *terwayTypes.IPNetSet) error {
+	for _, podIP := range podIPs(podIPNet) {
+		err := setHostPortDNAT(ctx, podIP, nil)
+		if err != nil {
+			return fmt.Errorf("error remove host ports of %s, %w", podIP, err)
+		}
+	}
+	return nil
+}
+
+func podIPs(podIPNet *terwayTypes.IPNetSet) []net.IP {
+	var ips []net.IP
+	if podIPNet == nil {
+		return nil
+	}
+	if podIPNet.IPv4 != nil {
+		ips = append(ips, podIPNet.IPv4.IP)
+	}
+	if podIPNet.IPv6 != nil {
+		ips = append(ips, podIPNet.IPv6.IP)
+	}
+	return ips
+}
+
+// hostPortsOfFamily return the host ports served by the pod ip, the host ports bound to the host ip of other family are skipped
+func hostPortsOfFamily(hostPorts []types.HostPort, podIP net.IP) []types.HostPort {
+	var ports []types.HostPort
+	for _, hp := range hostPorts {
+		if hp.HostIP != nil && (hp.HostIP.To4() == nil) != (podIP.To4() == nil) {
+			continue
+		}
+		ports = append(ports, hp)
+	}
+	return ports
+}
+
+// hostPortSpecs return the rules of the host port, the connection is marked before DNAT.
+// The pod ip is in the comment to find the mark rules of the pod.
+func hostPortSpecs(hp types.HostPort, podIP net.IP) [][]string {
+	match := []string{"-p", hp.Protocol}
+	if hp.HostIP != nil {
+		match = append(match, "-d", utils.NewIPNetWithMaxMask(&net.IPNet{IP: hp.HostIP}).String())
+	}
+	match = append(match,
+		"-m", hp.Protocol, "--dport", strconv.Itoa(hp.HostPort),
+		"-m", "comment", "--comment", hostPortComment+" "+podIP.String(),
+	)
+
+	mark := append(append([]string{}, match...), "-j", "CONNMARK", "--set-xmark", hostPortMarkSpec)
+	dnat := append(append([]string{}, match...),
+		"-j", "DNAT", "--to-destination", net.JoinHostPort(podIP.String(), strconv.Itoa(hp.ContainerPort)))
+	return [][]string{mark, dnat}
+}
+
+// hostPortRuleTarget return the pod ip of the rule, nil if not found
+func hostPortRuleTarget(spec []string) net.IP {
+	for i := 0; i+1 < len(spec); i++ {
+		switch spec[i] {
+		case "--to-destination":
+			host, _, err := net.SplitHostPort(spec[i+1])
+			if err != nil {
+				return nil
+			}
+			return net.ParseIP(host)
+		case "--comment":
+			podIP, ok := strings.CutPrefix(spec[i+1], hostPortComment+" ")
+			if ok {
+				return net.ParseIP(podIP)
+			}
+		}
+	}
+	return nil
+}
+
+func setHostPortDNAT(ctx context.Context, podIP net.IP, expected [][]string) error {
+	l := logr.FromContextOrDiscard(ctx)
+	proto := iptables.ProtocolIPv4
+	if podIP.To4() == nil {
+		proto = iptables.ProtocolIPv6
+	}
+	ipt, err := iptables.NewWithProtocol(proto)
+	if err != nil {
+		return err
+	}
+
+	ok, err := ipt.ChainExists("nat", hostPortChain)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if len(expected) == 0 {
+			return nil
+		}
+		err = ipt.NewChain("nat", hostPortChain)
+		if err != nil {
+			return err
+		}
+	}
+	if len(expected) > 0 {
+		// traffic from the outside and the host itself
+		jump := []string{"-m", "addrtype", "--dst-type", "LOCAL", "-m", "comment", "--comment", hostPortComment, "-j", hostPortChain}
+		for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+			ok, err = ipt.Exists("nat", chain, jump...)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+			l.Info("iptables insert", "table", "nat", "chain", chain, "rule", jump)
+			err = ipt.Insert("nat", chain, 1, jump...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the rules left by the previous pod with the same ip are removed
+	lines, err := ipt.List("nat", hostPortChain)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fields := splitRuleSpec(line)
+		if len(fields) < 2 || fields[0] != "-A" || !podIP.Equal(hostPortRuleTarget(fields[2:])) {
+			continue
+		}
+		l.Info("iptables delete", "table", "nat", "chain", hostPortChain, "rule", fields[2:])
+		err = ipt.DeleteIfExists("nat", hostPortChain, fields[2:]...)
+		if err != nil {
+			return err
+		}
+	}
+	for _, spec := range expected {
+		l.Info("iptables append", "table", "nat", "chain", hostPortChain, "rule", spec)
+		err = ipt.Append("nat", hostPortChain, spec...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostPortReplyFilter restore the conntrack mark of the host stack to the packets from the pod ip on the eni egress.
+// The classification is continued, so the marked replies are redirected by the following filter.
+func hostPortReplyFilter(index int, podIP net.IP) *netlink.U32 {
+	src := utils.NewIPNetWithMaxMask(&net.IPNet{IP: podIP})
+	keys := tc.U32MatchSrc(src)
+
+	connmark := netlink.NewConnmarkAction()
+	connmark.Action = netlink.TC_ACT_UNSPEC
+	return &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: index,
+			Parent:    netlink.HANDLE_MIN_EGRESS,
+			Priority:  hostPortFilterPriority,
+			Protocol:  tc.Protocol(src),
+		},
+		Sel: &netlink.TcU32Sel{
+			Nkeys: uint8(len(keys)),
+			Flags: nl.TC_U32_TERMINAL,
+			Keys:  keys,
+		},
+		Actions: []netlink.Action{connmark},
+	}
+}
+
+// hostPortRedirectInstructions redirect the packets with the host port mark to the ingress of the link,
+// the destination mac is rewritten to the link, the other packets are passed to the next filter.
+// The mark is cleared, so the replies NATed by the host stack are not redirected again.
+func hostPortRedirectInstructions(link netlink.Link) asm.Instructions {
+	mac := make([]byte, 8)
+	copy(mac, link.Attrs().HardwareAddr)
+
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.LoadMem(asm.R2, asm.R6, skbMarkOff, asm.Word),
+		asm.Mov.Reg(asm.R3, asm.R2),
+		asm.And.Imm(asm.R3, hostPortMark),
+		asm.JEq.Imm(asm.R3, 0, "continue"),
+		asm.And.Imm(asm.R2, ^hostPortMark),
+		asm.StoreMem(asm.R6, skbMarkOff, asm.R2, asm.Word),
+
+		// the replies are sent to the gateway
+		asm.StoreImm(asm.RFP, -8, int64(int32(binary.NativeEndian.Uint32(mac))), asm.Word),
+		asm.StoreImm(asm.RFP, -4, int64(int16(binary.NativeEndian.Uint16(mac[4:]))), asm.Half),
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, 0),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -8),
+		asm.Mov.Imm(asm.R4, 6),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnSkbStoreBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "continue"),
+
+		asm.Mov.Imm(asm.R1, int32(link.Attrs().Index)),
+		asm.Mov.Imm(asm.R2, bpfFIngress),
+		asm.FnRedirect.Call(),
+		asm.Return(),
+
+		asm.Mov.Imm(asm.R0, tcActUnspec).WithSymbol("continue"),
+		asm.Return(),
+	}
+}
+
+// setHostPortRedirectFilters redirect the replies of the host ports from the eni egress to the host stack by the ipvlan slave
+func setHostPortRedirectFilters(ctx context.Context, parentLink, slaveLink netlink.Link, podIPNet *terwayTypes.IPNetSet) error {
+	err := delHostPortRedirectFilters(ctx, parentLink, podIPNet)
+	if err != nil {
+		return err
+	}
+	for _, podIP := range podIPs(podIPNet) {
+		err = utils.FilterAdd(ctx, hostPortReplyFilter(parentLink.Attrs().Index, podIP))
+		if err != nil {
+			return fmt.Errorf("add filter for %s error, %w", parentLink.Attrs().Name, err)
+		}
+	}
+
+	// the redirect filter is shared by the pods on the eni
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         "terway_hostport",
+		Type:         ebpf.SchedCLS,
+		License:      "GPL",
+		Instructions: hostPortRedirectInstructions(slaveLink),
+	})
+	if err != nil {
+		return fmt.Errorf("error load host port program, %w", err)
+	}
+	defer prog.Close()
+
+	return utils.FilterReplace(ctx, &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: parentLink.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_EGRESS,
+			Handle:    1,
+			Priority:  hostPortRedirectPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           prog.FD(),
+		Name:         "terway-hostport",
+		DirectAction: true,
+	})
+}
+
+func delHostPortRedirectFilters(ctx context.Context, parentLink netlink.Link, podIPNet *terwayTypes.IPNetSet) error {
+	filters, err := netlink.FilterList(parentLink, netlink.HANDLE_MIN_EGRESS)
+	if err != nil {
+		return fmt.Errorf("list egress filter for %s error, %w", parentLink.Attrs().Name, err)
+	}
+	for _, podIP := range podIPs(podIPNet) {
+		src := tc.U32MatchSrc(utils.NewIPNetWithMaxMask(&net.IPNet{IP: podIP}))
+		for _, filter := range filters {
+			u32, ok := filter.(*netlink.U32)
+			if !ok || u32.Priority != hostPortFilterPriority || u32.Protocol != tc.Protocol(&net.IPNet{IP: podIP}) ||
+				u32.Sel == nil || !tc.Contain(u32.Sel.Keys, src) {
+				continue
+			}
+			err = utils.FilterDel(ctx, u32)
+			if err != nil {
+				return fmt.Errorf("delete filter of %s error, %w", parentLink.Attrs().Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// hostPortConnMarkSpecs the mangle rules in the pod net ns, the connections from veth1 are marked,
+// and the mark is restored to the packets, so the replies are routed to veth1 by the fwmark rule.
+func hostPortConnMarkSpecs() [][]string {
+	comment := []string{"-m", "comment", "--comment", hostPortComment}
+	restore := append([]string{"-m", "connmark", "--mark", hostPortMarkSpec}, comment...)
+	restore = append(restore, "-j", "MARK", "--set-xmark", hostPortMarkSpec)
+
+	mark := append([]string{"-i", defaultVethForENI}, comment...)
+	mark = append(mark, "-j", "CONNMARK", "--set-xmark", hostPortMarkSpec)
+
+	return [][]string{
+		append([]string{"PREROUTING"}, mark...),
+		// the reverse path of the requests is validated by the mark
+		append([]string{"PREROUTING"}, restore...),
+		append([]string{"OUTPUT"}, restore...),
+	}
+}
+
+func setHostPortConnMark(ctx context.Context, proto iptables.Protocol) error {
+	l := logr.FromContextOrDiscard(ctx)
+	ipt, err := iptables.NewWithProtocol(proto)
+	if err != nil {
+		return err
+	}
+	for _, spec := range hostPortConnMarkSpecs() {
+		ok, err := ipt.Exists("mangle", spec[0], spec[1:]...)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		l.Info("iptables append", "table", "mangle", "chain", spec[0], "rule", spec[1:])
+		err = ipt.Append("mangle", spec[0], spec[1:]...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupHostPortReplyRoutes route the replies of the connections from veth1 to the host peer in the pod net ns, for the exclusive eni
+func setupHostPortReplyRoutes(ctx context.Context, netNS ns.NetNS, podIPNet *terwayTypes.IPNetSet) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		veth1, err := netlink.LinkByName(defaultVethForENI)
+		if err != nil {
+			return fmt.Errorf("error get link %s, %w", defaultVethForENI, err)
+		}
+		table := utils.GetRouteTableID(veth1.Attrs().Index)
+
+		newRule := func(family int) *netlink.Rule {
+			rule := netlink.NewRule()
+			rule.Family = family
+			rule.Mark = hostPortMark
+			rule.Mask = hostPortMark
+			rule.Table = table
+			rule.Priority = hostPortPriority
+			return rule
+		}
+
+		conf := &nic.Conf{}
+		if podIPNet.IPv4 != nil {
+			conf.Routes = append(conf.Routes, &netlink.Route{
+				LinkIndex: veth1.Attrs().Index,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Flags:     int(netlink.FLAG_ONLINK),
+				Dst:       defaultRoute,
+				Gw:        LinkIP,
+				Table:     table,
+			})
+			conf.Rules = append(conf.Rules, newRule(netlink.FAMILY_V4))
+			conf.SysCtl = map[string][]string{
+				"src_valid_mark": {fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/src_valid_mark", defaultVethForENI), "1"},
+			}
+		}
+		if podIPNet.IPv6 != nil {
+			conf.Routes = append(conf.Routes, &netlink.Route{
+				LinkIndex: veth1.Attrs().Index,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Flags:     int(netlink.FLAG_ONLINK),
+				Dst:       defaultRouteIPv6,
+				Gw:        LinkIPv6,
+				Table:     table,
+			})
+			conf.Rules = append(conf.Rules, newRule(netlink.FAMILY_V6))
+		}
+		err = nic.Setup(ctx, veth1, conf)
+		if err != nil {
+			return err
+		}
+
+		if podIPNet.IPv4 != nil {
+			err = setHostPortConnMark(ctx, iptables.ProtocolIPv4)
+			if err != nil {
+				return err
+			}
+		}
+		if podIPNet.IPv6 != nil {
+			return setHostPortConnMark(ctx, iptables.ProtocolIPv6)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,367 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/pkg/sysctl"
+	"github.com/AliyunContainerService/terway/pkg/tc"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+var testHostPorts = []types.HostPort{
+	{Protocol: "tcp", HostPort: 8080, ContainerPort: 80},
+	{Protocol: "udp", HostPort: 8080, ContainerPort: 80},
+	{Protocol: "tcp", HostIP: net.ParseIP("192.168.0.1"), HostPort: 8443, ContainerPort: 443},
+}
+
+func TestHostPortSpecs(t *testing.T) {
+	podIP := net.ParseIP("192.168.1.10")
+	specs := hostPortSpecs(testHostPorts[2], podIP)
+	require.Len(t, specs, 2)
+
+	// as listed by iptables -S, the connection is marked before DNAT
+	for i, line := range []string{
+		`-A TERWAY-HOSTPORT -d 192.168.0.1/32 -p tcp -m tcp --dport 8443 -m comment --comment "terway hostport 192.168.1.10" -j CONNMARK --set-xmark 0x80000/0x80000`,
+		`-A TERWAY-HOSTPORT -d 192.168.0.1/32 -p tcp -m tcp --dport 8443 -m comment --comment "terway hostport 192.168.1.10" -j DNAT --to-destination 192.168.1.10:443`,
+	} {
+		fields := splitRuleSpec(line)
+		assert.ElementsMatch(t, specs[i], fields[2:])
+		assert.True(t, podIP.Equal(hostPortRuleTarget(fields[2:])))
+	}
+
+	podIPv6 := net.ParseIP("fd00::10")
+	specs = hostPortSpecs(testHostPorts[0], podIPv6)
+	assert.Equal(t, "[fd00::10]:80", specs[1][len(specs[1])-1])
+	for _, spec := range specs {
+		assert.True(t, podIPv6.Equal(hostPortRuleTarget(spec)))
+	}
+	assert.Nil(t, hostPortRuleTarget([]string{"-m", "comment", "--comment", hostPortComment, "-j", "MASQUERADE"}))
+
+	// the host port bound to the ipv4 address is not served by ipv6
+	assert.Len(t, hostPortsOfFamily(testHostPorts, podIP), 3)
+	assert.Len(t, hostPortsOfFamily(testHostPorts, podIPv6), 2)
+}
+
+// ipvlan datapath, the conntrack mark is restored and the marked replies are redirected to the ipvlan slave on the eni egress
+func TestSetHostPortRedirectFilters(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	defer func() {
+		_ = hostNS.Close()
+		_ = testutils.UnmountNS(hostNS)
+	}()
+	require.NoError(t, hostNS.Set())
+
+	for _, name := range []string{"eni", "slave"} {
+		require.NoError(t, netlink.LinkAdd(&netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: name}}))
+	}
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+	slave, err := netlink.LinkByName("slave")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, utils.EnsureClsActQdsic(ctx, eni))
+
+	// the host stack cidr filter is kept
+	_, cidr, _ := net.ParseCIDR("192.168.0.0/16")
+	require.NoError(t, NewIPVlanDriver().setupFilters(ctx, eni, []*net.IPNet{cidr}, slave.Attrs().Index))
+
+	podIPNet := &terwayTypes.IPNetSet{}
+	podIPNet.SetIPNet("192.168.1.10/32")
+	podIPNet.SetIPNet("fd00::10/128")
+
+	list := func(podIP net.IP) []*netlink.U32 {
+		filters, err := netlink.FilterList(eni, netlink.HANDLE_MIN_EGRESS)
+		require.NoError(t, err)
+		var found []*netlink.U32
+		for _, f := range filters {
+			u32, ok := f.(*netlink.U32)
+			if ok && u32.Priority == hostPortFilterPriority && u32.Protocol == protocolOf(podIP) {
+				found = append(found, u32)
+			}
+		}
+		return found
+	}
+	redirect := func() []netlink.Filter {
+		filters, err := netlink.FilterList(eni, netlink.HANDLE_MIN_EGRESS)
+		require.NoError(t, err)
+		var found []netlink.Filter
+		for _, f := range filters {
+			if f.Attrs().Priority == hostPortRedirectPriority {
+				found = append(found, f)
+			}
+		}
+		return found
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, setHostPortRedirectFilters(ctx, eni, slave, podIPNet))
+	}
+	for _, podIP := range podIPs(podIPNet) {
+		filters := list(podIP)
+		require.Len(t, filters, 1)
+		assert.True(t, tc.Contain(filters[0].Sel.Keys, tc.U32MatchSrc(utils.NewIPNetWithMaxMask(&net.IPNet{IP: podIP}))))
+		require.Len(t, filters[0].Actions, 1)
+		connmark, ok := filters[0].Actions[0].(*netlink.ConnmarkAction)
+		require.True(t, ok)
+		// the classification is continued to the redirect filter
+		assert.Equal(t, netlink.TC_ACT_UNSPEC, connmark.Attrs().Action)
+	}
+	filters := redirect()
+	require.Len(t, filters, 1)
+	bpf, ok := filters[0].(*netlink.BpfFilter)
+	require.True(t, ok)
+	assert.True(t, bpf.DirectAction)
+
+	require.NoError(t, delHostPortRedirectFilters(ctx, eni, podIPNet))
+	assert.Empty(t, list(podIPNet.IPv4.IP))
+	assert.Empty(t, list(podIPNet.IPv6.IP))
+
+	// the redirect filter is shared by the pods
+	assert.Len(t, redirect(), 1)
+	all, err := netlink.FilterList(eni, netlink.HANDLE_MIN_EGRESS)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func protocolOf(ip net.IP) uint16 {
+	if ip.To4() == nil {
+		return unix.ETH_P_IPV6
+	}
+	return unix.ETH_P_IP
+}
+
+// exclusive eni datapath, the replies are routed to veth1 by the conntrack mark in the pod net ns
+func TestSetupHostPortReplyRoutes(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	containerNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	defer func() {
+		_ = containerNS.Close()
+		_ = testutils.UnmountNS(containerNS)
+	}()
+
+	err = containerNS.Do(func(netNS ns.NetNS) error {
+		// the onlink gateway is checked against the local table
+		lo, err := netlink.LinkByName("lo")
+		if err != nil {
+			return err
+		}
+		err = netlink.LinkSetUp(lo)
+		if err != nil {
+			return err
+		}
+		err = netlink.LinkAdd(&netlink.Veth{
+			LinkAttrs: netlink.LinkAttrs{Name: defaultVethForENI},
+			PeerName:  "host-peer",
+		})
+		if err != nil {
+			return err
+		}
+		veth1, err := netlink.LinkByName(defaultVethForENI)
+		if err != nil {
+			return err
+		}
+		err = netlink.LinkSetUp(veth1)
+		if err != nil {
+			return err
+		}
+		// same as the veth1 setup
+		for _, dst := range []*net.IPNet{LinkIPNet, LinkIPNetv6} {
+			err = netlink.RouteAdd(&netlink.Route{LinkIndex: veth1.Attrs().Index, Scope: netlink.SCOPE_LINK, Dst: dst})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	podIPNet := &terwayTypes.IPNetSet{}
+	podIPNet.SetIPNet("192.168.1.10/32")
+	podIPNet.SetIPNet("fd00::10/128")
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, setupHostPortReplyRoutes(ctx, containerNS, podIPNet))
+	}
+
+	err = containerNS.Do(func(netNS ns.NetNS) error {
+		veth1, err := netlink.LinkByName(defaultVethForENI)
+		require.NoError(t, err)
+		table := utils.GetRouteTableID(veth1.Attrs().Index)
+
+		for _, c := range []struct {
+			family int
+			proto  iptables.Protocol
+			gw     net.IP
+		}{
+			{family: netlink.FAMILY_V4, proto: iptables.ProtocolIPv4, gw: LinkIP},
+			{family: netlink.FAMILY_V6, proto: iptables.ProtocolIPv6, gw: LinkIPv6},
+		} {
+			rules, err := netlink.RuleList(c.family)
+			require.NoError(t, err)
+			var found []netlink.Rule
+			for _, r := range rules {
+				if r.Priority == hostPortPriority {
+					found = append(found, r)
+				}
+			}
+			require.Len(t, found, 1)
+			assert.Equal(t, table, found[0].Table)
+			assert.Equal(t, hostPortMark, found[0].Mark)
+			assert.Nil(t, found[0].Sport)
+
+			routes, err := netlink.RouteListFiltered(c.family, &netlink.Route{Table: table}, netlink.RT_FILTER_TABLE)
+			require.NoError(t, err)
+			require.Len(t, routes, 1)
+			assert.True(t, c.gw.Equal(routes[0].Gw))
+
+			ipt, err := iptables.NewWithProtocol(c.proto)
+			require.NoError(t, err)
+			for _, spec := range hostPortConnMarkSpecs() {
+				ok, err := ipt.Exists("mangle", spec[0], spec[1:]...)
+				require.NoError(t, err)
+				assert.True(t, ok, "rule %v", spec)
+			}
+			// the rules are not duplicated
+			lines, err := ipt.List("mangle", "OUTPUT")
+			require.NoError(t, err)
+			assert.Len(t, lines, 2)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// hostPortTestPorts the host port accessed by the client in the datapath tests
+var hostPortTestPorts = []types.HostPort{{Protocol: "tcp", HostPort: 8080, ContainerPort: 80}}
+
+// newHostPortClient create the net ns of the client, the host stack forwards the replies to the client
+func newHostPortClient(t *testing.T) ns.NetNS {
+	clientNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, clientNS.Close())
+		assert.NoError(t, testutils.UnmountNS(clientNS))
+	})
+	require.NoError(t, sysctl.EnsureConf("/proc/sys/net/ipv4/ip_forward", "1"))
+	return clientNS
+}
+
+// connectHostPortClient create the veth in the current net ns, the peer is moved to the client with the addrs
+func connectHostPortClient(t *testing.T, clientNS ns.NetNS, name, peerName string, clientAddrs ...string) netlink.Link {
+	err := netlink.LinkAdd(&netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: name},
+		PeerName:  peerName,
+	})
+	require.NoError(t, err)
+	peer, err := netlink.LinkByName(peerName)
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetNsFd(peer, int(clientNS.Fd())))
+
+	link, err := netlink.LinkByName(name)
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetUp(link))
+
+	err = clientNS.Do(func(_ ns.NetNS) error {
+		peer, err := netlink.LinkByName(peerName)
+		if err != nil {
+			return err
+		}
+		for _, addr := range clientAddrs {
+			ipNet, err := netlink.ParseIPNet(addr)
+			if err != nil {
+				return err
+			}
+			err = netlink.AddrAdd(peer, &netlink.Addr{IPNet: ipNet})
+			if err != nil {
+				return err
+			}
+		}
+		return netlink.LinkSetUp(peer)
+	})
+	require.NoError(t, err)
+	return link
+}
+
+// assertHostPortReply access the host port from the client, the echo server listens on the container port in the pod.
+// The connection is established only if the replies are NATed back to the host port.
+func assertHostPortReply(t *testing.T, clientNS, podNS ns.NetNS, hostIP net.IP) {
+	var l net.Listener
+	err := podNS.Do(func(_ ns.NetNS) error {
+		var err error
+		l, err = net.Listen("tcp", fmt.Sprintf(":%d", hostPortTestPorts[0].ContainerPort))
+		return err
+	})
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	err = clientNS.Do(func(_ ns.NetNS) error {
+		addr := net.JoinHostPort(hostIP.String(), strconv.Itoa(hostPortTestPorts[0].HostPort))
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		err = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if err != nil {
+			return err
+		}
+
+		msg := []byte("terway hostport")
+		_, err = conn.Write(msg)
+		if err != nil {
+			return err
+		}
+		reply := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, reply)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(msg, reply) {
+			return fmt.Errorf("unexpected reply %q", reply)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestVlanHostPortUnsupported(t *testing.T) {
+	err := NewVlan().Setup(context.Background(), &types.SetupConfig{HostPorts: testHostPorts}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"net"
+	"testing"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+// ingressPriorityClasses the classes used in the ingress priority tests
+var ingressPriorityClasses = []*types.IngressPriority{
+	{ClassID: netlink.MakeHandle(1, 1), Prio: 0, Quantum: 6000, Rate: 600000, Ceil: 1000000},
+	{ClassID: netlink.MakeHandle(1, 2), Prio: 1, Quantum: 3000, Rate: 300000, Ceil: 1000000},
+	{ClassID: netlink.MakeHandle(1, 3), Prio: 2, Quantum: 1500, Rate: 100000, Ceil: 1000000},
+}
+
+// newIngressTestENI create the eni in the current netns, the peer is in the remote netns to send traffic to the eni
+func newIngressTestENI(t *testing.T) (netlink.Link, ns.NetNS) {
+	remoteNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, remoteNS.Close())
+		assert.NoError(t, testutils.UnmountNS(remoteNS))
+	})
+
+	err = netlink.LinkAdd(&netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+		PeerName:  "wire",
+	})
+	require.NoError(t, err)
+	wire, err := netlink.LinkByName("wire")
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetNsFd(wire, int(remoteNS.Fd())))
+
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetUp(eni))
+
+	err = remoteNS.Do(func(_ ns.NetNS) error {
+		wire, err := netlink.LinkByName("wire")
+		if err != nil {
+			return err
+		}
+		err = netlink.AddrAdd(wire, &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP("169.10.0.1"), Mask: net.CIDRMask(24, 32)}})
+		if err != nil {
+			return err
+		}
+		return netlink.LinkSetUp(wire)
+	})
+	require.NoError(t, err)
+	return eni, remoteNS
+}
+
+// sendToPods send udp packets from the remote netns to the pod ips through the eni
+func sendToPods(t *testing.T, eni netlink.Link, remoteNS ns.NetNS, ips map[string]int) {
+	err := remoteNS.Do(func(_ ns.NetNS) error {
+		wire, err := netlink.LinkByName("wire")
+		if err != nil {
+			return err
+		}
+		for ip, count := range ips {
+			err = netlink.NeighSet(&netlink.Neigh{
+				LinkIndex:    wire.Attrs().Index,
+				State:        netlink.NUD_PERMANENT,
+				IP:           net.ParseIP(ip),
+				HardwareAddr: eni.Attrs().HardwareAddr,
+			})
+			if err != nil {
+				return err
+			}
+			conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP(ip), Port: 9999})
+			if err != nil {
+				return err
+			}
+			for i := 0; i < count; i++ {
+				_, err = conn.Write(make([]byte, 100))
+				if err != nil {
+					_ = conn.Close()
+					return err
+				}
+			}
+			_ = conn.Close()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// ingressClassPackets the packets scheduled by the classes on the ifb of the eni
+func ingressClassPackets(t *testing.T, eni netlink.Link) map[uint32]uint32 {
+	ifb, err := netlink.LinkByName(utils.IngressPriorityIFBName(eni))
+	require.NoError(t, err)
+	classes, err := netlink.ClassList(ifb, netlink.MakeHandle(1, 0))
+	require.NoError(t, err)
+
+	r := make(map[uint32]uint32)
+	for _, class := range classes {
+		if class.Attrs().Statistics == nil || class.Attrs().Statistics.Basic == nil {
+			continue
+		}
+		r[class.Attrs().Handle] = class.Attrs().Statistics.Basic.Packets
+	}
+	return r
+}
+
+// assertIngressClasses check the classes on the ifb of the eni are same as the config
+func assertIngressClasses(t *testing.T, eni netlink.Link) {
+	ifb, err := netlink.LinkByName(utils.IngressPriorityIFBName(eni))
+	require.NoError(t, err)
+	classes, err := netlink.ClassList(ifb, netlink.MakeHandle(1, 0))
+	require.NoError(t, err)
+
+	got := make(map[uint32]*netlink.HtbClass)
+	for _, class := range classes {
+		htb, ok := class.(*netlink.HtbClass)
+		if ok {
+			got[htb.Handle] = htb
+		}
+	}
+	for _, want := range ingressPriorityClasses {
+		class, ok := got[want.ClassID]
+		if assert.True(t, ok, "class %x", want.ClassID) {
+			assert.Equal(t, want.Rate, class.Rate)
+			assert.Equal(t, want.Ceil, class.Ceil)
+			assert.Equal(t, want.Prio, class.Prio)
+		}
+	}
+}
@@ -2,13 +2,11 @@ package datapath
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
 	"net"
-	"regexp"
-	"strconv"
-	"syscall"
 
+	terwayIP "github.com/AliyunContainerService/terway/pkg/ip"
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/ipvlan"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
@@ -16,20 +14,12 @@ import (
 	terwayTypes "github.com/AliyunContainerService/terway/types"
 
 	"github.com/containernetworking/plugins/pkg/ns"
-	"github.com/pkg/errors"
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
 )
 
-const (
-	ipVlanRequirementMajor = 4
-	ipVlanRequirementMinor = 19
-)
-
 var (
-	regexKernelVersion = regexp.MustCompile(`^(\d+)\.(\d+)`)
-
 	defaultMAC, _ = net.ParseMAC("ee:ff:ff:ff:ff:ff")
 )
 
@@ -181,6 +171,9 @@ func generateContCfgForIPVlan(cfg *types.SetupConfig, link netlink.Link) *nic.Co
 		sysctl = utils.GenerateIPv6Sysctl(cfg.ContainerIfName, true, false)
 	}
 
+	routes = append(routes, generateExtraRoutes(link, cfg.ExtraRoutes)...)
+	rules = append(rules, generateExtraRules(cfg)...)
+
 	contCfg := &nic.Conf{
 		IfName:    cfg.ContainerIfName,
 		MTU:       cfg.MTU,
@@ -251,6 +244,10 @@ func generateSlaveLinkCfgForIPVlan(cfg *types.SetupConfig, link netlink.Link) *n
 }
 
 func (d *IPvlanDriver) Setup(ctx context.Context, cfg *types.SetupConfig, netNS ns.NetNS) error {
+	// no host side link to translate the traffic, the veth datapath is used for nat64 pods
+	if cfg.NAT64 != nil {
+		return fmt.Errorf("nat64 is not supported by ipvlan datapath")
+	}
 	var err error
 
 	parentLink, err := netlink.LinkByIndex(cfg.ENIIndex)
@@ -270,6 +267,13 @@ func (d *IPvlanDriver) Setup(ctx context.Context, cfg *types.SetupConfig, netNS
 		}
 	}
 
+	if cfg.IngressPriority != nil {
+		err = utils.SetIngressPriority(ctx, parentLink, cfg.IngressClasses, cfg.IngressPriority.ClassID, cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+	}
+
 	if cfg.StripVlan {
 		err = utils.EnsureVlanTag(ctx, parentLink, cfg.ContainerIPNet, uint16(cfg.Vid))
 		if err != nil {
@@ -334,6 +338,10 @@ func (d *IPvlanDriver) Teardown(ctx context.Context, cfg *types.TeardownCfg, net
 			if err != nil {
 				return err
 			}
+			err = utils.DelIngressPriority(ctx, link, cfg.ContainerIPNet)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -345,6 +353,11 @@ func (d *IPvlanDriver) Teardown(ctx context.Context, cfg *types.TeardownCfg, net
 			}
 			return nil
 		}
+		// the host port filters also match the pod ip
+		err = delHostPortRedirectFilters(ctx, link, cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
 		return utils.DelFilter(ctx, link, netlink.HANDLE_MIN_EGRESS, cfg.ContainerIPNet)
 	}()
 	if err != nil {
@@ -382,7 +395,7 @@ func (d *IPvlanDriver) Check(ctx context.Context, cfg *types.CheckConfig) error
 			cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", cfg.ContainerIfName, cfg.MTU))
 		}
 
-		return utils.EnsureNetConfSet(true, false)
+		return utils.EnsureNetConfSet(cfg.ContainerIPNet.IPv4 != nil, cfg.ContainerIPNet.IPv6 != nil)
 	})
 	if err != nil {
 		if _, ok := err.(ns.NSPathNotExistErr); ok {
@@ -520,15 +533,37 @@ func (d *IPvlanDriver) setupInitNamespace(ctx context.Context, parentLink netlin
 		return err
 	}
 
-	redirectCIDRs := append(cfg.HostStackCIDRs, cfg.ServiceCIDR.IPv4)
-	err = d.setupFilters(ctx, parentLink, redirectCIDRs, slaveLink.Attrs().Index)
+	err = d.setupFilters(ctx, parentLink, redirectCIDRs(cfg), slaveLink.Attrs().Index)
 	if err != nil {
 		return err
 	}
 
+	if len(cfg.HostPorts) > 0 {
+		// the replies bypass the host stack, redirect the marked replies to the slave
+		err = setHostPortRedirectFilters(ctx, parentLink, slaveLink, cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+		return setupHostPorts(ctx, cfg.ContainerIPNet, cfg.HostPorts)
+	}
 	return nil
 }
 
+// SetupRedirectFilters reconcile the redirect filters on the eni, traffic to the cidrs is sent to the host stack by the ipvlan slave
+func (d *IPvlanDriver) SetupRedirectFilters(ctx context.Context, parentLink netlink.Link, cidrs []*net.IPNet) error {
+	slaveName := d.initSlaveName(parentLink.Attrs().Index)
+	slaveLink, err := netlink.LinkByName(slaveName)
+	if err != nil {
+		return fmt.Errorf("error get ipvlan link %s, %w", slaveName, err)
+	}
+
+	err = utils.EnsureClsActQdsic(ctx, parentLink)
+	if err != nil {
+		return err
+	}
+	return d.setupFilters(ctx, parentLink, cidrs, slaveLink.Attrs().Index)
+}
+
 func (d *IPvlanDriver) teardownInitNamespace(ctx context.Context, containerIP *terwayTypes.IPNetSet) error {
 	if containerIP == nil {
 		return nil
@@ -570,33 +605,49 @@ func (d *IPvlanDriver) initSlaveName(parentIndex int) string {
 	return fmt.Sprintf("ipvl_%d", parentIndex)
 }
 
+// redirectCIDRs return the cidrs redirect to the host stack, only the ip family of the container is included
+func redirectCIDRs(cfg *types.SetupConfig) []*net.IPNet {
+	var cidrs []*net.IPNet
+	for _, cidr := range cfg.HostStackCIDRs {
+		if terwayIP.IPv6(cidr.IP) {
+			if cfg.ContainerIPNet.IPv6 != nil {
+				cidrs = append(cidrs, cidr)
+			}
+		} else if cfg.ContainerIPNet.IPv4 != nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	if cfg.ServiceCIDR != nil {
+		if cfg.ContainerIPNet.IPv4 != nil && cfg.ServiceCIDR.IPv4 != nil {
+			cidrs = append(cidrs, cfg.ServiceCIDR.IPv4)
+		}
+		if cfg.ContainerIPNet.IPv6 != nil && cfg.ServiceCIDR.IPv6 != nil {
+			cidrs = append(cidrs, cfg.ServiceCIDR.IPv6)
+		}
+	}
+	return cidrs
+}
+
 type redirectRule struct {
 	index    int
 	proto    uint16
-	offset   int32
-	value    uint32
-	mask     uint32
+	keys     []netlink.TcU32Key
 	redir    netlink.MirredAct
 	dstIndex int
 }
 
 func dstIPRule(index int, ip *net.IPNet, dstIndex int, redir netlink.MirredAct) (*redirectRule, error) {
-	v4 := ip.IP.Mask(ip.Mask).To4()
-	if v4 == nil {
-		return nil, fmt.Errorf("only support ipv4")
+	if ip == nil || len(ip.IP) == 0 {
+		return nil, fmt.Errorf("invalid cidr %v", ip)
 	}
-
-	v4Mask := net.IP(ip.Mask).To4()
-	if v4Mask == nil {
-		return nil, fmt.Errorf("only support ipv4")
+	if (ip.IP.To4() == nil) != (len(ip.Mask) == net.IPv6len) {
+		return nil, fmt.Errorf("invalid mask of cidr %s", ip.String())
 	}
 
 	return &redirectRule{
 		index:    index,
-		proto:    unix.ETH_P_IP,
-		offset:   16,
-		value:    binary.BigEndian.Uint32(v4),
-		mask:     binary.BigEndian.Uint32(v4Mask),
+		proto:    tc.Protocol(ip),
+		keys:     tc.U32MatchDst(ip),
 		redir:    redir,
 		dstIndex: dstIndex,
 	}, nil
@@ -611,12 +662,7 @@ func (rule *redirectRule) isMatch(filter netlink.Filter) bool {
 		return false
 	}
 
-	if len(u32.Sel.Keys) != 1 {
-		return false
-	}
-
-	key := u32.Sel.Keys[0]
-	if key.Mask != rule.mask || key.Off != rule.offset || key.Val != rule.value {
+	if u32.Sel == nil || len(u32.Sel.Keys) != len(rule.keys) || !tc.Contain(u32.Sel.Keys, rule.keys) {
 		return false
 	}
 
@@ -689,60 +735,14 @@ func (rule *redirectRule) toU32Filter() *netlink.U32 {
 			Protocol:  rule.proto,
 		},
 		Sel: &netlink.TcU32Sel{
-			Nkeys: 1,
+			Nkeys: uint8(len(rule.keys)),
 			Flags: nl.TC_U32_TERMINAL,
-			Keys: []netlink.TcU32Key{
-				{
-					Mask: rule.mask,
-					Val:  rule.value,
-					Off:  rule.offset,
-				},
-			},
+			Keys:  rule.keys,
 		},
 		Actions: rule.toActions(),
 	}
 }
 
-func int8ToString(arr []int8) string {
-	var bytes []byte
-	for _, v := range arr {
-		if v == 0 {
-			break
-		}
-
-		bytes = append(bytes, byte(v))
-	}
-
-	return string(bytes)
-}
-
-// CheckIPVLanAvailable checks if current kernel version meet the requirement (>= 4.19)
-func CheckIPVLanAvailable() (bool, error) {
-	var uts syscall.Utsname
-	err := syscall.Uname(&uts)
-	if err != nil {
-		return false, err
-	}
-
-	result := regexKernelVersion.FindStringSubmatch(int8ToString(uts.Release[:]))
-	if len(result) != 3 {
-		return false, errors.New("can't determine linux kernel version")
-	}
-
-	major, err := strconv.Atoi(result[1])
-	if err != nil {
-		return false, err
-	}
-
-	minor, err := strconv.Atoi(result[2])
-	if err != nil {
-		return false, err
-	}
-
-	return (major == ipVlanRequirementMajor && minor >= ipVlanRequirementMinor) ||
-		major > ipVlanRequirementMajor, nil
-}
-
 func ensureFQ(ctx context.Context, link netlink.Link) error {
 	fq := &netlink.GenericQdisc{
 		QdiscAttrs: netlink.QdiscAttrs{
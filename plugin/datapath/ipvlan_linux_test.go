@@ -8,14 +8,17 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	types2 "github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/types"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 func TestRedirectRule(t *testing.T) {
@@ -305,3 +308,337 @@ func TestDataPathIPvlanL2(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(routes))
 }
+
+func TestDataPathIPvlanIngressPriority(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	eni, remoteNS := newIngressTestENI(t)
+
+	guaranteed := &types.IPNetSet{
+		IPv4: &net.IPNet{IP: net.ParseIP("169.10.0.11"), Mask: net.CIDRMask(32, 32)},
+		IPv6: &net.IPNet{IP: net.ParseIP("fd00:10::11"), Mask: net.CIDRMask(128, 128)},
+	}
+	bestEffort := &types.IPNetSet{
+		IPv4: &net.IPNet{IP: net.ParseIP("169.10.0.12"), Mask: net.CIDRMask(32, 32)},
+	}
+	err = utils.SetIngressPriority(context.Background(), eni, ingressPriorityClasses, netlink.MakeHandle(1, 1), guaranteed)
+	assert.NoError(t, err)
+	err = utils.SetIngressPriority(context.Background(), eni, ingressPriorityClasses, netlink.MakeHandle(1, 3), bestEffort)
+	assert.NoError(t, err)
+	// idempotent
+	err = utils.SetIngressPriority(context.Background(), eni, ingressPriorityClasses, netlink.MakeHandle(1, 3), bestEffort)
+	assert.NoError(t, err)
+
+	assertIngressClasses(t, eni)
+
+	ifb, err := netlink.LinkByName(utils.IngressPriorityIFBName(eni))
+	assert.NoError(t, err)
+
+	priorityOf := func(ipNet *net.IPNet) uint32 {
+		filters, err := netlink.FilterList(eni, netlink.HANDLE_MIN_INGRESS)
+		assert.NoError(t, err)
+		for _, f := range filters {
+			u32, ok := f.(*netlink.U32)
+			if !ok || u32.Sel == nil || !tc.Contain(u32.Sel.Keys, tc.U32MatchDst(ipNet)) {
+				continue
+			}
+			if !assert.Len(t, u32.Actions, 2) {
+				return 0
+			}
+			mirred, ok := u32.Actions[1].(*netlink.MirredAction)
+			assert.True(t, ok)
+			assert.Equal(t, ifb.Attrs().Index, mirred.Ifindex)
+
+			act, ok := u32.Actions[0].(*netlink.SkbEditAction)
+			if ok && act.Priority != nil {
+				return *act.Priority
+			}
+		}
+		return 0
+	}
+
+	// the classes are separated by the pod ip
+	assert.Equal(t, netlink.MakeHandle(1, 1), priorityOf(guaranteed.IPv4))
+	assert.Equal(t, netlink.MakeHandle(1, 1), priorityOf(guaranteed.IPv6))
+	assert.Equal(t, netlink.MakeHandle(1, 3), priorityOf(bestEffort.IPv4))
+
+	// the traffic to the pods is scheduled by the class of the pod
+	sendToPods(t, eni, remoteNS, map[string]int{"169.10.0.11": 10, "169.10.0.12": 20})
+	packets := ingressClassPackets(t, eni)
+	assert.GreaterOrEqual(t, packets[netlink.MakeHandle(1, 1)], uint32(10))
+	assert.Less(t, packets[netlink.MakeHandle(1, 1)], uint32(20))
+	assert.GreaterOrEqual(t, packets[netlink.MakeHandle(1, 3)], uint32(20))
+	assert.Zero(t, packets[netlink.MakeHandle(1, 2)])
+
+	// class changed
+	err = utils.SetIngressPriority(context.Background(), eni, ingressPriorityClasses, netlink.MakeHandle(1, 2), bestEffort)
+	assert.NoError(t, err)
+	assert.Equal(t, netlink.MakeHandle(1, 2), priorityOf(bestEffort.IPv4))
+
+	sendToPods(t, eni, remoteNS, map[string]int{"169.10.0.12": 10})
+	assert.GreaterOrEqual(t, ingressClassPackets(t, eni)[netlink.MakeHandle(1, 2)], uint32(10))
+
+	err = utils.DelIngressPriority(context.Background(), eni, bestEffort)
+	assert.NoError(t, err)
+	assert.Zero(t, priorityOf(bestEffort.IPv4))
+	assert.Equal(t, netlink.MakeHandle(1, 1), priorityOf(guaranteed.IPv4))
+	// the classes are kept for the other pods
+	assertIngressClasses(t, eni)
+}
+
+func TestDataPathIPvlanHostPort(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	// the client owns the gateway of the eni, and accesses the host ip by the primary link of the host
+	clientNS := newHostPortClient(t)
+	eni := connectHostPortClient(t, clientNS, "eni", "wire", "169.10.0.253/24")
+	primary := connectHostPortClient(t, clientNS, "primary", "wire0", "169.20.0.1/24")
+	err = netlink.AddrAdd(primary, &netlink.Addr{IPNet: eth0IPNet})
+	assert.NoError(t, err)
+
+	cfg := &types2.SetupConfig{
+		HostVETHName:    "hostipvl",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &types.IPNetSet{
+			IPv4: containerIPNet,
+		},
+		GatewayIP: &types.IPSet{
+			IPv4: ipv4GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		HostIPSet: &types.IPNetSet{
+			IPv4: eth0IPNet,
+		},
+		DefaultRoute: true,
+		HostPorts:    hostPortTestPorts,
+	}
+	d := NewIPVlanDriver()
+
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	// without the redirect the replies are sent to the client from the pod ip by the eni
+	assertHostPortReply(t, clientNS, containerNS, eth0IPNet.IP)
+
+	err = d.Teardown(context.Background(), &types2.TeardownCfg{
+		HostVETHName:    cfg.HostVETHName,
+		ContainerIfName: cfg.ContainerIfName,
+		ContainerIPNet:  cfg.ContainerIPNet,
+		ENIIndex:        eni.Attrs().Index,
+	}, containerNS)
+	assert.NoError(t, err)
+
+	filters, err := netlink.FilterList(eni, netlink.HANDLE_MIN_EGRESS)
+	assert.NoError(t, err)
+	for _, f := range filters {
+		assert.NotEqual(t, hostPortFilterPriority, int(f.Attrs().Priority))
+	}
+}
+
+func TestRedirectCIDRs(t *testing.T) {
+	_, hostStackV4, _ := net.ParseCIDR("169.254.20.10/32")
+	_, hostStackV6, _ := net.ParseCIDR("fd00:40::10/128")
+
+	cfg := &types2.SetupConfig{
+		ContainerIPNet: &types.IPNetSet{
+			IPv6: containerIPNetIPv6,
+		},
+		HostStackCIDRs: []*net.IPNet{hostStackV4, hostStackV6},
+		ServiceCIDR: &types.IPNetSet{
+			IPv6: serviceCIDRIPv6,
+		},
+	}
+	assert.Equal(t, []*net.IPNet{hostStackV6, serviceCIDRIPv6}, redirectCIDRs(cfg))
+
+	cfg.ContainerIPNet.IPv4 = containerIPNet
+	cfg.ServiceCIDR.IPv4 = serviceCIDR
+	assert.Equal(t, []*net.IPNet{hostStackV4, hostStackV6, serviceCIDR, serviceCIDRIPv6}, redirectCIDRs(cfg))
+
+	cfg.ServiceCIDR = nil
+	assert.Equal(t, []*net.IPNet{hostStackV4, hostStackV6}, redirectCIDRs(cfg))
+}
+
+func TestRedirectRuleIPv6(t *testing.T) {
+	_, cidr, err := net.ParseCIDR("fd00:30::/120")
+	assert.NoError(t, err)
+
+	rule, err := dstIPRule(1, cidr, 2, netlink.TCA_INGRESS_REDIR)
+	assert.NoError(t, err)
+
+	u32 := rule.toU32Filter()
+	assert.Equal(t, uint16(unix.ETH_P_IPV6), u32.Protocol)
+	assert.Equal(t, 4, len(u32.Sel.Keys))
+	assert.Equal(t, uint8(4), u32.Sel.Nkeys)
+	assert.True(t, rule.isMatch(u32))
+
+	_, err = dstIPRule(1, nil, 2, netlink.TCA_INGRESS_REDIR)
+	assert.Error(t, err)
+}
+
+func TestDataPathIPvlanIPv6Only(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var err error
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	cfg := &types2.SetupConfig{
+		HostVETHName:    "hostipvl",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &types.IPNetSet{
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &types.IPSet{
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		ServiceCIDR: &types.IPNetSet{
+			IPv6: serviceCIDRIPv6,
+		},
+		HostIPSet: &types.IPNetSet{
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+	d := NewIPVlanDriver()
+
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		containerLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		ok, err := FindIP(containerLink, cfg.ContainerIPNet)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		// default via fd00:10::fffd dev eth0
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+			Dst: nil,
+		}, netlink.RT_FILTER_DST)
+		assert.NoError(t, err)
+		if assert.Equal(t, 1, len(routes)) {
+			assert.Equal(t, ipv6GW.String(), routes[0].Gw.String())
+		}
+
+		ok, err = FindNeigh(containerLink, eth0IPNetIPv6.IP, eni.Attrs().HardwareAddr)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		routes, err = netlink.RouteList(containerLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(routes))
+		return nil
+	})
+
+	slaveLink, err := netlink.LinkByName(d.initSlaveName(eni.Attrs().Index))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ok, err := FindIP(slaveLink, &types.IPNetSet{
+		IPv6: utils.NewIPNetWithMaxMask(cfg.HostIPSet.IPv6),
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	addrs, err := netlink.AddrList(slaveLink, netlink.FAMILY_V4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(addrs))
+
+	// service cidr is redirect to ipvl_x
+	filters, err := netlink.FilterList(eni, uint32(netlink.HANDLE_CLSACT&0xffff0000|netlink.HANDLE_MIN_EGRESS&0x0000ffff))
+	assert.NoError(t, err)
+	rule, err := dstIPRule(eni.Attrs().Index, serviceCIDRIPv6, slaveLink.Attrs().Index, netlink.TCA_INGRESS_REDIR)
+	assert.NoError(t, err)
+	assert.True(t, lo.ContainsBy(filters, rule.isMatch))
+
+	err = utils.GenericTearDown(context.Background(), containerNS)
+	assert.NoError(t, err)
+
+	err = d.Teardown(context.Background(), &types2.TeardownCfg{
+		HostVETHName:    cfg.HostVETHName,
+		ContainerIfName: cfg.ContainerIfName,
+		ContainerIPNet:  cfg.ContainerIPNet,
+		ENIIndex:        eni.Attrs().Index,
+	}, containerNS)
+	assert.NoError(t, err)
+
+	routes, err := utils.FoundRoutes(&netlink.Route{
+		Dst: utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(routes))
+}
@@ -0,0 +1,153 @@
+package datapath
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/pkg/nat64"
+	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+const nat64FilterPriority = 40000
+
+// nat64Route route the nat64 prefix to the host in the pod net ns
+func nat64Route(link netlink.Link, prefix *net.IPNet) *netlink.Route {
+	return &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Flags:     int(netlink.FLAG_ONLINK),
+		Dst:       prefix,
+		Gw:        LinkIPv6,
+	}
+}
+
+// setupNAT64 translate the traffic of the pod to the nat64 prefix on the host side link.
+// The pod is assigned an ipv4 in the pool, which is routed to the host side link, podMAC is the mac of the pod side link.
+func setupNAT64(ctx context.Context, hostLink netlink.Link, podMAC net.HardwareAddr, cfg *types.SetupConfig) error {
+	podIPv4, err := allocNAT64IP(hostLink, cfg.NAT64.IPv4Pool)
+	if err != nil {
+		return err
+	}
+
+	name := hostLink.Attrs().Name
+	hostCfg := &nic.Conf{
+		Routes: []*netlink.Route{
+			{
+				LinkIndex: hostLink.Attrs().Index,
+				Scope:     netlink.SCOPE_LINK,
+				Dst:       utils.NewIPNetWithMaxMask(&net.IPNet{IP: podIPv4}),
+			},
+		},
+		// the pod has no ipv4, so the neigh is static
+		Neighs: []*netlink.Neigh{
+			{
+				LinkIndex:    hostLink.Attrs().Index,
+				IP:           podIPv4,
+				HardwareAddr: podMAC,
+				State:        netlink.NUD_PERMANENT,
+			},
+		},
+		SysCtl: map[string][]string{
+			"forwarding": {fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/forwarding", name), "1"},
+			"rp_filter":  {fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/rp_filter", name), "0"},
+		},
+	}
+	err = nic.Setup(ctx, hostLink, hostCfg)
+	if err != nil {
+		return fmt.Errorf("setup nat64 for %s, %w", name, err)
+	}
+
+	conf := &nat64.Config{
+		Prefix:  cfg.NAT64.Prefix,
+		PodIPv6: cfg.ContainerIPNet.IPv6.IP,
+		PodIPv4: podIPv4,
+	}
+	ingress, err := nat64.NewIngressProgram(conf)
+	if err != nil {
+		return err
+	}
+	defer ingress.Close()
+	egress, err := nat64.NewEgressProgram(conf)
+	if err != nil {
+		return err
+	}
+	defer egress.Close()
+
+	err = utils.EnsureClsActQdsic(ctx, hostLink)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		parent uint32
+		fd     int
+	}{
+		{parent: netlink.HANDLE_MIN_INGRESS, fd: ingress.FD()},
+		{parent: netlink.HANDLE_MIN_EGRESS, fd: egress.FD()},
+	} {
+		err = utils.FilterReplace(ctx, &netlink.BpfFilter{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: hostLink.Attrs().Index,
+				Parent:    f.parent,
+				Handle:    1,
+				Priority:  nat64FilterPriority,
+				Protocol:  unix.ETH_P_ALL,
+			},
+			Fd:           f.fd,
+			Name:         "terway-nat64",
+			DirectAction: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocNAT64IP return the ipv4 in pool which is not routed to other links.
+// The ip already routed to the link is reused, caller should hold the cni lock.
+func allocNAT64IP(link netlink.Link, pool *net.IPNet) (net.IP, error) {
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{
+		Table: unix.RT_TABLE_MAIN,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("error list routes, %w", err)
+	}
+	used := make(map[uint32]struct{})
+	for _, r := range routes {
+		if r.Dst == nil || !pool.Contains(r.Dst.IP) {
+			continue
+		}
+		if ones, _ := r.Dst.Mask.Size(); ones != 32 {
+			continue
+		}
+		if r.LinkIndex == link.Attrs().Index {
+			return r.Dst.IP.To4(), nil
+		}
+		used[binary.BigEndian.Uint32(r.Dst.IP.To4())] = struct{}{}
+	}
+
+	ones, bits := pool.Mask.Size()
+	first := binary.BigEndian.Uint32(pool.IP.To4())
+	last := first | (uint32(1)<<uint(bits-ones) - 1)
+	// skip the network and broadcast address
+	if bits-ones > 1 {
+		first++
+		last--
+	}
+	for i := first; i <= last && i >= first; i++ {
+		if _, ok := used[i]; ok {
+			continue
+		}
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, i)
+		return ip, nil
+	}
+	return nil, fmt.Errorf("no ip left in nat64 pool %s", pool)
+}
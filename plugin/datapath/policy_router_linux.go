@@ -138,26 +138,14 @@ func generateContCfgForPolicy(cfg *types.SetupConfig, link netlink.Link, mac net
 				Table:     table,
 			})
 		}
+		if cfg.NAT64 != nil {
+			routes = append(routes, nat64Route(link, cfg.NAT64.Prefix))
+		}
 		sysctl = utils.GenerateIPv6Sysctl(cfg.ContainerIfName, true, false)
 	}
 
-	for i := range cfg.ExtraRoutes {
-		if cfg.ExtraRoutes[i].GW != nil {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_UNIVERSE,
-				Flags:     int(netlink.FLAG_ONLINK),
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-				Gw:        cfg.ExtraRoutes[i].GW,
-			})
-		} else {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_LINK,
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-			})
-		}
-	}
+	routes = append(routes, generateExtraRoutes(link, cfg.ExtraRoutes)...)
+	rules = append(rules, generateExtraRules(cfg)...)
 
 	contCfg := &nic.Conf{
 		IfName: cfg.ContainerIfName,
@@ -335,6 +323,7 @@ func (d *PolicyRoute) Setup(ctx context.Context, cfg *types.SetupConfig, netNS n
 		return err
 	}
 
+	var contMAC net.HardwareAddr
 	err = netNS.Do(func(_ ns.NetNS) error {
 
 		// 2. add address for container interface
@@ -342,6 +331,7 @@ func (d *PolicyRoute) Setup(ctx context.Context, cfg *types.SetupConfig, netNS n
 		if err != nil {
 			return fmt.Errorf("error find link %s in container, %w", cfg.ContainerIfName, err)
 		}
+		contMAC = contLink.Attrs().HardwareAddr
 
 		contCfg := generateContCfgForPolicy(cfg, contLink, hostVETH.Attrs().HardwareAddr)
 		err = nic.Setup(ctx, contLink, contCfg)
@@ -375,6 +365,13 @@ func (d *PolicyRoute) Setup(ctx context.Context, cfg *types.SetupConfig, netNS n
 		}
 	}
 
+	if cfg.IngressPriority != nil {
+		err = utils.SetIngressPriority(ctx, eni, cfg.IngressClasses, cfg.IngressPriority.ClassID, cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+	}
+
 	table := utils.GetRouteTableID(eni.Attrs().Index)
 
 	eniCfg := generateENICfgForPolicy(cfg, eni, table)
@@ -396,6 +393,21 @@ func (d *PolicyRoute) Setup(ctx context.Context, cfg *types.SetupConfig, netNS n
 		return fmt.Errorf("setup host veth config, %w", err)
 	}
 
+	if cfg.NAT64 != nil {
+		err = setupNAT64(ctx, hostVETH, contMAC, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the pod traffic goes through the host stack, so the DNAT is enough
+	if len(cfg.HostPorts) > 0 {
+		err = setupHostPorts(ctx, cfg.ContainerIPNet, cfg.HostPorts)
+		if err != nil {
+			return err
+		}
+	}
+
 	if cfg.BandwidthMode != types.BandwidthModeEDT && cfg.Ingress > 0 {
 		return utils.SetupTC(hostVETH, cfg.Ingress)
 	}
@@ -493,7 +505,11 @@ func (d *PolicyRoute) Teardown(ctx context.Context, cfg *types.TeardownCfg, netN
 		return nil
 	}
 
-	return utils.DelEgressPriority(ctx, link, cfg.ContainerIPNet)
+	err = utils.DelEgressPriority(ctx, link, cfg.ContainerIPNet)
+	if err != nil {
+		return err
+	}
+	return utils.DelIngressPriority(ctx, link, cfg.ContainerIPNet)
 }
 
 func ensureMQFQ(ctx context.Context, link netlink.Link) error {
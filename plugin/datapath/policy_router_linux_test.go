@@ -4,6 +4,7 @@ package datapath
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"runtime"
 	"testing"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/coreos/go-iptables/iptables"
 	"github.com/stretchr/testify/assert"
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
@@ -203,3 +205,386 @@ func TestDataPathPolicyRoute(t *testing.T) {
 		t.Logf("%s %#v ", r, r)
 	}
 }
+
+func TestDataPathPolicyRouteIngressPriority(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	eni, remoteNS := newIngressTestENI(t)
+
+	d := &PolicyRoute{}
+	pods := map[string]*types.IngressPriority{
+		"169.10.0.11": ingressPriorityClasses[0],
+		"169.10.0.12": ingressPriorityClasses[2],
+	}
+	i := 0
+	for ip, prio := range pods {
+		i++
+		containerNS, err := testutils.NewNS()
+		assert.NoError(t, err)
+		defer func() {
+			err := containerNS.Close()
+			assert.NoError(t, err)
+
+			err = testutils.UnmountNS(containerNS)
+			assert.NoError(t, err)
+		}()
+
+		cfg := &types.SetupConfig{
+			HostVETHName:    fmt.Sprintf("hostveth%d", i),
+			ContainerIfName: "eth0",
+			ContainerIPNet: &terwayTypes.IPNetSet{
+				IPv4: &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(24, 32)},
+			},
+			GatewayIP: &terwayTypes.IPSet{
+				IPv4: ipv4GW,
+			},
+			MTU:      1500,
+			ENIIndex: eni.Attrs().Index,
+			HostIPSet: &terwayTypes.IPNetSet{
+				IPv4: eth0IPNet,
+			},
+			DefaultRoute:    true,
+			IngressPriority: prio,
+			IngressClasses:  ingressPriorityClasses,
+		}
+		err = d.Setup(context.Background(), cfg, containerNS)
+		assert.NoError(t, err)
+
+		// the pods share the classes on the eni, nothing is shaped on the host veth
+		hostVETHLink, err := netlink.LinkByName(cfg.HostVETHName)
+		assert.NoError(t, err)
+		classes, err := netlink.ClassList(hostVETHLink, netlink.MakeHandle(1, 0))
+		assert.NoError(t, err)
+		assert.Empty(t, classes)
+	}
+
+	assertIngressClasses(t, eni)
+
+	// the traffic to the pods is scheduled by the class of the pod
+	sendToPods(t, eni, remoteNS, map[string]int{"169.10.0.11": 10, "169.10.0.12": 20})
+	packets := ingressClassPackets(t, eni)
+	assert.GreaterOrEqual(t, packets[netlink.MakeHandle(1, 1)], uint32(10))
+	assert.Less(t, packets[netlink.MakeHandle(1, 1)], uint32(20))
+	assert.GreaterOrEqual(t, packets[netlink.MakeHandle(1, 3)], uint32(20))
+	assert.Zero(t, packets[netlink.MakeHandle(1, 2)])
+}
+
+func TestDataPathPolicyRouteExtraRoutes(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	_, dst, _ := net.ParseCIDR("192.168.100.0/24")
+	_, from, _ := net.ParseCIDR("169.10.0.0/24")
+	cfg := &types.SetupConfig{
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+		},
+		MTU:      1500,
+		ENIIndex: eni.Attrs().Index,
+		ExtraRoutes: []types.Route{
+			{Dst: *dst, GW: LinkIP, Src: containerIPNet.IP, Metric: 100, Table: 100, MTU: 1400},
+		},
+		ExtraRules: []types.Rule{
+			{Src: from, Priority: 300, Table: 100},
+			{Mark: 0x10, Table: 100},
+		},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+		},
+		DefaultRoute: true,
+	}
+
+	d := &PolicyRoute{}
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{
+			Dst:   dst,
+			Table: 100,
+		}, netlink.RT_FILTER_DST|netlink.RT_FILTER_TABLE)
+		assert.NoError(t, err)
+		assert.Len(t, routes, 1)
+		assert.Equal(t, LinkIP.String(), routes[0].Gw.String())
+		assert.Equal(t, containerIPNet.IP.String(), routes[0].Src.String())
+		assert.Equal(t, 100, routes[0].Priority)
+		assert.Equal(t, 1400, routes[0].MTU)
+
+		rules, err := netlink.RuleListFiltered(netlink.FAMILY_V4, &netlink.Rule{Src: from}, netlink.RT_FILTER_SRC)
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, 300, rules[0].Priority)
+		assert.Equal(t, 100, rules[0].Table)
+
+		rules, err = netlink.RuleListFiltered(netlink.FAMILY_V4, &netlink.Rule{Mark: 0x10}, netlink.RT_FILTER_MARK)
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, extraRulePriority, rules[0].Priority)
+		return nil
+	})
+}
+
+func TestDataPathPolicyRouteIPv6Only(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var err error
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	cfg := &types.SetupConfig{
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		ServiceCIDR: &terwayTypes.IPNetSet{
+			IPv6: serviceCIDRIPv6,
+		},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := &PolicyRoute{}
+
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		containerLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		ok, err := FindIP(containerLink, utils.NewIPNet(cfg.ContainerIPNet))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		addrs, err := netlink.AddrList(containerLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(addrs))
+
+		// default via fe80::1 dev eth0
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+			Dst: nil,
+		}, netlink.RT_FILTER_DST)
+		assert.NoError(t, err)
+		if assert.Equal(t, 1, len(routes)) {
+			assert.Equal(t, LinkIPv6.String(), routes[0].Gw.String())
+		}
+
+		routes, err = netlink.RouteList(containerLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(routes))
+		return nil
+	})
+
+	hostVETHLink, err := netlink.LinkByName(cfg.HostVETHName)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// fd00:10::10 dev hostVETH
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+		Dst:       utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6),
+		LinkIndex: hostVETHLink.Attrs().Index,
+	}, netlink.RT_FILTER_DST|netlink.RT_FILTER_OIF)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(routes))
+
+	routes, err = netlink.RouteList(hostVETHLink, netlink.FAMILY_V4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(routes))
+
+	// default via fd00:10::fffd dev eni table
+	routes, err = netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+		Table: utils.GetRouteTableID(eni.Attrs().Index),
+	}, netlink.RT_FILTER_TABLE)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(routes)) {
+		assert.Equal(t, ipv6GW.String(), routes[0].Gw.String())
+	}
+
+	rules, err := netlink.RuleListFiltered(netlink.FAMILY_V6, &netlink.Rule{
+		Priority: fromContainerPriority,
+		Table:    utils.GetRouteTableID(eni.Attrs().Index),
+		Src:      utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6),
+	}, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_SRC|netlink.RT_FILTER_PRIORITY)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules))
+
+	// no ipv4 rule is added
+	rules, err = netlink.RuleListFiltered(netlink.FAMILY_V4, &netlink.Rule{
+		Table: utils.GetRouteTableID(eni.Attrs().Index),
+	}, netlink.RT_FILTER_TABLE)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(rules))
+
+	err = utils.GenericTearDown(context.Background(), containerNS)
+	assert.NoError(t, err)
+
+	err = d.Teardown(context.Background(), &types.TeardownCfg{
+		HostVETHName:    cfg.HostVETHName,
+		ContainerIfName: cfg.ContainerIfName,
+		ContainerIPNet:  cfg.ContainerIPNet,
+		ENIIndex:        eni.Attrs().Index,
+	}, containerNS)
+	assert.NoError(t, err)
+
+	rules, err = netlink.RuleListFiltered(netlink.FAMILY_V6, &netlink.Rule{
+		Priority: fromContainerPriority,
+	}, netlink.RT_FILTER_PRIORITY)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(rules))
+}
+
+func TestDataPathPolicyRouteHostPort(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	// the client owns the gateway of the eni, the host ip is on the eni
+	clientNS := newHostPortClient(t)
+	eni := connectHostPortClient(t, clientNS, "eni", "wire", "169.20.0.1/24", "169.10.0.253/24")
+	err = netlink.AddrAdd(eni, &netlink.Addr{IPNet: eth0IPNet})
+	assert.NoError(t, err)
+
+	cfg := &types.SetupConfig{
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+		},
+		DefaultRoute: true,
+		HostPorts:    hostPortTestPorts,
+	}
+
+	d := &PolicyRoute{}
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	assertHostPortReply(t, clientNS, containerNS, eth0IPNet.IP)
+
+	// the rules are removed with the pod
+	err = TeardownHostPorts(context.Background(), cfg.ContainerIPNet)
+	assert.NoError(t, err)
+	ipt, err := iptables.New()
+	assert.NoError(t, err)
+	lines, err := ipt.List("nat", hostPortChain)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-N " + hostPortChain}, lines)
+}
@@ -0,0 +1,69 @@
+package datapath
+
+import (
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+)
+
+// extraRulePriority is used for the extra rules without priority
+const extraRulePriority = 1024
+
+// generateExtraRoutes render the extra routes on the container link
+func generateExtraRoutes(link netlink.Link, extraRoutes []types.Route) []*netlink.Route {
+	var routes []*netlink.Route
+	for i := range extraRoutes {
+		r := &extraRoutes[i]
+		route := &netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       &r.Dst,
+			Src:       r.Src,
+			Priority:  r.Metric,
+			Table:     r.Table,
+			MTU:       r.MTU,
+		}
+		if r.GW != nil {
+			route.Scope = netlink.SCOPE_UNIVERSE
+			route.Flags = int(netlink.FLAG_ONLINK)
+			route.Gw = r.GW
+		} else {
+			route.Scope = netlink.SCOPE_LINK
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// generateExtraRules render the extra rules in container, rule only match fwmark is added for each ip family of the container
+func generateExtraRules(cfg *types.SetupConfig) []*netlink.Rule {
+	var rules []*netlink.Rule
+	for _, r := range cfg.ExtraRules {
+		newRule := func(family int) *netlink.Rule {
+			rule := netlink.NewRule()
+			rule.Family = family
+			rule.Src = r.Src
+			rule.Dst = r.Dst
+			if r.Mark > 0 {
+				rule.Mark = int(r.Mark)
+			}
+			rule.Table = r.Table
+			rule.Priority = r.Priority
+			if rule.Priority == 0 {
+				rule.Priority = extraRulePriority
+			}
+			return rule
+		}
+		if r.Src != nil || r.Dst != nil {
+			rules = append(rules, newRule(0))
+			continue
+		}
+		if cfg.ContainerIPNet.IPv4 != nil {
+			rules = append(rules, newRule(unix.AF_INET))
+		}
+		if cfg.ContainerIPNet.IPv6 != nil {
+			rules = append(rules, newRule(unix.AF_INET6))
+		}
+	}
+	return rules
+}
@@ -109,23 +109,8 @@ func generateContCfgForVlan(cfg *types.SetupConfig, link netlink.Link) *nic.Conf
 		sysctl = utils.GenerateIPv6Sysctl(cfg.ContainerIfName, true, false)
 	}
 
-	for i := range cfg.ExtraRoutes {
-		if cfg.ExtraRoutes[i].GW != nil {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_UNIVERSE,
-				Flags:     int(netlink.FLAG_ONLINK),
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-				Gw:        cfg.ExtraRoutes[i].GW,
-			})
-		} else {
-			routes = append(routes, &netlink.Route{
-				LinkIndex: link.Attrs().Index,
-				Scope:     netlink.SCOPE_LINK,
-				Dst:       &cfg.ExtraRoutes[i].Dst,
-			})
-		}
-	}
+	routes = append(routes, generateExtraRoutes(link, cfg.ExtraRoutes)...)
+	rules = append(rules, generateExtraRules(cfg)...)
 
 	contCfg := &nic.Conf{
 		IfName: cfg.ContainerIfName,
@@ -149,6 +134,12 @@ func generateENICfgForVlan(cfg *types.SetupConfig) *nic.Conf {
 }
 
 func (d *Vlan) Setup(ctx context.Context, cfg *types.SetupConfig, netNS ns.NetNS) error {
+	if cfg.NAT64 != nil {
+		return fmt.Errorf("nat64 is not supported by vlan datapath")
+	}
+	if len(cfg.HostPorts) > 0 {
+		return fmt.Errorf("host port is not supported by vlan datapath")
+	}
 	master, err := netlink.LinkByIndex(cfg.ENIIndex)
 	if err != nil {
 		return fmt.Errorf("error get link by index %d, %w", cfg.ENIIndex, err)
@@ -0,0 +1,121 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func TestDataPathVlanIPv6Only(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var err error
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := containerNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(containerNS)
+		assert.NoError(t, err)
+
+		err = hostNS.Close()
+		assert.NoError(t, err)
+
+		err = testutils.UnmountNS(hostNS)
+		assert.NoError(t, err)
+	}()
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	cfg := &types.SetupConfig{
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		Vid:      100,
+		ServiceCIDR: &terwayTypes.IPNetSet{
+			IPv6: serviceCIDRIPv6,
+		},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := NewVlan()
+	err = d.Setup(context.Background(), cfg, containerNS)
+	assert.NoError(t, err)
+
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		containerLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		assert.Equal(t, "vlan", containerLink.Type())
+		assert.Equal(t, cfg.MTU, containerLink.Attrs().MTU)
+
+		ok, err := FindIP(containerLink, cfg.ContainerIPNet)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		addrs, err := netlink.AddrList(containerLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(addrs))
+
+		// default via fd00:10::fffd dev eth0
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{
+			Dst: nil,
+		}, netlink.RT_FILTER_DST)
+		assert.NoError(t, err)
+		if assert.Equal(t, 1, len(routes)) {
+			assert.Equal(t, ipv6GW.String(), routes[0].Gw.String())
+		}
+
+		routes, err = netlink.RouteList(containerLink, netlink.FAMILY_V4)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(routes))
+		return nil
+	})
+
+	err = d.Check(context.Background(), &types.CheckConfig{
+		NetNS:           containerNS,
+		ContainerIfName: cfg.ContainerIfName,
+		ContainerIPNet:  cfg.ContainerIPNet,
+		MTU:             cfg.MTU,
+		RecordPodEvent:  func(msg string) {},
+	})
+	assert.NoError(t, err)
+
+	err = utils.GenericTearDown(context.Background(), containerNS)
+	assert.NoError(t, err)
+}
@@ -1,9 +1,11 @@
 package types
 
 import (
+	"fmt"
 	"net"
 	"strings"
 
+	"github.com/AliyunContainerService/terway/pkg/nat64"
 	"github.com/AliyunContainerService/terway/plugin/terway/cni"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
 
@@ -38,6 +40,17 @@ type CNIConf struct {
 	// EnableNetworkPriority by enable priority control, eni qdisc is replaced with tc_prio
 	EnableNetworkPriority bool `json:"enable_network_priority"`
 
+	// NetworkPriorityClasses the ingress share of the network priority classes, copied from the eni config
+	NetworkPriorityClasses map[terwayTypes.NetworkPrio]terwayTypes.NetworkPriorityClass `json:"network_priority_classes,omitempty"`
+	// NetworkPriorityBandwidth the ingress bandwidth shared by the classes, in bytes per second
+	NetworkPriorityBandwidth uint64 `json:"network_priority_bandwidth,omitempty"`
+
+	// NAT64IPv4Pool enable the stateless nat64 for ipv6 only pods on this node, each pod is translated to an ipv4 in the pool.
+	// The pool should be routed to this node.
+	NAT64IPv4Pool string `json:"nat64_ipv4_pool,omitempty"`
+	// NAT64Prefix the /96 prefix the ipv4 address is embedded in, default to 64:ff9b::/96
+	NAT64Prefix string `json:"nat64_prefix,omitempty"`
+
 	// Debug
 	Debug bool `json:"debug"`
 }
@@ -46,6 +59,150 @@ func (n *CNIConf) IPVlan() bool {
 	return strings.ToLower(n.ENIIPVirtualType) == "ipvlan"
 }
 
+// minIngressRate the htb class requires a positive rate
+const minIngressRate = 125
+
+// IngressPriority return the ingress shaping of the pod priority class, nil if the class is not configured.
+// The classes share the bandwidth of the eni, so the bandwidth is required.
+func (n *CNIConf) IngressPriority(prio string, classID uint32) *IngressPriority {
+	if !n.EnableNetworkPriority || len(n.NetworkPriorityClasses) == 0 || n.NetworkPriorityBandwidth == 0 {
+		return nil
+	}
+	p := terwayTypes.NetworkPrio(prio)
+	if p == "" {
+		p = terwayTypes.NetworkPrioBurstable
+	}
+	class, ok := n.NetworkPriorityClasses[p]
+	if !ok {
+		return nil
+	}
+
+	r := &IngressPriority{
+		ClassID: classID,
+		Rate:    max(n.NetworkPriorityBandwidth*uint64(class.RateShare)/100, minIngressRate),
+		Ceil:    n.NetworkPriorityBandwidth,
+	}
+	// classes with higher weight are served first
+	for _, other := range n.NetworkPriorityClasses {
+		if other.Weight > class.Weight {
+			r.Prio++
+		}
+	}
+	mtu := n.MTU
+	if mtu <= 0 {
+		mtu = 1500
+	}
+	r.Quantum = class.Weight * uint32(mtu)
+	return r
+}
+
+// GetHostStackCIDRs return the cidrs in conf and the cidrs served by daemon, duplicated ones are ignored
+func (n *CNIConf) GetHostStackCIDRs(served []string) ([]*net.IPNet, error) {
+	seen := make(map[string]struct{})
+	cidrs := make([]*net.IPNet, 0)
+	for _, v := range append(append([]string{}, n.HostStackCIDRs...), served...) {
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("host_stack_cidrs(%s) is invaild: %v", v, err)
+		}
+		if _, ok := seen[cidr.String()]; ok {
+			continue
+		}
+		seen[cidr.String()] = struct{}{}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// GetNAT64 return the nat64 config of the node, nil if nat64 is not enabled
+func (n *CNIConf) GetNAT64() (*NAT64, error) {
+	if n.NAT64IPv4Pool == "" {
+		return nil, nil
+	}
+	ip, pool, err := net.ParseCIDR(n.NAT64IPv4Pool)
+	if err != nil {
+		return nil, fmt.Errorf("nat64_ipv4_pool(%s) is invaild: %v", n.NAT64IPv4Pool, err)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("nat64_ipv4_pool(%s) is not ipv4", n.NAT64IPv4Pool)
+	}
+	prefix, err := nat64.ParsePrefix(n.NAT64Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &NAT64{Prefix: prefix, IPv4Pool: pool}, nil
+}
+
+// NAT64 is the stateless nat64 config of the node
+type NAT64 struct {
+	Prefix   *net.IPNet
+	IPv4Pool *net.IPNet
+}
+
+// GetHostPorts return the port mappings passed by the runtime, the terway plugin receives them only if the
+// portMappings capability is declared in its own config
+func (n *CNIConf) GetHostPorts() ([]HostPort, error) {
+	var ports []HostPort
+	for _, m := range n.RuntimeConfig.PortMaps {
+		if m.HostPort <= 0 || m.HostPort > 65535 || m.ContainerPort <= 0 || m.ContainerPort > 65535 {
+			return nil, fmt.Errorf("port mapping %d:%d is invalid", m.HostPort, m.ContainerPort)
+		}
+		p := HostPort{
+			Protocol:      strings.ToLower(m.Protocol),
+			HostPort:      m.HostPort,
+			ContainerPort: m.ContainerPort,
+		}
+		switch p.Protocol {
+		case "":
+			p.Protocol = "tcp"
+		case "tcp", "udp", "sctp":
+		default:
+			return nil, fmt.Errorf("protocol %s of port mapping %d is not supported", m.Protocol, m.HostPort)
+		}
+		if m.HostIP != "" {
+			p.HostIP = net.ParseIP(m.HostIP)
+			if p.HostIP == nil {
+				return nil, fmt.Errorf("host ip %s of port mapping %d is invalid", m.HostIP, m.HostPort)
+			}
+			// listen on all addresses
+			if p.HostIP.IsUnspecified() {
+				p.HostIP = nil
+			}
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// HostPort is the port mapping of the pod, the traffic to the host port is DNATed by the host stack
+type HostPort struct {
+	// Protocol tcp, udp or sctp
+	Protocol string
+	// HostIP nil for all addresses of the host
+	HostIP        net.IP
+	HostPort      int
+	ContainerPort int
+}
+
+// Route is the extra route in container, route without GW is on link
+type Route struct {
+	Dst    net.IPNet
+	GW     net.IP
+	Src    net.IP
+	Metric int
+	Table  int
+	MTU    int
+}
+
+// Rule is the extra policy routing rule in container, rule without Src and Dst applies to all ip families of the container
+type Rule struct {
+	Src      *net.IPNet
+	Dst      *net.IPNet
+	Mark     uint32
+	Priority int
+	Table    int
+}
+
 // VlanStripType how datapath handle vlan
 type VlanStripType string
 
@@ -101,7 +258,13 @@ type SetupConfig struct {
 	MultiNetwork bool
 
 	// add extra route in container
-	ExtraRoutes []cniTypes.Route
+	ExtraRoutes []Route
+	// add extra policy routing rules in container
+	ExtraRules []Rule
+	// NAT64 translate the traffic to the nat64 prefix, only for ipv6 only pods, nil if not enabled
+	NAT64 *NAT64
+	// HostPorts the port mappings served by terway, the replies are steered back to the host stack
+	HostPorts []HostPort
 
 	ServiceCIDR *terwayTypes.IPNetSet
 	HostIPSet   *terwayTypes.IPNetSet
@@ -114,6 +277,10 @@ type SetupConfig struct {
 
 	EnableNetworkPriority bool
 	NetworkPriority       uint32
+	// IngressPriority nil if the ingress priority is not enabled
+	IngressPriority *IngressPriority
+	// IngressClasses all the ingress priority classes shared by the pods on the eni
+	IngressClasses []*IngressPriority
 
 	RuntimeConfig cni.RuntimeConfig
 
@@ -122,6 +289,18 @@ type SetupConfig struct {
 	AssistantGatewayIP      *terwayTypes.IPSet
 }
 
+// IngressPriority is the ingress shaping of the pod priority class
+type IngressPriority struct {
+	// ClassID same as the egress class
+	ClassID uint32
+	// Prio the rank of the class, 0 is served first
+	Prio    uint32
+	Quantum uint32
+	// Rate the bandwidth guaranteed to the class, Ceil the bandwidth can borrow, in bytes per second
+	Rate uint64
+	Ceil uint64
+}
+
 type TeardownCfg struct {
 	DP DataPath
 
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+func TestCNIConf_IngressPriority(t *testing.T) {
+	conf := &CNIConf{
+		EnableNetworkPriority: true,
+		NetworkPriorityClasses: map[terwayTypes.NetworkPrio]terwayTypes.NetworkPriorityClass{
+			terwayTypes.NetworkPrioGuaranteed: {Weight: 4, RateShare: 60},
+			terwayTypes.NetworkPrioBurstable:  {Weight: 2, RateShare: 30},
+			terwayTypes.NetworkPrioBestEffort: {Weight: 1},
+		},
+		NetworkPriorityBandwidth: 1000000,
+	}
+
+	assert.Equal(t, &IngressPriority{ClassID: 1, Prio: 0, Quantum: 6000, Rate: 600000, Ceil: 1000000},
+		conf.IngressPriority(string(terwayTypes.NetworkPrioGuaranteed), 1))
+	// no priority is burstable
+	assert.Equal(t, &IngressPriority{ClassID: 2, Prio: 1, Quantum: 3000, Rate: 300000, Ceil: 1000000},
+		conf.IngressPriority("", 2))
+	assert.Equal(t, &IngressPriority{ClassID: 3, Prio: 2, Quantum: 1500, Rate: minIngressRate, Ceil: 1000000},
+		conf.IngressPriority(string(terwayTypes.NetworkPrioBestEffort), 3))
+
+	// the bandwidth shared by the classes is required
+	conf.NetworkPriorityBandwidth = 0
+	assert.Nil(t, conf.IngressPriority(string(terwayTypes.NetworkPrioGuaranteed), 1))
+	conf.NetworkPriorityBandwidth = 1000000
+
+	conf.EnableNetworkPriority = false
+	assert.Nil(t, conf.IngressPriority(string(terwayTypes.NetworkPrioGuaranteed), 1))
+}
+
+func TestCNIConf_GetHostStackCIDRs(t *testing.T) {
+	conf := &CNIConf{HostStackCIDRs: []string{"169.254.20.10/32"}}
+
+	cidrs, err := conf.GetHostStackCIDRs([]string{"169.254.20.10/32", "192.168.0.0/24"})
+	assert.NoError(t, err)
+	assert.Len(t, cidrs, 2)
+	assert.Equal(t, "169.254.20.10/32", cidrs[0].String())
+	assert.Equal(t, "192.168.0.0/24", cidrs[1].String())
+
+	_, err = conf.GetHostStackCIDRs([]string{"foo"})
+	assert.Error(t, err)
+}
+
+func TestCNIConf_GetNAT64(t *testing.T) {
+	conf := &CNIConf{}
+	nat64, err := conf.GetNAT64()
+	assert.NoError(t, err)
+	assert.Nil(t, nat64)
+
+	conf.NAT64IPv4Pool = "100.64.0.1/24"
+	nat64, err = conf.GetNAT64()
+	assert.NoError(t, err)
+	assert.Equal(t, "100.64.0.0/24", nat64.IPv4Pool.String())
+	assert.Equal(t, "64:ff9b::/96", nat64.Prefix.String())
+
+	conf.NAT64Prefix = "fd64::/96"
+	nat64, err = conf.GetNAT64()
+	assert.NoError(t, err)
+	assert.Equal(t, "fd64::/96", nat64.Prefix.String())
+
+	conf.NAT64Prefix = "fd64::/64"
+	_, err = conf.GetNAT64()
+	assert.Error(t, err)
+
+	conf.NAT64Prefix = ""
+	conf.NAT64IPv4Pool = "fd00::/64"
+	_, err = conf.GetNAT64()
+	assert.Error(t, err)
+}
+
+func TestCNIConf_GetHostPorts(t *testing.T) {
+	conf := &CNIConf{}
+	ports, err := conf.GetHostPorts()
+	assert.NoError(t, err)
+	assert.Empty(t, ports)
+
+	conf.RuntimeConfig.PortMaps = []cni.RuntimePortMapEntry{
+		{HostPort: 8080, ContainerPort: 80},
+		{HostPort: 5353, ContainerPort: 53, Protocol: "UDP", HostIP: "192.168.0.1"},
+		{HostPort: 9090, ContainerPort: 90, Protocol: "sctp", HostIP: "0.0.0.0"},
+	}
+	ports, err = conf.GetHostPorts()
+	assert.NoError(t, err)
+	assert.Equal(t, []HostPort{
+		{Protocol: "tcp", HostPort: 8080, ContainerPort: 80},
+		{Protocol: "udp", HostIP: net.ParseIP("192.168.0.1"), HostPort: 5353, ContainerPort: 53},
+		{Protocol: "sctp", HostPort: 9090, ContainerPort: 90},
+	}, ports)
+
+	for _, m := range []cni.RuntimePortMapEntry{
+		{HostPort: 0, ContainerPort: 80},
+		{HostPort: 8080, ContainerPort: 65536},
+		{HostPort: 8080, ContainerPort: 80, Protocol: "icmp"},
+		{HostPort: 8080, ContainerPort: 80, HostIP: "foo"},
+	} {
+		conf.RuntimeConfig.PortMaps = []cni.RuntimePortMapEntry{m}
+		_, err = conf.GetHostPorts()
+		assert.Error(t, err, m)
+	}
+}
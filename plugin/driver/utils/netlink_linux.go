@@ -191,6 +191,27 @@ func QdiscReplace(ctx context.Context, qdisc netlink.Qdisc) error {
 	}
 	return nil
 }
+
+func FilterReplace(ctx context.Context, filter netlink.Filter) error {
+	cmd := fmt.Sprintf("tc filter replace %s", filter.Attrs().String())
+	logr.FromContextOrDiscard(ctx).Info(cmd)
+	err := netlink.FilterReplace(filter)
+	if err != nil {
+		return fmt.Errorf("error %s, %w", cmd, err)
+	}
+	return nil
+}
+
+func ClassReplace(ctx context.Context, class netlink.Class) error {
+	cmd := fmt.Sprintf("tc class replace %s", class.Attrs().String())
+	logr.FromContextOrDiscard(ctx).Info(cmd)
+	err := netlink.ClassReplace(class)
+	if err != nil {
+		return fmt.Errorf("error %s, %w", cmd, err)
+	}
+	return nil
+}
+
 func QdiscDel(ctx context.Context, qdisc netlink.Qdisc) error {
 	cmd := fmt.Sprintf("tc qdisc del %s", qdisc.Attrs().String())
 	logr.FromContextOrDiscard(ctx).Info(cmd)
@@ -14,6 +14,7 @@ import (
 	terwayIP "github.com/AliyunContainerService/terway/pkg/ip"
 	terwaySysctl "github.com/AliyunContainerService/terway/pkg/sysctl"
 	"github.com/AliyunContainerService/terway/pkg/tc"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
 
 	"github.com/containernetworking/plugins/pkg/ip"
@@ -31,11 +32,14 @@ func GetRouteTableID(linkIndex int) int {
 
 // EnsureHostNsConfig setup host namespace configs
 func EnsureHostNsConfig(ipv4, ipv6 bool) error {
-	for _, key := range []string{"default", "all"} {
-		for _, cfg := range ipv4NetConfig {
-			err := terwaySysctl.EnsureConf(fmt.Sprintf(cfg[0], key), cfg[1])
-			if err != nil {
-				return err
+	// ipv4 sysctl is not required by ipv6 only stack
+	if ipv4 {
+		for _, key := range []string{"default", "all"} {
+			for _, cfg := range ipv4NetConfig {
+				err := terwaySysctl.EnsureConf(fmt.Sprintf(cfg[0], key), cfg[1])
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -200,9 +204,12 @@ func FindIPRule(rule *netlink.Rule) ([]netlink.Rule, error) {
 	var filterMask uint64
 	family := netlink.FAMILY_V4
 
-	if rule.Src == nil && rule.Dst == nil && rule.OifName == "" {
+	if rule.Src == nil && rule.Dst == nil && rule.OifName == "" && rule.Mark <= 0 {
 		return nil, errors.New("both src and dst is nil")
 	}
+	if rule.Family != 0 {
+		family = rule.Family
+	}
 
 	if rule.Src != nil {
 		filterMask = filterMask | netlink.RT_FILTER_SRC
@@ -216,6 +223,9 @@ func FindIPRule(rule *netlink.Rule) ([]netlink.Rule, error) {
 		filterMask = filterMask | netlink.RT_FILTER_OIF
 		family = netlink.FAMILY_V4
 	}
+	if rule.Mark > 0 {
+		filterMask = filterMask | netlink.RT_FILTER_MARK
+	}
 
 	if rule.Priority >= 0 {
 		filterMask = filterMask | netlink.RT_FILTER_PRIORITY
@@ -613,8 +623,15 @@ func SetFilter(ctx context.Context, link netlink.Link, parentID, classID uint32,
 		if err != nil {
 			return err
 		}
-		if found != nil && found.ClassId == classID {
-			return nil
+		if found != nil {
+			if found.ClassId == classID {
+				return nil
+			}
+			// class changed, the filter is replaced
+			err = FilterDel(ctx, found)
+			if err != nil {
+				return err
+			}
 		}
 
 		u32 := &netlink.U32{
@@ -725,6 +742,251 @@ func DelEgressPriority(ctx context.Context, link netlink.Link, ipNetSet *terwayT
 	return nil
 }
 
+const (
+	// ingressPriorityFilterPrio the priority of the filters classifying the pod ingress traffic on the eni
+	ingressPriorityFilterPrio = 40001
+	// ingressPriorityParentMinor the minor of the htb class shared by the priority classes
+	ingressPriorityParentMinor = 0x100
+)
+
+// IngressPriorityIFBName the ifb device shaping the ingress traffic of the eni
+func IngressPriorityIFBName(eni netlink.Link) string {
+	return fmt.Sprintf("ifb%d", eni.Attrs().Index)
+}
+
+// SetIngressPriority schedule the traffic to the pod by the priority class at the eni ingress.
+// The eni ingress is the point the pods on the eni contend for the bandwidth.
+func SetIngressPriority(ctx context.Context, eni netlink.Link, classes []*cnitypes.IngressPriority, classID uint32, ipNetSet *terwayTypes.IPNetSet) error {
+	ifb, err := ensureIngressPriority(ctx, eni, classes)
+	if err != nil {
+		return err
+	}
+	return setIngressPriorityFilter(ctx, eni, ifb, classID, ipNetSet)
+}
+
+// ensureIngressPriority ensure the ifb device of the eni and the htb classes of the priority classes on it.
+// The traffic classified by setIngressPriorityFilter is redirected to the ifb, and scheduled to the class by the skb priority.
+// All the classes borrow from the parent class limited to the ceil, flows in a class are fair-queued.
+func ensureIngressPriority(ctx context.Context, eni netlink.Link, classes []*cnitypes.IngressPriority) (netlink.Link, error) {
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("no ingress priority class")
+	}
+	name := IngressPriorityIFBName(eni)
+	ifb, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return nil, err
+		}
+		err = LinkAdd(ctx, &netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: name, TxQLen: 1000}})
+		if err != nil {
+			return nil, err
+		}
+		ifb, err = netlink.LinkByName(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = EnsureLinkUp(ctx, ifb)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := netlink.MakeHandle(1, ingressPriorityParentMinor)
+	// the class is picked by the skb priority, the traffic not classified is not limited
+	htb := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: ifb.Attrs().Index,
+		Parent:    netlink.HANDLE_ROOT,
+		Handle:    netlink.MakeHandle(1, 0),
+	})
+	err = QdiscReplace(ctx, htb)
+	if err != nil {
+		return nil, err
+	}
+	err = ClassReplace(ctx, netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: ifb.Attrs().Index,
+		Parent:    netlink.MakeHandle(1, 0),
+		Handle:    parent,
+	}, netlink.HtbClassAttrs{
+		Rate: classes[0].Ceil,
+		Ceil: classes[0].Ceil,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, class := range classes {
+		_, minor := netlink.MajorMinor(class.ClassID)
+		err = ClassReplace(ctx, netlink.NewHtbClass(netlink.ClassAttrs{
+			LinkIndex: ifb.Attrs().Index,
+			Parent:    parent,
+			Handle:    class.ClassID,
+		}, netlink.HtbClassAttrs{
+			Rate:    class.Rate,
+			Ceil:    class.Ceil,
+			Prio:    class.Prio,
+			Quantum: class.Quantum,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		err = QdiscReplace(ctx, netlink.NewFqCodel(netlink.QdiscAttrs{
+			LinkIndex: ifb.Attrs().Index,
+			Parent:    class.ClassID,
+			Handle:    netlink.MakeHandle(10+minor, 0),
+		}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ifb, nil
+}
+
+// delRootQdisc del the root qdisc of the kinds, a qdisc can not be replaced by another kind with the same handle
+func delRootQdisc(ctx context.Context, link netlink.Link, kinds ...string) error {
+	qds, err := netlink.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+	}
+	for _, q := range qds {
+		if q.Attrs().Parent != netlink.HANDLE_ROOT {
+			continue
+		}
+		for _, kind := range kinds {
+			if q.Type() == kind {
+				return QdiscDel(ctx, q)
+			}
+		}
+	}
+	return nil
+}
+
+// setIngressPriorityFilter classify the traffic to the pod at the eni ingress, the skb priority is set to the class
+// and the traffic is redirected to the ifb set by ensureIngressPriority.
+func setIngressPriorityFilter(ctx context.Context, link, ifb netlink.Link, classID uint32, ipNetSet *terwayTypes.IPNetSet) error {
+	err := EnsureClsActQdsic(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	exec := func(ipNet *net.IPNet) error {
+		found, err := tc.FilterByDstIP(link, netlink.HANDLE_MIN_INGRESS, ipNet, ingressPriorityFilterPrio)
+		if err != nil {
+			return err
+		}
+		if found != nil {
+			if len(found.Actions) == 2 {
+				act, ok := found.Actions[0].(*netlink.SkbEditAction)
+				mirred, mirredOK := found.Actions[1].(*netlink.MirredAction)
+				if ok && act.Priority != nil && *act.Priority == classID &&
+					mirredOK && mirred.Ifindex == ifb.Attrs().Index {
+					return nil
+				}
+			}
+			err = FilterDel(ctx, found)
+			if err != nil {
+				return err
+			}
+		}
+
+		act := netlink.NewSkbEditAction()
+		act.Priority = &classID
+		mirred := netlink.NewMirredAction(ifb.Attrs().Index)
+		mirred.MirredAction = netlink.TCA_EGRESS_REDIR
+		u32 := &netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    netlink.HANDLE_MIN_INGRESS,
+				Priority:  ingressPriorityFilterPrio,
+				Protocol:  tc.Protocol(ipNet),
+			},
+			Actions: []netlink.Action{act, mirred},
+		}
+		tc.MatchDst(u32, ipNet)
+
+		return FilterAdd(ctx, u32)
+	}
+
+	if ipNetSet.IPv4 != nil {
+		err = exec(NewIPNetWithMaxMask(ipNetSet.IPv4))
+		if err != nil {
+			return err
+		}
+	}
+	if ipNetSet.IPv6 != nil {
+		err = exec(NewIPNetWithMaxMask(ipNetSet.IPv6))
+	}
+	return err
+}
+
+// DelIngressPriority del the classification of the pod set by SetIngressPriority, the classes are kept for other pods.
+// The ifb of the eni is deleted with the last classification on the eni.
+func DelIngressPriority(ctx context.Context, link netlink.Link, ipNetSet *terwayTypes.IPNetSet) error {
+	exec := func(ipNet *net.IPNet) error {
+		found, err := tc.FilterByDstIP(link, netlink.HANDLE_MIN_INGRESS, ipNet, ingressPriorityFilterPrio)
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return nil
+		}
+		return FilterDel(ctx, found)
+	}
+
+	if ipNetSet.IPv4 != nil {
+		err := exec(NewIPNetWithMaxMask(ipNetSet.IPv4))
+		if err != nil {
+			return err
+		}
+	}
+	if ipNetSet.IPv6 != nil {
+		err := exec(NewIPNetWithMaxMask(ipNetSet.IPv6))
+		if err != nil {
+			return err
+		}
+	}
+
+	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_INGRESS)
+	if err != nil {
+		return err
+	}
+	for _, f := range filters {
+		if f.Attrs().Priority == ingressPriorityFilterPrio {
+			return nil
+		}
+	}
+	return DelLinkByName(ctx, IngressPriorityIFBName(link))
+}
+
+// GCIngressPriorityIFB del the ifb set by SetIngressPriority whose eni is detached
+func GCIngressPriorityIFB(ctx context.Context) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		if _, ok := l.(*netlink.Ifb); !ok {
+			continue
+		}
+		var index int
+		_, err = fmt.Sscanf(l.Attrs().Name, "ifb%d", &index)
+		if err != nil || l.Attrs().Name != fmt.Sprintf("ifb%d", index) {
+			continue
+		}
+		_, err = netlink.LinkByIndex(index)
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return err
+		}
+		err = LinkDel(ctx, l)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func SetupTC(link netlink.Link, bandwidthInBytes uint64) error {
 	rule := &tc.TrafficShapingRule{
 		Rate: bandwidthInBytes,
@@ -732,6 +994,11 @@ func SetupTC(link netlink.Link, bandwidthInBytes uint64) error {
 	return tc.SetRule(link, rule)
 }
 
+// DelTC remove the tbf qdisc set by SetupTC
+func DelTC(ctx context.Context, link netlink.Link) error {
+	return delRootQdisc(ctx, link, "tbf")
+}
+
 // GenericTearDown target to clean all related resource as much as possible
 func GenericTearDown(ctx context.Context, netNS ns.NetNS) error {
 	var errList []error
@@ -16,6 +16,7 @@ import (
 	"github.com/vishvananda/netlink"
 
 	"github.com/AliyunContainerService/terway/pkg/tc"
+	cnitypes "github.com/AliyunContainerService/terway/plugin/driver/types"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
 )
 
@@ -300,3 +301,51 @@ func TestParseERdmaLinkHwAddress(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "02:16:3e:04:d3:0d", hwaddr.String())
 }
+
+func TestDelIngressPriority(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, hostNS.Close())
+		assert.NoError(t, testutils.UnmountNS(hostNS))
+	}()
+	err = hostNS.Set()
+	assert.NoError(t, err)
+
+	err = netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	assert.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	assert.NoError(t, err)
+
+	classID := netlink.MakeHandle(1, 1)
+	classes := []*cnitypes.IngressPriority{{ClassID: classID, Quantum: 1500, Rate: 1000, Ceil: 10000}}
+	pod1 := &terwayTypes.IPNetSet{}
+	pod1.SetIPNet("192.168.0.1/32")
+	pod2 := &terwayTypes.IPNetSet{}
+	pod2.SetIPNet("192.168.0.2/32")
+
+	assert.NoError(t, SetIngressPriority(context.Background(), eni, classes, classID, pod1))
+	assert.NoError(t, SetIngressPriority(context.Background(), eni, classes, classID, pod2))
+
+	// the ifb is kept for the other pod on the eni
+	assert.NoError(t, DelIngressPriority(context.Background(), eni, pod1))
+	_, err = netlink.LinkByName(IngressPriorityIFBName(eni))
+	assert.NoError(t, err)
+
+	assert.NoError(t, DelIngressPriority(context.Background(), eni, pod2))
+	_, err = netlink.LinkByName(IngressPriorityIFBName(eni))
+	assert.IsType(t, netlink.LinkNotFoundError{}, err)
+
+	// the eni is detached
+	assert.NoError(t, SetIngressPriority(context.Background(), eni, classes, classID, pod1))
+	ifbName := IngressPriorityIFBName(eni)
+	assert.NoError(t, netlink.LinkDel(eni))
+	assert.NoError(t, GCIngressPriorityIFB(context.Background()))
+	_, err = netlink.LinkByName(ifbName)
+	assert.IsType(t, netlink.LinkNotFoundError{}, err)
+}
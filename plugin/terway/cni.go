@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net"
 	"runtime"
@@ -11,13 +10,8 @@ import (
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
-	"github.com/AliyunContainerService/terway/pkg/link"
-	"github.com/AliyunContainerService/terway/plugin/datapath"
-	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/rpc"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
@@ -232,273 +226,3 @@ func getNetworkClient(ctx context.Context) (rpc.TerwayBackendClient, *grpc.Clien
 	client := rpc.NewTerwayBackendClient(conn)
 	return client, conn, nil
 }
-
-func parseSetupConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.SetupConfig, error) {
-	var (
-		err            error
-		containerIPNet *terwayTypes.IPNetSet
-		gatewayIP      *terwayTypes.IPSet
-		serviceCIDR    *terwayTypes.IPNetSet
-		eniGatewayIP   *terwayTypes.IPSet
-		deviceID       int32
-		trunkENI       bool
-		vid            uint32
-		erdma          bool
-
-		ingress         uint64
-		egress          uint64
-		networkPriority uint32
-
-		routes []cniTypes.Route
-
-		disableCreatePeer bool
-	)
-
-	serviceCIDR, err = terwayTypes.ToIPNetSet(alloc.GetBasicInfo().GetServiceCIDR())
-	if err != nil {
-		return nil, err
-	}
-
-	if ipType == rpc.IPType_TypeVPCIP {
-		subnetStr := alloc.GetBasicInfo().GetPodCIDR().GetIPv4()
-		_, subnet, err := net.ParseCIDR(subnetStr)
-		if err != nil {
-			return nil, fmt.Errorf("parse cidr %s, %w", subnetStr, err)
-		}
-		containerIPNet = &terwayTypes.IPNetSet{
-			IPv4: subnet,
-			IPv6: nil,
-		}
-	} else if alloc.GetBasicInfo() != nil {
-		podIP := alloc.GetBasicInfo().GetPodIP()
-		subNet := alloc.GetBasicInfo().GetPodCIDR()
-		gw := alloc.GetBasicInfo().GetGatewayIP()
-
-		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
-		if err != nil {
-			return nil, err
-		}
-		gatewayIP, err = terwayTypes.ToIPSet(gw)
-		if err != nil {
-			return nil, err
-		}
-		disableCreatePeer = conf.DisableHostPeer
-	}
-
-	if alloc.GetENIInfo() != nil {
-		mac := alloc.GetENIInfo().GetMAC()
-		if mac != "" {
-			err = retry.OnError(wait.Backoff{
-				Steps:    10,
-				Duration: 1 * time.Second,
-				Factor:   1.0,
-				Jitter:   0,
-			}, func(err error) bool {
-				return errors.Is(err, link.ErrNotFound)
-			}, func() error {
-				deviceID, err = link.GetDeviceNumber(mac)
-				return err
-			})
-			if err != nil {
-				return nil, err
-			}
-		}
-		trunkENI = alloc.GetENIInfo().GetTrunk()
-		vid = alloc.GetENIInfo().GetVid()
-		erdma = alloc.GetENIInfo().GetERDMA()
-		if alloc.GetENIInfo().GetGatewayIP() != nil {
-			eniGatewayIP, err = terwayTypes.ToIPSet(alloc.GetENIInfo().GetGatewayIP())
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-	if alloc.GetPod() != nil {
-		ingress = alloc.GetPod().GetIngress()
-		egress = alloc.GetPod().GetEgress()
-		networkPriority = datapath.PrioMap[alloc.GetPod().GetNetworkPriority()]
-	}
-	if conf.RuntimeConfig.Bandwidth.EgressRate > 0 {
-		egress = uint64(conf.RuntimeConfig.Bandwidth.EgressRate / 8)
-	}
-	if conf.RuntimeConfig.Bandwidth.IngressRate > 0 {
-		ingress = uint64(conf.RuntimeConfig.Bandwidth.IngressRate / 8)
-	}
-
-	hostStackCIDRs := make([]*net.IPNet, 0)
-	for _, v := range conf.HostStackCIDRs {
-		_, cidr, err := net.ParseCIDR(v)
-		if err != nil {
-			return nil, fmt.Errorf("host_stack_cidrs(%s) is invaild: %v", v, err)
-
-		}
-		hostStackCIDRs = append(hostStackCIDRs, cidr)
-	}
-
-	name := alloc.IfName
-	if name == "" {
-		name = args.IfName
-	}
-	for _, r := range alloc.GetExtraRoutes() {
-		ip, n, err := net.ParseCIDR(r.Dst)
-		if err != nil {
-			return nil, fmt.Errorf("error parse extra routes, %w", err)
-		}
-		route := cniTypes.Route{Dst: *n}
-		if ip.To4() != nil {
-			route.GW = gatewayIP.IPv4
-		} else {
-			route.GW = gatewayIP.IPv6
-		}
-		routes = append(routes, route)
-	}
-
-	dp := getDatePath(ipType, conf.VlanStripType, trunkENI)
-	return &types.SetupConfig{
-		DP:                    dp,
-		ContainerIfName:       name,
-		ContainerIPNet:        containerIPNet,
-		GatewayIP:             gatewayIP,
-		MTU:                   conf.MTU,
-		ENIIndex:              int(deviceID),
-		ERDMA:                 erdma,
-		ENIGatewayIP:          eniGatewayIP,
-		ServiceCIDR:           serviceCIDR,
-		HostStackCIDRs:        hostStackCIDRs,
-		BandwidthMode:         conf.BandwidthMode,
-		EnableNetworkPriority: conf.EnableNetworkPriority,
-		Ingress:               ingress,
-		Egress:                egress,
-		StripVlan:             trunkENI,
-		Vid:                   int(vid),
-		DefaultRoute:          alloc.GetDefaultRoute(),
-		ExtraRoutes:           routes,
-		DisableCreatePeer:     disableCreatePeer,
-		RuntimeConfig:         conf.RuntimeConfig,
-		NetworkPriority:       networkPriority,
-	}, nil
-}
-
-func parseTearDownConf(alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.TeardownCfg, error) {
-	if alloc.GetBasicInfo() == nil {
-		return nil, fmt.Errorf("return empty pod alloc info: %v", alloc)
-	}
-
-	var (
-		err            error
-		containerIPNet *terwayTypes.IPNetSet
-		serviceCIDR    *terwayTypes.IPNetSet
-		eniIndex       int32
-	)
-
-	serviceCIDR, err = terwayTypes.ToIPNetSet(alloc.GetBasicInfo().GetServiceCIDR())
-	if err != nil {
-		return nil, err
-	}
-
-	if ipType == rpc.IPType_TypeVPCIP {
-		subnetStr := alloc.GetBasicInfo().GetPodCIDR().GetIPv4()
-		_, subnet, err := net.ParseCIDR(subnetStr)
-		if err != nil {
-			return nil, fmt.Errorf("parse cidr %s, %w", subnetStr, err)
-		}
-		containerIPNet = &terwayTypes.IPNetSet{
-			IPv4: subnet,
-			IPv6: nil,
-		}
-	} else if alloc.GetBasicInfo() != nil {
-		podIP := alloc.GetBasicInfo().GetPodIP()
-		subNet := alloc.GetBasicInfo().GetPodCIDR()
-
-		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
-		if err != nil {
-			return nil, err
-		}
-	}
-	if alloc.GetENIInfo() != nil {
-		mac := alloc.GetENIInfo().GetMAC()
-		if mac != "" {
-			eniIndex, _ = link.GetDeviceNumber(mac)
-		}
-	}
-
-	dp := getDatePath(ipType, conf.VlanStripType, false)
-	return &types.TeardownCfg{
-		DP:                    dp,
-		ContainerIPNet:        containerIPNet,
-		ServiceCIDR:           serviceCIDR,
-		ENIIndex:              int(eniIndex),
-		EnableNetworkPriority: conf.EnableNetworkPriority,
-	}, nil
-}
-
-func parseCheckConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.CheckConfig, error) {
-	var (
-		err            error
-		containerIPNet *terwayTypes.IPNetSet
-		gatewayIP      *terwayTypes.IPSet
-		deviceID       int32
-		trunkENI       bool
-	)
-
-	if alloc.GetBasicInfo() != nil {
-		podIP := alloc.GetBasicInfo().GetPodIP()
-		subNet := alloc.GetBasicInfo().GetPodCIDR()
-		gw := alloc.GetBasicInfo().GetGatewayIP()
-
-		containerIPNet, err = terwayTypes.BuildIPNet(podIP, subNet)
-		if err != nil {
-			return nil, err
-		}
-		gatewayIP, err = terwayTypes.ToIPSet(gw)
-		if err != nil {
-			return nil, err
-		}
-	}
-	if alloc.GetENIInfo() != nil {
-		mac := alloc.GetENIInfo().GetMAC()
-		if mac != "" {
-			deviceID, err = link.GetDeviceNumber(mac)
-			if err != nil {
-				return nil, err
-			}
-		}
-		trunkENI = alloc.GetENIInfo().GetTrunk()
-	}
-
-	name := alloc.IfName
-	if name == "" {
-		name = args.IfName
-	}
-
-	dp := getDatePath(ipType, conf.VlanStripType, trunkENI)
-	return &types.CheckConfig{
-		DP:              dp,
-		ContainerIfName: name,
-		ContainerIPNet:  containerIPNet,
-		GatewayIP:       gatewayIP,
-		MTU:             conf.MTU,
-		ENIIndex:        deviceID,
-		TrunkENI:        trunkENI,
-		DefaultRoute:    alloc.GetDefaultRoute(),
-	}, nil
-}
-
-func getDatePath(ipType rpc.IPType, vlanStripType types.VlanStripType, trunk bool) types.DataPath {
-	switch ipType {
-	case rpc.IPType_TypeVPCIP:
-		return types.VPCRoute
-	case rpc.IPType_TypeVPCENI:
-		if trunk {
-			return types.Vlan
-		}
-		return types.ExclusiveENI
-	case rpc.IPType_TypeENIMultiIP:
-		if trunk && vlanStripType == types.VlanStripTypeVlan {
-			return types.Vlan
-		}
-		return types.IPVlan
-	default:
-		panic(fmt.Sprintf("unsupported ipType %s", ipType))
-	}
-}
@@ -184,7 +184,7 @@ func doCmdAdd(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 
 	for _, netConf := range allocResult.NetConfs {
 		var setupCfg *types.SetupConfig
-		setupCfg, err = parseSetupConf(args, netConf, conf, allocResult.IPType)
+		setupCfg, err = datapath.ParseSetupConf(args.IfName, netConf, conf, allocResult.IPType)
 		if err != nil {
 			err = fmt.Errorf("error parse config, %w", err)
 			return
@@ -196,24 +196,24 @@ func doCmdAdd(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 
 		switch setupCfg.DP {
 		case types.IPVlan:
-			if conf.IPVlan() {
-				available := false
-				available, err = datapath.CheckIPVLanAvailable()
+			// nat64 is done on the host veth
+			if setupCfg.NAT64 == nil {
+				if setupCfg.ContainerIfName == args.IfName {
+					containerIPNet = setupCfg.ContainerIPNet
+					gatewayIPSet = setupCfg.GatewayIP
+				}
+				ctx = logr.NewContext(ctx, log.WithValues("dp", "ipvlan"))
+				err = datapath.NewIPVlanDriver().Setup(ctx, setupCfg, cniNetns)
 				if err != nil {
 					return
 				}
-				if available {
-					if setupCfg.ContainerIfName == args.IfName {
-						containerIPNet = setupCfg.ContainerIPNet
-						gatewayIPSet = setupCfg.GatewayIP
-					}
-					ctx = logr.NewContext(ctx, log.WithValues("dp", "ipvlan"))
-					err = datapath.NewIPVlanDriver().Setup(ctx, setupCfg, cniNetns)
-					if err != nil {
-						return
-					}
-					continue
-				}
+				continue
+			}
+			fallthrough
+		case types.PolicyRoute:
+			ctx = logr.NewContext(ctx, log.WithValues("dp", "policyRoute"))
+
+			if setupCfg.DP == types.PolicyRoute && conf.IPVlan() {
 				_, _ = client.RecordEvent(ctx, &rpc.EventRequest{
 					EventTarget:     rpc.EventTarget_EventTargetPod,
 					K8SPodName:      string(k8sConfig.K8S_POD_NAME),
@@ -223,10 +223,6 @@ func doCmdAdd(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 					Message:         "IPVLan seems unavailable, use Veth instead",
 				})
 			}
-			fallthrough
-		case types.PolicyRoute:
-			ctx = logr.NewContext(ctx, log.WithValues("dp", "policyRoute"))
-
 			if setupCfg.ContainerIfName == args.IfName {
 				containerIPNet = setupCfg.ContainerIPNet
 				gatewayIPSet = setupCfg.GatewayIP
@@ -272,7 +268,7 @@ func doCmdAdd(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 }
 
 func doCmdDel(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniCmdArgs) error {
-	var conf, cniNetns, k8sConfig = cmdArgs.conf, cmdArgs.netNS, cmdArgs.k8sArgs
+	var conf, cniNetns, k8sConfig, args = cmdArgs.conf, cmdArgs.netNS, cmdArgs.k8sArgs, cmdArgs.inputArgs
 
 	log := logr.FromContextOrDiscard(ctx)
 
@@ -281,6 +277,13 @@ func doCmdDel(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 		return err
 	}
 
+	// the pod may be set up before the ipvlan capability is recorded or changed,
+	// so the datapath is detected before the links are removed
+	podDP, detectErr := datapath.PodDataPath(cniNetns, args.IfName)
+	if detectErr != nil {
+		log.Info("datapath of the pod is not detected, use the config", "err", detectErr.Error())
+	}
+
 	// try cleanup all resource
 	err = utils.GenericTearDown(ctx, cniNetns)
 	if err != nil {
@@ -310,30 +313,31 @@ func doCmdDel(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 		defer l.Close()
 		for _, netConf := range getResult.NetConfs {
 			var teardownCfg *types.TeardownCfg
-			teardownCfg, err = parseTearDownConf(netConf, conf, getResult.IPType)
+			teardownCfg, err = datapath.ParseTearDownConf(netConf, conf, getResult.IPType)
 			if err != nil {
 				log.Error(err, "error parse config")
 				return nil
 			}
 
+			// the runtime passes the same port mappings as the add
+			if len(conf.RuntimeConfig.PortMaps) > 0 {
+				err = datapath.TeardownHostPorts(ctx, teardownCfg.ContainerIPNet)
+				if err != nil {
+					return err
+				}
+			}
+
+			if detectErr == nil && (teardownCfg.DP == types.IPVlan || teardownCfg.DP == types.PolicyRoute) {
+				teardownCfg.DP = podDP
+			}
+
 			switch teardownCfg.DP {
 			case types.IPVlan:
-				if conf.IPVlan() {
-					available := false
-					available, err = datapath.CheckIPVLanAvailable()
-					if err != nil {
-						return err
-					}
-					if available {
-						ctx = logr.NewContext(ctx, log.WithValues("dp", "ipvlan"))
-						err = datapath.NewIPVlanDriver().Teardown(ctx, teardownCfg, cniNetns)
-						if err != nil {
-							return err
-						}
-						continue
-					}
+				ctx = logr.NewContext(ctx, log.WithValues("dp", "ipvlan"))
+				err = datapath.NewIPVlanDriver().Teardown(ctx, teardownCfg, cniNetns)
+				if err != nil {
+					return err
 				}
-				fallthrough
 			case types.PolicyRoute:
 				ctx = logr.NewContext(ctx, log.WithValues("dp", "policyRoute"))
 
@@ -398,7 +402,7 @@ func doCmdCheck(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cn
 
 	for _, netConf := range getResult.NetConfs {
 		var checkCfg *types.CheckConfig
-		checkCfg, err = parseCheckConf(args, netConf, conf, getResult.IPType)
+		checkCfg, err = datapath.ParseCheckConf(args.IfName, netConf, conf, getResult.IPType)
 		if err != nil {
 			return fmt.Errorf("error parse config, %w", err)
 		}
@@ -423,21 +427,10 @@ func doCmdCheck(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cn
 		case types.IPVlan:
 			log = log.WithValues("dp", "ipvlan")
 
-			if conf.IPVlan() {
-				available := false
-				available, err = datapath.CheckIPVLanAvailable()
-				if err != nil {
-					return err
-				}
-				if available {
-					err = datapath.NewIPVlanDriver().Check(ctx, checkCfg)
-					if err != nil {
-						return err
-					}
-					continue
-				}
+			err = datapath.NewIPVlanDriver().Check(ctx, checkCfg)
+			if err != nil {
+				return err
 			}
-			fallthrough
 		case types.PolicyRoute:
 			ctx = logr.NewContext(ctx, log.WithValues("dp", "policyRoute"))
 			err = datapath.NewPolicyRoute().Check(ctx, checkCfg)
@@ -158,7 +158,7 @@ func doCmdAdd(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 
 	for _, netConf := range allocResult.NetConfs {
 		var setupCfg *types.SetupConfig
-		setupCfg, err = parseSetupConf(args, netConf, conf, allocResult.IPType)
+		setupCfg, err = datapath.ParseSetupConf(args.IfName, netConf, conf, allocResult.IPType)
 		if err != nil {
 			err = fmt.Errorf("error parse config, %w", err)
 			return
@@ -347,7 +347,7 @@ func doCmdDel(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cniC
 
 	for _, netConf := range infoResult.NetConfs {
 		var teardownCfg *types.TeardownCfg
-		teardownCfg, err = parseTearDownConf(netConf, conf, infoResult.IPType)
+		teardownCfg, err = datapath.ParseTearDownConf(netConf, conf, infoResult.IPType)
 		if err != nil {
 			logger.Errorf("error parse config: %v", err)
 			return nil
@@ -437,7 +437,7 @@ func doCmdCheck(ctx context.Context, client rpc.TerwayBackendClient, cmdArgs *cn
 
 	for _, netConf := range getResult.NetConfs {
 		var checkCfg *types.CheckConfig
-		checkCfg, err = parseCheckConf(args, netConf, conf, getResult.IPType)
+		checkCfg, err = datapath.ParseCheckConf(args.IfName, netConf, conf, getResult.IPType)
 		if err != nil {
 			return fmt.Errorf("error parse config, %w", err)
 		}
@@ -18,4 +18,13 @@ const (
 
 	TagKubernetesPodName      = "k8s_pod_name"
 	TagKubernetesPodNamespace = "k8s_pod_namespace"
+
+	// TagTerwayComponent is the terway component creates the eni, e.g. terway-daemon, pod, multi-ip-node
+	TagTerwayComponent = "terway-component"
+
+	// TagENISecurityGroups is the security groups the eni is dedicated to, sorted and joined by comma
+	TagENISecurityGroups = "terway-security-groups"
+
+	// ComponentTerwayDaemon is the TagTerwayComponent of enis created by the daemon
+	ComponentTerwayDaemon = "terway-daemon"
 )
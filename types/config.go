@@ -18,6 +18,7 @@ type ENIConfig struct {
 	ENITags          map[string]string
 	SecurityGroupIDs []string
 	InstanceID       string
+	NodeName         string
 
 	VSwitchSelectionPolicy vswitch.SelectionPolicy
 	EniSelectionPolicy     EniSelectionPolicy
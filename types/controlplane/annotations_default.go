@@ -28,6 +28,7 @@ type PodNetworks struct {
 	SecurityGroupIDs     []string                     `json:"securityGroupIDs"`
 	Interface            string                       `json:"interface"`
 	ExtraRoutes          []route.Route                `json:"extraRoutes,omitempty"`
+	ExtraRules           []route.Rule                 `json:"extraRules,omitempty"`
 	ENIOptions           v1beta1.ENIOptions           `json:"eniOptions,omitempty"`
 	VSwitchSelectOptions v1beta1.VSwitchSelectOptions `json:"vSwitchSelectOptions,omitempty"`
 }
@@ -49,6 +49,12 @@ type Config struct {
 	PodENIMaxConcurrent int `json:"podENIMaxConcurrent" validate:"gt=0,lte=10000" mod:"default=10"`
 	NodeController
 	MultiIPController
+	MultiIPShard
+	SecurityGroupSync
+	MemberENIPool
+	OpenAPIQuotaSync
+	OpenAPIAudit
+	OrphanENI
 
 	Controllers []string `json:"controllers"`
 
@@ -88,12 +94,55 @@ type Credential struct {
 type MultiIPController struct {
 	MultiIPPodMaxConcurrent       int    `json:"multiIPPodMaxConcurrent" validate:"gt=0,lte=20000" mod:"default=500"`
 	MultiIPNodeMaxConcurrent      int    `json:"multiIPNodeMaxConcurrent" validate:"gt=0,lte=20000" mod:"default=500"`
-	MultiIPNodeSyncPeriod         string `json:"multiIPNodeSyncPeriod" mod:"default=12h"`
+	MultiIPNodeSyncPeriod         string `json:"multiIPNodeSyncPeriod" mod:"default=24h"`
 	MultiIPGCPeriod               string `json:"multiIPGCPeriod" mod:"default=2m"`
 	MultiIPMinSyncPeriodOnFailure string `json:"multiIPMinSyncPeriodOnFailure" mod:"default=1s"`
 	MultiIPMaxSyncPeriodOnFailure string `json:"multiIPMaxSyncPeriodOnFailure" mod:"default=300s"`
 }
 
+// MultiIPShard split the nodes between controlplane replicas by Leases, the node and multi-ip controllers run on
+// every replica without leader election and only handle the nodes in the replica's hash range.
+type MultiIPShard struct {
+	EnableMultiIPShard        bool   `json:"enableMultiIPShard"`
+	MultiIPShardLeaseDuration string `json:"multiIPShardLeaseDuration" mod:"default=30s"`
+	MultiIPShardRenewPeriod   string `json:"multiIPShardRenewPeriod" mod:"default=10s"`
+}
+
+// SecurityGroupSync control how security groups changes are applied to exist enis
+type SecurityGroupSync struct {
+	DisableSecurityGroupSync bool    `json:"disableSecurityGroupSync"`
+	SecurityGroupSyncQPS     float32 `json:"securityGroupSyncQPS" validate:"gt=0,lte=100" mod:"default=1"`
+	SecurityGroupSyncPeriod  string  `json:"securityGroupSyncPeriod" mod:"default=5m"`
+}
+
+// MemberENIPool pre-create member enis for trunk pods, the pool is disabled if the max size is 0
+type MemberENIPool struct {
+	MemberENIPoolMinSize    int    `json:"memberENIPoolMinSize" validate:"gte=0,ltefield=MemberENIPoolMaxSize"`
+	MemberENIPoolMaxSize    int    `json:"memberENIPoolMaxSize" validate:"gte=0,lte=100"`
+	MemberENIPoolSyncPeriod string `json:"memberENIPoolSyncPeriod" mod:"default=1m"`
+}
+
+// OpenAPIQuotaSync share the account openAPI quota with node daemons, disabled if the quota is empty
+type OpenAPIQuotaSync struct {
+	// OpenAPIQuota is the account quota, api name to requests per minute
+	OpenAPIQuota           map[string]int `json:"openAPIQuota"`
+	OpenAPIQuotaSyncPeriod string         `json:"openAPIQuotaSyncPeriod" mod:"default=1m"`
+}
+
+// OpenAPIAudit record the mutating openAPI calls, disabled if both are empty
+type OpenAPIAudit struct {
+	OpenAPIAuditLog   string `json:"openAPIAuditLog"`
+	OpenAPIAuditEvent bool   `json:"openAPIAuditEvent"`
+}
+
+// OrphanENI find the enis tagged by this cluster, whose Node or PodENI is gone. Enable the orphan-eni controller to use it.
+type OrphanENI struct {
+	// OrphanENIAction is report or delete, only available enis are deleted
+	OrphanENIAction      string `json:"orphanENIAction" validate:"oneof=report delete" mod:"default=report"`
+	OrphanENIGracePeriod string `json:"orphanENIGracePeriod" mod:"default=1h"`
+	OrphanENISyncPeriod  string `json:"orphanENISyncPeriod" mod:"default=10m"`
+}
+
 type NodeController struct {
 	NodeMaxConcurrent int `json:"nodeMaxConcurrent" validate:"gt=0,lte=10000" mod:"default=10"`
 }
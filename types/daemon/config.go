@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 
@@ -45,7 +46,7 @@ type Config struct {
 	EniCapShift                 int                     `yaml:"eni_cap_shift" json:"eni_cap_shift"`
 	VSwitchSelectionPolicy      string                  `yaml:"vswitch_selection_policy" json:"vswitch_selection_policy" mod:"default=random"`
 	EniSelectionPolicy          string                  `yaml:"eni_selection_policy" json:"eni_selection_policy" mod:"default=most_ips"`
-	IPStack                     string                  `yaml:"ip_stack" json:"ip_stack" validate:"oneof=ipv4 ipv6 dual" mod:"default=ipv4"` // default ipv4 , support ipv4 dual
+	IPStack                     string                  `yaml:"ip_stack" json:"ip_stack" validate:"oneof=ipv4 ipv6 dual" mod:"default=ipv4"` // default ipv4 , support ipv4 ipv6 dual
 	EnableENITrunking           bool                    `yaml:"enable_eni_trunking" json:"enable_eni_trunking"`
 	EnableERDMA                 bool                    `yaml:"enable_erdma" json:"enable_erdma"`
 	CustomStatefulWorkloadKinds []string                `yaml:"custom_stateful_workload_kinds" json:"custom_stateful_workload_kinds"`
@@ -58,6 +59,14 @@ type Config struct {
 	KubeClientBurst             int                     `json:"kube_client_burst"`
 	ResourceGroupID             string                  `json:"resource_group_id"`
 	RateLimit                   map[string]int          `json:"rate_limit"`
+	OpenAPIQuotaCoordination    bool                    `json:"openapi_quota_coordination"` // use the openAPI quota assigned by controlplane
+	OpenAPIAuditLog             string                  `json:"openapi_audit_log"`          // write the mutating openAPI calls to the file
+	OpenAPIAuditEvent           bool                    `json:"openapi_audit_event"`        // emit the mutating openAPI calls as events
+
+	NetworkPriorityClasses   map[types.NetworkPrio]types.NetworkPriorityClass `json:"network_priority_classes,omitempty"` // ingress share of the network priority classes
+	NetworkPriorityBandwidth uint64                                           `json:"network_priority_bandwidth"`         // ingress bandwidth shared by the classes, in bytes per second
+
+	HostStackCIDRs []string `json:"host_stack_cidrs,omitempty"` // cidrs redirect to the host network stack, served to the cni and synced to running pods
 }
 
 func (c *Config) GetSecurityGroups() []string {
@@ -101,7 +110,7 @@ func (c *Config) Populate() {
 
 func (c *Config) Validate() error {
 	switch c.IPStack {
-	case "", string(types.IPStackIPv4), string(types.IPStackDual):
+	case "", string(types.IPStackIPv4), string(types.IPStackIPv6), string(types.IPStackDual):
 	default:
 		return fmt.Errorf("unsupported ipStack %s in configMap", c.IPStack)
 	}
@@ -110,6 +119,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("security groups should not be more than 5, current %d", len(c.SecurityGroups))
 	}
 
+	var rateShare uint32
+	for prio, class := range c.NetworkPriorityClasses {
+		switch prio {
+		case types.NetworkPrioBestEffort, types.NetworkPrioBurstable, types.NetworkPrioGuaranteed:
+		default:
+			return fmt.Errorf("unsupported network priority class %s", prio)
+		}
+		if class.Weight == 0 {
+			return fmt.Errorf("weight of network priority class %s should be positive", prio)
+		}
+		rateShare += class.RateShare
+	}
+	if rateShare > 100 {
+		return fmt.Errorf("rate share of network priority classes should not exceed 100, current %d", rateShare)
+	}
+	if len(c.NetworkPriorityClasses) > 0 && c.NetworkPriorityBandwidth == 0 {
+		return fmt.Errorf("network_priority_bandwidth is required by the network priority classes")
+	}
+
+	for _, cidr := range c.HostStackCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("host_stack_cidrs(%s) is invalid, %w", cidr, err)
+		}
+	}
+
 	return nil
 }
 
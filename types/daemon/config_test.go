@@ -1,11 +1,14 @@
 package daemon
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/types"
 )
 
 func Test_MergeConfigAndUnmarshal(t *testing.T) {
@@ -105,3 +108,50 @@ func TestGetAddonSecret(t *testing.T) {
 	assert.Equal(t, "key", ak)
 	assert.Equal(t, "secret", sk)
 }
+
+func TestConfig_ValidateNetworkPriorityClasses(t *testing.T) {
+	cfg := &Config{}
+	err := json.Unmarshal([]byte(`{
+		"network_priority_classes": {
+			"guaranteed": {"weight": 4, "rate_share": 60},
+			"best-effort": {"weight": 1, "rate_share": 10}
+		},
+		"network_priority_bandwidth": 125000000
+	}`), cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, uint32(60), cfg.NetworkPriorityClasses[types.NetworkPrioGuaranteed].RateShare)
+
+	cfg.NetworkPriorityBandwidth = 0
+	assert.Error(t, cfg.Validate())
+	cfg.NetworkPriorityBandwidth = 125000000
+
+	cfg.NetworkPriorityClasses[types.NetworkPrioBurstable] = types.NetworkPriorityClass{Weight: 2, RateShare: 40}
+	assert.Error(t, cfg.Validate())
+
+	cfg.NetworkPriorityClasses[types.NetworkPrioBurstable] = types.NetworkPriorityClass{RateShare: 30}
+	assert.Error(t, cfg.Validate())
+
+	cfg.NetworkPriorityClasses = map[types.NetworkPrio]types.NetworkPriorityClass{"foo": {Weight: 1}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfig_HostStackCIDRs(t *testing.T) {
+	cfg, err := MergeConfigAndUnmarshal([]byte(`{"host_stack_cidrs":["169.254.20.10/32","fd00::10/128"]}`), []byte(`{"host_stack_cidrs":["169.254.20.10/32"]}`))
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"169.254.20.10/32", "fd00::10/128"}, cfg.HostStackCIDRs)
+
+	cfg.HostStackCIDRs = append(cfg.HostStackCIDRs, "169.254.20.10")
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfig_ValidateIPStack(t *testing.T) {
+	for _, stack := range []string{"", "ipv4", "ipv6", "dual"} {
+		cfg := &Config{IPStack: stack}
+		assert.NoError(t, cfg.Validate(), stack)
+	}
+
+	cfg := &Config{IPStack: "foo"}
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,29 @@
+package daemon
+
+import "time"
+
+const (
+	DataPathMigrationMigrated   = "Migrated"
+	DataPathMigrationFailed     = "Failed"
+	DataPathMigrationRolledBack = "RolledBack"
+)
+
+// DataPathMigration is the datapath change of a running pod
+// NOTE: this is the type store in db
+type DataPathMigration struct {
+	Pod         string    `json:"pod"`
+	ContainerID string    `json:"container_id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	State       string    `json:"state"`
+	Message     string    `json:"message,omitempty"`
+	Time        time.Time `json:"time"`
+	// Chained the confs of the chained plugins the pod is set up with, the endpoints are deleted by them when switched again
+	Chained []map[string]interface{} `json:"chained,omitempty"`
+}
+
+// DataPathMigrationStatus is the reply of the migration status command
+type DataPathMigrationStatus struct {
+	Running bool                `json:"running"`
+	Records []DataPathMigration `json:"records"`
+}
@@ -39,6 +39,8 @@ type PodInfo struct {
 	PodUID          string
 	NetworkPriority string
 	ERdma           bool
+	NAT64           bool
+	EgressGateway   *types.EgressGateway
 }
 
 // ExtraEipInfo store extra eip info
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// EgressGateway SNAT the egress traffic of the pod to the ip reserved for the gateway on the node
+type EgressGateway struct {
+	// Name of the gateway, pods with the same name share the reserved ip on each node
+	Name string `json:"name"`
+	// DestinationCIDRs only the traffic to the ipv4 cidrs is SNATed
+	DestinationCIDRs []string `json:"destinationCIDRs"`
+	// VSwitchID the ip is reserved on the eni in the vSwitch, so the source ip is in the cidr of the vSwitch. Any eni is used if empty
+	VSwitchID string `json:"vSwitchID,omitempty"`
+}
+
+// ParseEgressGateway parse the egress gateway from the annotation, nil if not set
+func ParseEgressGateway(s string) (*EgressGateway, error) {
+	if s == "" {
+		return nil, nil
+	}
+	gw := &EgressGateway{}
+	err := json.Unmarshal([]byte(s), gw)
+	if err != nil {
+		return nil, fmt.Errorf("error parse egress gateway %s, %w", s, err)
+	}
+	err = gw.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return gw, nil
+}
+
+// Validate check the egress gateway is well-formed
+func (g *EgressGateway) Validate() error {
+	if errs := validation.IsDNS1123Label(g.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid egress gateway name %q, %v", g.Name, errs)
+	}
+	if g.VSwitchID != "" && !strings.HasPrefix(g.VSwitchID, "vsw-") {
+		return fmt.Errorf("invalid egress gateway vSwitch %q", g.VSwitchID)
+	}
+	if len(g.DestinationCIDRs) == 0 {
+		return fmt.Errorf("egress gateway %s has no destination cidrs", g.Name)
+	}
+	for _, v := range g.DestinationCIDRs {
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			return fmt.Errorf("invalid egress gateway destination %q", v)
+		}
+		if cidr.IP.To4() == nil {
+			return fmt.Errorf("egress gateway destination %s is not ipv4", v)
+		}
+	}
+	return nil
+}
+
+// ParsedDestinationCIDRs return the destination cidrs, the gateway should be validated
+func (g *EgressGateway) ParsedDestinationCIDRs() []*net.IPNet {
+	var out []*net.IPNet
+	for _, v := range g.DestinationCIDRs {
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			continue
+		}
+		out = append(out, cidr)
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func TestParseEgressGateway(t *testing.T) {
+	gw, err := types.ParseEgressGateway("")
+	assert.NoError(t, err)
+	assert.Nil(t, gw)
+
+	gw, err = types.ParseEgressGateway(`{"name":"partner","destinationCIDRs":["203.0.113.0/24","198.51.100.1/32"]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "partner", gw.Name)
+	cidrs := gw.ParsedDestinationCIDRs()
+	assert.Len(t, cidrs, 2)
+	assert.Equal(t, "203.0.113.0/24", cidrs[0].String())
+
+	gw, err = types.ParseEgressGateway(`{"name":"partner","destinationCIDRs":["203.0.113.0/24"],"vSwitchID":"vsw-1"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-1", gw.VSwitchID)
+
+	for _, s := range []string{
+		`{`,
+		`{"name":"partner"}`,
+		`{"name":"Partner_A","destinationCIDRs":["203.0.113.0/24"]}`,
+		`{"name":"partner","destinationCIDRs":["203.0.113.0"]}`,
+		`{"name":"partner","destinationCIDRs":["2001:db8::/64"]}`,
+		`{"name":"partner","destinationCIDRs":["203.0.113.0/24"],"vSwitchID":"eni-1"}`,
+	} {
+		_, err = types.ParseEgressGateway(s)
+		assert.Error(t, err, s)
+	}
+}
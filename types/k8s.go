@@ -52,6 +52,9 @@ const (
 	// PodUID store pod uid
 	PodUID = AnnotationPrefix + "pod-uid"
 
+	// PodENICreateAttempt the count of eni creation rolled back for the pod, used to generate new client token
+	PodENICreateAttempt = AnnotationPrefix + "eni-create-attempt"
+
 	// NetworkPriority set pod network priority
 	NetworkPriority = AnnotationPrefix + "network-priority"
 
@@ -59,8 +62,29 @@ const (
 	ENIRelatedNodeName = AnnotationPrefix + "node"
 
 	PodIPs = AnnotationPrefix + "pod-ips"
+
+	// PodSecurityGroups comma separated security group ids, for pod using ip from the shared eni
+	PodSecurityGroups = AnnotationPrefix + "pod-security-groups"
+
+	// PodNAT64 set to true to translate the traffic of ipv6 only pod to the nat64 prefix, set by the podNetworking
+	PodNAT64 = AnnotationPrefix + "pod-nat64"
+
+	// NamespaceEgressGateway namespace annotation, the egress gateway of the pods in the namespace in json
+	NamespaceEgressGateway = AnnotationPrefix + "egress-gateway"
+
+	// PodEgressGateway the egress gateway of the pod in json, set by the webhook from the podNetworking or namespace
+	PodEgressGateway = AnnotationPrefix + "pod-egress-gateway"
+
+	// NodeEgressGatewayIPs node annotation, the ip reserved for each egress gateway on the node in json
+	NodeEgressGatewayIPs = AnnotationPrefix + "egress-gateway-ips"
+
+	// NodeENIRelease node annotation, set to true to release the enis on the node before the instance is terminated
+	NodeENIRelease = AnnotationPrefix + "eni-release"
 )
 
+// TaintToBeDeletedByClusterAutoscaler is added by cluster autoscaler before the node is scaled in
+const TaintToBeDeletedByClusterAutoscaler = "ToBeDeletedByClusterAutoscaler"
+
 // labels
 
 const (
@@ -68,6 +92,9 @@ const (
 	IgnoreByTerway = LabelPrefix + "ignore-by-terway"
 
 	ExclusiveENIModeLabel = LabelPrefix + "exclusive-mode-eni-type"
+
+	// NodeFeatureLabelPrefix the kernel features probed on the node, e.g. feature.k8s.aliyun.com/ipvlan_l2=true
+	NodeFeatureLabelPrefix = "feature." + LabelPrefix
 )
 
 // FinalizerPodENI finalizer for podENI resource
@@ -87,6 +114,9 @@ const (
 
 	EventUpdatePodENIFailed = "UpdatePodENIFailed"
 
+	EventUpdateENISecurityGroupSucceed = "UpdateENISecurityGroupSucceed"
+	EventUpdateENISecurityGroupFailed  = "UpdateENISecurityGroupFailed"
+
 	EventSyncPodNetworkingSucceed = "SyncPodNetworkingSucceed"
 	EventSyncPodNetworkingFailed  = "SyncPodNetworkingFailed"
 )
@@ -104,6 +134,18 @@ func PodUseENI(pod *corev1.Pod) bool {
 	return v
 }
 
+// PodSecurityGroupIDs return the security groups requested by the pod, nil if not set
+func PodSecurityGroupIDs(pod *corev1.Pod) []string {
+	var ids []string
+	for _, id := range strings.Split(pod.GetAnnotations()[PodSecurityGroups], ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // IgnoredByTerway for both pods and nodes
 func IgnoredByTerway(labels map[string]string) bool {
 	return labels[IgnoreByTerway] == "true"
@@ -119,6 +161,14 @@ const (
 	NetworkPrioGuaranteed NetworkPrio = "guaranteed"
 )
 
+// NetworkPriorityClass define how a network priority class shares the ingress bandwidth
+type NetworkPriorityClass struct {
+	// Weight the higher weight is served first under contention, and gets more quantum when borrowing
+	Weight uint32 `json:"weight"`
+	// RateShare percent of the ingress bandwidth guaranteed to the class
+	RateShare uint32 `json:"rate_share"`
+}
+
 // PodIPTypeIPs Pod IP address type
 type PodIPTypeIPs string
 
@@ -160,3 +210,16 @@ func NodeExclusiveENIMode(labels map[string]string) ExclusiveENI {
 		return ExclusiveDefault
 	}
 }
+
+// NodeDraining whether the enis on the node should be released, the node is going to be removed
+func NodeDraining(node *corev1.Node) bool {
+	if node.Annotations[NodeENIRelease] == "true" {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == TaintToBeDeletedByClusterAutoscaler {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/AliyunContainerService/terway/types"
 )
@@ -39,3 +41,37 @@ func TestNodeExclusiveENIMode(t *testing.T) {
 		assert.Equal(t, types.ExclusiveENIOnly, result)
 	})
 }
+
+func TestPodSecurityGroupIDs(t *testing.T) {
+	t.Run("Returns nil when annotation is missing", func(t *testing.T) {
+		pod := &corev1.Pod{}
+		assert.Nil(t, types.PodSecurityGroupIDs(pod))
+	})
+
+	t.Run("Ignores spaces and empty items", func(t *testing.T) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					types.PodSecurityGroups: " sg-1, ,sg-2,",
+				},
+			},
+		}
+		assert.Equal(t, []string{"sg-1", "sg-2"}, types.PodSecurityGroupIDs(pod))
+	})
+}
+
+func TestNodeDraining(t *testing.T) {
+	assert.False(t, types.NodeDraining(&corev1.Node{}))
+
+	assert.True(t, types.NodeDraining(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{types.NodeENIRelease: "true"},
+		},
+	}))
+
+	assert.True(t, types.NodeDraining(&corev1.Node{
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{{Key: types.TaintToBeDeletedByClusterAutoscaler, Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}))
+}
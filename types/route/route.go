@@ -1,5 +1,81 @@
 package route
 
+import (
+	"fmt"
+	"net"
+)
+
 type Route struct {
 	Dst string `json:"dst"`
+	// Gateway the next hop, default to the gateway of the interface
+	Gateway string `json:"gateway,omitempty"`
+	Metric  int    `json:"metric,omitempty"`
+	// Table route table, default to main table
+	Table int `json:"table,omitempty"`
+	// Src source address hint
+	Src string `json:"src,omitempty"`
+	MTU int    `json:"mtu,omitempty"`
+}
+
+// Validate check the route is well-formed
+func (r *Route) Validate() error {
+	_, dst, err := net.ParseCIDR(r.Dst)
+	if err != nil {
+		return fmt.Errorf("invalid route dst %q", r.Dst)
+	}
+	v4 := dst.IP.To4() != nil
+	for name, v := range map[string]string{"gateway": r.Gateway, "src": r.Src} {
+		if v == "" {
+			continue
+		}
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return fmt.Errorf("invalid route %s %q", name, v)
+		}
+		if (ip.To4() != nil) != v4 {
+			return fmt.Errorf("route %s %s and dst %s should be the same ip family", name, v, r.Dst)
+		}
+	}
+	if r.Metric < 0 || r.Table < 0 || r.MTU < 0 {
+		return fmt.Errorf("metric, table and mtu of route %s should not be negative", r.Dst)
+	}
+	return nil
+}
+
+// Rule is the policy routing rule, at least one selector of From, To and FwMark is required
+type Rule struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	FwMark   uint32 `json:"fwmark,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	// Table the route table to lookup
+	Table int `json:"table"`
+}
+
+// Validate check the rule is well-formed
+func (r *Rule) Validate() error {
+	if r.From == "" && r.To == "" && r.FwMark == 0 {
+		return fmt.Errorf("rule should have at least one of from, to and fwmark")
+	}
+	var family []bool
+	for name, v := range map[string]string{"from": r.From, "to": r.To} {
+		if v == "" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			return fmt.Errorf("invalid rule %s %q", name, v)
+		}
+		family = append(family, cidr.IP.To4() != nil)
+	}
+	if len(family) == 2 && family[0] != family[1] {
+		return fmt.Errorf("rule from %s and to %s should be the same ip family", r.From, r.To)
+	}
+	if r.Table <= 0 {
+		return fmt.Errorf("rule table should be positive")
+	}
+	if r.Priority < 0 {
+		return fmt.Errorf("rule priority should not be negative")
+	}
+	return nil
 }
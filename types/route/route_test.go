@@ -0,0 +1,28 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute_Validate(t *testing.T) {
+	assert.NoError(t, (&Route{Dst: "192.168.0.0/24"}).Validate())
+	assert.NoError(t, (&Route{Dst: "192.168.0.0/24", Gateway: "10.0.0.1", Src: "10.0.0.10", Metric: 100, Table: 100, MTU: 1400}).Validate())
+	assert.NoError(t, (&Route{Dst: "fd00::/64", Gateway: "fe80::1"}).Validate())
+
+	assert.Error(t, (&Route{Dst: "192.168.0.1"}).Validate())
+	assert.Error(t, (&Route{Dst: "192.168.0.0/24", Gateway: "foo"}).Validate())
+	assert.Error(t, (&Route{Dst: "192.168.0.0/24", Gateway: "fe80::1"}).Validate())
+	assert.Error(t, (&Route{Dst: "192.168.0.0/24", Table: -1}).Validate())
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, (&Rule{From: "192.168.0.0/24", Table: 100}).Validate())
+	assert.NoError(t, (&Rule{FwMark: 0x10, Priority: 300, Table: 100}).Validate())
+
+	assert.Error(t, (&Rule{Table: 100}).Validate())
+	assert.Error(t, (&Rule{To: "192.168.0.0/24"}).Validate())
+	assert.Error(t, (&Rule{From: "192.168.0.0/24", To: "fd00::/64", Table: 100}).Validate())
+	assert.Error(t, (&Rule{From: "foo", Table: 100}).Validate())
+}